@@ -7,13 +7,21 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/espennoreng/go-http-rental-server/internal/api"
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
 	"github.com/espennoreng/go-http-rental-server/internal/config"
+	"github.com/espennoreng/go-http-rental-server/internal/jobs"
 	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/notification"
+	"github.com/espennoreng/go-http-rental-server/internal/outbox"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories/postgres"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/espennoreng/go-http-rental-server/internal/storage"
+	"github.com/espennoreng/go-http-rental-server/internal/webhook"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -44,24 +52,157 @@ func main() {
 	userRepo := postgres.NewUserRepository(dbpool, log)
 	organizationRepo := postgres.NewOrganizationRepository(dbpool, log)
 	organizationUserRepo := postgres.NewOrganizationUserRepository(dbpool, log)
+	idempotencyKeyRepo := postgres.NewIdempotencyKeyRepository(dbpool, log)
+	webhookRepo := postgres.NewWebhookRepository(dbpool, log)
+	outboxRepo := postgres.NewOutboxRepository(dbpool, log)
+	outboxNotifier := postgres.NewOutboxNotifier(dbpool, log)
+	jobRepo := postgres.NewJobRepository(dbpool, log)
+	attachmentRepo := postgres.NewAttachmentRepository(dbpool, log)
+	locationRepo := postgres.NewLocationRepository(dbpool, log)
+	termsRepo := postgres.NewTermsRepository(dbpool, log)
+	customerRepo := postgres.NewCustomerRepository(dbpool, log)
 
 	accessService := services.NewAccessService(organizationUserRepo, log)
+	// Webhooks may not reach internal addresses, and only development
+	// allows plain http receivers.
+	webhookTargets := webhook.TargetPolicy{AllowHTTP: config.Env(os.Getenv("APP_ENV")) == config.Development}
+	webhookService := services.NewWebhookService(webhookRepo, accessService, webhookTargets, log)
+	locationService := services.NewLocationService(locationRepo, accessService, log)
+	termsService := services.NewTermsService(termsRepo, accessService, log)
+	customerService := services.NewCustomerService(customerRepo, accessService, log)
+	publicCatalogService := services.NewPublicCatalogService(organizationRepo, locationRepo, log)
 	organizationUserService := services.NewOrganizationUserService(organizationUserRepo, accessService)
 	userService := services.NewUserService(userRepo, organizationUserRepo, log)
 	organizationService := services.NewOrganizationService(organizationRepo, log)
+	idempotencyService := services.NewIdempotencyService(idempotencyKeyRepo, cfg.IdempotencyTTL, log)
+
+	// In-process subscribers such as event streams receive every published
+	// event, whichever instance published it, via the outbox notifier.
+	eventBus := outbox.NewBus()
+	eventService := services.NewEventService(outboxRepo, eventBus, accessService, log)
+	jobService := services.NewJobService(jobRepo, cfg.AdminUserIDs, log)
+
+	// The job runner is created early because services queue jobs; it is
+	// started once every handler is registered below.
+	jobRunner := jobs.NewRunner(jobRepo, jobs.DefaultOptions(), log)
+
+	notificationTemplates, err := notification.LoadTemplates()
+	if err != nil {
+		log.Error("Could not load notification templates", slog.Any("error", err))
+		os.Exit(1)
+	}
+	var notificationSender notification.Sender = notification.NewLogSender(log)
+	if cfg.Notifications.SMTPAddr != "" {
+		notificationSender, err = notification.NewSMTPSender(notification.SMTPOptions{
+			Addr:     cfg.Notifications.SMTPAddr,
+			Username: cfg.Notifications.SMTPUsername,
+			Password: cfg.Notifications.SMTPPassword,
+			From:     cfg.Notifications.From,
+		})
+		if err != nil {
+			log.Error("Invalid SMTP configuration", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+	notificationService := services.NewNotificationService(userRepo, organizationRepo, jobRunner, notificationTemplates, notificationSender, services.NotificationOptions{
+		BaseURL:           cfg.PublicURL,
+		UnsubscribeSecret: cfg.Notifications.UnsubscribeSecret,
+	}, log)
+
+	blobStore, err := newBlobStore(cfg.Storage)
+	if err != nil {
+		log.Error("Invalid storage configuration", slog.Any("error", err))
+		os.Exit(1)
+	}
+	attachmentService := services.NewAttachmentService(attachmentRepo, blobStore, accessService, services.AttachmentOptions{
+		BaseURL:       cfg.PublicURL,
+		SigningSecret: cfg.Storage.SigningSecret,
+	}, log)
 
 	tokenVerifier := &auth.GoogleTokenVerifier{}
 
 	// 4. Set up the HTTP server
-	server := api.NewServer(cfg, tokenVerifier, log, userService, organizationService, organizationUserService, accessService)
+	server := api.NewServer(cfg, tokenVerifier, log, userService, organizationService, organizationUserService, accessService, idempotencyService, webhookService, eventService, jobService, notificationService, attachmentService, locationService, termsService, customerService, publicCatalogService)
+
+	// 5. Start background workers; they stop when the process is signalled.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Events committed to the outbox are fanned out to the sinks below.
+	outboxDispatcher := outbox.NewDispatcher(outboxRepo, outbox.DefaultOptions(), log)
+	outboxDispatcher.Register("webhooks", webhookService)
+	outboxDispatcher.Register("notify", outboxNotifier)
+	outboxDispatcher.Register("notifications", notificationService)
+	if config.Env(os.Getenv("APP_ENV")) == config.Development {
+		outboxDispatcher.Register("log", outbox.NewLogSink(log))
+	}
 
-	// 5. Start the server using the port from the config
-	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Info("Starting server", slog.String("address", addr))
-	if err := http.ListenAndServe(addr, server); err != nil {
-		log.Error("Could not start server", slog.Any("error", err))
+	go outboxDispatcher.Run(ctx)
+	go outboxNotifier.Listen(ctx, eventBus.Publish)
+	webhookOptions := webhook.DefaultOptions()
+	webhookOptions.Targets = webhookTargets
+	go webhook.NewDispatcher(webhookRepo, webhookOptions, log).Run(ctx)
+
+	// Background jobs. Handlers are registered here so every kind a replica
+	// can enqueue is one it can also run.
+	jobs.Handle(jobRunner, services.NotificationJobKind, notificationService.Deliver)
+	jobs.Handle(jobRunner, "idempotency_keys.purge", func(ctx context.Context, _ struct{}) error {
+		_, err := idempotencyService.PurgeExpired(ctx)
+		return err
+	})
+	if err := jobRunner.Cron("purge-idempotency-keys", "@hourly", "idempotency_keys.purge", struct{}{}); err != nil {
+		log.Error("Invalid job schedule", slog.Any("error", err))
 		os.Exit(1)
 	}
+
+	jobsDone := make(chan struct{})
+	go func() {
+		jobRunner.Run(ctx)
+		close(jobsDone)
+	}()
+
+	// 6. Start the server using the port from the config
+	addr := fmt.Sprintf(":%s", cfg.Port)
+	httpServer := &http.Server{Addr: addr, Handler: server}
+	go func() {
+		log.Info("Starting server", slog.String("address", addr))
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("Could not start server", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}()
+
+	// 7. On shutdown, stop taking requests and let running jobs drain.
+	<-ctx.Done()
+	log.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		// Long-lived connections such as event streams are cut off.
+		log.Warn("Server did not shut down in time, closing connections", slog.Any("error", err))
+		httpServer.Close()
+	}
+	<-jobsDone
+
+	log.Info("Server stopped")
+}
+
+// shutdownTimeout bounds how long in-flight requests may take to finish.
+const shutdownTimeout = 10 * time.Second
+
+// newBlobStore creates the blob store selected in the configuration.
+func newBlobStore(cfg config.StorageConfig) (storage.BlobStore, error) {
+	if cfg.Driver == "s3" {
+		return storage.NewS3Store(storage.S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+	}
+	return storage.NewFileStore(cfg.Path)
 }
 
 // connectToDB establishes a connection to the database, runs migrations,
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+type attachmentHandler struct {
+	attachmentService services.AttachmentService
+	log               *slog.Logger
+}
+
+func NewAttachmentHandler(attachmentService services.AttachmentService, log *slog.Logger) *attachmentHandler {
+	return &attachmentHandler{
+		attachmentService: attachmentService,
+		log:               log.With(slog.String("component", "attachment_handler")),
+	}
+}
+
+// UploadAttachment stores the request body as a file. The file name is
+// given in the filename query parameter and the content type is sniffed,
+// so clients can send any Content-Type.
+func (h *attachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, services.MaxAttachmentSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Warn("Attachment is too large")
+			respondError(w, r, services.ErrAttachmentTooLarge)
+			return
+		}
+		log.Warn("Failed to read attachment", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	attachment, err := h.attachmentService.UploadAttachment(r.Context(), services.UploadAttachmentParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+		Filename:     r.URL.Query().Get("filename"),
+		Content:      content,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to upload attachment", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Attachment uploaded successfully", slog.String("attachment_id", attachment.ID))
+
+	respondJSON(w, http.StatusCreated, NewAttachmentResponse(attachment))
+}
+
+func (h *attachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.attachmentParams(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.attachmentService.GetAttachment(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("attachment_id", params.AttachmentID)), "Failed to fetch attachment", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewAttachmentResponse(attachment))
+}
+
+func (h *attachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.attachmentParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.DeleteAttachment(r.Context(), params); err != nil {
+		logServiceError(h.log.With(slog.String("attachment_id", params.AttachmentID)), "Failed to delete attachment", err)
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DownloadAttachment serves a file from a signed download link. Images are
+// shown inline; anything else is downloaded, and the content is never
+// sniffed or run as a page of this origin.
+func (h *attachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	log := h.log.With(slog.String("attachment_id", chi.URLParam(r, "attachmentID")))
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		log.Warn("Invalid expires parameter in download link", slog.String("expires", query.Get("expires")))
+		respondError(w, r, services.ErrInvalidDownloadLink)
+		return
+	}
+	variant := services.AttachmentVariant(query.Get("variant"))
+	if variant == "" {
+		variant = services.AttachmentOriginal
+	}
+
+	content, err := h.attachmentService.OpenAttachment(r.Context(), services.OpenAttachmentParams{
+		AttachmentID: chi.URLParam(r, "attachmentID"),
+		Variant:      variant,
+		Expires:      expires,
+		Signature:    query.Get("signature"),
+	})
+	if err != nil {
+		logServiceError(log, "Failed to open attachment", err)
+		respondError(w, r, err)
+		return
+	}
+	defer content.Body.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(content.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set(ContentType, content.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(content.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": content.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(max(expires-time.Now().Unix(), 0), 10))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content.Body); err != nil {
+		log.Warn("Failed to send attachment", slog.Any("error", err))
+	}
+}
+
+// attachmentParams reads the acting user and the attachment from the
+// request, writing an error response and returning false if either is
+// missing.
+func (h *attachmentHandler) attachmentParams(w http.ResponseWriter, r *http.Request) (services.AttachmentParams, bool) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return services.AttachmentParams{}, false
+	}
+
+	return services.AttachmentParams{
+		OrgID:        chi.URLParam(r, "orgID"),
+		ActingUserID: identity.UserID,
+		AttachmentID: chi.URLParam(r, "attachmentID"),
+	}, true
+}
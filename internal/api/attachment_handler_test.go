@@ -0,0 +1,177 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAttachmentService struct {
+	uploadAttachmentFunc func(ctx context.Context, params services.UploadAttachmentParams) (*services.SignedAttachment, error)
+	getAttachmentFunc    func(ctx context.Context, params services.AttachmentParams) (*services.SignedAttachment, error)
+	deleteAttachmentFunc func(ctx context.Context, params services.AttachmentParams) error
+	openAttachmentFunc   func(ctx context.Context, params services.OpenAttachmentParams) (*services.AttachmentContent, error)
+}
+
+func (m *mockAttachmentService) UploadAttachment(ctx context.Context, params services.UploadAttachmentParams) (*services.SignedAttachment, error) {
+	return m.uploadAttachmentFunc(ctx, params)
+}
+
+func (m *mockAttachmentService) GetAttachment(ctx context.Context, params services.AttachmentParams) (*services.SignedAttachment, error) {
+	return m.getAttachmentFunc(ctx, params)
+}
+
+func (m *mockAttachmentService) DeleteAttachment(ctx context.Context, params services.AttachmentParams) error {
+	return m.deleteAttachmentFunc(ctx, params)
+}
+
+func (m *mockAttachmentService) OpenAttachment(ctx context.Context, params services.OpenAttachmentParams) (*services.AttachmentContent, error) {
+	return m.openAttachmentFunc(ctx, params)
+}
+
+func TestAttachmentHandler_UploadAttachment(t *testing.T) {
+	identity := auth.Identity{UserID: "member-user"}
+
+	newRouter := func(service services.AttachmentService) chi.Router {
+		handler := api.NewAttachmentHandler(service, logger.NewTestLogger(t))
+		r := chi.NewRouter()
+		r.Method(http.MethodPost, "/organizations/{orgID}/attachments", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.UploadAttachment), identity))
+		return r
+	}
+
+	t.Run("returns signed links", func(t *testing.T) {
+		service := &mockAttachmentService{
+			uploadAttachmentFunc: func(ctx context.Context, params services.UploadAttachmentParams) (*services.SignedAttachment, error) {
+				assert.Equal(t, "org-1", params.OrgID)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				assert.Equal(t, "bike photo.png", params.Filename)
+				assert.Equal(t, []byte("image bytes"), params.Content)
+				return &services.SignedAttachment{
+					Attachment:   &models.Attachment{ID: "att-1", OrgID: params.OrgID, Filename: params.Filename, ContentType: "image/png"},
+					URL:          "https://rental.example.com/files/att-1?signature=a",
+					ThumbnailURL: "https://rental.example.com/files/att-1?variant=thumbnail&signature=b",
+				}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/attachments?filename=bike+photo.png", strings.NewReader("image bytes"))
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		var response api.AttachmentResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, "att-1", response.ID)
+		assert.Equal(t, "image/png", response.ContentType)
+		assert.Contains(t, response.ThumbnailURL, "variant=thumbnail")
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		body := bytes.NewReader(make([]byte, services.MaxAttachmentSize+1))
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/attachments?filename=big.pdf", body)
+		res := httptest.NewRecorder()
+
+		newRouter(&mockAttachmentService{}).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusRequestEntityTooLarge)
+		api.AssertProblemBody(t, res, problem.CodeRequestTooLarge, "too large")
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		service := &mockAttachmentService{
+			uploadAttachmentFunc: func(ctx context.Context, params services.UploadAttachmentParams) (*services.SignedAttachment, error) {
+				return nil, services.ErrUnsupportedAttachmentType
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/attachments?filename=notes.txt", strings.NewReader("hello"))
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusUnsupportedMediaType)
+		api.AssertProblemBody(t, res, problem.CodeUnsupportedMediaType, "PDF")
+	})
+}
+
+func TestAttachmentHandler_DownloadAttachment(t *testing.T) {
+	newRouter := func(service services.AttachmentService) chi.Router {
+		handler := api.NewAttachmentHandler(service, logger.NewTestLogger(t))
+		r := chi.NewRouter()
+		r.Get("/files/{attachmentID}", handler.DownloadAttachment)
+		return r
+	}
+
+	t.Run("streams the file", func(t *testing.T) {
+		expires := time.Now().Add(10 * time.Minute).Unix()
+		service := &mockAttachmentService{
+			openAttachmentFunc: func(ctx context.Context, params services.OpenAttachmentParams) (*services.AttachmentContent, error) {
+				assert.Equal(t, services.OpenAttachmentParams{AttachmentID: "att-1", Variant: services.AttachmentOriginal, Expires: expires, Signature: "sig"}, params)
+				return &services.AttachmentContent{
+					Filename:    "contract ø.pdf",
+					ContentType: "application/pdf",
+					Size:        8,
+					Body:        io.NopCloser(strings.NewReader("%PDF-1.7")),
+				}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/files/att-1?expires="+strconv.FormatInt(expires, 10)+"&signature=sig", nil)
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "%PDF-1.7", res.Body.String())
+		assert.Equal(t, "application/pdf", res.Header().Get("Content-Type"))
+		assert.Equal(t, "8", res.Header().Get("Content-Length"))
+		assert.Equal(t, "nosniff", res.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, `attachment; filename*=utf-8''contract%20%C3%B8.pdf`, res.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("images are shown inline", func(t *testing.T) {
+		service := &mockAttachmentService{
+			openAttachmentFunc: func(ctx context.Context, params services.OpenAttachmentParams) (*services.AttachmentContent, error) {
+				assert.Equal(t, services.AttachmentThumbnail, params.Variant)
+				return &services.AttachmentContent{Filename: "bike-thumbnail.jpg", ContentType: "image/jpeg", Body: io.NopCloser(strings.NewReader(""))}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/files/att-1?variant=thumbnail&expires=1&signature=sig", nil)
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "inline; filename=bike-thumbnail.jpg", res.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("malformed expiry", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/files/att-1?expires=soon&signature=sig", nil)
+		res := httptest.NewRecorder()
+
+		newRouter(&mockAttachmentService{}).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusForbidden)
+		api.AssertProblemBody(t, res, problem.CodeInvalidDownloadLink, "expired")
+	})
+}
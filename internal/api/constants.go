@@ -1,6 +1,12 @@
 package api
 
+import "github.com/espennoreng/go-http-rental-server/internal/problem"
+
 const (
-	ContentTypeJSON = "application/json"
-	ContentType     = "Content-Type" // This is a constant for the Content-Type header key.
+	ContentTypeJSON        = "application/json"
+	ContentTypeProblemJSON = problem.ContentType
+	ContentTypeCSV         = "text/csv"
+	ContentTypeNDJSON      = "application/x-ndjson"
+	ContentTypeEventStream = "text/event-stream"
+	ContentType            = "Content-Type" // This is a constant for the Content-Type header key.
 )
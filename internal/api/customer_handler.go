@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+type customerHandler struct {
+	customerService services.CustomerService
+	log             *slog.Logger
+}
+
+func NewCustomerHandler(customerService services.CustomerService, log *slog.Logger) *customerHandler {
+	return &customerHandler{
+		customerService: customerService,
+		log:             log.With(slog.String("component", "customer_handler")),
+	}
+}
+
+func (h *customerHandler) RegisterCustomer(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.ownCustomerParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	input, ok := h.decodeProfile(w, r, log)
+	if !ok {
+		return
+	}
+
+	customer, err := h.customerService.RegisterCustomer(r.Context(), services.RegisterCustomerParams{
+		OrgID:           params.OrgID,
+		ActingUserID:    params.ActingUserID,
+		CustomerProfile: input.profile(),
+	})
+	if err != nil {
+		logServiceError(log, "Failed to register customer", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Customer registered successfully", slog.String("customer_id", customer.ID))
+
+	setETag(w, customer.Version)
+	respondJSON(w, http.StatusCreated, NewCustomerResponse(customer))
+}
+
+func (h *customerHandler) GetOwnCustomer(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.ownCustomerParams(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err := h.customerService.GetOwnCustomer(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", params.OrgID)), "Failed to fetch own customer", err)
+		respondError(w, r, err)
+		return
+	}
+
+	setETag(w, customer.Version)
+	respondJSON(w, http.StatusOK, NewCustomerResponse(customer))
+}
+
+func (h *customerHandler) UpdateOwnCustomer(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.ownCustomerParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		log.Warn("Missing or invalid If-Match header for customer update", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	input, ok := h.decodeProfile(w, r, log)
+	if !ok {
+		return
+	}
+
+	customer, err := h.customerService.UpdateOwnCustomer(r.Context(), services.UpdateOwnCustomerParams{
+		OwnCustomerParams: params,
+		CustomerProfile:   input.profile(),
+		Version:           version,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to update own customer", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Customer profile updated successfully", slog.Int("version", customer.Version))
+
+	setETag(w, customer.Version)
+	respondJSON(w, http.StatusOK, NewCustomerResponse(customer))
+}
+
+func (h *customerHandler) ListCustomers(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.ownCustomerParams(w, r)
+	if !ok {
+		return
+	}
+
+	customers, err := h.customerService.ListCustomers(r.Context(), services.ListCustomersParams{
+		OrgID:        params.OrgID,
+		ActingUserID: params.ActingUserID,
+	})
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", params.OrgID)), "Failed to list customers", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewCustomersResponse(customers))
+}
+
+func (h *customerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.customerParams(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err := h.customerService.GetCustomer(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("customer_id", params.CustomerID)), "Failed to fetch customer", err)
+		respondError(w, r, err)
+		return
+	}
+
+	setETag(w, customer.Version)
+	respondJSON(w, http.StatusOK, NewCustomerResponse(customer))
+}
+
+func (h *customerHandler) SetCustomerVerification(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.customerParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("customer_id", params.CustomerID))
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		log.Warn("Missing or invalid If-Match header for customer verification", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	var input SetCustomerVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		log.Warn("Validation failed for customer verification", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	customer, err := h.customerService.SetCustomerVerification(r.Context(), services.SetCustomerVerificationParams{
+		CustomerParams: params,
+		Status:         input.Status,
+		Version:        version,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to set customer verification", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Customer verification updated successfully", slog.String("status", string(customer.VerificationStatus)))
+
+	setETag(w, customer.Version)
+	respondJSON(w, http.StatusOK, NewCustomerResponse(customer))
+}
+
+// decodeProfile reads the request body, writing an error response and
+// returning false if it is invalid. The profile is validated by the
+// service.
+func (h *customerHandler) decodeProfile(w http.ResponseWriter, r *http.Request, log *slog.Logger) (*CustomerProfileRequest, bool) {
+	var input CustomerProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return nil, false
+	}
+	return &input, true
+}
+
+// ownCustomerParams reads the acting user and the organization from the
+// request, writing an error response and returning false if the user is
+// missing.
+func (h *customerHandler) ownCustomerParams(w http.ResponseWriter, r *http.Request) (services.OwnCustomerParams, bool) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return services.OwnCustomerParams{}, false
+	}
+
+	return services.OwnCustomerParams{
+		OrgID:        chi.URLParam(r, "orgID"),
+		ActingUserID: identity.UserID,
+	}, true
+}
+
+// customerParams reads the acting user and the customer from the request,
+// writing an error response and returning false if the user is missing.
+func (h *customerHandler) customerParams(w http.ResponseWriter, r *http.Request) (services.CustomerParams, bool) {
+	own, ok := h.ownCustomerParams(w, r)
+	if !ok {
+		return services.CustomerParams{}, false
+	}
+
+	return services.CustomerParams{
+		OrgID:        own.OrgID,
+		ActingUserID: own.ActingUserID,
+		CustomerID:   chi.URLParam(r, "customerID"),
+	}, true
+}
@@ -0,0 +1,184 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCustomerService struct {
+	registerCustomerFunc        func(ctx context.Context, params services.RegisterCustomerParams) (*models.Customer, error)
+	getOwnCustomerFunc          func(ctx context.Context, params services.OwnCustomerParams) (*models.Customer, error)
+	updateOwnCustomerFunc       func(ctx context.Context, params services.UpdateOwnCustomerParams) (*models.Customer, error)
+	listCustomersFunc           func(ctx context.Context, params services.ListCustomersParams) ([]*models.Customer, error)
+	getCustomerFunc             func(ctx context.Context, params services.CustomerParams) (*models.Customer, error)
+	setCustomerVerificationFunc func(ctx context.Context, params services.SetCustomerVerificationParams) (*models.Customer, error)
+}
+
+func (m *mockCustomerService) RegisterCustomer(ctx context.Context, params services.RegisterCustomerParams) (*models.Customer, error) {
+	return m.registerCustomerFunc(ctx, params)
+}
+
+func (m *mockCustomerService) GetOwnCustomer(ctx context.Context, params services.OwnCustomerParams) (*models.Customer, error) {
+	return m.getOwnCustomerFunc(ctx, params)
+}
+
+func (m *mockCustomerService) UpdateOwnCustomer(ctx context.Context, params services.UpdateOwnCustomerParams) (*models.Customer, error) {
+	return m.updateOwnCustomerFunc(ctx, params)
+}
+
+func (m *mockCustomerService) ListCustomers(ctx context.Context, params services.ListCustomersParams) ([]*models.Customer, error) {
+	return m.listCustomersFunc(ctx, params)
+}
+
+func (m *mockCustomerService) GetCustomer(ctx context.Context, params services.CustomerParams) (*models.Customer, error) {
+	return m.getCustomerFunc(ctx, params)
+}
+
+func (m *mockCustomerService) SetCustomerVerification(ctx context.Context, params services.SetCustomerVerificationParams) (*models.Customer, error) {
+	return m.setCustomerVerificationFunc(ctx, params)
+}
+
+func newCustomerRouter(t *testing.T, service services.CustomerService, identity auth.Identity) chi.Router {
+	handler := api.NewCustomerHandler(service, logger.NewTestLogger(t))
+	r := chi.NewRouter()
+	r.Method(http.MethodPost, "/organizations/{orgID}/customers", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.RegisterCustomer), identity))
+	r.Method(http.MethodPut, "/organizations/{orgID}/customers/me", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.UpdateOwnCustomer), identity))
+	r.Method(http.MethodPut, "/organizations/{orgID}/customers/{customerID}/verification", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.SetCustomerVerification), identity))
+	return r
+}
+
+func TestCustomerHandler_RegisterCustomer(t *testing.T) {
+	identity := auth.Identity{UserID: "renter-user"}
+
+	t.Run("success", func(t *testing.T) {
+		service := &mockCustomerService{
+			registerCustomerFunc: func(ctx context.Context, params services.RegisterCustomerParams) (*models.Customer, error) {
+				assert.Equal(t, "org-1", params.OrgID)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				assert.Equal(t, "Oslo", params.Address.City)
+				return &models.Customer{
+					ID: "customer-1", OrgID: params.OrgID, UserID: params.ActingUserID,
+					Phone: params.Phone, Address: params.Address,
+					VerificationStatus: models.VerificationUnverified, Version: 1,
+				}, nil
+			},
+		}
+
+		body := `{"phone": "+4791234567", "address": {"street": "Storgata 1", "city": "Oslo", "country": "NO"}}`
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/customers", strings.NewReader(body))
+		res := httptest.NewRecorder()
+
+		newCustomerRouter(t, service, identity).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, `"1"`, res.Header().Get("ETag"))
+		var response api.CustomerResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, "+4791234567", response.Phone)
+		assert.Equal(t, models.VerificationUnverified, response.VerificationStatus)
+		require.NotNil(t, response.Address)
+		assert.Equal(t, "Oslo", response.Address.City)
+	})
+
+	t.Run("already a customer", func(t *testing.T) {
+		service := &mockCustomerService{
+			registerCustomerFunc: func(ctx context.Context, params services.RegisterCustomerParams) (*models.Customer, error) {
+				return nil, services.ErrCustomerExists
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/customers", strings.NewReader(`{}`))
+		res := httptest.NewRecorder()
+
+		newCustomerRouter(t, service, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusConflict)
+		api.AssertProblemBody(t, res, problem.CodeCustomerExists, "already a customer")
+	})
+}
+
+func TestCustomerHandler_UpdateOwnCustomer(t *testing.T) {
+	identity := auth.Identity{UserID: "renter-user"}
+
+	t.Run("requires If-Match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/organizations/org-1/customers/me", strings.NewReader(`{"phone": "+4791234567"}`))
+		res := httptest.NewRecorder()
+
+		newCustomerRouter(t, &mockCustomerService{}, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusPreconditionRequired)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		service := &mockCustomerService{
+			updateOwnCustomerFunc: func(ctx context.Context, params services.UpdateOwnCustomerParams) (*models.Customer, error) {
+				assert.Equal(t, 2, params.Version)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				return &models.Customer{ID: "customer-1", Phone: params.Phone, Version: 3}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/organizations/org-1/customers/me", strings.NewReader(`{"phone": "+4791234567"}`))
+		req.Header.Set("If-Match", `"2"`)
+		res := httptest.NewRecorder()
+
+		newCustomerRouter(t, service, identity).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"3"`, res.Header().Get("ETag"))
+		var response api.CustomerResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Nil(t, response.Address)
+	})
+}
+
+func TestCustomerHandler_SetCustomerVerification(t *testing.T) {
+	identity := auth.Identity{UserID: "admin-user"}
+
+	t.Run("missing status", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/organizations/org-1/customers/customer-1/verification", strings.NewReader(`{}`))
+		req.Header.Set("If-Match", `"1"`)
+		res := httptest.NewRecorder()
+
+		newCustomerRouter(t, &mockCustomerService{}, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusBadRequest)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "status is required")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		service := &mockCustomerService{
+			setCustomerVerificationFunc: func(ctx context.Context, params services.SetCustomerVerificationParams) (*models.Customer, error) {
+				assert.Equal(t, "customer-1", params.CustomerID)
+				assert.Equal(t, models.VerificationVerified, params.Status)
+				return &models.Customer{ID: params.CustomerID, VerificationStatus: params.Status, Version: 2}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/organizations/org-1/customers/customer-1/verification", strings.NewReader(`{"status": "verified"}`))
+		req.Header.Set("If-Match", `"1"`)
+		res := httptest.NewRecorder()
+
+		newCustomerRouter(t, service, identity).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var response api.CustomerResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, models.VerificationVerified, response.VerificationStatus)
+	})
+}
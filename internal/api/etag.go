@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+)
+
+const (
+	headerETag         = "ETag"
+	headerIfMatch      = "If-Match"
+	headerIfNoneMatch  = "If-None-Match"
+	headerCacheControl = "Cache-Control"
+)
+
+// errIfMatchRequired builds a new Problem on each call, since writing a
+// Problem fills in the request's instance and request ID.
+func errIfMatchRequired() *problem.Problem {
+	return problem.New(http.StatusPreconditionRequired, problem.CodePreconditionRequired, "If-Match header is required; fetch the resource to obtain its ETag")
+}
+
+// formatETag renders a resource version as a strong entity tag.
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag adds the ETag header for a resource version to the response.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set(headerETag, formatETag(version))
+}
+
+// versionFromIfMatch reads the version a client expects to modify from the
+// If-Match header. "*" matches any version. Weak or malformed tags can never
+// match under the strong comparison If-Match requires, so they are reported
+// as a version mismatch.
+func versionFromIfMatch(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get(headerIfMatch))
+	if header == "" {
+		return 0, errIfMatchRequired()
+	}
+	if header == "*" {
+		return services.AnyVersion, nil
+	}
+
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if !ok || err != nil || version <= 0 {
+		return 0, services.ErrVersionMismatch
+	}
+	return version, nil
+}
@@ -0,0 +1,41 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingIfMatch_ReportsEachRequest(t *testing.T) {
+	router := newCustomerRouter(t, &mockCustomerService{}, auth.Identity{UserID: "admin-user"})
+	handler := middleware.RequestID(router)
+
+	requestIDs := map[string]bool{}
+	for _, path := range []string{
+		"/organizations/org-1/customers/me",
+		"/organizations/org-2/customers/customer-1/verification",
+	} {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"status": "verified"}`))
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusPreconditionRequired, res.Code)
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, problem.CodePreconditionRequired, body["code"])
+		assert.Equal(t, path, body["instance"])
+		requestID, _ := body["request_id"].(string)
+		require.NotEmpty(t, requestID)
+		requestIDs[requestID] = true
+	}
+	assert.Len(t, requestIDs, 2)
+}
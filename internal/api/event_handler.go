@@ -0,0 +1,216 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	headerLastEventID = "Last-Event-ID"
+
+	// heartbeatInterval keeps idle streams alive through proxies
+	// that close quiet connections.
+	heartbeatInterval = 15 * time.Second
+	// eventWriteTimeout bounds how long a slow client may block a write
+	// before its stream is closed.
+	eventWriteTimeout = 10 * time.Second
+	// eventRetryMillis is the reconnect delay suggested to clients.
+	eventRetryMillis = 3000
+)
+
+type eventHandler struct {
+	eventService services.EventService
+	log          *slog.Logger
+}
+
+func NewEventHandler(eventService services.EventService, log *slog.Logger) *eventHandler {
+	return &eventHandler{
+		eventService: eventService,
+		log:          log.With(slog.String("component", "event_handler")),
+	}
+}
+
+// StreamEvents streams the organization's events as server-sent events.
+// Each event's id is its position, so a client reconnecting with
+// Last-Event-ID first receives the stored events it missed. A client that
+// cannot keep up is disconnected and catches up the same way.
+//
+// Events are always sent from storage in position order. Live events
+// only signal that there is more to read: positions follow commit order
+// but live events need not, so sending them as they arrive could skip an
+// event that committed just before them.
+func (h *eventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+
+	var lastPosition int64
+	resume := false
+	if header := strings.TrimSpace(r.Header.Get(headerLastEventID)); header != "" {
+		lastPosition, err = strconv.ParseInt(header, 10, 64)
+		if err != nil || lastPosition < 0 {
+			log.Warn("Invalid Last-Event-ID header", slog.String("last_event_id", header))
+			respondError(w, r, services.NewValidationError(headerLastEventID, "must be the id of a received event"))
+			return
+		}
+		resume = true
+	}
+
+	// Subscribe before catching up so no event falls between the two.
+	sub, err := h.eventService.SubscribeEvents(r.Context(), services.SubscribeEventsParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to subscribe to events", err)
+		respondError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	if !resume {
+		lastPosition = sub.Start()
+	}
+	list := func() ([]*models.Event, error) {
+		return h.eventService.ListEvents(r.Context(), services.ListEventsParams{
+			OrgID:         orgID,
+			ActingUserID:  identity.UserID,
+			AfterPosition: lastPosition,
+		})
+	}
+
+	var missed []*models.Event
+	if resume {
+		// Load the first page before sending headers so access errors
+		// can still be reported as a problem response.
+		missed, err = list()
+		if err != nil {
+			logServiceError(log, "Failed to list missed events", err)
+			respondError(w, r, err)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// Clear the per-write deadline so it does not outlive the stream on a
+	// kept-alive connection.
+	defer rc.SetWriteDeadline(time.Time{})
+	w.Header().Set(ContentType, ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Ask reverse proxies such as nginx not to buffer the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, rc: rc}
+	if err := stream.write(fmt.Sprintf("retry: %d\n\n", eventRetryMillis)); err != nil {
+		log.Warn("Failed to start event stream", slog.Any("error", err))
+		return
+	}
+
+	log.Info("Event stream opened", slog.Bool("resumed", resume), slog.Int64("last_position", lastPosition))
+
+	// sendStored sends events, which are a page of stored events, and
+	// then every further page. It reports whether the stream is still
+	// usable.
+	sendStored := func(events []*models.Event) bool {
+		for {
+			for _, event := range events {
+				if err := stream.send(event); err != nil {
+					log.Info("Event stream closed", slog.Any("error", err))
+					return false
+				}
+				lastPosition = event.Position
+			}
+			if len(events) < services.MaxListedEvents {
+				return true
+			}
+			if events, err = list(); err != nil {
+				logServiceError(log, "Failed to list events", err)
+				return false
+			}
+		}
+	}
+
+	if !sendStored(missed) {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Info("Event stream closed by client")
+			return
+
+		case <-heartbeat.C:
+			if err := stream.write(": heartbeat\n\n"); err != nil {
+				log.Info("Event stream closed", slog.Any("error", err))
+				return
+			}
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The client reconnects with Last-Event-ID and catches
+				// up from the stored events.
+				log.Warn("Event stream fell behind, disconnecting", slog.Bool("lagged", sub.Lagged()))
+				return
+			}
+			// Events already sent while catching up arrive again live.
+			if event.Position <= lastPosition {
+				continue
+			}
+			events, err := list()
+			if err != nil {
+				logServiceError(log, "Failed to list events", err)
+				return
+			}
+			if !sendStored(events) {
+				return
+			}
+			heartbeat.Reset(heartbeatInterval)
+		}
+	}
+}
+
+// eventStream writes server-sent events, flushing each one and giving up
+// on clients that stop reading.
+type eventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *eventStream) send(event *models.Event) error {
+	data, err := json.Marshal(NewEventResponse(event))
+	if err != nil {
+		return err
+	}
+	// JSON encoding never produces newlines, so data fits on one line.
+	return s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.Position, event.Type, data))
+}
+
+func (s *eventStream) write(frame string) error {
+	// Not every writer supports deadlines; flushing still applies.
+	_ = s.rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+	if _, err := fmt.Fprint(s.w, frame); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,212 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEventService struct {
+	listEventsFunc      func(ctx context.Context, params services.ListEventsParams) ([]*models.Event, error)
+	subscribeEventsFunc func(ctx context.Context, params services.SubscribeEventsParams) (*services.EventSubscription, error)
+}
+
+func (m *mockEventService) ListEvents(ctx context.Context, params services.ListEventsParams) ([]*models.Event, error) {
+	return m.listEventsFunc(ctx, params)
+}
+
+func (m *mockEventService) SubscribeEvents(ctx context.Context, params services.SubscribeEventsParams) (*services.EventSubscription, error) {
+	return m.subscribeEventsFunc(ctx, params)
+}
+
+func newEventsTestServer(t *testing.T, service services.EventService) *httptest.Server {
+	handler := api.NewEventHandler(service, logger.NewTestLogger(t))
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/organizations/{orgID}/events", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.StreamEvents), auth.Identity{UserID: "member-user"}))
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readEvent reads the next server-sent event, skipping comments and
+// retry frames, and returns its fields.
+func readEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if _, ok := fields["id"]; ok {
+				return fields
+			}
+			fields = map[string]string{}
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+}
+
+// storedEvents is an event log that ListEvents reads by position.
+type storedEvents struct {
+	mu     sync.Mutex
+	events []*models.Event
+}
+
+func (s *storedEvents) add(events ...*models.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+}
+
+func (s *storedEvents) list(ctx context.Context, params services.ListEventsParams) ([]*models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []*models.Event
+	for _, event := range s.events {
+		if event.OrgID == params.OrgID && event.Position > params.AfterPosition {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestEventHandler_StreamEvents(t *testing.T) {
+	t.Run("replays missed events and then streams live ones", func(t *testing.T) {
+		stored := &storedEvents{}
+		stored.add(
+			&models.Event{Position: 3, ID: "e3", OrgID: "org-1", Type: models.EventMemberAdded},
+			&models.Event{Position: 5, ID: "e5", OrgID: "org-1", Type: models.EventMemberAdded},
+			&models.Event{Position: 6, ID: "e6", OrgID: "org-1", Type: models.EventMemberRemoved},
+		)
+		sub, send := services.NewEventSubscription(0, 8)
+		// Position 6 is published live while it is also being replayed.
+		send(models.Event{Position: 6, ID: "e6", OrgID: "org-1"})
+
+		service := &mockEventService{
+			subscribeEventsFunc: func(ctx context.Context, params services.SubscribeEventsParams) (*services.EventSubscription, error) {
+				assert.Equal(t, "org-1", params.OrgID)
+				assert.Equal(t, "member-user", params.ActingUserID)
+				return sub, nil
+			},
+			listEventsFunc: stored.list,
+		}
+		server := newEventsTestServer(t, service)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/organizations/org-1/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "4")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, api.ContentTypeEventStream, res.Header.Get(api.ContentType))
+
+		reader := bufio.NewReader(res.Body)
+		assert.Equal(t, "5", readEvent(t, reader)["id"])
+		assert.Equal(t, "6", readEvent(t, reader)["id"])
+
+		// Position 8 is announced before 7, which committed first. Both
+		// are sent in position order and the late announcement of 7 is
+		// skipped.
+		stored.add(
+			&models.Event{Position: 7, ID: "e7", OrgID: "org-1", Type: models.EventMemberAdded, Data: json.RawMessage(`{"user_id":"u7"}`)},
+			&models.Event{Position: 8, ID: "e8", OrgID: "org-1", Type: models.EventMemberRoleChanged, Data: json.RawMessage(`{"user_id":"u8","role":"admin"}`)},
+		)
+		send(models.Event{Position: 8, ID: "e8", OrgID: "org-1"})
+		send(models.Event{Position: 7, ID: "e7", OrgID: "org-1"})
+
+		assert.Equal(t, "7", readEvent(t, reader)["id"])
+		event := readEvent(t, reader)
+		assert.Equal(t, "8", event["id"])
+		assert.Equal(t, string(models.EventMemberRoleChanged), event["event"])
+
+		var data api.EventResponse
+		require.NoError(t, json.Unmarshal([]byte(event["data"]), &data))
+		assert.Equal(t, "e8", data.ID)
+		assert.Equal(t, "org-1", data.OrganizationID)
+		assert.Equal(t, map[string]any{"user_id": "u8", "role": "admin"}, data.Data)
+	})
+
+	t.Run("starts after the subscription's position", func(t *testing.T) {
+		stored := &storedEvents{}
+		stored.add(
+			&models.Event{Position: 1, ID: "e1", OrgID: "org-1"},
+			&models.Event{Position: 2, ID: "e2", OrgID: "org-1"},
+		)
+		sub, send := services.NewEventSubscription(1, 1)
+		send(models.Event{Position: 2, OrgID: "org-1"})
+		// Overflowing the buffer cuts the subscriber off.
+		send(models.Event{Position: 3, OrgID: "org-1"})
+
+		service := &mockEventService{
+			subscribeEventsFunc: func(ctx context.Context, params services.SubscribeEventsParams) (*services.EventSubscription, error) {
+				return sub, nil
+			},
+			listEventsFunc: stored.list,
+		}
+		server := newEventsTestServer(t, service)
+
+		res, err := http.Get(server.URL + "/organizations/org-1/events")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "id: 1\n")
+		assert.Contains(t, string(body), "id: 2\n")
+	})
+
+	t.Run("rejects an invalid Last-Event-ID", func(t *testing.T) {
+		server := newEventsTestServer(t, &mockEventService{})
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/organizations/org-1/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "not-a-number")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("reports access errors as problems", func(t *testing.T) {
+		service := &mockEventService{
+			subscribeEventsFunc: func(ctx context.Context, params services.SubscribeEventsParams) (*services.EventSubscription, error) {
+				return nil, services.ErrUnauthorized
+			},
+		}
+		server := newEventsTestServer(t, service)
+
+		res, err := http.Get(server.URL + "/organizations/org-1/events")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Equal(t, api.ContentTypeProblemJSON, res.Header.Get(api.ContentType))
+	})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+type jobHandler struct {
+	jobService services.JobService
+	log        *slog.Logger
+}
+
+func NewJobHandler(jobService services.JobService, log *slog.Logger) *jobHandler {
+	return &jobHandler{
+		jobService: jobService,
+		log:        log.With(slog.String("component", "job_handler")),
+	}
+}
+
+func (h *jobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	jobs, err := h.jobService.ListJobs(r.Context(), services.ListJobsParams{
+		ActingUserID: identity.UserID,
+		Status:       models.JobStatus(query.Get("status")),
+		Kind:         query.Get("kind"),
+	})
+	if err != nil {
+		logServiceError(h.log.With(slog.String("acting_user_id", identity.UserID)), "Failed to list jobs", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewJobsResponse(jobs))
+}
+
+func (h *jobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.jobParams(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobService.GetJob(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("job_id", params.JobID)), "Failed to fetch job", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewJobResponse(job))
+}
+
+func (h *jobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.jobParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("job_id", params.JobID))
+
+	job, err := h.jobService.RetryJob(r.Context(), params)
+	if err != nil {
+		logServiceError(log, "Failed to retry job", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Job queued for retry")
+
+	respondJSON(w, http.StatusAccepted, NewJobResponse(job))
+}
+
+// jobParams reads the acting user and the job from the request, writing an
+// error response and returning false if the user is missing.
+func (h *jobHandler) jobParams(w http.ResponseWriter, r *http.Request) (services.JobParams, bool) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return services.JobParams{}, false
+	}
+
+	return services.JobParams{
+		ActingUserID: identity.UserID,
+		JobID:        chi.URLParam(r, "jobID"),
+	}, true
+}
@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockJobService struct {
+	listJobsFunc func(ctx context.Context, params services.ListJobsParams) ([]*models.Job, error)
+	getJobFunc   func(ctx context.Context, params services.JobParams) (*models.Job, error)
+	retryJobFunc func(ctx context.Context, params services.JobParams) (*models.Job, error)
+}
+
+func (m *mockJobService) ListJobs(ctx context.Context, params services.ListJobsParams) ([]*models.Job, error) {
+	return m.listJobsFunc(ctx, params)
+}
+
+func (m *mockJobService) GetJob(ctx context.Context, params services.JobParams) (*models.Job, error) {
+	return m.getJobFunc(ctx, params)
+}
+
+func (m *mockJobService) RetryJob(ctx context.Context, params services.JobParams) (*models.Job, error) {
+	return m.retryJobFunc(ctx, params)
+}
+
+func newJobsTestRouter(t *testing.T, service services.JobService) chi.Router {
+	handler := api.NewJobHandler(service, logger.NewTestLogger(t))
+	identity := auth.Identity{UserID: "operator"}
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/admin/jobs", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.ListJobs), identity))
+	r.Method(http.MethodPost, "/admin/jobs/{jobID}/retry", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.RetryJob), identity))
+	return r
+}
+
+func TestJobHandler_ListJobs(t *testing.T) {
+	service := &mockJobService{
+		listJobsFunc: func(ctx context.Context, params services.ListJobsParams) ([]*models.Job, error) {
+			assert.Equal(t, "operator", params.ActingUserID)
+			assert.Equal(t, models.JobDead, params.Status)
+			assert.Equal(t, "idempotency_keys.purge", params.Kind)
+			return []*models.Job{{
+				ID:        "job-1",
+				Kind:      params.Kind,
+				Payload:   json.RawMessage(`{"batch":3}`),
+				Status:    models.JobDead,
+				LastError: "db down",
+			}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs?status=dead&kind=idempotency_keys.purge", nil)
+	res := httptest.NewRecorder()
+
+	newJobsTestRouter(t, service).ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	var body struct {
+		Jobs []map[string]any `json:"jobs"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	require.Len(t, body.Jobs, 1)
+	assert.Equal(t, "dead", body.Jobs[0]["status"])
+	assert.Equal(t, map[string]any{"batch": float64(3)}, body.Jobs[0]["payload"])
+	assert.Equal(t, "db down", body.Jobs[0]["last_error"])
+}
+
+func TestJobHandler_RetryJob(t *testing.T) {
+	t.Run("queues the job", func(t *testing.T) {
+		service := &mockJobService{
+			retryJobFunc: func(ctx context.Context, params services.JobParams) (*models.Job, error) {
+				assert.Equal(t, "job-1", params.JobID)
+				return &models.Job{ID: params.JobID, Status: models.JobPending, Payload: json.RawMessage(`{}`)}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/jobs/job-1/retry", nil)
+		res := httptest.NewRecorder()
+
+		newJobsTestRouter(t, service).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusAccepted, res.Code)
+	})
+
+	t.Run("rejects jobs that are not dead", func(t *testing.T) {
+		service := &mockJobService{
+			retryJobFunc: func(ctx context.Context, params services.JobParams) (*models.Job, error) {
+				return nil, services.ErrJobNotRetryable
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/jobs/job-1/retry", nil)
+		res := httptest.NewRecorder()
+
+		newJobsTestRouter(t, service).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+		var p problem.Problem
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&p))
+		assert.Equal(t, problem.CodeJobNotRetryable, p.Code)
+	})
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+type locationHandler struct {
+	locationService services.LocationService
+	log             *slog.Logger
+}
+
+func NewLocationHandler(locationService services.LocationService, log *slog.Logger) *locationHandler {
+	return &locationHandler{
+		locationService: locationService,
+		log:             log.With(slog.String("component", "location_handler")),
+	}
+}
+
+func (h *locationHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+
+	input, ok := h.decodeLocation(w, r, log)
+	if !ok {
+		return
+	}
+
+	location, err := h.locationService.CreateLocation(r.Context(), services.CreateLocationParams{
+		OrgID:           orgID,
+		ActingUserID:    identity.UserID,
+		LocationDetails: input.details(),
+	})
+	if err != nil {
+		logServiceError(log, "Failed to create location", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Location created successfully", slog.String("location_id", location.ID))
+
+	setETag(w, location.Version)
+	respondJSON(w, http.StatusCreated, NewLocationResponse(location))
+}
+
+func (h *locationHandler) ListLocations(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+
+	locations, err := h.locationService.ListLocations(r.Context(), services.ListLocationsParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+	})
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", orgID)), "Failed to list locations", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewLocationsResponse(locations))
+}
+
+func (h *locationHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.locationParams(w, r)
+	if !ok {
+		return
+	}
+
+	location, err := h.locationService.GetLocation(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("location_id", params.LocationID)), "Failed to fetch location", err)
+		respondError(w, r, err)
+		return
+	}
+
+	setETag(w, location.Version)
+	respondJSON(w, http.StatusOK, NewLocationResponse(location))
+}
+
+func (h *locationHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.locationParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("location_id", params.LocationID))
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		log.Warn("Missing or invalid If-Match header for location update", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	input, ok := h.decodeLocation(w, r, log)
+	if !ok {
+		return
+	}
+
+	location, err := h.locationService.UpdateLocation(r.Context(), services.UpdateLocationParams{
+		LocationParams:  params,
+		LocationDetails: input.details(),
+		Version:         version,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to update location", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Location updated successfully", slog.Int("version", location.Version))
+
+	setETag(w, location.Version)
+	respondJSON(w, http.StatusOK, NewLocationResponse(location))
+}
+
+func (h *locationHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.locationParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("location_id", params.LocationID))
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		log.Warn("Missing or invalid If-Match header for location deletion", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	if err := h.locationService.DeleteLocation(r.Context(), services.DeleteLocationParams{LocationParams: params, Version: version}); err != nil {
+		logServiceError(log, "Failed to delete location", err)
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeLocation reads and validates the request body, writing an error
+// response and returning false if it is invalid.
+func (h *locationHandler) decodeLocation(w http.ResponseWriter, r *http.Request, log *slog.Logger) (*LocationRequest, bool) {
+	var input LocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return nil, false
+	}
+
+	if err := input.Validate(); err != nil {
+		log.Warn("Validation failed for location", slog.Any("error", err))
+		respondError(w, r, err)
+		return nil, false
+	}
+
+	return &input, true
+}
+
+// locationParams reads the acting user and the location from the request,
+// writing an error response and returning false if either is missing.
+func (h *locationHandler) locationParams(w http.ResponseWriter, r *http.Request) (services.LocationParams, bool) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return services.LocationParams{}, false
+	}
+
+	return services.LocationParams{
+		OrgID:        chi.URLParam(r, "orgID"),
+		ActingUserID: identity.UserID,
+		LocationID:   chi.URLParam(r, "locationID"),
+	}, true
+}
@@ -0,0 +1,160 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLocationService struct {
+	createLocationFunc func(ctx context.Context, params services.CreateLocationParams) (*models.Location, error)
+	listLocationsFunc  func(ctx context.Context, params services.ListLocationsParams) ([]*models.Location, error)
+	getLocationFunc    func(ctx context.Context, params services.LocationParams) (*models.Location, error)
+	updateLocationFunc func(ctx context.Context, params services.UpdateLocationParams) (*models.Location, error)
+	deleteLocationFunc func(ctx context.Context, params services.DeleteLocationParams) error
+}
+
+func (m *mockLocationService) CreateLocation(ctx context.Context, params services.CreateLocationParams) (*models.Location, error) {
+	return m.createLocationFunc(ctx, params)
+}
+
+func (m *mockLocationService) ListLocations(ctx context.Context, params services.ListLocationsParams) ([]*models.Location, error) {
+	return m.listLocationsFunc(ctx, params)
+}
+
+func (m *mockLocationService) GetLocation(ctx context.Context, params services.LocationParams) (*models.Location, error) {
+	return m.getLocationFunc(ctx, params)
+}
+
+func (m *mockLocationService) UpdateLocation(ctx context.Context, params services.UpdateLocationParams) (*models.Location, error) {
+	return m.updateLocationFunc(ctx, params)
+}
+
+func (m *mockLocationService) DeleteLocation(ctx context.Context, params services.DeleteLocationParams) error {
+	return m.deleteLocationFunc(ctx, params)
+}
+
+const locationBody = `{
+	"name": "Oslo depot",
+	"address": {"street": "Karl Johans gate 1", "postal_code": "0154", "city": "Oslo", "country": "NO"},
+	"time_zone": "Europe/Oslo",
+	"opening_hours": [{"day": "monday", "opens": "08:00", "closes": "16:00"}]
+}`
+
+func TestLocationHandler_CreateLocation(t *testing.T) {
+	identity := auth.Identity{UserID: "admin-user"}
+
+	newRouter := func(service services.LocationService) chi.Router {
+		handler := api.NewLocationHandler(service, logger.NewTestLogger(t))
+		r := chi.NewRouter()
+		r.Method(http.MethodPost, "/organizations/{orgID}/locations", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.CreateLocation), identity))
+		return r
+	}
+
+	t.Run("success", func(t *testing.T) {
+		service := &mockLocationService{
+			createLocationFunc: func(ctx context.Context, params services.CreateLocationParams) (*models.Location, error) {
+				assert.Equal(t, "org-1", params.OrgID)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				assert.Equal(t, "Oslo", params.Address.City)
+				assert.Equal(t, []models.OpeningHours{{Day: models.Monday, Opens: "08:00", Closes: "16:00"}}, params.OpeningHours)
+				return &models.Location{
+					ID: "loc-1", OrgID: params.OrgID, Name: params.Name, Address: params.Address,
+					TimeZone: params.TimeZone, OpeningHours: params.OpeningHours, Version: 1,
+				}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/locations", strings.NewReader(locationBody))
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, `"1"`, res.Header().Get("ETag"))
+		var response api.LocationResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, "loc-1", response.ID)
+		assert.Equal(t, "Europe/Oslo", response.TimeZone)
+		assert.Equal(t, "NO", response.Address.Country)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/locations", strings.NewReader(`{}`))
+		res := httptest.NewRecorder()
+
+		newRouter(&mockLocationService{}).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusBadRequest)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "name is required")
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		service := &mockLocationService{
+			createLocationFunc: func(ctx context.Context, params services.CreateLocationParams) (*models.Location, error) {
+				return nil, services.ErrLocationNameTaken
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/locations", strings.NewReader(locationBody))
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusConflict)
+		api.AssertProblemBody(t, res, problem.CodeLocationConflict, "already has a location")
+	})
+}
+
+func TestLocationHandler_UpdateLocation(t *testing.T) {
+	newRouter := func(service services.LocationService) chi.Router {
+		handler := api.NewLocationHandler(service, logger.NewTestLogger(t))
+		r := chi.NewRouter()
+		r.Method(http.MethodPut, "/organizations/{orgID}/locations/{locationID}",
+			middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.UpdateLocation), auth.Identity{UserID: "admin-user"}))
+		return r
+	}
+
+	t.Run("success", func(t *testing.T) {
+		service := &mockLocationService{
+			updateLocationFunc: func(ctx context.Context, params services.UpdateLocationParams) (*models.Location, error) {
+				assert.Equal(t, "loc-1", params.LocationID)
+				assert.Equal(t, 3, params.Version)
+				return &models.Location{ID: params.LocationID, Name: params.Name, Version: 4}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/organizations/org-1/locations/loc-1", strings.NewReader(locationBody))
+		req.Header.Set("If-Match", `"3"`)
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusOK)
+		assert.Equal(t, `"4"`, res.Header().Get("ETag"))
+	})
+
+	t.Run("requires If-Match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/organizations/org-1/locations/loc-1", strings.NewReader(locationBody))
+		res := httptest.NewRecorder()
+
+		newRouter(&mockLocationService{}).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusPreconditionRequired)
+		api.AssertProblemBody(t, res, problem.CodePreconditionRequired, "If-Match")
+	})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+)
+
+// memberCSVHeader is the column order of exported members. Imports only
+// need the user_id and role columns, in any order, so an export can be
+// edited and imported again.
+var memberCSVHeader = []string{"user_id", "username", "email", "role", "version"}
+
+func memberCSVRecord(user *models.UserWithRole) []string {
+	return []string{
+		csvCell(user.ID), csvCell(user.Username), csvCell(user.Email),
+		csvCell(string(user.Role)), strconv.Itoa(user.MembershipVersion),
+	}
+}
+
+// csvCell prefixes values that a spreadsheet would run as a formula with
+// a quote, so they are shown as text. Usernames and emails are chosen by
+// users, so an export must not be able to run their contents.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// parseMemberCSV reads the rows of a member import. Malformed rows are
+// reported with their line number in a *services.ValidationError.
+func parseMemberCSV(r io.Reader) ([]services.ImportMemberRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, services.NewValidationError("file", "is empty")
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	userIDColumn, roleColumn := -1, -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "user_id":
+			userIDColumn = i
+		case "role":
+			roleColumn = i
+		}
+	}
+	if userIDColumn < 0 || roleColumn < 0 {
+		return nil, services.NewValidationError("header", "must include user_id and role columns")
+	}
+
+	var rows []services.ImportMemberRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(rows) == services.MaxImportRows {
+			return nil, services.NewValidationError("file", fmt.Sprintf("must not contain more than %d rows", services.MaxImportRows))
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, services.ImportMemberRow{
+			Line:   line,
+			UserID: strings.TrimSpace(record[userIDColumn]),
+			Role:   models.Role(strings.TrimSpace(record[roleColumn])),
+		})
+	}
+
+	return rows, nil
+}
+
+// csvError reports malformed CSV as a validation error and returns read
+// errors, such as an oversized body, unchanged.
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &services.ValidationError{Fields: []services.FieldError{
+			{Line: parseErr.Line, Field: "file", Message: parseErr.Err.Error()},
+		}}
+	}
+	return err
+}
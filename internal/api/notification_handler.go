@@ -0,0 +1,119 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+)
+
+//go:embed static/unsubscribe.html
+var unsubscribePageSource string
+
+// unsubscribePage is shown to people following the unsubscribe link in a
+// notification email.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(unsubscribePageSource))
+
+type notificationHandler struct {
+	notificationService services.NotificationService
+	log                 *slog.Logger
+}
+
+func NewNotificationHandler(notificationService services.NotificationService, log *slog.Logger) *notificationHandler {
+	return &notificationHandler{
+		notificationService: notificationService,
+		log:                 log.With(slog.String("component", "notification_handler")),
+	}
+}
+
+func (h *notificationHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", identity.UserID))
+
+	prefs, err := h.notificationService.GetNotificationPreferences(r.Context(), identity.UserID)
+	if err != nil {
+		logServiceError(log, "Failed to get notification preferences", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewNotificationPreferencesResponse(prefs))
+}
+
+func (h *notificationHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", identity.UserID))
+
+	var input UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	prefs, err := h.notificationService.UpdateNotificationPreferences(r.Context(), input.params(identity.UserID))
+	if err != nil {
+		logServiceError(log, "Failed to update notification preferences", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewNotificationPreferencesResponse(prefs))
+}
+
+// ConfirmUnsubscribe shows a confirmation form rather than unsubscribing
+// right away, because mail scanners follow links in emails.
+func (h *notificationHandler) ConfirmUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	state := "confirm"
+	status := http.StatusOK
+	if token == "" {
+		state, status = "invalid", http.StatusBadRequest
+	}
+	h.renderUnsubscribePage(w, status, state, token)
+}
+
+// Unsubscribe handles both the confirmation form and RFC 8058 one-click
+// unsubscribe requests from mail clients.
+func (h *notificationHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.PostFormValue("token")
+	}
+
+	err := h.notificationService.Unsubscribe(r.Context(), token)
+	switch {
+	case err == nil:
+		h.renderUnsubscribePage(w, http.StatusOK, "done", "")
+	case errors.Is(err, services.ErrInvalidInput), errors.Is(err, services.ErrUserNotFound):
+		h.log.Warn("Rejected unsubscribe request", slog.Any("error", err))
+		h.renderUnsubscribePage(w, http.StatusBadRequest, "invalid", "")
+	default:
+		logServiceError(h.log, "Failed to unsubscribe", err)
+		respondError(w, r, err)
+	}
+}
+
+func (h *notificationHandler) renderUnsubscribePage(w http.ResponseWriter, status int, state, token string) {
+	w.Header().Set(ContentType, "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := unsubscribePage.Execute(w, struct{ State, Token string }{state, token}); err != nil {
+		h.log.Error("Failed to render unsubscribe page", slog.Any("error", err))
+	}
+}
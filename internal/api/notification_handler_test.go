@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNotificationService struct {
+	getNotificationPreferencesFunc    func(ctx context.Context, actingUserID string) (*models.NotificationPreferences, error)
+	updateNotificationPreferencesFunc func(ctx context.Context, params services.UpdateNotificationPreferencesParams) (*models.NotificationPreferences, error)
+	unsubscribeFunc                   func(ctx context.Context, token string) error
+}
+
+func (m *mockNotificationService) GetNotificationPreferences(ctx context.Context, actingUserID string) (*models.NotificationPreferences, error) {
+	return m.getNotificationPreferencesFunc(ctx, actingUserID)
+}
+
+func (m *mockNotificationService) UpdateNotificationPreferences(ctx context.Context, params services.UpdateNotificationPreferencesParams) (*models.NotificationPreferences, error) {
+	return m.updateNotificationPreferencesFunc(ctx, params)
+}
+
+func (m *mockNotificationService) Unsubscribe(ctx context.Context, token string) error {
+	return m.unsubscribeFunc(ctx, token)
+}
+
+func newNotificationsTestRouter(t *testing.T, service services.NotificationService) chi.Router {
+	handler := api.NewNotificationHandler(service, logger.NewTestLogger(t))
+	identity := auth.Identity{UserID: "user-1"}
+	r := chi.NewRouter()
+	r.Method(http.MethodPatch, "/users/me/notification-preferences", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.UpdateNotificationPreferences), identity))
+	r.Get("/notifications/unsubscribe", handler.ConfirmUnsubscribe)
+	r.Post("/notifications/unsubscribe", handler.Unsubscribe)
+	return r
+}
+
+func TestNotificationHandler_UpdateNotificationPreferences(t *testing.T) {
+	service := &mockNotificationService{
+		updateNotificationPreferencesFunc: func(ctx context.Context, params services.UpdateNotificationPreferencesParams) (*models.NotificationPreferences, error) {
+			assert.Equal(t, "user-1", params.ActingUserID)
+			assert.Equal(t, map[models.NotificationChannel]bool{models.NotificationEmail: false}, params.Channels)
+			return &models.NotificationPreferences{Locale: "nb", Channels: params.Channels}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/me/notification-preferences", strings.NewReader(`{"channels":{"email":false}}`))
+	res := httptest.NewRecorder()
+
+	newNotificationsTestRouter(t, service).ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"locale":"nb","channels":{"email":false}}`, res.Body.String())
+}
+
+func TestNotificationHandler_Unsubscribe(t *testing.T) {
+	t.Run("link shows a confirmation form without unsubscribing", func(t *testing.T) {
+		service := &mockNotificationService{
+			unsubscribeFunc: func(ctx context.Context, token string) error {
+				t.Fatal("GET must not unsubscribe")
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/notifications/unsubscribe?token=abc.def", nil)
+		res := httptest.NewRecorder()
+
+		newNotificationsTestRouter(t, service).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), `name="token" value="abc.def"`)
+	})
+
+	t.Run("form and one-click requests unsubscribe", func(t *testing.T) {
+		var tokens []string
+		service := &mockNotificationService{
+			unsubscribeFunc: func(ctx context.Context, token string) error {
+				tokens = append(tokens, token)
+				return nil
+			},
+		}
+		router := newNotificationsTestRouter(t, service)
+
+		form := httptest.NewRequest(http.MethodPost, "/notifications/unsubscribe", strings.NewReader(url.Values{"token": {"from-form"}}.Encode()))
+		form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		oneClick := httptest.NewRequest(http.MethodPost, "/notifications/unsubscribe?token=from-link", strings.NewReader("List-Unsubscribe=One-Click"))
+		oneClick.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		for _, req := range []*http.Request{form, oneClick} {
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Contains(t, res.Body.String(), "You have been unsubscribed")
+		}
+		assert.Equal(t, []string{"from-form", "from-link"}, tokens)
+	})
+
+	t.Run("invalid tokens get an error page", func(t *testing.T) {
+		service := &mockNotificationService{
+			unsubscribeFunc: func(ctx context.Context, token string) error {
+				return services.NewValidationError("token", "is invalid")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/notifications/unsubscribe?token=forged", nil)
+		res := httptest.NewRecorder()
+
+		newNotificationsTestRouter(t, service).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Contains(t, res.Body.String(), "This link is not valid")
+	})
+}
+
+func TestNotificationPreferencesResponse(t *testing.T) {
+	body, err := json.Marshal(api.NewNotificationPreferencesResponse(&models.NotificationPreferences{Locale: "en"}))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"locale":"en","channels":{"email":true}}`, string(body))
+}
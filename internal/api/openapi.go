@@ -0,0 +1,890 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	customMiddleware "github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+	apiVersion     = "1.0.0"
+)
+
+// routeDoc describes a registered route in the OpenAPI document. Every route
+// registered on the router must have an entry in routeDocs, keyed by
+// "METHOD /pattern", otherwise document generation fails.
+type routeDoc struct {
+	Summary     string
+	Tag         string
+	Public      bool
+	Request     any
+	Status      int
+	Response    any
+	ContentType string
+	// ETag marks responses that carry the resource version in an ETag header.
+	ETag bool
+	// IfMatch marks writes that require the version in an If-Match header.
+	IfMatch bool
+	// Cacheable marks reads that send Cache-Control and a content ETag, and
+	// answer a matching If-None-Match header with 304 Not Modified.
+	Cacheable bool
+	// Idempotent marks writes that accept an optional Idempotency-Key header.
+	Idempotent bool
+	// LastEventID marks event streams that resume after the event named
+	// in an optional Last-Event-ID header.
+	LastEventID bool
+	// RequestContentType overrides the JSON request body, e.g. for uploads.
+	RequestContentType string
+	// AltContentTypes lists further content types the success response can
+	// be sent as, with the same schema.
+	AltContentTypes []string
+	Query           []queryParamDoc
+}
+
+type queryParamDoc struct {
+	Name        string
+	Description string
+	Enum        []string
+}
+
+var routeDocs = map[string]routeDoc{
+	"GET /": {
+		Summary:     "Welcome message",
+		Tag:         "meta",
+		Public:      true,
+		Status:      http.StatusOK,
+		Response:    "",
+		ContentType: "text/plain",
+	},
+	"GET /openapi.json": {
+		Summary:     "This OpenAPI document",
+		Tag:         "meta",
+		Public:      true,
+		Status:      http.StatusOK,
+		Response:    map[string]any{},
+		ContentType: ContentTypeJSON,
+	},
+	"GET /docs": {
+		Summary:     "Interactive API documentation",
+		Tag:         "meta",
+		Public:      true,
+		Status:      http.StatusOK,
+		Response:    "",
+		ContentType: "text/html",
+	},
+	"POST /users": {
+		Summary:    "Create a user",
+		Tag:        "users",
+		Request:    CreateUserRequest{},
+		Status:     http.StatusCreated,
+		Response:   UserResponse{},
+		Idempotent: true,
+	},
+	"GET /users/{id}": {
+		Summary:  "Get a user visible to the caller",
+		Tag:      "users",
+		Status:   http.StatusOK,
+		Response: UserResponse{},
+	},
+	"GET /users/me/notification-preferences": {
+		Summary:  "Get the caller's notification preferences",
+		Tag:      "notifications",
+		Status:   http.StatusOK,
+		Response: NotificationPreferencesResponse{},
+	},
+	"PATCH /users/me/notification-preferences": {
+		Summary:  "Change the caller's notification locale or channels",
+		Tag:      "notifications",
+		Request:  UpdateNotificationPreferencesRequest{},
+		Status:   http.StatusOK,
+		Response: NotificationPreferencesResponse{},
+	},
+	"GET /notifications/unsubscribe": {
+		Summary:     "Confirmation page for an unsubscribe link",
+		Tag:         "notifications",
+		Public:      true,
+		Status:      http.StatusOK,
+		Response:    "",
+		ContentType: "text/html",
+		Query: []queryParamDoc{
+			{Name: "token", Description: "Token from the unsubscribe link"},
+		},
+	},
+	"POST /notifications/unsubscribe": {
+		Summary:            "Turn off the channel named in an unsubscribe token, including RFC 8058 one-click requests",
+		Tag:                "notifications",
+		Public:             true,
+		Request:            "",
+		RequestContentType: "application/x-www-form-urlencoded",
+		Status:             http.StatusOK,
+		Response:           "",
+		ContentType:        "text/html",
+		Query: []queryParamDoc{
+			{Name: "token", Description: "Token from the unsubscribe link"},
+		},
+	},
+	"POST /organizations": {
+		Summary:    "Create an organization with the caller as admin",
+		Tag:        "organizations",
+		Request:    CreateOrganizationRequest{},
+		Status:     http.StatusCreated,
+		Response:   OrganizationResponse{},
+		Idempotent: true,
+	},
+	"GET /organizations/{orgID}": {
+		Summary:  "Get an organization",
+		Tag:      "organizations",
+		Status:   http.StatusOK,
+		Response: OrganizationResponse{},
+		ETag:     true,
+	},
+	"PATCH /organizations/{orgID}": {
+		Summary:  "Update an organization",
+		Tag:      "organizations",
+		Request:  UpdateOrganizationRequest{},
+		Status:   http.StatusOK,
+		Response: OrganizationResponse{},
+		ETag:     true,
+		IfMatch:  true,
+	},
+	"GET /organizations/{orgID}/users": {
+		Summary:  "List organization members",
+		Tag:      "members",
+		Status:   http.StatusOK,
+		Response: OrganizationMembersResponse{},
+	},
+	"POST /organizations/{orgID}/users": {
+		Summary:    "Add a user to an organization",
+		Tag:        "members",
+		Request:    AddUserToOrganizationRequest{},
+		Status:     http.StatusCreated,
+		Response:   OrganizationUserResponse{},
+		Idempotent: true,
+	},
+	"POST /organizations/{orgID}/users:batch": {
+		Summary:    "Add, re-role or remove several members at once",
+		Tag:        "members",
+		Request:    BatchOrganizationUsersRequest{},
+		Status:     http.StatusOK,
+		Response:   BatchOrganizationUsersResponse{},
+		Idempotent: true,
+	},
+	"POST /organizations/{orgID}/users/import": {
+		Summary:            "Import members from CSV with user_id and role columns",
+		Tag:                "members",
+		Request:            "",
+		RequestContentType: ContentTypeCSV,
+		Status:             http.StatusOK,
+		Response:           ImportReportResponse{},
+		Idempotent:         true,
+		Query: []queryParamDoc{
+			{Name: "dry_run", Description: "Validate every row and report errors without importing", Enum: []string{"true", "false"}},
+		},
+	},
+	"GET /organizations/{orgID}/users/export": {
+		Summary:         "Stream organization members as CSV or JSON lines",
+		Tag:             "members",
+		Status:          http.StatusOK,
+		Response:        "",
+		ContentType:     ContentTypeCSV,
+		AltContentTypes: []string{ContentTypeNDJSON},
+		Query: []queryParamDoc{
+			{Name: "format", Description: "Output format, csv by default", Enum: []string{"csv", "jsonl"}},
+		},
+	},
+	"GET /organizations/{orgID}/users/{userID}": {
+		Summary:  "Get an organization member",
+		Tag:      "members",
+		Status:   http.StatusOK,
+		Response: OrganizationUserResponse{},
+		ETag:     true,
+	},
+	"PUT /organizations/{orgID}/users/{userID}": {
+		Summary: "Change a member's role",
+		Tag:     "members",
+		Request: UpdateUserRoleRequest{},
+		Status:  http.StatusNoContent,
+		ETag:    true,
+		IfMatch: true,
+	},
+	"DELETE /organizations/{orgID}/users/{userID}": {
+		Summary: "Remove a member from an organization",
+		Tag:     "members",
+		Status:  http.StatusNoContent,
+		IfMatch: true,
+	},
+	"GET /admin/jobs": {
+		Summary:  "List background jobs, newest first",
+		Tag:      "admin",
+		Status:   http.StatusOK,
+		Response: JobsResponse{},
+		Query: []queryParamDoc{
+			{Name: "status", Description: "Only jobs with this status", Enum: []string{
+				string(models.JobPending), string(models.JobRunning), string(models.JobSucceeded), string(models.JobDead),
+			}},
+			{Name: "kind", Description: "Only jobs of this kind"},
+		},
+	},
+	"GET /admin/jobs/{jobID}": {
+		Summary:  "Get a background job",
+		Tag:      "admin",
+		Status:   http.StatusOK,
+		Response: JobResponse{},
+	},
+	"POST /admin/jobs/{jobID}/retry": {
+		Summary:  "Retry a dead background job",
+		Tag:      "admin",
+		Status:   http.StatusAccepted,
+		Response: JobResponse{},
+	},
+	"GET /organizations/{orgID}/events": {
+		Summary:     "Stream organization events as server-sent events",
+		Tag:         "events",
+		Status:      http.StatusOK,
+		Response:    EventResponse{},
+		ContentType: ContentTypeEventStream,
+		LastEventID: true,
+	},
+	"GET /organizations/{orgID}/locations": {
+		Summary:  "List an organization's locations",
+		Tag:      "locations",
+		Status:   http.StatusOK,
+		Response: LocationsResponse{},
+	},
+	"POST /organizations/{orgID}/locations": {
+		Summary:    "Create a location with its address, time zone and opening hours",
+		Tag:        "locations",
+		Request:    LocationRequest{},
+		Status:     http.StatusCreated,
+		Response:   LocationResponse{},
+		ETag:       true,
+		Idempotent: true,
+	},
+	"GET /organizations/{orgID}/locations/{locationID}": {
+		Summary:  "Get a location",
+		Tag:      "locations",
+		Status:   http.StatusOK,
+		Response: LocationResponse{},
+		ETag:     true,
+	},
+	"PUT /organizations/{orgID}/locations/{locationID}": {
+		Summary:  "Replace a location",
+		Tag:      "locations",
+		Request:  LocationRequest{},
+		Status:   http.StatusOK,
+		Response: LocationResponse{},
+		ETag:     true,
+		IfMatch:  true,
+	},
+	"DELETE /organizations/{orgID}/locations/{locationID}": {
+		Summary: "Delete a location",
+		Tag:     "locations",
+		Status:  http.StatusNoContent,
+		IfMatch: true,
+	},
+	"GET /organizations/{orgID}/terms": {
+		Summary:  "List every version of an organization's rental terms, newest first",
+		Tag:      "terms",
+		Status:   http.StatusOK,
+		Response: TermsListResponse{},
+	},
+	"POST /organizations/{orgID}/terms": {
+		Summary:    "Publish a new version of the rental terms",
+		Tag:        "terms",
+		Request:    PublishTermsRequest{},
+		Status:     http.StatusCreated,
+		Response:   TermsResponse{},
+		Idempotent: true,
+	},
+	"GET /organizations/{orgID}/terms/{version}": {
+		Summary:  "Get a version of the rental terms, or the current one with version \"current\"",
+		Tag:      "terms",
+		Status:   http.StatusOK,
+		Response: TermsResponse{},
+	},
+	"GET /organizations/{orgID}/terms/{version}/acceptance": {
+		Summary:  "Get the caller's acceptance of a version of the rental terms",
+		Tag:      "terms",
+		Status:   http.StatusOK,
+		Response: TermsAcceptanceResponse{},
+	},
+	"POST /organizations/{orgID}/terms/{version}/acceptance": {
+		Summary:    "Accept the current rental terms, recording the caller's IP address and the hash of the accepted text",
+		Tag:        "terms",
+		Request:    AcceptTermsRequest{},
+		Status:     http.StatusCreated,
+		Response:   TermsAcceptanceResponse{},
+		Idempotent: true,
+	},
+	"GET /public/{orgSlug}": {
+		Summary:   "Get an organization's public storefront, if it has opted in to a public catalog",
+		Tag:       "public",
+		Public:    true,
+		Status:    http.StatusOK,
+		Response:  PublicStorefrontResponse{},
+		Cacheable: true,
+	},
+	"POST /organizations/{orgID}/customers": {
+		Summary:    "Register the caller as a customer of the organization, without membership",
+		Tag:        "customers",
+		Request:    CustomerProfileRequest{},
+		Status:     http.StatusCreated,
+		Response:   CustomerResponse{},
+		ETag:       true,
+		Idempotent: true,
+	},
+	"GET /organizations/{orgID}/customers/me": {
+		Summary:  "Get the caller's customer profile",
+		Tag:      "customers",
+		Status:   http.StatusOK,
+		Response: CustomerResponse{},
+		ETag:     true,
+	},
+	"PUT /organizations/{orgID}/customers/me": {
+		Summary:  "Replace the caller's customer profile",
+		Tag:      "customers",
+		Request:  CustomerProfileRequest{},
+		Status:   http.StatusOK,
+		Response: CustomerResponse{},
+		ETag:     true,
+		IfMatch:  true,
+	},
+	"GET /organizations/{orgID}/customers": {
+		Summary:  "List an organization's customers",
+		Tag:      "customers",
+		Status:   http.StatusOK,
+		Response: CustomersResponse{},
+	},
+	"GET /organizations/{orgID}/customers/{customerID}": {
+		Summary:  "Get a customer",
+		Tag:      "customers",
+		Status:   http.StatusOK,
+		Response: CustomerResponse{},
+		ETag:     true,
+	},
+	"PUT /organizations/{orgID}/customers/{customerID}/verification": {
+		Summary:  "Set a customer's ID verification status",
+		Tag:      "customers",
+		Request:  SetCustomerVerificationRequest{},
+		Status:   http.StatusOK,
+		Response: CustomerResponse{},
+		ETag:     true,
+		IfMatch:  true,
+	},
+	"POST /organizations/{orgID}/attachments": {
+		Summary:            "Upload a JPEG, PNG or GIF image or a PDF document of at most 10 MiB",
+		Tag:                "attachments",
+		Request:            "",
+		RequestContentType: "application/octet-stream",
+		Status:             http.StatusCreated,
+		Response:           AttachmentResponse{},
+		Idempotent:         true,
+		Query: []queryParamDoc{
+			{Name: "filename", Description: "Name of the uploaded file"},
+		},
+	},
+	"GET /organizations/{orgID}/attachments/{attachmentID}": {
+		Summary:  "Get an attachment with fresh download links",
+		Tag:      "attachments",
+		Status:   http.StatusOK,
+		Response: AttachmentResponse{},
+	},
+	"DELETE /organizations/{orgID}/attachments/{attachmentID}": {
+		Summary: "Delete an attachment; members can only delete their own",
+		Tag:     "attachments",
+		Status:  http.StatusNoContent,
+	},
+	"GET /files/{attachmentID}": {
+		Summary:     "Download an attachment from a signed link",
+		Tag:         "attachments",
+		Public:      true,
+		Status:      http.StatusOK,
+		Response:    "",
+		ContentType: "application/octet-stream",
+		Query: []queryParamDoc{
+			{Name: "variant", Description: "File to download, original by default", Enum: []string{string(services.AttachmentOriginal), string(services.AttachmentThumbnail)}},
+			{Name: "expires", Description: "Expiry of the link as a Unix time"},
+			{Name: "signature", Description: "Signature of the link"},
+		},
+	},
+	"GET /organizations/{orgID}/webhooks": {
+		Summary:  "List an organization's webhooks",
+		Tag:      "webhooks",
+		Status:   http.StatusOK,
+		Response: WebhooksResponse{},
+	},
+	"POST /organizations/{orgID}/webhooks": {
+		Summary:    "Register a webhook; the signing secret is only returned here",
+		Tag:        "webhooks",
+		Request:    CreateWebhookRequest{},
+		Status:     http.StatusCreated,
+		Response:   CreatedWebhookResponse{},
+		Idempotent: true,
+	},
+	"GET /organizations/{orgID}/webhooks/{webhookID}": {
+		Summary:  "Get a webhook",
+		Tag:      "webhooks",
+		Status:   http.StatusOK,
+		Response: WebhookResponse{},
+	},
+	"DELETE /organizations/{orgID}/webhooks/{webhookID}": {
+		Summary: "Delete a webhook and its delivery log",
+		Tag:     "webhooks",
+		Status:  http.StatusNoContent,
+	},
+	"GET /organizations/{orgID}/webhooks/{webhookID}/deliveries": {
+		Summary:  "List a webhook's most recent deliveries",
+		Tag:      "webhooks",
+		Status:   http.StatusOK,
+		Response: WebhookDeliveriesResponse{},
+	},
+	"POST /organizations/{orgID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver": {
+		Summary:    "Queue a delivery to be sent again",
+		Tag:        "webhooks",
+		Status:     http.StatusAccepted,
+		Response:   WebhookDeliveryResponse{},
+		Idempotent: true,
+	},
+}
+
+// enumValues lists the allowed values for string types that are enums.
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(models.Role("")): {string(models.RoleAdmin), string(models.RoleMember)},
+	reflect.TypeOf(BatchMode("")):   {string(BatchModeAtomic), string(BatchModePartial)},
+	reflect.TypeOf(services.BatchOperationType("")): {
+		string(services.BatchOperationAdd), string(services.BatchOperationUpdateRole), string(services.BatchOperationRemove),
+	},
+	reflect.TypeOf(models.EventType("")): {
+		string(models.EventMemberAdded), string(models.EventMemberRoleChanged), string(models.EventMemberRemoved),
+	},
+	reflect.TypeOf(models.DeliveryStatus("")): {
+		string(models.DeliveryPending), string(models.DeliverySucceeded), string(models.DeliveryFailed),
+	},
+	reflect.TypeOf(models.Weekday("")): {
+		string(models.Monday), string(models.Tuesday), string(models.Wednesday), string(models.Thursday),
+		string(models.Friday), string(models.Saturday), string(models.Sunday),
+	},
+	reflect.TypeOf(models.VerificationStatus("")): {
+		string(models.VerificationUnverified), string(models.VerificationPending),
+		string(models.VerificationVerified), string(models.VerificationRejected),
+	},
+	reflect.TypeOf(models.JobStatus("")): {
+		string(models.JobPending), string(models.JobRunning), string(models.JobSucceeded), string(models.JobDead),
+	},
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Headers     map[string]openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Format     string                    `json:"format,omitempty"`
+	Enum       []string                  `json:"enum,omitempty"`
+	MaxLength  int                       `json:"maxLength,omitempty"`
+	Items      *openAPISchema            `json:"items,omitempty"`
+	Properties map[string]*openAPISchema `json:"properties,omitempty"`
+	Required   []string                  `json:"required,omitempty"`
+}
+
+// openAPISpec holds the rendered document served at /openapi.json and the
+// error, if any, from generating it.
+type openAPISpec struct {
+	body []byte
+	err  error
+}
+
+// docsPage is a self-contained documentation viewer that renders
+// /openapi.json without loading anything from the network.
+//
+//go:embed static/docs.html
+var docsPage []byte
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// generate walks the router and renders the OpenAPI document. It returns an
+// error when a registered route has no routeDocs entry or an entry no
+// longer matches a registered route.
+func (s *openAPISpec) generate(routes chi.Routes) error {
+	doc := openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:       "Rental Server API",
+			Version:     apiVersion,
+			Description: "Errors are returned as RFC 7807 application/problem+json documents.",
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: map[string]*openAPISchema{},
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Google ID token",
+				},
+			},
+		},
+	}
+	schemas := &schemaRegistry{schemas: doc.Components.Schemas}
+	problemSchema := schemas.schemaFor(reflect.TypeOf(problem.Problem{}))
+
+	var undocumented []string
+	documented := map[string]bool{}
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = normalizeRoute(route)
+		key := method + " " + route
+		rd, ok := routeDocs[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			return nil
+		}
+		documented[key] = true
+
+		if doc.Paths[route] == nil {
+			doc.Paths[route] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[route][strings.ToLower(method)] = buildOperation(method, route, rd, schemas, problemSchema)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk routes: %w", err)
+	}
+
+	var stale []string
+	for key := range routeDocs {
+		if !documented[key] {
+			stale = append(stale, key)
+		}
+	}
+
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode OpenAPI document: %w", err)
+	}
+	s.body = append(body, '\n')
+
+	if len(undocumented) > 0 || len(stale) > 0 {
+		sort.Strings(undocumented)
+		sort.Strings(stale)
+		return fmt.Errorf("routes without documentation: %v; documentation without routes: %v", undocumented, stale)
+	}
+	return nil
+}
+
+func buildOperation(method, route string, rd routeDoc, schemas *schemaRegistry, problemSchema *openAPISchema) *openAPIOperation {
+	op := &openAPIOperation{
+		OperationID: operationID(method, route),
+		Summary:     rd.Summary,
+		Tags:        []string{rd.Tag},
+		Responses:   map[string]openAPIResponse{},
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route, -1) {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &openAPISchema{Type: "string"},
+		})
+	}
+
+	if rd.IfMatch {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:     headerIfMatch,
+			In:       "header",
+			Required: true,
+			Schema:   &openAPISchema{Type: "string"},
+		})
+	}
+
+	if rd.Cacheable {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:        headerIfNoneMatch,
+			In:          "header",
+			Description: "ETag of a cached response; it is not sent again if unchanged",
+			Schema:      &openAPISchema{Type: "string"},
+		})
+	}
+
+	if rd.Idempotent {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:        customMiddleware.HeaderIdempotencyKey,
+			In:          "header",
+			Description: "Makes the request safe to retry; the first response is replayed for repeats",
+			Schema:      &openAPISchema{Type: "string", MaxLength: 255},
+		})
+	}
+
+	if rd.LastEventID {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:        headerLastEventID,
+			In:          "header",
+			Description: "Resumes the stream after the event with this id",
+			Schema:      &openAPISchema{Type: "string"},
+		})
+	}
+
+	for _, q := range rd.Query {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:        q.Name,
+			In:          "query",
+			Description: q.Description,
+			Schema:      &openAPISchema{Type: "string", Enum: q.Enum},
+		})
+	}
+
+	if rd.Request != nil {
+		requestContentType := rd.RequestContentType
+		if requestContentType == "" {
+			requestContentType = ContentTypeJSON
+		}
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]openAPIMediaType{
+				requestContentType: {Schema: schemas.schemaFor(reflect.TypeOf(rd.Request))},
+			},
+		}
+	}
+
+	success := openAPIResponse{Description: http.StatusText(rd.Status)}
+	if rd.Response != nil {
+		contentType := rd.ContentType
+		if contentType == "" {
+			contentType = ContentTypeJSON
+		}
+		schema := schemas.schemaFor(reflect.TypeOf(rd.Response))
+		success.Content = map[string]openAPIMediaType{contentType: {Schema: schema}}
+		for _, alt := range rd.AltContentTypes {
+			success.Content[alt] = openAPIMediaType{Schema: schema}
+		}
+	}
+	if rd.ETag {
+		success.Headers = map[string]openAPIHeader{
+			headerETag: {Description: "Current version of the resource", Schema: &openAPISchema{Type: "string"}},
+		}
+	}
+	if rd.Cacheable {
+		success.Headers = map[string]openAPIHeader{
+			headerETag:         {Description: "Hash of the response body", Schema: &openAPISchema{Type: "string"}},
+			headerCacheControl: {Description: "How long the response may be cached", Schema: &openAPISchema{Type: "string"}},
+		}
+		op.Responses[strconv.Itoa(http.StatusNotModified)] = openAPIResponse{Description: http.StatusText(http.StatusNotModified)}
+	}
+	op.Responses[strconv.Itoa(rd.Status)] = success
+	op.Responses["default"] = openAPIResponse{
+		Description: "Error",
+		Content: map[string]openAPIMediaType{
+			ContentTypeProblemJSON: {Schema: problemSchema},
+		},
+	}
+
+	if !rd.Public {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	return op
+}
+
+// normalizeRoute strips the trailing slash chi adds to routes registered as
+// "/" inside a sub-router, so "/users/" is documented as "/users".
+func normalizeRoute(route string) string {
+	if len(route) > 1 {
+		return strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// operationID derives a stable identifier such as "getOrganizationsOrgIDUsers".
+func operationID(method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(route, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '.' || r == ':' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if route == "/" {
+		b.WriteString("Root")
+	}
+	return b.String()
+}
+
+// schemaRegistry converts Go types into JSON schemas, registering named
+// struct types under components/schemas and referencing them by $ref.
+type schemaRegistry struct {
+	schemas map[string]*openAPISchema
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (r *schemaRegistry) schemaFor(t reflect.Type) *openAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := enumValues[t]; ok {
+		return &openAPISchema{Type: "string", Enum: values}
+	}
+	if t == timeType {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+	if t == rawJSONType {
+		// Any JSON value.
+		return &openAPISchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &openAPISchema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map, reflect.Interface:
+		return &openAPISchema{Type: "object"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if _, ok := r.schemas[t.Name()]; !ok {
+			// Register a placeholder first so recursive types terminate.
+			r.schemas[t.Name()] = &openAPISchema{}
+			*r.schemas[t.Name()] = *r.structSchema(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + t.Name()}
+	}
+
+	return &openAPISchema{}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	r.addFields(schema, t)
+	return schema
+}
+
+func (r *schemaRegistry) addFields(schema *openAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.addFields(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = r.schemaFor(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func setupDocsRoutes(r chi.Router, spec *openAPISpec) {
+	r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentType, ContentTypeJSON)
+		w.Write(spec.body)
+	})
+
+	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentType, "text/html; charset=utf-8")
+		w.Write(docsPage)
+	})
+}
+
+// OpenAPISpec returns the OpenAPI document served at /openapi.json. The error
+// is non-nil if the routes and their documentation have drifted apart.
+func (s *Server) OpenAPISpec() ([]byte, error) {
+	return s.openAPI.body, s.openAPI.err
+}
@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/config"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// committedSpecPath is the OpenAPI document checked in for client generation.
+const committedSpecPath = "../../docs/openapi.json"
+
+var update = flag.Bool("update", false, "rewrite the committed OpenAPI document")
+
+func newDocsTestServer(t *testing.T) *api.Server {
+	return api.NewServer(
+		&config.AppConfig{},
+		&auth.GoogleTokenVerifier{},
+		logger.NewTestLogger(t),
+		&mockUserService{},
+		&mockOrganizationService{},
+		&mockOrganizationUserService{},
+		&mockAccessService{},
+		&mockIdempotencyService{},
+		&mockWebhookService{},
+		&mockEventService{},
+		&mockJobService{},
+		&mockNotificationService{},
+		&mockAttachmentService{},
+		&mockLocationService{},
+		&mockTermsService{},
+		&mockCustomerService{},
+		&mockPublicCatalogService{},
+	)
+}
+
+type mockIdempotencyService struct{}
+
+func (m *mockIdempotencyService) BeginRequest(ctx context.Context, params services.BeginIdempotentRequestParams) (*models.IdempotencyKey, error) {
+	return nil, nil
+}
+
+func (m *mockIdempotencyService) CompleteRequest(ctx context.Context, params services.CompleteIdempotentRequestParams) error {
+	return nil
+}
+
+func (m *mockIdempotencyService) ReleaseRequest(ctx context.Context, req services.IdempotentRequest) error {
+	return nil
+}
+
+func (m *mockIdempotencyService) PurgeExpired(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func TestOpenAPISpec_DocumentsEveryRoute(t *testing.T) {
+	server := newDocsTestServer(t)
+
+	_, err := server.OpenAPISpec()
+
+	require.NoError(t, err, "add or update the routeDocs entry for every changed route")
+}
+
+func TestOpenAPISpec_MatchesCommittedDocument(t *testing.T) {
+	server := newDocsTestServer(t)
+
+	spec, err := server.OpenAPISpec()
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, os.WriteFile(committedSpecPath, spec, 0o644))
+	}
+
+	committed, err := os.ReadFile(committedSpecPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(spec), "run 'make openapi' to regenerate docs/openapi.json")
+}
+
+func TestOpenAPISpec_Served(t *testing.T) {
+	server := newDocsTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	res := httptest.NewRecorder()
+
+	server.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	api.AssertJSONContentType(t, res)
+
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Contains(t, doc.Paths["/organizations/{orgID}/users"], "get")
+	assert.Contains(t, doc.Paths["/organizations/{orgID}/users"], "post")
+}
+
+func TestDocsPage_Served(t *testing.T) {
+	server := newDocsTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	res := httptest.NewRecorder()
+
+	server.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, res.Body.String(), "openapi.json")
+}
@@ -2,11 +2,11 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 )
@@ -27,7 +27,7 @@ func (h *organizationHandler) CreateOrganization(w http.ResponseWriter, r *http.
 	identity, err := auth.FromContext(r.Context())
 	if err != nil {
 		h.log.Error("User identity not found in request context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 
@@ -38,35 +38,26 @@ func (h *organizationHandler) CreateOrganization(w http.ResponseWriter, r *http.
 	var input CreateOrganizationRequest
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		log.Warn("Failed to decode request body", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	if err := input.Validate(); err != nil {
 		log.Warn("Validation failed for organization creation", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
 	org, err := h.organizationService.CreateOrganization(r.Context(), services.CreateOrganizationParams{
 		Name:      input.Name,
+		Currency:  input.Currency,
+		Slug:      input.Slug,
 		CreatedBy: identity.UserID,
 	})
 
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			log.Warn("Invalid input for organization creation", slog.Any("error", err))
-			respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		if errors.Is(err, services.ErrOrganizationWithDuplicateDetailsExists) {
-			log.Warn("Organization with duplicate details already exists", slog.Any("error", err))
-			respondError(w, http.StatusConflict, err.Error())
-			return
-		}
-		
-		log.Error("Failed to create organization due to internal error", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to create organization", err)
+		respondError(w, r, err)
 		return
 	}
 
@@ -80,25 +71,80 @@ func (h *organizationHandler) CreateOrganization(w http.ResponseWriter, r *http.
 func (h *organizationHandler) GetOrganizationByID(w http.ResponseWriter, r *http.Request) {
 	_, err := auth.FromContext(r.Context())
 	if err != nil {
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 
 	orgID := chi.URLParam(r, "orgID")
 	if orgID == "" {
-		respondError(w, http.StatusBadRequest, "organization ID is required")
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
 		return
 	}
 
 	org, err := h.organizationService.GetOrganizationByID(r.Context(), services.GetOrganizationByIDParams{ID: orgID})
 	if err != nil {
-		if errors.Is(err, services.ErrOrganizationNotFound) {
-			respondError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(h.log.With(slog.String("org_id", orgID)), "Failed to fetch organization", err)
+		respondError(w, r, err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, org)
+	setETag(w, org.Version)
+	respondJSON(w, http.StatusOK, NewOrganizationResponse(org))
+}
+
+func (h *organizationHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("User identity not found in request context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	if orgID == "" {
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
+		return
+	}
+
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		log.Warn("Missing or invalid If-Match header for organization update", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	var input UpdateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		log.Warn("Validation failed for organization update", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	org, err := h.organizationService.UpdateOrganization(r.Context(), services.UpdateOrganizationParams{
+		ID:            orgID,
+		ActingUserID:  identity.UserID,
+		Name:          input.Name,
+		Currency:      input.Currency,
+		Slug:          input.Slug,
+		PublicCatalog: input.PublicCatalog,
+		Version:       version,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to update organization", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Organization updated successfully", slog.Int("version", org.Version))
+
+	setETag(w, org.Version)
+	respondJSON(w, http.StatusOK, NewOrganizationResponse(org))
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/espennoreng/go-http-rental-server/internal/logger"
 	"github.com/espennoreng/go-http-rental-server/internal/middleware"
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +23,7 @@ import (
 type mockOrganizationService struct {
 	createOrganizationFunc  func(ctx context.Context, params services.CreateOrganizationParams) (*models.Organization, error)
 	getOrganizationByIDFunc func(ctx context.Context, params services.GetOrganizationByIDParams) (*models.Organization, error)
+	updateOrganizationFunc  func(ctx context.Context, params services.UpdateOrganizationParams) (*models.Organization, error)
 }
 
 func (m *mockOrganizationService) CreateOrganization(ctx context.Context, params services.CreateOrganizationParams) (*models.Organization, error) {
@@ -32,6 +34,10 @@ func (m *mockOrganizationService) GetOrganizationByID(ctx context.Context, param
 	return m.getOrganizationByIDFunc(ctx, params)
 }
 
+func (m *mockOrganizationService) UpdateOrganization(ctx context.Context, params services.UpdateOrganizationParams) (*models.Organization, error) {
+	return m.updateOrganizationFunc(ctx, params)
+}
+
 
 func TestOrganizationHandler_CreateOrganization(t *testing.T) {
 	userID := "test-user-id"
@@ -99,8 +105,9 @@ func TestOrganizationHandler_GetOrganizationByID(t *testing.T) {
 			getOrganizationByIDFunc: func(ctx context.Context, params services.GetOrganizationByIDParams) (*models.Organization, error) {
 				if params.ID == orgID {
 					return &models.Organization{
-						ID:   orgID,
-						Name: orgName,
+						ID:      orgID,
+						Name:    orgName,
+						Version: 4,
 					}, nil
 				}
 				return nil, services.ErrOrganizationNotFound
@@ -139,6 +146,8 @@ func TestOrganizationHandler_GetOrganizationByID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, orgID, response.ID)
 		assert.Equal(t, orgName, response.Name)
+		assert.Equal(t, 4, response.Version)
+		assert.Equal(t, `"4"`, res.Header().Get("ETag"))
 	})
 
 	t.Run("Unauthorized access", func(t *testing.T) {
@@ -156,3 +165,66 @@ func TestOrganizationHandler_GetOrganizationByID(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, res.Code)
 	})
 }
+
+func TestOrganizationHandler_UpdateOrganization(t *testing.T) {
+	actingUserID := "test-acting-user-id"
+	orgID := "org-001"
+
+	logger := logger.NewTestLogger(t)
+
+	newRouter := func(mockService *mockOrganizationService) *chi.Mux {
+		r := chi.NewRouter()
+		handler := api.NewOrganizationHandler(mockService, logger)
+		authedHandler := middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.UpdateOrganization), auth.Identity{UserID: actingUserID})
+		r.Method(http.MethodPatch, "/organizations/{orgID}", authedHandler)
+		return r
+	}
+
+	t.Run("successful update", func(t *testing.T) {
+		mockService := &mockOrganizationService{
+			updateOrganizationFunc: func(ctx context.Context, params services.UpdateOrganizationParams) (*models.Organization, error) {
+				assert.Equal(t, orgID, params.ID)
+				assert.Equal(t, actingUserID, params.ActingUserID)
+				assert.Equal(t, "Renamed", params.Name)
+				assert.Equal(t, 2, params.Version)
+				return &models.Organization{ID: orgID, Name: params.Name, Version: 3}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/organizations/%s", orgID), bytes.NewBufferString(`{"name":"Renamed"}`))
+		req.Header.Set("If-Match", `"2"`)
+		res := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"3"`, res.Header().Get("ETag"))
+	})
+
+	t.Run("missing If-Match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/organizations/%s", orgID), bytes.NewBufferString(`{"name":"Renamed"}`))
+		res := httptest.NewRecorder()
+
+		newRouter(&mockOrganizationService{}).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusPreconditionRequired, res.Code)
+		api.AssertProblemBody(t, res, problem.CodePreconditionRequired, "If-Match")
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		mockService := &mockOrganizationService{
+			updateOrganizationFunc: func(ctx context.Context, params services.UpdateOrganizationParams) (*models.Organization, error) {
+				return nil, services.ErrVersionMismatch
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/organizations/%s", orgID), bytes.NewBufferString(`{"name":"Renamed"}`))
+		req.Header.Set("If-Match", `"1"`)
+		res := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+		api.AssertProblemBody(t, res, problem.CodeVersionMismatch, "")
+	})
+}
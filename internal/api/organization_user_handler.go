@@ -2,12 +2,18 @@ package api
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 )
@@ -28,14 +34,14 @@ func (h *organizationUserHandler) AddUserToOrganization(w http.ResponseWriter, r
 	identity, err := auth.FromContext(r.Context())
 	if err != nil {
 		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 	orgID := chi.URLParam(r, "orgID")
 
 	if orgID == "" {
 		h.log.Warn("Organization ID is required for adding user", slog.String("orgID", orgID))
-		respondError(w, http.StatusBadRequest, "organization ID is required")
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
 		return
 	}
 
@@ -43,13 +49,13 @@ func (h *organizationUserHandler) AddUserToOrganization(w http.ResponseWriter, r
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		h.log.Error("Failed to decode request body", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	if err := input.Validate(); err != nil {
 		h.log.Warn("Validation failed for adding user to organization", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
@@ -64,18 +70,8 @@ func (h *organizationUserHandler) AddUserToOrganization(w http.ResponseWriter, r
 	})
 
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			log.Warn("Invalid input for adding user to organization", slog.Any("error", err))
-			respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		if errors.Is(err, services.ErrUserAlreadyHasARoleInOrganization) {
-			log.Warn("User already has a role in the organization", slog.Any("error", err))
-			respondError(w, http.StatusConflict, err.Error())
-			return
-		}
-		log.Error("Failed to add user to organization", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to add user to organization", err)
+		respondError(w, r, err)
 		return
 	}
 
@@ -91,7 +87,7 @@ func (h *organizationUserHandler) GetUsersByOrganizationID(w http.ResponseWriter
 
 	if err != nil {
 		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 
@@ -99,7 +95,7 @@ func (h *organizationUserHandler) GetUsersByOrganizationID(w http.ResponseWriter
 
 	if orgID == "" {
 		h.log.Warn("Organization ID is required for fetching users", slog.String("orgID", orgID))
-		respondError(w, http.StatusBadRequest, "organization ID is required")
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
 		return
 	}
 
@@ -112,13 +108,8 @@ func (h *organizationUserHandler) GetUsersByOrganizationID(w http.ResponseWriter
 	})
 
 	if err != nil {
-		if errors.Is(err, services.ErrUnauthorized) {
-			log.Warn("Unauthorized access attempt", slog.Any("error", err))
-			respondError(w, http.StatusForbidden, err.Error())
-			return
-		}
-		log.Error("Failed to fetch users for organization", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to fetch users for organization", err)
+		respondError(w, r, err)
 		return
 	}
 
@@ -127,11 +118,51 @@ func (h *organizationUserHandler) GetUsersByOrganizationID(w http.ResponseWriter
 	respondJSON(w, http.StatusOK, response)
 }
 
+func (h *organizationUserHandler) GetOrganizationUser(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	if orgID == "" {
+		h.log.Warn("Organization ID is required for fetching organization user", slog.String("orgID", orgID))
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
+		return
+	}
+
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.log.Warn("User ID is required for fetching organization user", slog.String("userID", userID))
+		respondError(w, r, services.NewValidationError("userID", "is required"))
+		return
+	}
+
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+	log.Info("Fetching organization user", slog.String("user_id", userID))
+
+	orgUser, err := h.organizationUserService.GetOrganizationUser(r.Context(), services.GetOrganizationUserParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+		UserID:       userID,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to fetch organization user", err)
+		respondError(w, r, err)
+		return
+	}
+
+	setETag(w, orgUser.Version)
+	respondJSON(w, http.StatusOK, NewOrganizationUserResponse(orgUser))
+}
+
 func (h *organizationUserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	identity, err := auth.FromContext(r.Context())
 	if err != nil {
 		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 
@@ -139,14 +170,21 @@ func (h *organizationUserHandler) UpdateUserRole(w http.ResponseWriter, r *http.
 
 	if orgID == "" {
 		h.log.Warn("Organization ID is required for updating user role", slog.String("orgID", orgID))
-		respondError(w, http.StatusBadRequest, "organization ID is required")
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
 		return
 	}
 
 	userID := chi.URLParam(r, "userID")
 	if userID == "" {
 		h.log.Warn("User ID is required for updating user role", slog.String("userID", userID))
-		respondError(w, http.StatusBadRequest, "user ID is required")
+		respondError(w, r, services.NewValidationError("userID", "is required"))
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		h.log.Warn("Missing or invalid If-Match header for updating user role", slog.Any("error", err))
+		respondError(w, r, err)
 		return
 	}
 
@@ -154,42 +192,34 @@ func (h *organizationUserHandler) UpdateUserRole(w http.ResponseWriter, r *http.
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		h.log.Error("Failed to decode request body", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	if err := input.Validate(); err != nil {
 		h.log.Warn("Validation failed for updating user role", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
 	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
 	log.Info("Updating user role in organization", slog.String("user_id", userID), slog.String("role", string(input.Role)))
 
-	err = h.organizationUserService.UpdateUserRole(context.Background(), services.UpdateUserRoleParams{
+	orgUser, err := h.organizationUserService.UpdateUserRole(context.Background(), services.UpdateUserRoleParams{
 		OrgID:        orgID,
 		ActingUserID: identity.UserID,
 		UserID:       userID,
 		Role:         input.Role,
+		Version:      version,
 	})
 
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			log.Warn("Invalid input for updating user role", slog.Any("error", err))
-			respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		if errors.Is(err, services.ErrUnauthorized) {
-			log.Warn("Unauthorized access attempt", slog.Any("error", err))
-			respondError(w, http.StatusForbidden, err.Error())
-			return
-		}
-		log.Error("Failed to update user role in organization", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to update user role in organization", err)
+		respondError(w, r, err)
 		return
 	}
 
+	setETag(w, orgUser.Version)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -197,7 +227,7 @@ func (h *organizationUserHandler) DeleteUserFromOrganization(w http.ResponseWrit
 	identity, err := auth.FromContext(r.Context())
 	if err != nil {
 		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 
@@ -205,14 +235,21 @@ func (h *organizationUserHandler) DeleteUserFromOrganization(w http.ResponseWrit
 
 	if orgID == "" {
 		h.log.Warn("Organization ID is required for deleting user", slog.String("orgID", orgID))
-		respondError(w, http.StatusBadRequest, "organization ID is required")
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
 		return
 	}
 
 	userIDToDelete := chi.URLParam(r, "userID")
 	if userIDToDelete == "" {
 		h.log.Warn("User ID is required for deleting user from organization", slog.String("userID", userIDToDelete))
-		respondError(w, http.StatusBadRequest, "user ID is required")
+		respondError(w, r, services.NewValidationError("userID", "is required"))
+		return
+	}
+
+	version, err := versionFromIfMatch(r)
+	if err != nil {
+		h.log.Warn("Missing or invalid If-Match header for deleting user from organization", slog.Any("error", err))
+		respondError(w, r, err)
 		return
 	}
 
@@ -223,16 +260,12 @@ func (h *organizationUserHandler) DeleteUserFromOrganization(w http.ResponseWrit
 		ActingUserID:   identity.UserID,
 		OrgID:          orgID,
 		UserIDToDelete: userIDToDelete,
+		Version:        version,
 	})
 
 	if err != nil {
-		if errors.Is(err, services.ErrUnauthorized) {
-			log.Warn("Unauthorized access attempt", slog.Any("error", err))
-			respondError(w, http.StatusForbidden, err.Error())
-			return
-		}
-		log.Error("Internal error while deleting user from organization", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to delete user from organization", err)
+		respondError(w, r, err)
 		return
 	}
 
@@ -240,3 +273,221 @@ func (h *organizationUserHandler) DeleteUserFromOrganization(w http.ResponseWrit
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *organizationUserHandler) BatchOrganizationUsers(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	if orgID == "" {
+		h.log.Warn("Organization ID is required for batch membership operations", slog.String("orgID", orgID))
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
+		return
+	}
+
+	var input BatchOrganizationUsersRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		h.log.Warn("Validation failed for batch membership operations", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	operations := make([]services.BatchOperation, len(input.Operations))
+	for i, op := range input.Operations {
+		operations[i] = services.BatchOperation{
+			Type:   op.Op,
+			UserID: op.UserID,
+			Role:   op.Role,
+		}
+		if op.Op != services.BatchOperationAdd {
+			// Validate has checked the versions of update_role and remove.
+			operations[i].Version, _ = op.expectedVersion()
+		}
+	}
+
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+	log.Info("Applying batch membership operations", slog.Int("operation_count", len(operations)), slog.String("mode", string(input.Mode)))
+
+	results, err := h.organizationUserService.BatchOrganizationUsers(r.Context(), services.BatchOrganizationUsersParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+		Atomic:       input.Mode != BatchModePartial,
+		Operations:   operations,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to apply batch membership operations", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewBatchOrganizationUsersResponse(results))
+}
+
+// maxImportBytes bounds the size of an uploaded import file.
+const maxImportBytes = 5 << 20
+
+func (h *organizationUserHandler) ImportOrganizationUsers(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	if orgID == "" {
+		h.log.Warn("Organization ID is required for importing users", slog.String("orgID", orgID))
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get(ContentType)); mediaType != ContentTypeCSV {
+		h.log.Warn("Unsupported content type for importing users", slog.String("content_type", r.Header.Get(ContentType)))
+		respondProblem(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Imports must be sent as text/csv")
+		return
+	}
+
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			h.log.Warn("Invalid dry_run parameter for importing users", slog.String("dry_run", raw))
+			respondError(w, r, services.NewValidationError("dry_run", "must be true or false"))
+			return
+		}
+	}
+
+	rows, err := parseMemberCSV(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		var validationErr *services.ValidationError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondProblem(w, r, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, "Import file is too large")
+		case errors.As(err, &validationErr):
+			h.log.Warn("Import file is malformed", slog.Any("error", err))
+			respondError(w, r, err)
+		default:
+			h.log.Error("Failed to read import file", slog.Any("error", err))
+			respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		}
+		return
+	}
+
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+	log.Info("Importing users into organization", slog.Int("row_count", len(rows)), slog.Bool("dry_run", dryRun))
+
+	report, err := h.organizationUserService.ImportOrganizationUsers(r.Context(), services.ImportOrganizationUsersParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+		Rows:         rows,
+		DryRun:       dryRun,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to import users into organization", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewImportReportResponse(report))
+}
+
+// ExportOrganizationUsers streams the members of an organization as CSV or,
+// with format=jsonl, as one JSON object per line.
+func (h *organizationUserHandler) ExportOrganizationUsers(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	if orgID == "" {
+		h.log.Warn("Organization ID is required for exporting users", slog.String("orgID", orgID))
+		respondError(w, r, services.NewValidationError("orgID", "is required"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		h.log.Warn("Unsupported export format", slog.String("format", format))
+		respondError(w, r, services.NewValidationError("format", "must be csv or jsonl"))
+		return
+	}
+
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID), slog.String("format", format))
+	log.Info("Exporting organization users")
+
+	csvWriter := csv.NewWriter(w)
+	jsonEncoder := json.NewEncoder(w)
+	started := false
+	// start sends the headers once the first member is read, so access
+	// errors can still be reported as a problem response.
+	start := func() error {
+		started = true
+		contentType := ContentTypeNDJSON
+		if format == "csv" {
+			contentType = ContentTypeCSV + "; charset=utf-8"
+		}
+		w.Header().Set(ContentType, contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="members-%s.%s"`, orgID, format))
+		w.WriteHeader(http.StatusOK)
+		if format == "csv" {
+			return csvWriter.Write(memberCSVHeader)
+		}
+		return nil
+	}
+
+	err = h.organizationUserService.ExportOrganizationUsers(r.Context(), services.ExportOrganizationUsersParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+	}, func(user *models.UserWithRole) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if format == "csv" {
+			return csvWriter.Write(memberCSVRecord(user))
+		}
+		return jsonEncoder.Encode(OrganizationMemberResponse{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     user.Role,
+			Version:  user.MembershipVersion,
+		})
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		csvWriter.Flush()
+		err = csvWriter.Error()
+	}
+	if err != nil {
+		if !started {
+			logServiceError(log, "Failed to export organization users", err)
+			respondError(w, r, err)
+			return
+		}
+		// The status line has been sent; abort the connection so the client
+		// sees a failed download rather than a silently truncated file.
+		log.Error("Export of organization users failed after streaming started", slog.Any("error", err))
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -3,6 +3,7 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -14,14 +15,20 @@ import (
 	"github.com/espennoreng/go-http-rental-server/internal/logger"
 	"github.com/espennoreng/go-http-rental-server/internal/middleware"
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
 )
 
 type mockOrganizationUserService struct {
 	createOrganizationUserFunc     func(ctx context.Context, params services.CreateOrganizationUserParams) (*models.OrganizationUser, error)
 	getUsersByOrganizationIDFunc   func(ctx context.Context, params services.GetUsersByOrganizationIDParams) ([]*models.UserWithRole, error)
-	updateUserRoleFunc             func(ctx context.Context, params services.UpdateUserRoleParams) error
+	getOrganizationUserFunc        func(ctx context.Context, params services.GetOrganizationUserParams) (*models.OrganizationUser, error)
+	updateUserRoleFunc             func(ctx context.Context, params services.UpdateUserRoleParams) (*models.OrganizationUser, error)
+	batchOrganizationUsersFunc     func(ctx context.Context, params services.BatchOrganizationUsersParams) ([]services.BatchOperationResult, error)
+	importOrganizationUsersFunc    func(ctx context.Context, params services.ImportOrganizationUsersParams) (*services.ImportReport, error)
+	exportOrganizationUsersFunc    func(ctx context.Context, params services.ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error
 	deleteUserFromOrganizationFunc func(ctx context.Context, params services.DeleteOrganizationUserParams) error
 }
 
@@ -33,7 +40,23 @@ func (m *mockOrganizationUserService) GetUsersByOrganizationID(ctx context.Conte
 	return m.getUsersByOrganizationIDFunc(ctx, params)
 }
 
-func (m *mockOrganizationUserService) UpdateUserRole(ctx context.Context, params services.UpdateUserRoleParams) error {
+func (m *mockOrganizationUserService) GetOrganizationUser(ctx context.Context, params services.GetOrganizationUserParams) (*models.OrganizationUser, error) {
+	return m.getOrganizationUserFunc(ctx, params)
+}
+
+func (m *mockOrganizationUserService) BatchOrganizationUsers(ctx context.Context, params services.BatchOrganizationUsersParams) ([]services.BatchOperationResult, error) {
+	return m.batchOrganizationUsersFunc(ctx, params)
+}
+
+func (m *mockOrganizationUserService) ImportOrganizationUsers(ctx context.Context, params services.ImportOrganizationUsersParams) (*services.ImportReport, error) {
+	return m.importOrganizationUsersFunc(ctx, params)
+}
+
+func (m *mockOrganizationUserService) ExportOrganizationUsers(ctx context.Context, params services.ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error {
+	return m.exportOrganizationUsersFunc(ctx, params, fn)
+}
+
+func (m *mockOrganizationUserService) UpdateUserRole(ctx context.Context, params services.UpdateUserRoleParams) (*models.OrganizationUser, error) {
 	return m.updateUserRoleFunc(ctx, params)
 }
 func (m *mockOrganizationUserService) DeleteUserFromOrganization(ctx context.Context, params services.DeleteOrganizationUserParams) error {
@@ -196,6 +219,9 @@ func TestOrganizationUserHandler_DeleteOrganizationUser(t *testing.T) {
 			if params.UserIDToDelete != userToDeleteID {
 				t.Errorf("expected userIDToDelete %s, got %s", userToDeleteID, params.UserIDToDelete)
 			}
+			if params.Version != 3 {
+				t.Errorf("expected version 3, got %d", params.Version)
+			}
 			// Simulating a successful deletion
 			return nil
 		},
@@ -223,6 +249,7 @@ func TestOrganizationUserHandler_DeleteOrganizationUser(t *testing.T) {
 	r.Method(http.MethodDelete, "/organizations/{orgID}/users/{userID}", authedHandler)
 
 	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/organizations/%s/users/%s", orgID, userToDeleteID), nil)
+	req.Header.Set("If-Match", `"3"`)
 	res := httptest.NewRecorder()
 
 	r.ServeHTTP(res, req)
@@ -231,3 +258,363 @@ func TestOrganizationUserHandler_DeleteOrganizationUser(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusNoContent, res.Code)
 	}
 }
+
+func TestOrganizationUserHandler_DeleteOrganizationUser_Preconditions(t *testing.T) {
+	const actingUserID = "admin-user-007"
+	const orgID = "org-001"
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		serviceErr     error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "missing If-Match",
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedCode:   problem.CodePreconditionRequired,
+		},
+		{
+			name:           "malformed If-Match",
+			ifMatch:        "W/\"3\"",
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   problem.CodeVersionMismatch,
+		},
+		{
+			name:           "stale version",
+			ifMatch:        `"2"`,
+			serviceErr:     services.ErrVersionMismatch,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   problem.CodeVersionMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logger.NewTestLogger(t)
+
+			mockService := &mockOrganizationUserService{
+				deleteUserFromOrganizationFunc: func(ctx context.Context, params services.DeleteOrganizationUserParams) error {
+					return tt.serviceErr
+				},
+			}
+
+			r := chi.NewRouter()
+			handler := api.NewOrganizationUserHandler(mockService, logger)
+			authedHandler := middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.DeleteUserFromOrganization), auth.Identity{UserID: actingUserID})
+			r.Method(http.MethodDelete, "/organizations/{orgID}/users/{userID}", authedHandler)
+
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/organizations/%s/users/%s", orgID, "member-user-001"), nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			res := httptest.NewRecorder()
+
+			r.ServeHTTP(res, req)
+
+			if res.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, res.Code)
+			}
+			api.AssertProblemContentType(t, res)
+			api.AssertProblemBody(t, res, tt.expectedCode, "")
+		})
+	}
+}
+
+func TestOrganizationUserHandler_BatchOrganizationUsers(t *testing.T) {
+	const actingUserID = "admin-user-007"
+	const orgID = "org-001"
+
+	newRouter := func(t *testing.T, mockService *mockOrganizationUserService) *chi.Mux {
+		r := chi.NewRouter()
+		handler := api.NewOrganizationUserHandler(mockService, logger.NewTestLogger(t))
+		authedHandler := middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.BatchOrganizationUsers), auth.Identity{UserID: actingUserID})
+		r.Method(http.MethodPost, "/organizations/{orgID}/users:batch", authedHandler)
+		return r
+	}
+
+	t.Run("partial batch returns per-item results", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			batchOrganizationUsersFunc: func(ctx context.Context, params services.BatchOrganizationUsersParams) ([]services.BatchOperationResult, error) {
+				assert.Equal(t, orgID, params.OrgID)
+				assert.Equal(t, actingUserID, params.ActingUserID)
+				assert.False(t, params.Atomic)
+				assert.Len(t, params.Operations, 2)
+				assert.Equal(t, services.AnyVersion, params.Operations[1].Version)
+				return []services.BatchOperationResult{
+					{
+						Operation: params.Operations[0],
+						OrgUser:   &models.OrganizationUser{OrgID: orgID, UserID: "user-1", Role: models.RoleMember, Version: 1},
+					},
+					{
+						Operation: params.Operations[1],
+						Err:       services.ErrOrganizationUserNotFound,
+					},
+				}, nil
+			},
+		}
+
+		body := `{"mode":"partial","operations":[{"op":"add","user_id":"user-1","role":"member"},{"op":"remove","user_id":"user-2","version":"*"}]}`
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/organizations/%s/users:batch", orgID), bytes.NewBufferString(body))
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		var response api.BatchOrganizationUsersResponse
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Len(t, response.Results, 2)
+		assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+		assert.Equal(t, "user-1", response.Results[0].Membership.UserID)
+		assert.Equal(t, http.StatusNotFound, response.Results[1].Status)
+		assert.Equal(t, problem.CodeMembershipNotFound, response.Results[1].Error.Code)
+	})
+
+	t.Run("failed atomic batch names the operation", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			batchOrganizationUsersFunc: func(ctx context.Context, params services.BatchOrganizationUsersParams) ([]services.BatchOperationResult, error) {
+				assert.True(t, params.Atomic)
+				return nil, &services.BatchOperationError{Index: 0, Err: services.ErrUserAlreadyHasARoleInOrganization}
+			},
+		}
+
+		body := `{"operations":[{"op":"add","user_id":"user-1","role":"member"}]}`
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/organizations/%s/users:batch", orgID), bytes.NewBufferString(body))
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+		api.AssertProblemBody(t, res, problem.CodeMembershipExists, "operations[0]")
+	})
+
+	t.Run("invalid operations are rejected", func(t *testing.T) {
+		body := `{"mode":"sometimes","operations":[{"op":"promote","user_id":""}]}`
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/organizations/%s/users:batch", orgID), bytes.NewBufferString(body))
+		res := httptest.NewRecorder()
+
+		newRouter(t, &mockOrganizationUserService{}).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "operations[0].op")
+	})
+
+	t.Run("versions are passed on", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			batchOrganizationUsersFunc: func(ctx context.Context, params services.BatchOrganizationUsersParams) ([]services.BatchOperationResult, error) {
+				assert.Equal(t, 3, params.Operations[0].Version)
+				return []services.BatchOperationResult{{Operation: params.Operations[0], OrgUser: &models.OrganizationUser{Version: 4}}}, nil
+			},
+		}
+
+		body := `{"operations":[{"op":"update_role","user_id":"user-1","role":"admin","version":3}]}`
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/organizations/%s/users:batch", orgID), bytes.NewBufferString(body))
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	for name, version := range map[string]string{
+		"missing version": "",
+		"null version":    `,"version":null`,
+		"zero version":    `,"version":0`,
+		"other string":    `,"version":"3"`,
+	} {
+		t.Run(name+" is rejected", func(t *testing.T) {
+			body := `{"operations":[{"op":"remove","user_id":"user-1"` + version + `},{"op":"update_role","user_id":"user-2","role":"admin"` + version + `}]}`
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/organizations/%s/users:batch", orgID), bytes.NewBufferString(body))
+			res := httptest.NewRecorder()
+
+			newRouter(t, &mockOrganizationUserService{}).ServeHTTP(res, req)
+
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Contains(t, res.Body.String(), "operations[0].version")
+			api.AssertProblemBody(t, res, problem.CodeInvalidInput, "operations[1].version")
+		})
+	}
+}
+
+func TestOrganizationUserHandler_ImportOrganizationUsers(t *testing.T) {
+	const actingUserID = "admin-user-007"
+	const orgID = "org-001"
+
+	newRouter := func(t *testing.T, mockService *mockOrganizationUserService) *chi.Mux {
+		r := chi.NewRouter()
+		handler := api.NewOrganizationUserHandler(mockService, logger.NewTestLogger(t))
+		authedHandler := middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.ImportOrganizationUsers), auth.Identity{UserID: actingUserID})
+		r.Method(http.MethodPost, "/organizations/{orgID}/users/import", authedHandler)
+		return r
+	}
+	newRequest := func(query, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/organizations/%s/users/import%s", orgID, query), bytes.NewBufferString(body))
+		req.Header.Set(api.ContentType, "text/csv; charset=utf-8")
+		return req
+	}
+
+	t.Run("dry run parses rows with line numbers", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			importOrganizationUsersFunc: func(ctx context.Context, params services.ImportOrganizationUsersParams) (*services.ImportReport, error) {
+				assert.True(t, params.DryRun)
+				assert.Equal(t, []services.ImportMemberRow{
+					{Line: 2, UserID: "user-1", Role: models.RoleMember},
+					{Line: 4, UserID: "user-2", Role: models.RoleAdmin},
+				}, params.Rows)
+				return &services.ImportReport{
+					DryRun:   true,
+					Rows:     2,
+					Imported: 1,
+					Errors:   []services.FieldError{{Line: 4, Field: "user_id", Message: "must be a valid UUID"}},
+				}, nil
+			},
+		}
+
+		// Extra columns are ignored and blank lines skipped, so exports can be re-imported.
+		body := "email,role,user_id\nann@example.com,member,user-1\n\nbob@example.com,admin,user-2\n"
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, newRequest("?dry_run=true", body))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		var response api.ImportReportResponse
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, 1, response.Imported)
+		assert.Equal(t, 4, response.Errors[0].Line)
+	})
+
+	t.Run("rejected import returns line-level errors", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			importOrganizationUsersFunc: func(ctx context.Context, params services.ImportOrganizationUsersParams) (*services.ImportReport, error) {
+				return nil, &services.ValidationError{Fields: []services.FieldError{{Line: 2, Field: "role", Message: "must be one of admin, member"}}}
+			},
+		}
+
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, newRequest("", "user_id,role\nuser-1,owner\n"))
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "line 2: role")
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		res := httptest.NewRecorder()
+
+		newRouter(t, &mockOrganizationUserService{}).ServeHTTP(res, newRequest("", "id,role\nuser-1,member\n"))
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "header")
+	})
+
+	t.Run("wrong content type", func(t *testing.T) {
+		req := newRequest("", "user_id,role\n")
+		req.Header.Set(api.ContentType, api.ContentTypeJSON)
+		res := httptest.NewRecorder()
+
+		newRouter(t, &mockOrganizationUserService{}).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, res.Code)
+	})
+}
+
+func TestOrganizationUserHandler_ExportOrganizationUsers(t *testing.T) {
+	const actingUserID = "member-user-001"
+	const orgID = "org-001"
+
+	members := []*models.UserWithRole{
+		{User: models.User{ID: "user-1", Username: "ann", Email: "ann@example.com"}, Role: models.RoleAdmin, MembershipVersion: 1},
+		{User: models.User{ID: "user-2", Username: "bob", Email: "bob@example.com"}, Role: models.RoleMember, MembershipVersion: 3},
+	}
+
+	newRouter := func(t *testing.T, mockService *mockOrganizationUserService) *chi.Mux {
+		r := chi.NewRouter()
+		handler := api.NewOrganizationUserHandler(mockService, logger.NewTestLogger(t))
+		authedHandler := middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.ExportOrganizationUsers), auth.Identity{UserID: actingUserID})
+		r.Method(http.MethodGet, "/organizations/{orgID}/users/export", authedHandler)
+		return r
+	}
+	streamingService := &mockOrganizationUserService{
+		exportOrganizationUsersFunc: func(ctx context.Context, params services.ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error {
+			for _, member := range members {
+				if err := fn(member); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/organizations/%s/users/export", orgID), nil)
+		res := httptest.NewRecorder()
+
+		newRouter(t, streamingService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get(api.ContentType))
+		assert.Equal(t, "user_id,username,email,role,version\nuser-1,ann,ann@example.com,admin,1\nuser-2,bob,bob@example.com,member,3\n", res.Body.String())
+	})
+
+	t.Run("csv escapes formulas", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			exportOrganizationUsersFunc: func(ctx context.Context, params services.ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error {
+				return fn(&models.UserWithRole{
+					User: models.User{ID: "user-3", Username: "=HYPERLINK(\"http://evil.example\")", Email: "@sum(1)@example.com"},
+					Role: models.RoleMember, MembershipVersion: 1,
+				})
+			},
+		}
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/organizations/%s/users/export", orgID), nil)
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "user_id,username,email,role,version\nuser-3,\"'=HYPERLINK(\"\"http://evil.example\"\")\",'@sum(1)@example.com,member,1\n", res.Body.String())
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/organizations/%s/users/export?format=jsonl", orgID), nil)
+		res := httptest.NewRecorder()
+
+		newRouter(t, streamingService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, api.ContentTypeNDJSON, res.Header().Get(api.ContentType))
+		decoder := json.NewDecoder(res.Body)
+		var lines []api.OrganizationMemberResponse
+		for decoder.More() {
+			var line api.OrganizationMemberResponse
+			assert.NoError(t, decoder.Decode(&line))
+			lines = append(lines, line)
+		}
+		assert.Len(t, lines, 2)
+		assert.Equal(t, 3, lines[1].Version)
+	})
+
+	t.Run("access errors are reported before streaming", func(t *testing.T) {
+		mockService := &mockOrganizationUserService{
+			exportOrganizationUsersFunc: func(ctx context.Context, params services.ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error {
+				return services.ErrUserNotPartOfOrganization
+			},
+		}
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/organizations/%s/users/export", orgID), nil)
+		res := httptest.NewRecorder()
+
+		newRouter(t, mockService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusForbidden, res.Code)
+		api.AssertProblemContentType(t, res)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/organizations/%s/users/export?format=xlsx", orgID), nil)
+		res := httptest.NewRecorder()
+
+		newRouter(t, streamingService).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
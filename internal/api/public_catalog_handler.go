@@ -0,0 +1,90 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// staleWhileRevalidate is how long caches may keep serving a public
+// response after it expires while they fetch a fresh one.
+const staleWhileRevalidate = 24 * time.Hour
+
+type publicCatalogHandler struct {
+	publicCatalogService services.PublicCatalogService
+	cacheMaxAge          time.Duration
+	log                  *slog.Logger
+}
+
+// NewPublicCatalogHandler creates a handler for the unauthenticated
+// /public endpoints. Responses may be cached for cacheMaxAge.
+func NewPublicCatalogHandler(publicCatalogService services.PublicCatalogService, cacheMaxAge time.Duration, log *slog.Logger) *publicCatalogHandler {
+	return &publicCatalogHandler{
+		publicCatalogService: publicCatalogService,
+		cacheMaxAge:          cacheMaxAge,
+		log:                  log.With(slog.String("component", "public_catalog_handler")),
+	}
+}
+
+func (h *publicCatalogHandler) GetStorefront(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "orgSlug")
+
+	storefront, err := h.publicCatalogService.GetStorefront(r.Context(), services.GetStorefrontParams{Slug: slug})
+	if err != nil {
+		logServiceError(h.log.With(slog.String("slug", slug)), "Failed to fetch storefront", err)
+		respondError(w, r, err)
+		return
+	}
+
+	h.respondCached(w, r, NewPublicStorefrontResponse(storefront))
+}
+
+// respondCached writes data as JSON that browsers and shared caches may
+// keep. The ETag is a hash of the body, so a client that sends it back in
+// If-None-Match gets 304 Not Modified until the content changes.
+func (h *publicCatalogHandler) respondCached(w http.ResponseWriter, r *http.Request, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		h.log.Error("Failed to encode public response", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set(headerETag, etag)
+	w.Header().Set(headerCacheControl, fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d",
+		int(h.cacheMaxAge.Seconds()), int(staleWhileRevalidate.Seconds())))
+	// The endpoints are anonymous and read-only, so any site may embed them.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if etagMatches(r.Header.Get(headerIfNoneMatch), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set(ContentType, ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag. It uses
+// the weak comparison RFC 9110 requires for If-None-Match, so W/ prefixes
+// added by compressing proxies are ignored.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPublicCatalogService struct {
+	getStorefrontFunc func(ctx context.Context, params services.GetStorefrontParams) (*services.Storefront, error)
+}
+
+func (m *mockPublicCatalogService) GetStorefront(ctx context.Context, params services.GetStorefrontParams) (*services.Storefront, error) {
+	return m.getStorefrontFunc(ctx, params)
+}
+
+func newPublicCatalogRouter(t *testing.T, service services.PublicCatalogService) chi.Router {
+	handler := api.NewPublicCatalogHandler(service, 5*time.Minute, logger.NewTestLogger(t))
+	r := chi.NewRouter()
+	r.Get("/public/{orgSlug}", handler.GetStorefront)
+	return r
+}
+
+func TestPublicCatalogHandler_GetStorefront(t *testing.T) {
+	service := &mockPublicCatalogService{
+		getStorefrontFunc: func(ctx context.Context, params services.GetStorefrontParams) (*services.Storefront, error) {
+			if params.Slug != "oslo-ski-rental" {
+				return nil, services.ErrCatalogNotFound
+			}
+			return &services.Storefront{
+				Organization: &models.Organization{ID: "org-1", Name: "Oslo Ski Rental", Slug: params.Slug, Currency: "NOK", CreatedBy: "user-1", PublicCatalog: true},
+				Locations: []*models.Location{
+					{ID: "location-1", OrgID: "org-1", Name: "Oslo depot", TimeZone: "Europe/Oslo", Version: 3},
+				},
+			}, nil
+		},
+	}
+
+	var etag string
+
+	t.Run("exposes only public fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public/oslo-ski-rental", nil)
+		res := httptest.NewRecorder()
+
+		newPublicCatalogRouter(t, service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "public, max-age=300, stale-while-revalidate=86400", res.Header().Get("Cache-Control"))
+		assert.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+		etag = res.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		assert.Equal(t, "Oslo Ski Rental", body["name"])
+		assert.NotContains(t, body, "id")
+		assert.NotContains(t, body, "created_by")
+		location := body["locations"].([]any)[0].(map[string]any)
+		assert.NotContains(t, location, "id")
+		assert.NotContains(t, location, "version")
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public/oslo-ski-rental", nil)
+		req.Header.Set("If-None-Match", "W/"+etag)
+		res := httptest.NewRecorder()
+
+		newPublicCatalogRouter(t, service).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotModified, res.Code)
+		assert.Empty(t, res.Body.String())
+		assert.Equal(t, etag, res.Header().Get("ETag"))
+	})
+
+	t.Run("no catalog", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public/private-rental", nil)
+		res := httptest.NewRecorder()
+
+		newPublicCatalogRouter(t, service).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusNotFound)
+		api.AssertProblemBody(t, res, problem.CodeCatalogNotFound, "catalog not found")
+		assert.Empty(t, res.Header().Get("Cache-Control"))
+	})
+}
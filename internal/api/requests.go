@@ -1,9 +1,12 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
 )
 
 type AddUserToOrganizationRequest struct {
@@ -12,13 +15,14 @@ type AddUserToOrganizationRequest struct {
 }
 
 func (r *AddUserToOrganizationRequest) Validate() error {
+	var errs services.ValidationError
 	if r.UserID == "" {
-		return errors.New("user_id is required")
+		errs.Add("user_id", "is required")
 	}
 	if r.Role == "" {
-		return errors.New("role is required")
+		errs.Add("role", "is required")
 	}
-	return nil
+	return errs.Err()
 }
 
 type UpdateUserRoleRequest struct {
@@ -26,21 +30,118 @@ type UpdateUserRoleRequest struct {
 }
 
 func (r *UpdateUserRoleRequest) Validate() error {
+	var errs services.ValidationError
 	if r.Role == "" {
-		return errors.New("role is required")
+		errs.Add("role", "is required")
 	}
-	return nil
+	return errs.Err()
+}
+
+// BatchMode selects whether a membership batch is all-or-nothing.
+type BatchMode string
+
+const (
+	BatchModeAtomic  BatchMode = "atomic"
+	BatchModePartial BatchMode = "partial"
+)
+
+type BatchOperationRequest struct {
+	Op     services.BatchOperationType `json:"op"`
+	UserID string                      `json:"user_id"`
+	Role   models.Role                 `json:"role,omitempty"`
+	// Version is the expected membership version, required for
+	// update_role and remove. Like If-Match, it is a version number or
+	// "*" to skip the check.
+	Version json.RawMessage `json:"version,omitempty"`
+}
+
+// expectedVersion reads Version, mapping "*" to services.AnyVersion.
+func (r *BatchOperationRequest) expectedVersion() (int, error) {
+	if len(r.Version) == 0 || string(r.Version) == "null" {
+		return 0, errors.New(`is required; use "*" to skip the version check`)
+	}
+	if string(r.Version) == `"*"` {
+		return services.AnyVersion, nil
+	}
+	var version int
+	if err := json.Unmarshal(r.Version, &version); err != nil || version <= 0 {
+		return 0, errors.New(`must be a positive integer or "*"`)
+	}
+	return version, nil
+}
+
+type BatchOrganizationUsersRequest struct {
+	// Mode defaults to atomic.
+	Mode       BatchMode               `json:"mode,omitempty"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+func (r *BatchOrganizationUsersRequest) Validate() error {
+	var errs services.ValidationError
+	if r.Mode != "" && r.Mode != BatchModeAtomic && r.Mode != BatchModePartial {
+		errs.Add("mode", "must be atomic or partial")
+	}
+	if len(r.Operations) == 0 {
+		errs.Add("operations", "is required")
+	}
+	for i, op := range r.Operations {
+		field := fmt.Sprintf("operations[%d]", i)
+		switch op.Op {
+		case services.BatchOperationAdd, services.BatchOperationUpdateRole:
+			if op.Role == "" {
+				errs.Add(field+".role", "is required")
+			}
+		case services.BatchOperationRemove:
+		case "":
+			errs.Add(field+".op", "is required")
+		default:
+			errs.Add(field+".op", "must be one of add, update_role, remove")
+		}
+		if op.Op == services.BatchOperationUpdateRole || op.Op == services.BatchOperationRemove {
+			if _, err := op.expectedVersion(); err != nil {
+				errs.Add(field+".version", err.Error())
+			}
+		}
+		if op.UserID == "" {
+			errs.Add(field+".user_id", "is required")
+		}
+	}
+	return errs.Err()
 }
 
 type CreateOrganizationRequest struct {
 	Name string `json:"name"`
+	// Currency is an ISO 4217 code such as "NOK"; it defaults to NOK.
+	Currency string `json:"currency,omitempty"`
+	// Slug names the organization in public URLs, such as
+	// "oslo-ski-rental"; it is optional.
+	Slug string `json:"slug,omitempty"`
 }
 
 func (r *CreateOrganizationRequest) Validate() error {
+	var errs services.ValidationError
 	if r.Name == "" {
-		return errors.New("name is required")
+		errs.Add("name", "is required")
 	}
-	return nil
+	return errs.Err()
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name"`
+	// Currency, Slug and PublicCatalog are left unchanged if omitted.
+	Currency string `json:"currency,omitempty"`
+	Slug     string `json:"slug,omitempty"`
+	// PublicCatalog opts in to the unauthenticated /public endpoints,
+	// which needs a slug.
+	PublicCatalog *bool `json:"public_catalog,omitempty"`
+}
+
+func (r *UpdateOrganizationRequest) Validate() error {
+	var errs services.ValidationError
+	if r.Name == "" {
+		errs.Add("name", "is required")
+	}
+	return errs.Err()
 }
 
 type CreateUserRequest struct {
@@ -49,11 +150,137 @@ type CreateUserRequest struct {
 }
 
 func (r *CreateUserRequest) Validate() error {
+	var errs services.ValidationError
 	if r.Username == "" {
-		return errors.New("username is required")
+		errs.Add("username", "is required")
 	}
 	if r.Email == "" {
-		return errors.New("email is required")
+		errs.Add("email", "is required")
+	}
+	return errs.Err()
+}
+
+type CreateWebhookRequest struct {
+	URL         string             `json:"url"`
+	Events      []models.EventType `json:"events"`
+	Description string             `json:"description,omitempty"`
+}
+
+func (r *CreateWebhookRequest) Validate() error {
+	var errs services.ValidationError
+	if r.URL == "" {
+		errs.Add("url", "is required")
+	}
+	if len(r.Events) == 0 {
+		errs.Add("events", "is required")
+	}
+	return errs.Err()
+}
+
+// UpdateNotificationPreferencesRequest changes only the fields it sets.
+type UpdateNotificationPreferencesRequest struct {
+	Locale   string                       `json:"locale,omitempty"`
+	Channels *NotificationChannelsRequest `json:"channels,omitempty"`
+}
+
+type NotificationChannelsRequest struct {
+	Email *bool `json:"email,omitempty"`
+}
+
+func (r *UpdateNotificationPreferencesRequest) params(actingUserID string) services.UpdateNotificationPreferencesParams {
+	params := services.UpdateNotificationPreferencesParams{
+		ActingUserID: actingUserID,
+		Locale:       r.Locale,
+		Channels:     map[models.NotificationChannel]bool{},
+	}
+	if r.Channels != nil && r.Channels.Email != nil {
+		params.Channels[models.NotificationEmail] = *r.Channels.Email
+	}
+	return params
+}
+
+// LocationRequest creates a location or replaces all of its fields.
+type LocationRequest struct {
+	Name         string                `json:"name"`
+	Address      models.Address        `json:"address"`
+	TimeZone     string                `json:"time_zone"`
+	OpeningHours []models.OpeningHours `json:"opening_hours"`
+}
+
+func (r *LocationRequest) Validate() error {
+	var errs services.ValidationError
+	if r.Name == "" {
+		errs.Add("name", "is required")
+	}
+	if r.TimeZone == "" {
+		errs.Add("time_zone", "is required")
+	}
+	return errs.Err()
+}
+
+func (r *LocationRequest) details() services.LocationDetails {
+	return services.LocationDetails{
+		Name:         r.Name,
+		Address:      r.Address,
+		TimeZone:     r.TimeZone,
+		OpeningHours: r.OpeningHours,
+	}
+}
+
+// PublishTermsRequest publishes a new version of an organization's terms.
+type PublishTermsRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+func (r *PublishTermsRequest) Validate() error {
+	var errs services.ValidationError
+	if r.Title == "" {
+		errs.Add("title", "is required")
+	}
+	if r.Body == "" {
+		errs.Add("body", "is required")
+	}
+	return errs.Err()
+}
+
+// AcceptTermsRequest accepts a version of an organization's terms.
+// ContentHash is the content_hash of the terms the user was shown.
+type AcceptTermsRequest struct {
+	ContentHash string `json:"content_hash"`
+}
+
+func (r *AcceptTermsRequest) Validate() error {
+	var errs services.ValidationError
+	if r.ContentHash == "" {
+		errs.Add("content_hash", "is required")
+	}
+	return errs.Err()
+}
+
+// CustomerProfileRequest registers the caller as a customer or replaces
+// their profile. Address may be omitted.
+type CustomerProfileRequest struct {
+	Phone   string          `json:"phone,omitempty"`
+	Address *models.Address `json:"address,omitempty"`
+}
+
+func (r *CustomerProfileRequest) profile() services.CustomerProfile {
+	profile := services.CustomerProfile{Phone: r.Phone}
+	if r.Address != nil {
+		profile.Address = *r.Address
+	}
+	return profile
+}
+
+type SetCustomerVerificationRequest struct {
+	Status models.VerificationStatus `json:"status"`
+}
+
+func (r *SetCustomerVerificationRequest) Validate() error {
+	var errs services.ValidationError
+	if r.Status == "" {
+		errs.Add("status", "is required")
 	}
-	return nil
+	return errs.Err()
 }
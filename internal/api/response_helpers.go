@@ -3,7 +3,10 @@ package api
 import (
 	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 )
 
 // RespondJSON sends a JSON response with the given status code and data.
@@ -21,13 +24,24 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-func respondError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set(ContentType, ContentTypeJSON)
-	w.WriteHeader(status)
-	response := struct {
-		Message string `json:"message"`
-	}{
-		Message: message,
+// respondError sends err as a problem+json response, mapping known service
+// errors to their status code and error code.
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	problem.WriteError(w, r, err)
+}
+
+// respondProblem sends a problem+json response for errors that originate in
+// the handler itself rather than in a service.
+func respondProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	problem.Write(w, r, problem.New(status, code, detail))
+}
+
+// logServiceError logs a service error at a level matching its severity:
+// client errors are warnings, everything else is an error.
+func logServiceError(log *slog.Logger, msg string, err error) {
+	if problem.FromError(err).Status < http.StatusInternalServerError {
+		log.Warn(msg, slog.Any("error", err))
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+	log.Error(msg, slog.Any("error", err))
 }
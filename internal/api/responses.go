@@ -1,22 +1,28 @@
 package api
 
 import (
+	"encoding/json"
+	"net/http"
 	"time"
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
 )
 
 type OrganizationUserResponse struct {
-	UserID string      `json:"user_id"`
-	OrgID  string      `json:"org_id"`
-	Role   models.Role `json:"role"`
+	UserID  string      `json:"user_id"`
+	OrgID   string      `json:"org_id"`
+	Role    models.Role `json:"role"`
+	Version int         `json:"version"`
 }
 
 func NewOrganizationUserResponse(user *models.OrganizationUser) *OrganizationUserResponse {
 	return &OrganizationUserResponse{
-		UserID: user.UserID,
-		OrgID:  user.OrgID,
-		Role:   user.Role,
+		UserID:  user.UserID,
+		OrgID:   user.OrgID,
+		Role:    user.Role,
+		Version: user.Version,
 	}
 }
 
@@ -25,6 +31,9 @@ type OrganizationMemberResponse struct {
 	Username string      `json:"username"`
 	Email    string      `json:"email"`
 	Role     models.Role `json:"role"`
+	// Version is the membership version, usable as If-Match when
+	// changing or removing the member.
+	Version int `json:"version"`
 }
 
 type OrganizationMembersResponse struct {
@@ -39,20 +48,91 @@ func NewOrganizationMembersResponse(users []*models.UserWithRole) *OrganizationM
 			Username: user.Username,
 			Email:    user.Email,
 			Role:     user.Role,
+			Version:  user.MembershipVersion,
 		}
 	}
 	return &OrganizationMembersResponse{Users: memberResponses}
 }
 
+// BatchOperationResultResponse reports one operation of a batch. Status is
+// the HTTP status the operation would have had as a single request.
+type BatchOperationResultResponse struct {
+	Index      int                         `json:"index"`
+	Op         services.BatchOperationType `json:"op"`
+	UserID     string                      `json:"user_id"`
+	Status     int                         `json:"status"`
+	Membership *OrganizationUserResponse   `json:"membership,omitempty"`
+	Error      *problem.Problem            `json:"error,omitempty"`
+}
+
+type BatchOrganizationUsersResponse struct {
+	Results []BatchOperationResultResponse `json:"results"`
+}
+
+func NewBatchOrganizationUsersResponse(results []services.BatchOperationResult) *BatchOrganizationUsersResponse {
+	responses := make([]BatchOperationResultResponse, len(results))
+	for i, result := range results {
+		response := BatchOperationResultResponse{
+			Index:  i,
+			Op:     result.Operation.Type,
+			UserID: result.Operation.UserID,
+		}
+		switch {
+		case result.Err != nil:
+			response.Error = problem.FromError(result.Err)
+			response.Status = response.Error.Status
+		case result.Operation.Type == services.BatchOperationAdd:
+			response.Status = http.StatusCreated
+		case result.Operation.Type == services.BatchOperationRemove:
+			response.Status = http.StatusNoContent
+		default:
+			response.Status = http.StatusOK
+		}
+		if result.OrgUser != nil {
+			response.Membership = NewOrganizationUserResponse(result.OrgUser)
+		}
+		responses[i] = response
+	}
+	return &BatchOrganizationUsersResponse{Results: responses}
+}
+
+type ImportReportResponse struct {
+	DryRun   bool                  `json:"dry_run"`
+	Rows     int                   `json:"rows"`
+	Imported int                   `json:"imported"`
+	Errors   []services.FieldError `json:"errors"`
+}
+
+func NewImportReportResponse(report *services.ImportReport) *ImportReportResponse {
+	errs := report.Errors
+	if errs == nil {
+		errs = []services.FieldError{}
+	}
+	return &ImportReportResponse{
+		DryRun:   report.DryRun,
+		Rows:     report.Rows,
+		Imported: report.Imported,
+		Errors:   errs,
+	}
+}
+
 type OrganizationResponse struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Currency      string `json:"currency"`
+	Slug          string `json:"slug,omitempty"`
+	PublicCatalog bool   `json:"public_catalog"`
+	Version       int    `json:"version"`
 }
 
 func NewOrganizationResponse(org *models.Organization) *OrganizationResponse {
 	return &OrganizationResponse{
-		ID:   org.ID,
-		Name: org.Name,
+		ID:            org.ID,
+		Name:          org.Name,
+		Currency:      string(org.Currency),
+		Slug:          org.Slug,
+		PublicCatalog: org.PublicCatalog,
+		Version:       org.Version,
 	}
 }
 
@@ -73,3 +153,385 @@ func NewUserResponse(user *models.User) *UserResponse {
 		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
 	}
 }
+
+type WebhookResponse struct {
+	ID          string             `json:"id"`
+	OrgID       string             `json:"org_id"`
+	URL         string             `json:"url"`
+	Events      []models.EventType `json:"events"`
+	Description string             `json:"description"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+func NewWebhookResponse(webhook *models.Webhook) *WebhookResponse {
+	return &WebhookResponse{
+		ID:          webhook.ID,
+		OrgID:       webhook.OrgID,
+		URL:         webhook.URL,
+		Events:      webhook.Events,
+		Description: webhook.Description,
+		CreatedAt:   webhook.CreatedAt,
+	}
+}
+
+// CreatedWebhookResponse is only returned on creation, the one time the
+// signing secret is shown.
+type CreatedWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+func NewCreatedWebhookResponse(webhook *models.Webhook) *CreatedWebhookResponse {
+	return &CreatedWebhookResponse{
+		WebhookResponse: *NewWebhookResponse(webhook),
+		Secret:          webhook.Secret,
+	}
+}
+
+type WebhooksResponse struct {
+	Webhooks []*WebhookResponse `json:"webhooks"`
+}
+
+func NewWebhooksResponse(webhooks []*models.Webhook) *WebhooksResponse {
+	responses := make([]*WebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		responses[i] = NewWebhookResponse(webhook)
+	}
+	return &WebhooksResponse{Webhooks: responses}
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhook_id"`
+	EventID        string                `json:"event_id"`
+	EventType      models.EventType      `json:"event_type"`
+	Status         models.DeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at,omitempty"`
+	LastStatusCode int                   `json:"last_status_code,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+func NewWebhookDeliveryResponse(delivery *models.WebhookDelivery) *WebhookDeliveryResponse {
+	response := &WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	// The next attempt is only meaningful while the delivery is queued.
+	if delivery.Status == models.DeliveryPending {
+		next := delivery.NextAttemptAt
+		response.NextAttemptAt = &next
+	}
+	return response
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDeliveryResponse `json:"deliveries"`
+}
+
+func NewWebhookDeliveriesResponse(deliveries []*models.WebhookDelivery) *WebhookDeliveriesResponse {
+	responses := make([]*WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = NewWebhookDeliveryResponse(delivery)
+	}
+	return &WebhookDeliveriesResponse{Deliveries: responses}
+}
+
+// EventResponse is the data of one server-sent event.
+type EventResponse struct {
+	ID             string           `json:"id"`
+	Type           models.EventType `json:"type"`
+	OrganizationID string           `json:"organization_id"`
+	OccurredAt     time.Time        `json:"occurred_at"`
+	Data           any              `json:"data"`
+}
+
+func NewEventResponse(event *models.Event) *EventResponse {
+	return &EventResponse{
+		ID:             event.ID,
+		Type:           event.Type,
+		OrganizationID: event.OrgID,
+		OccurredAt:     event.OccurredAt.UTC(),
+		Data:           event.Data,
+	}
+}
+
+type JobResponse struct {
+	ID          string           `json:"id"`
+	Kind        string           `json:"kind"`
+	Payload     json.RawMessage  `json:"payload"`
+	Status      models.JobStatus `json:"status"`
+	Attempts    int              `json:"attempts"`
+	MaxAttempts int              `json:"max_attempts"`
+	RunAt       time.Time        `json:"run_at"`
+	LockedUntil *time.Time       `json:"locked_until,omitempty"`
+	LastError   string           `json:"last_error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	FinishedAt  *time.Time       `json:"finished_at,omitempty"`
+}
+
+func NewJobResponse(job *models.Job) *JobResponse {
+	return &JobResponse{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LockedUntil: job.LockedUntil,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+}
+
+type JobsResponse struct {
+	Jobs []*JobResponse `json:"jobs"`
+}
+
+func NewJobsResponse(jobs []*models.Job) *JobsResponse {
+	responses := make([]*JobResponse, len(jobs))
+	for i, job := range jobs {
+		responses[i] = NewJobResponse(job)
+	}
+	return &JobsResponse{Jobs: responses}
+}
+
+type NotificationPreferencesResponse struct {
+	Locale   string                       `json:"locale"`
+	Channels NotificationChannelsResponse `json:"channels"`
+}
+
+type NotificationChannelsResponse struct {
+	Email bool `json:"email"`
+}
+
+func NewNotificationPreferencesResponse(prefs *models.NotificationPreferences) *NotificationPreferencesResponse {
+	return &NotificationPreferencesResponse{
+		Locale: prefs.Locale,
+		Channels: NotificationChannelsResponse{
+			Email: prefs.Enabled(models.NotificationEmail),
+		},
+	}
+}
+
+type AttachmentResponse struct {
+	ID          string    `json:"id"`
+	OrgID       string    `json:"org_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// URL and ThumbnailURL are signed download links that stop working
+	// at URLExpiresAt. ThumbnailURL is only set for images.
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	URLExpiresAt time.Time `json:"url_expires_at"`
+}
+
+func NewAttachmentResponse(attachment *services.SignedAttachment) *AttachmentResponse {
+	return &AttachmentResponse{
+		ID:           attachment.ID,
+		OrgID:        attachment.OrgID,
+		Filename:     attachment.Filename,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		CreatedBy:    attachment.CreatedBy,
+		CreatedAt:    attachment.CreatedAt,
+		URL:          attachment.URL,
+		ThumbnailURL: attachment.ThumbnailURL,
+		URLExpiresAt: attachment.ExpiresAt,
+	}
+}
+
+type LocationResponse struct {
+	ID           string                `json:"id"`
+	OrgID        string                `json:"org_id"`
+	Name         string                `json:"name"`
+	Address      models.Address        `json:"address"`
+	TimeZone     string                `json:"time_zone"`
+	OpeningHours []models.OpeningHours `json:"opening_hours"`
+	Version      int                   `json:"version"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+func NewLocationResponse(location *models.Location) *LocationResponse {
+	return &LocationResponse{
+		ID:           location.ID,
+		OrgID:        location.OrgID,
+		Name:         location.Name,
+		Address:      location.Address,
+		TimeZone:     location.TimeZone,
+		OpeningHours: location.OpeningHours,
+		Version:      location.Version,
+		CreatedAt:    location.CreatedAt,
+		UpdatedAt:    location.UpdatedAt,
+	}
+}
+
+type LocationsResponse struct {
+	Locations []*LocationResponse `json:"locations"`
+}
+
+func NewLocationsResponse(locations []*models.Location) *LocationsResponse {
+	responses := make([]*LocationResponse, len(locations))
+	for i, location := range locations {
+		responses[i] = NewLocationResponse(location)
+	}
+	return &LocationsResponse{Locations: responses}
+}
+
+type TermsResponse struct {
+	ID      string `json:"id"`
+	OrgID   string `json:"org_id"`
+	Version int    `json:"version"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	// ContentHash is the hex SHA-256 of body; send it back when accepting.
+	ContentHash string    `json:"content_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func NewTermsResponse(terms *models.Terms) *TermsResponse {
+	return &TermsResponse{
+		ID:          terms.ID,
+		OrgID:       terms.OrgID,
+		Version:     terms.Version,
+		Title:       terms.Title,
+		Body:        terms.Body,
+		ContentHash: terms.ContentHash,
+		CreatedAt:   terms.CreatedAt,
+	}
+}
+
+type TermsListResponse struct {
+	Terms []*TermsResponse `json:"terms"`
+}
+
+func NewTermsListResponse(versions []*models.Terms) *TermsListResponse {
+	responses := make([]*TermsResponse, len(versions))
+	for i, terms := range versions {
+		responses[i] = NewTermsResponse(terms)
+	}
+	return &TermsListResponse{Terms: responses}
+}
+
+type TermsAcceptanceResponse struct {
+	ID          string    `json:"id"`
+	TermsID     string    `json:"terms_id"`
+	OrgID       string    `json:"org_id"`
+	Version     int       `json:"version"`
+	UserID      string    `json:"user_id"`
+	IPAddress   string    `json:"ip_address"`
+	ContentHash string    `json:"content_hash"`
+	AcceptedAt  time.Time `json:"accepted_at"`
+}
+
+func NewTermsAcceptanceResponse(acceptance *models.TermsAcceptance) *TermsAcceptanceResponse {
+	return &TermsAcceptanceResponse{
+		ID:          acceptance.ID,
+		TermsID:     acceptance.TermsID,
+		OrgID:       acceptance.OrgID,
+		Version:     acceptance.Version,
+		UserID:      acceptance.UserID,
+		IPAddress:   acceptance.IPAddress,
+		ContentHash: acceptance.ContentHash,
+		AcceptedAt:  acceptance.AcceptedAt,
+	}
+}
+
+type CustomerResponse struct {
+	ID                 string                    `json:"id"`
+	OrgID              string                    `json:"org_id"`
+	UserID             string                    `json:"user_id"`
+	Username           string                    `json:"username"`
+	Email              string                    `json:"email"`
+	Phone              string                    `json:"phone,omitempty"`
+	Address            *models.Address           `json:"address,omitempty"`
+	VerificationStatus models.VerificationStatus `json:"verification_status"`
+	Version            int                       `json:"version"`
+	CreatedAt          time.Time                 `json:"created_at"`
+	UpdatedAt          time.Time                 `json:"updated_at"`
+}
+
+func NewCustomerResponse(customer *models.Customer) *CustomerResponse {
+	response := &CustomerResponse{
+		ID:                 customer.ID,
+		OrgID:              customer.OrgID,
+		UserID:             customer.UserID,
+		Username:           customer.Username,
+		Email:              customer.Email,
+		Phone:              customer.Phone,
+		VerificationStatus: customer.VerificationStatus,
+		Version:            customer.Version,
+		CreatedAt:          customer.CreatedAt,
+		UpdatedAt:          customer.UpdatedAt,
+	}
+	if customer.Address != (models.Address{}) {
+		response.Address = &customer.Address
+	}
+	return response
+}
+
+type CustomersResponse struct {
+	Customers []*CustomerResponse `json:"customers"`
+}
+
+func NewCustomersResponse(customers []*models.Customer) *CustomersResponse {
+	responses := make([]*CustomerResponse, len(customers))
+	for i, customer := range customers {
+		responses[i] = NewCustomerResponse(customer)
+	}
+	return &CustomersResponse{Customers: responses}
+}
+
+// PublicStorefrontResponse is an organization's public catalog. It holds
+// only what the organization has chosen to show anonymous visitors.
+type PublicStorefrontResponse struct {
+	Slug      string                   `json:"slug"`
+	Name      string                   `json:"name"`
+	Currency  string                   `json:"currency"`
+	Locations []PublicLocationResponse `json:"locations"`
+}
+
+type PublicLocationResponse struct {
+	Name         string                `json:"name"`
+	Address      models.Address        `json:"address"`
+	TimeZone     string                `json:"time_zone"`
+	OpeningHours []models.OpeningHours `json:"opening_hours"`
+}
+
+func NewPublicStorefrontResponse(storefront *services.Storefront) *PublicStorefrontResponse {
+	locations := make([]PublicLocationResponse, len(storefront.Locations))
+	for i, location := range storefront.Locations {
+		locations[i] = PublicLocationResponse{
+			Name:         location.Name,
+			Address:      location.Address,
+			TimeZone:     location.TimeZone,
+			OpeningHours: location.OpeningHours,
+		}
+	}
+	return &PublicStorefrontResponse{
+		Slug:      storefront.Organization.Slug,
+		Name:      storefront.Organization.Name,
+		Currency:  string(storefront.Organization.Currency),
+		Locations: locations,
+	}
+}
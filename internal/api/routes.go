@@ -7,13 +7,15 @@ import (
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
 	"github.com/espennoreng/go-http-rental-server/internal/config"
 	customMiddleware "github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Server struct {
-	router chi.Router
+	router  chi.Router
+	openAPI *openAPISpec
 }
 
 func NewServer(
@@ -24,21 +26,57 @@ func NewServer(
 	organizationService services.OrganizationService,
 	organizationUserService services.OrganizationUserService,
 	accessService services.AccessService,
+	idempotencyService services.IdempotencyService,
+	webhookService services.WebhookService,
+	eventService services.EventService,
+	jobService services.JobService,
+	notificationService services.NotificationService,
+	attachmentService services.AttachmentService,
+	locationService services.LocationService,
+	termsService services.TermsService,
+	customerService services.CustomerService,
+	publicCatalogService services.PublicCatalogService,
 ) *Server {
 	userHandler := NewUserHandler(userService, log)
 	organizationHandler := NewOrganizationHandler(organizationService, log)
 	organizationUserHandler := NewOrganizationUserHandler(organizationUserService, log)
+	webhookHandler := NewWebhookHandler(webhookService, log)
+	eventHandler := NewEventHandler(eventService, log)
+	jobHandler := NewJobHandler(jobService, log)
+	notificationHandler := NewNotificationHandler(notificationService, log)
+	attachmentHandler := NewAttachmentHandler(attachmentService, log)
+	locationHandler := NewLocationHandler(locationService, log)
+	termsHandler := NewTermsHandler(termsService, log)
+	customerHandler := NewCustomerHandler(customerService, log)
+	publicCatalogHandler := NewPublicCatalogHandler(publicCatalogService, cfg.PublicCatalog.CacheMaxAge, log)
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Recoverer)
 	r.Use(customMiddleware.NewSlogMiddleware(log))
+	r.Use(customMiddleware.NewRecoverer(log))
 
-	setupRoutes(r, cfg, log, verifier, userService, userHandler, organizationHandler, organizationUserHandler, accessService)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		respondProblem(w, r, http.StatusNotFound, problem.CodeNotFound, "resource not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		respondProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "method not allowed")
+	})
+
+	spec := &openAPISpec{}
+
+	setupRoutes(r, cfg, log, verifier, userService, userHandler, organizationHandler, organizationUserHandler, webhookHandler, eventHandler, jobHandler, notificationHandler, attachmentHandler, locationHandler, termsHandler, customerHandler, publicCatalogHandler, accessService, idempotencyService)
+	setupDocsRoutes(r, spec)
+
+	// The document is generated from the final router so it always
+	// describes the routes that are actually registered.
+	if spec.err = spec.generate(r); spec.err != nil {
+		log.Error("OpenAPI document is out of sync with the registered routes", slog.Any("error", spec.err))
+	}
 
 	return &Server{
-		router: r,
+		router:  r,
+		openAPI: spec,
 	}
 }
 
@@ -51,11 +89,23 @@ func setupRoutes(
 	userHandler *userHandler,
 	organizationHandler *organizationHandler,
 	organizationUserHandler *organizationUserHandler,
+	webhookHandler *webhookHandler,
+	eventHandler *eventHandler,
+	jobHandler *jobHandler,
+	notificationHandler *notificationHandler,
+	attachmentHandler *attachmentHandler,
+	locationHandler *locationHandler,
+	termsHandler *termsHandler,
+	customerHandler *customerHandler,
+	publicCatalogHandler *publicCatalogHandler,
 	accessService services.AccessService,
+	idempotencyService services.IdempotencyService,
 ) {
 
 	authMiddleware := customMiddleware.NewAuthMiddleware(log, verifier, userService, cfg.GoogleOAuthClientID)
 	accessMiddleware := customMiddleware.NewAccessMiddleware(accessService,log)
+	// Attachments are the largest bodies the idempotent routes accept.
+	idempotencyMiddleware := customMiddleware.NewIdempotencyMiddleware(idempotencyService, services.MaxAttachmentSize, log)
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Welcome to the Rental Server API"))
@@ -63,6 +113,7 @@ func setupRoutes(
 
 	r.Route("/users", func(r chi.Router) {
 		r.Use(authMiddleware)
+		r.Use(idempotencyMiddleware)
 
 		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
 			userHandler.CreateUser(w, r)
@@ -71,42 +122,245 @@ func setupRoutes(
 		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
 			userHandler.GetUserByID(w, r)
 		})
+
+		r.Get("/me/notification-preferences", func(w http.ResponseWriter, r *http.Request) {
+			notificationHandler.GetNotificationPreferences(w, r)
+		})
+
+		r.Patch("/me/notification-preferences", func(w http.ResponseWriter, r *http.Request) {
+			notificationHandler.UpdateNotificationPreferences(w, r)
+		})
+	})
+
+	// Unsubscribe links are followed from emails, without signing in; the
+	// token in the link authorizes the request.
+	r.Route("/notifications", func(r chi.Router) {
+		r.Get("/unsubscribe", func(w http.ResponseWriter, r *http.Request) {
+			notificationHandler.ConfirmUnsubscribe(w, r)
+		})
+
+		r.Post("/unsubscribe", func(w http.ResponseWriter, r *http.Request) {
+			notificationHandler.Unsubscribe(w, r)
+		})
+	})
+
+	// Download links are signed, so they work without signing in, e.g. in
+	// an <img> tag.
+	r.Get("/files/{attachmentID}", func(w http.ResponseWriter, r *http.Request) {
+		attachmentHandler.DownloadAttachment(w, r)
+	})
+
+	// Public catalogs are embedded on organizations' own sites, so they
+	// need no sign-in; rate limiting keeps anonymous traffic in check.
+	r.Route("/public", func(r chi.Router) {
+		r.Use(customMiddleware.NewRateLimiter(log, cfg.PublicCatalog.RequestsPerMinute, cfg.PublicCatalog.Burst, cfg.PublicCatalog.TrustedProxies))
+
+		r.Get("/{orgSlug}", func(w http.ResponseWriter, r *http.Request) {
+			publicCatalogHandler.GetStorefront(w, r)
+		})
 	})
 
 	r.Route("/organizations", func(r chi.Router) {
 		r.Use(authMiddleware)
+		r.Use(idempotencyMiddleware)
 
 		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
 			organizationHandler.CreateOrganization(w, r)
 		})
 
-		r.With(accessMiddleware.RequireMember).Route("/{orgID}", func(r chi.Router) {
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		r.Route("/{orgID}", func(r chi.Router) {
+			r.With(accessMiddleware.RequireMember).Get("/", func(w http.ResponseWriter, r *http.Request) {
 				organizationHandler.GetOrganizationByID(w, r)
 			})
+
+			r.With(accessMiddleware.RequireAdmin).Patch("/", func(w http.ResponseWriter, r *http.Request) {
+				organizationHandler.UpdateOrganization(w, r)
+			})
+		})
+
+		r.With(accessMiddleware.RequireAdmin).Post("/{orgID}/users:batch", func(w http.ResponseWriter, r *http.Request) {
+			organizationUserHandler.BatchOrganizationUsers(w, r)
+		})
+
+		r.Route("/{orgID}/users", func(r chi.Router) {
+			r.With(accessMiddleware.RequireMember).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				organizationUserHandler.GetUsersByOrganizationID(w, r)
+			})
+
+			r.With(accessMiddleware.RequireMember).Get("/export", func(w http.ResponseWriter, r *http.Request) {
+				organizationUserHandler.ExportOrganizationUsers(w, r)
+			})
+
+			r.With(accessMiddleware.RequireMember).Get("/{userID}", func(w http.ResponseWriter, r *http.Request) {
+				organizationUserHandler.GetOrganizationUser(w, r)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(accessMiddleware.RequireAdmin)
+
+				r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+					organizationUserHandler.AddUserToOrganization(w, r)
+				})
+
+				r.Post("/import", func(w http.ResponseWriter, r *http.Request) {
+					organizationUserHandler.ImportOrganizationUsers(w, r)
+				})
+
+				r.Put("/{userID}", func(w http.ResponseWriter, r *http.Request) {
+					organizationUserHandler.UpdateUserRole(w, r)
+				})
+
+				r.Delete("/{userID}", func(w http.ResponseWriter, r *http.Request) {
+					organizationUserHandler.DeleteUserFromOrganization(w, r)
+				})
+			})
+		})
+
+		r.With(accessMiddleware.RequireMember).Get("/{orgID}/events", func(w http.ResponseWriter, r *http.Request) {
+			eventHandler.StreamEvents(w, r)
+		})
+
+		r.Route("/{orgID}/locations", func(r chi.Router) {
+			r.With(accessMiddleware.RequireMember).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				locationHandler.ListLocations(w, r)
+			})
+
+			r.With(accessMiddleware.RequireMember).Get("/{locationID}", func(w http.ResponseWriter, r *http.Request) {
+				locationHandler.GetLocation(w, r)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(accessMiddleware.RequireAdmin)
+
+				r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+					locationHandler.CreateLocation(w, r)
+				})
+
+				r.Put("/{locationID}", func(w http.ResponseWriter, r *http.Request) {
+					locationHandler.UpdateLocation(w, r)
+				})
+
+				r.Delete("/{locationID}", func(w http.ResponseWriter, r *http.Request) {
+					locationHandler.DeleteLocation(w, r)
+				})
+			})
 		})
 
-		r.With(accessMiddleware.RequireAdmin).Route("/{orgID}/users", func(r chi.Router) {
+		r.Route("/{orgID}/terms", func(r chi.Router) {
+			r.With(accessMiddleware.RequireMember).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				termsHandler.ListTerms(w, r)
+			})
+
+			r.With(accessMiddleware.RequireAdmin).Post("/", func(w http.ResponseWriter, r *http.Request) {
+				termsHandler.PublishTerms(w, r)
+			})
+
+			// {version} is a version number or "current".
+			r.Route("/{version}", func(r chi.Router) {
+				r.Use(accessMiddleware.RequireMember)
 
+				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+					termsHandler.GetTerms(w, r)
+				})
+
+				r.Get("/acceptance", func(w http.ResponseWriter, r *http.Request) {
+					termsHandler.GetTermsAcceptance(w, r)
+				})
+
+				r.Post("/acceptance", func(w http.ResponseWriter, r *http.Request) {
+					termsHandler.AcceptTerms(w, r)
+				})
+			})
+		})
+
+		// Customers are not members, so registering and managing their own
+		// profile needs only a signed-in user.
+		r.Route("/{orgID}/customers", func(r chi.Router) {
 			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-				organizationUserHandler.AddUserToOrganization(w, r)
+				customerHandler.RegisterCustomer(w, r)
 			})
 
-			r.Put("/{userID}", func(w http.ResponseWriter, r *http.Request) {
-				organizationUserHandler.UpdateUserRole(w, r)
+			r.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+				customerHandler.GetOwnCustomer(w, r)
 			})
 
-			r.Delete("/{userID}", func(w http.ResponseWriter, r *http.Request) {
-				organizationUserHandler.DeleteUserFromOrganization(w, r)
+			r.Put("/me", func(w http.ResponseWriter, r *http.Request) {
+				customerHandler.UpdateOwnCustomer(w, r)
+			})
+
+			r.With(accessMiddleware.RequireMember).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				customerHandler.ListCustomers(w, r)
+			})
+
+			r.With(accessMiddleware.RequireMember).Get("/{customerID}", func(w http.ResponseWriter, r *http.Request) {
+				customerHandler.GetCustomer(w, r)
+			})
+
+			r.With(accessMiddleware.RequireAdmin).Put("/{customerID}/verification", func(w http.ResponseWriter, r *http.Request) {
+				customerHandler.SetCustomerVerification(w, r)
 			})
 		})
 
-		r.With(accessMiddleware.RequireMember).Route("/", func(r chi.Router) {
+		r.Route("/{orgID}/attachments", func(r chi.Router) {
+			r.Use(accessMiddleware.RequireMember)
+
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				attachmentHandler.UploadAttachment(w, r)
+			})
+
+			r.Get("/{attachmentID}", func(w http.ResponseWriter, r *http.Request) {
+				attachmentHandler.GetAttachment(w, r)
+			})
+
+			r.Delete("/{attachmentID}", func(w http.ResponseWriter, r *http.Request) {
+				attachmentHandler.DeleteAttachment(w, r)
+			})
+		})
+
+		r.Route("/{orgID}/webhooks", func(r chi.Router) {
+			r.Use(accessMiddleware.RequireAdmin)
+
 			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-				organizationUserHandler.GetUsersByOrganizationID(w, r)
+				webhookHandler.ListWebhooks(w, r)
+			})
+
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				webhookHandler.CreateWebhook(w, r)
+			})
+
+			r.Get("/{webhookID}", func(w http.ResponseWriter, r *http.Request) {
+				webhookHandler.GetWebhook(w, r)
+			})
+
+			r.Delete("/{webhookID}", func(w http.ResponseWriter, r *http.Request) {
+				webhookHandler.DeleteWebhook(w, r)
+			})
+
+			r.Get("/{webhookID}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+				webhookHandler.ListWebhookDeliveries(w, r)
+			})
+
+			r.Post("/{webhookID}/deliveries/{deliveryID}/redeliver", func(w http.ResponseWriter, r *http.Request) {
+				webhookHandler.RedeliverWebhook(w, r)
 			})
 		})
 	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(authMiddleware)
+
+		r.Get("/jobs", func(w http.ResponseWriter, r *http.Request) {
+			jobHandler.ListJobs(w, r)
+		})
+
+		r.Get("/jobs/{jobID}", func(w http.ResponseWriter, r *http.Request) {
+			jobHandler.GetJob(w, r)
+		})
+
+		r.Post("/jobs/{jobID}/retry", func(w http.ResponseWriter, r *http.Request) {
+			jobHandler.RetryJob(w, r)
+		})
+	})
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
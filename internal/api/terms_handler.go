@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// currentTermsVersion is the path segment that addresses an
+// organization's latest terms instead of a version number.
+const currentTermsVersion = "current"
+
+type termsHandler struct {
+	termsService services.TermsService
+	log          *slog.Logger
+}
+
+func NewTermsHandler(termsService services.TermsService, log *slog.Logger) *termsHandler {
+	return &termsHandler{
+		termsService: termsService,
+		log:          log.With(slog.String("component", "terms_handler")),
+	}
+}
+
+func (h *termsHandler) PublishTerms(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+
+	var input PublishTermsRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		log.Warn("Validation failed for terms", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	terms, err := h.termsService.PublishTerms(r.Context(), services.PublishTermsParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+		Title:        input.Title,
+		Body:         input.Body,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to publish terms", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Terms published successfully", slog.Int("version", terms.Version))
+
+	respondJSON(w, http.StatusCreated, NewTermsResponse(terms))
+}
+
+func (h *termsHandler) ListTerms(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+
+	versions, err := h.termsService.ListTerms(r.Context(), services.ListTermsParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+	})
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", orgID)), "Failed to list terms", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewTermsListResponse(versions))
+}
+
+func (h *termsHandler) GetTerms(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.termsParams(w, r)
+	if !ok {
+		return
+	}
+
+	terms, err := h.termsService.GetTerms(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", params.OrgID), slog.Int("version", params.Version)), "Failed to fetch terms", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewTermsResponse(terms))
+}
+
+func (h *termsHandler) AcceptTerms(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.termsParams(w, r)
+	if !ok {
+		return
+	}
+	log := h.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.Int("version", params.Version))
+
+	var input AcceptTermsRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		log.Warn("Validation failed for terms acceptance", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	acceptance, err := h.termsService.AcceptTerms(r.Context(), services.AcceptTermsParams{
+		TermsParams: params,
+		ContentHash: input.ContentHash,
+		IPAddress:   clientIP(r),
+	})
+	if err != nil {
+		logServiceError(log, "Failed to accept terms", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Terms accepted successfully", slog.Int("accepted_version", acceptance.Version))
+
+	respondJSON(w, http.StatusCreated, NewTermsAcceptanceResponse(acceptance))
+}
+
+func (h *termsHandler) GetTermsAcceptance(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.termsParams(w, r)
+	if !ok {
+		return
+	}
+
+	acceptance, err := h.termsService.GetTermsAcceptance(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", params.OrgID), slog.Int("version", params.Version)), "Failed to fetch terms acceptance", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewTermsAcceptanceResponse(acceptance))
+}
+
+// termsParams reads the acting user and the terms version from the
+// request, writing an error response and returning false if either is
+// missing or invalid.
+func (h *termsHandler) termsParams(w http.ResponseWriter, r *http.Request) (services.TermsParams, bool) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return services.TermsParams{}, false
+	}
+
+	version := services.CurrentTermsVersion
+	if v := chi.URLParam(r, "version"); v != currentTermsVersion {
+		version, err = strconv.Atoi(v)
+		if err != nil || version <= 0 {
+			respondError(w, r, services.NewValidationError("version", "must be a positive integer or current"))
+			return services.TermsParams{}, false
+		}
+	}
+
+	return services.TermsParams{
+		OrgID:        chi.URLParam(r, "orgID"),
+		ActingUserID: identity.UserID,
+		Version:      version,
+	}, true
+}
+
+// clientIP returns the address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
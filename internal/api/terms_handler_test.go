@@ -0,0 +1,181 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTermsService struct {
+	publishTermsFunc       func(ctx context.Context, params services.PublishTermsParams) (*models.Terms, error)
+	listTermsFunc          func(ctx context.Context, params services.ListTermsParams) ([]*models.Terms, error)
+	getTermsFunc           func(ctx context.Context, params services.TermsParams) (*models.Terms, error)
+	acceptTermsFunc        func(ctx context.Context, params services.AcceptTermsParams) (*models.TermsAcceptance, error)
+	getTermsAcceptanceFunc func(ctx context.Context, params services.TermsParams) (*models.TermsAcceptance, error)
+}
+
+func (m *mockTermsService) PublishTerms(ctx context.Context, params services.PublishTermsParams) (*models.Terms, error) {
+	return m.publishTermsFunc(ctx, params)
+}
+
+func (m *mockTermsService) ListTerms(ctx context.Context, params services.ListTermsParams) ([]*models.Terms, error) {
+	return m.listTermsFunc(ctx, params)
+}
+
+func (m *mockTermsService) GetTerms(ctx context.Context, params services.TermsParams) (*models.Terms, error) {
+	return m.getTermsFunc(ctx, params)
+}
+
+func (m *mockTermsService) AcceptTerms(ctx context.Context, params services.AcceptTermsParams) (*models.TermsAcceptance, error) {
+	return m.acceptTermsFunc(ctx, params)
+}
+
+func (m *mockTermsService) GetTermsAcceptance(ctx context.Context, params services.TermsParams) (*models.TermsAcceptance, error) {
+	return m.getTermsAcceptanceFunc(ctx, params)
+}
+
+func newTermsRouter(t *testing.T, service services.TermsService, identity auth.Identity) chi.Router {
+	handler := api.NewTermsHandler(service, logger.NewTestLogger(t))
+	r := chi.NewRouter()
+	r.Method(http.MethodPost, "/organizations/{orgID}/terms", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.PublishTerms), identity))
+	r.Method(http.MethodGet, "/organizations/{orgID}/terms/{version}", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.GetTerms), identity))
+	r.Method(http.MethodPost, "/organizations/{orgID}/terms/{version}/acceptance", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.AcceptTerms), identity))
+	return r
+}
+
+func TestTermsHandler_PublishTerms(t *testing.T) {
+	identity := auth.Identity{UserID: "admin-user"}
+
+	t.Run("success", func(t *testing.T) {
+		service := &mockTermsService{
+			publishTermsFunc: func(ctx context.Context, params services.PublishTermsParams) (*models.Terms, error) {
+				assert.Equal(t, "org-1", params.OrgID)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				return &models.Terms{ID: "terms-1", OrgID: params.OrgID, Version: 2, Title: params.Title, Body: params.Body, ContentHash: "abc"}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/terms", strings.NewReader(`{"title": "Rental terms", "body": "Return items clean."}`))
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, service, identity).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		var response api.TermsResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, 2, response.Version)
+		assert.Equal(t, "abc", response.ContentHash)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/terms", strings.NewReader(`{"title": "Rental terms"}`))
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, &mockTermsService{}, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusBadRequest)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "body is required")
+	})
+}
+
+func TestTermsHandler_GetTerms(t *testing.T) {
+	identity := auth.Identity{UserID: "member-user"}
+
+	service := &mockTermsService{
+		getTermsFunc: func(ctx context.Context, params services.TermsParams) (*models.Terms, error) {
+			if params.Version == services.CurrentTermsVersion {
+				return nil, services.ErrTermsNotFound
+			}
+			return &models.Terms{ID: "terms-1", Version: params.Version}, nil
+		},
+	}
+
+	t.Run("by version", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/organizations/org-1/terms/3", nil)
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, service, identity).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var response api.TermsResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, 3, response.Version)
+	})
+
+	t.Run("current without terms", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/organizations/org-1/terms/current", nil)
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, service, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusNotFound)
+		api.AssertProblemBody(t, res, problem.CodeTermsNotFound, "terms not found")
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/organizations/org-1/terms/latest", nil)
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, service, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusBadRequest)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "version must be a positive integer or current")
+	})
+}
+
+func TestTermsHandler_AcceptTerms(t *testing.T) {
+	identity := auth.Identity{UserID: "member-user"}
+
+	t.Run("records the client address", func(t *testing.T) {
+		service := &mockTermsService{
+			acceptTermsFunc: func(ctx context.Context, params services.AcceptTermsParams) (*models.TermsAcceptance, error) {
+				assert.Equal(t, services.CurrentTermsVersion, params.Version)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				assert.Equal(t, "abc", params.ContentHash)
+				return &models.TermsAcceptance{ID: "acceptance-1", Version: 2, UserID: params.ActingUserID, IPAddress: params.IPAddress, ContentHash: params.ContentHash}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/terms/current/acceptance", strings.NewReader(`{"content_hash": "abc"}`))
+		req.RemoteAddr = "[2001:db8::1]:54321"
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, service, identity).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		var response api.TermsAcceptanceResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, "2001:db8::1", response.IPAddress)
+		assert.Equal(t, 2, response.Version)
+	})
+
+	t.Run("outdated terms", func(t *testing.T) {
+		service := &mockTermsService{
+			acceptTermsFunc: func(ctx context.Context, params services.AcceptTermsParams) (*models.TermsAcceptance, error) {
+				return nil, services.ErrTermsOutdated
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/terms/1/acceptance", strings.NewReader(`{"content_hash": "abc"}`))
+		res := httptest.NewRecorder()
+
+		newTermsRouter(t, service, identity).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusConflict)
+		api.AssertProblemBody(t, res, problem.CodeTermsOutdated, "accept the current version")
+	})
+}
@@ -8,7 +8,8 @@ import (
 )
 
 type errorResponse struct {
-	Message string `json:"message"`
+	Detail string `json:"detail"`
+	Code   string `json:"code"`
 }
 
 // AssertStatus checks if the HTTP response recorder has the expected status code.
@@ -20,9 +21,9 @@ func AssertStatus(t *testing.T, res *httptest.ResponseRecorder, expected int) {
 	}
 }
 
-// AssertErrorBody checks if the response body contains the expected error message.
-// It also handles the trailing newline that http.Error adds.
-func AssertJSONErrorBody(t *testing.T, res *httptest.ResponseRecorder, expected string) {
+// AssertProblemBody checks if the problem+json response has the expected error code
+// and a detail containing the expected message.
+func AssertProblemBody(t *testing.T, res *httptest.ResponseRecorder, expectedCode, expectedDetail string) {
 	t.Helper() // Mark this function as a test helper.
 
 	var response errorResponse
@@ -30,8 +31,11 @@ func AssertJSONErrorBody(t *testing.T, res *httptest.ResponseRecorder, expected
 		t.Fatalf("failed to decode response body: %v", err)
 	}
 
-	if !strings.Contains(response.Message, expected) {
-		t.Errorf("expected error message to contain '%s', got '%s'", expected, response.Message)
+	if response.Code != expectedCode {
+		t.Errorf("expected error code '%s', got '%s'", expectedCode, response.Code)
+	}
+	if !strings.Contains(response.Detail, expectedDetail) {
+		t.Errorf("expected error detail to contain '%s', got '%s'", expectedDetail, response.Detail)
 	}
 }
 
@@ -44,3 +48,12 @@ func AssertJSONContentType(t *testing.T, res *httptest.ResponseRecorder) {
 	}
 }
 
+
+// AssertProblemContentType checks if the response has the Content-Type header for problem details.
+func AssertProblemContentType(t *testing.T, res *httptest.ResponseRecorder) {
+	t.Helper() // Mark this function as a test helper.
+
+	if res.Header().Get(ContentType) != ContentTypeProblemJSON {
+		t.Errorf("expected Content-Type application/problem+json, got %s", res.Header().Get(ContentType))
+	}
+}
@@ -2,11 +2,11 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 )
@@ -27,7 +27,7 @@ func (h *userHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	identity, err := auth.FromContext(r.Context())
 	if err != nil {
 		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 	log := h.log.With(slog.String("acting_user_id", identity.UserID))
@@ -35,13 +35,13 @@ func (h *userHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var input CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		h.log.Error("Failed to decode request body", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	if err := input.Validate(); err != nil {
 		h.log.Warn("Validation failed for user creation", slog.Any("error", err))
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 	log.With(slog.String("username", input.Username), slog.String("email", input.Email)).Info("Creating user")
@@ -52,18 +52,8 @@ func (h *userHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			log.Warn("Invalid input for user creation", slog.Any("error", err))
-			respondError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		if errors.Is(err, services.ErrUserWithDuplicateDetailsExists) {
-			log.Warn("User with duplicate details exists", slog.Any("error", err))
-			respondError(w, http.StatusConflict, err.Error())
-			return
-		}
-		log.Error("Failed to create user", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to create user", err)
+		respondError(w, r, err)
 		return
 	}
 
@@ -78,7 +68,7 @@ func (h *userHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	identity, err := auth.FromContext(r.Context())
 	if err != nil {
 		h.log.Error("User identity not found in request context", slog.Any("error", err))
-		respondError(w, http.StatusUnauthorized, "user ID not found in context")
+		respondError(w, r, err)
 		return
 	}
 
@@ -87,7 +77,7 @@ func (h *userHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
 		h.log.Warn("User ID is required for fetching user details", slog.String("id", id))
-		respondError(w, http.StatusBadRequest, "user ID is required")
+		respondError(w, r, services.NewValidationError("id", "is required"))
 		return
 	}
 
@@ -95,22 +85,12 @@ func (h *userHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userService.GetUserByID(r.Context(), services.GetUserByIDParams{UserID: id, ActingUserID: identity.UserID})
 	if err != nil {
-		if errors.Is(err, services.ErrUserNotFound) {
-			log.Warn("User not found", slog.Any("error", err))
-			respondError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		if errors.Is(err, services.ErrForbidden) {
-			log.Warn("Unauthorized access attempt", slog.Any("error", err))
-			respondError(w, http.StatusForbidden, err.Error())
-			return
-		}
-		log.Error("Internal error while fetching user by ID", slog.Any("error", err))
-		respondError(w, http.StatusInternalServerError, "internal server error")
+		logServiceError(log, "Failed to fetch user by ID", err)
+		respondError(w, r, err)
 		return
 	}
 
 	log.Info("User retrieved successfully", slog.String("user_id", user.ID))
 
-	respondJSON(w, http.StatusOK, user)
+	respondJSON(w, http.StatusOK, NewUserResponse(user))
 }
@@ -86,7 +86,8 @@ func TestUserHandler_CreateUser(t *testing.T) {
 		r.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusBadRequest, res.Code)
-		api.AssertJSONContentType(t, res)
+		api.AssertProblemContentType(t, res)
+		api.AssertProblemBody(t, res, "invalid_request_body", "Invalid request body")
 	})
 
 	t.Run("service returns validation error", func(t *testing.T) {
@@ -108,7 +109,8 @@ func TestUserHandler_CreateUser(t *testing.T) {
 		r.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusBadRequest, res.Code)
-		api.AssertJSONContentType(t, res)
+		api.AssertProblemContentType(t, res)
+		api.AssertProblemBody(t, res, "invalid_input", "username is required")
 	})
 
 	t.Run("service returns internal server error", func(t *testing.T) {
@@ -130,7 +132,7 @@ func TestUserHandler_CreateUser(t *testing.T) {
 		r.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusInternalServerError, res.Code)
-		api.AssertJSONContentType(t, res)
+		api.AssertProblemContentType(t, res)
 	})
 
 	t.Run("service returns user with duplicate details error", func(t *testing.T) {
@@ -152,7 +154,8 @@ func TestUserHandler_CreateUser(t *testing.T) {
 		r.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusConflict, res.Code)
-		api.AssertJSONContentType(t, res)
+		api.AssertProblemContentType(t, res)
+		api.AssertProblemBody(t, res, "user_conflict", "already exists")
 	})
 }
 
@@ -207,7 +210,8 @@ func TestUserHandler_GetUserByID(t *testing.T) {
 		r.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusNotFound, res.Code)
-		api.AssertJSONContentType(t, res)
+		api.AssertProblemContentType(t, res)
+		api.AssertProblemBody(t, res, "user_not_found", "user not found")
 	})
 
 	t.Run("service returns internal server error", func(t *testing.T) {
@@ -228,7 +232,7 @@ func TestUserHandler_GetUserByID(t *testing.T) {
 		r.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusInternalServerError, res.Code)
-		api.AssertJSONContentType(t, res)
+		api.AssertProblemContentType(t, res)
 	})
 
 	t.Run("unauthorized access", func(t *testing.T) {
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+type webhookHandler struct {
+	webhookService services.WebhookService
+	log            *slog.Logger
+}
+
+func NewWebhookHandler(webhookService services.WebhookService, log *slog.Logger) *webhookHandler {
+	return &webhookHandler{
+		webhookService: webhookService,
+		log:            log.With(slog.String("component", "webhook_handler")),
+	}
+}
+
+func (h *webhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+	log := h.log.With(slog.String("acting_user_id", identity.UserID), slog.String("org_id", orgID))
+
+	var input CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Warn("Failed to decode request body", slog.Any("error", err))
+		respondProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		log.Warn("Validation failed for webhook creation", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), services.CreateWebhookParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+		URL:          input.URL,
+		Events:       input.Events,
+		Description:  input.Description,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to create webhook", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Webhook created successfully", slog.String("webhook_id", webhook.ID))
+
+	respondJSON(w, http.StatusCreated, NewCreatedWebhookResponse(webhook))
+}
+
+func (h *webhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return
+	}
+
+	orgID := chi.URLParam(r, "orgID")
+
+	webhooks, err := h.webhookService.ListWebhooks(r.Context(), services.ListWebhooksParams{
+		OrgID:        orgID,
+		ActingUserID: identity.UserID,
+	})
+	if err != nil {
+		logServiceError(h.log.With(slog.String("org_id", orgID)), "Failed to list webhooks", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewWebhooksResponse(webhooks))
+}
+
+func (h *webhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("webhook_id", params.WebhookID)), "Failed to fetch webhook", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewWebhookResponse(webhook))
+}
+
+func (h *webhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), params); err != nil {
+		logServiceError(h.log.With(slog.String("webhook_id", params.WebhookID)), "Failed to delete webhook", err)
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *webhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.ListWebhookDeliveries(r.Context(), params)
+	if err != nil {
+		logServiceError(h.log.With(slog.String("webhook_id", params.WebhookID)), "Failed to list webhook deliveries", err)
+		respondError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, NewWebhookDeliveriesResponse(deliveries))
+}
+
+func (h *webhookHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID := chi.URLParam(r, "deliveryID")
+	log := h.log.With(slog.String("webhook_id", params.WebhookID), slog.String("delivery_id", deliveryID))
+
+	delivery, err := h.webhookService.RedeliverWebhook(r.Context(), services.RedeliverWebhookParams{
+		OrgID:        params.OrgID,
+		ActingUserID: params.ActingUserID,
+		WebhookID:    params.WebhookID,
+		DeliveryID:   deliveryID,
+	})
+	if err != nil {
+		logServiceError(log, "Failed to redeliver webhook delivery", err)
+		respondError(w, r, err)
+		return
+	}
+
+	log.Info("Webhook delivery queued for redelivery")
+
+	respondJSON(w, http.StatusAccepted, NewWebhookDeliveryResponse(delivery))
+}
+
+// webhookParams reads the acting user and the webhook from the request,
+// writing an error response and returning false if either is missing.
+func (h *webhookHandler) webhookParams(w http.ResponseWriter, r *http.Request) (services.WebhookParams, bool) {
+	identity, err := auth.FromContext(r.Context())
+	if err != nil {
+		h.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+		respondError(w, r, err)
+		return services.WebhookParams{}, false
+	}
+
+	return services.WebhookParams{
+		OrgID:        chi.URLParam(r, "orgID"),
+		ActingUserID: identity.UserID,
+		WebhookID:    chi.URLParam(r, "webhookID"),
+	}, true
+}
@@ -0,0 +1,164 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/api"
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWebhookService struct {
+	createWebhookFunc         func(ctx context.Context, params services.CreateWebhookParams) (*models.Webhook, error)
+	listWebhooksFunc          func(ctx context.Context, params services.ListWebhooksParams) ([]*models.Webhook, error)
+	getWebhookFunc            func(ctx context.Context, params services.WebhookParams) (*models.Webhook, error)
+	deleteWebhookFunc         func(ctx context.Context, params services.WebhookParams) error
+	listWebhookDeliveriesFunc func(ctx context.Context, params services.WebhookParams) ([]*models.WebhookDelivery, error)
+	redeliverWebhookFunc      func(ctx context.Context, params services.RedeliverWebhookParams) (*models.WebhookDelivery, error)
+}
+
+func (m *mockWebhookService) CreateWebhook(ctx context.Context, params services.CreateWebhookParams) (*models.Webhook, error) {
+	return m.createWebhookFunc(ctx, params)
+}
+
+func (m *mockWebhookService) ListWebhooks(ctx context.Context, params services.ListWebhooksParams) ([]*models.Webhook, error) {
+	return m.listWebhooksFunc(ctx, params)
+}
+
+func (m *mockWebhookService) GetWebhook(ctx context.Context, params services.WebhookParams) (*models.Webhook, error) {
+	return m.getWebhookFunc(ctx, params)
+}
+
+func (m *mockWebhookService) DeleteWebhook(ctx context.Context, params services.WebhookParams) error {
+	return m.deleteWebhookFunc(ctx, params)
+}
+
+func (m *mockWebhookService) ListWebhookDeliveries(ctx context.Context, params services.WebhookParams) ([]*models.WebhookDelivery, error) {
+	return m.listWebhookDeliveriesFunc(ctx, params)
+}
+
+func (m *mockWebhookService) RedeliverWebhook(ctx context.Context, params services.RedeliverWebhookParams) (*models.WebhookDelivery, error) {
+	return m.redeliverWebhookFunc(ctx, params)
+}
+
+func TestWebhookHandler_CreateWebhook(t *testing.T) {
+	identity := auth.Identity{UserID: "admin-user"}
+
+	newRouter := func(service services.WebhookService) chi.Router {
+		handler := api.NewWebhookHandler(service, logger.NewTestLogger(t))
+		r := chi.NewRouter()
+		r.Method(http.MethodPost, "/organizations/{orgID}/webhooks", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.CreateWebhook), identity))
+		return r
+	}
+
+	t.Run("returns the secret once", func(t *testing.T) {
+		service := &mockWebhookService{
+			createWebhookFunc: func(ctx context.Context, params services.CreateWebhookParams) (*models.Webhook, error) {
+				assert.Equal(t, "org-1", params.OrgID)
+				assert.Equal(t, identity.UserID, params.ActingUserID)
+				return &models.Webhook{ID: "wh-1", OrgID: params.OrgID, URL: params.URL, Events: params.Events, Secret: "whsec_abc"}, nil
+			},
+		}
+
+		body := `{"url": "https://example.com/hooks", "events": ["member.added"]}`
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/webhooks", bytes.NewBufferString(body))
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		var response api.CreatedWebhookResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, "wh-1", response.ID)
+		assert.Equal(t, "whsec_abc", response.Secret)
+		assert.Equal(t, []models.EventType{models.EventMemberAdded}, response.Events)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/webhooks", bytes.NewBufferString(`{}`))
+		res := httptest.NewRecorder()
+
+		newRouter(&mockWebhookService{}).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusBadRequest)
+		api.AssertProblemBody(t, res, problem.CodeInvalidInput, "url is required")
+	})
+}
+
+func TestWebhookHandler_ListWebhooks(t *testing.T) {
+	service := &mockWebhookService{
+		listWebhooksFunc: func(ctx context.Context, params services.ListWebhooksParams) ([]*models.Webhook, error) {
+			return []*models.Webhook{{ID: "wh-1", URL: "https://example.com", Secret: "whsec_abc"}}, nil
+		},
+	}
+	handler := api.NewWebhookHandler(service, logger.NewTestLogger(t))
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/organizations/{orgID}/webhooks", middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.ListWebhooks), auth.Identity{UserID: "admin-user"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/organizations/org-1/webhooks", nil)
+	res := httptest.NewRecorder()
+
+	r.ServeHTTP(res, req)
+
+	api.AssertStatus(t, res, http.StatusOK)
+	assert.NotContains(t, res.Body.String(), "whsec_abc", "secrets must only be shown on creation")
+}
+
+func TestWebhookHandler_RedeliverWebhook(t *testing.T) {
+	newRouter := func(service services.WebhookService) chi.Router {
+		handler := api.NewWebhookHandler(service, logger.NewTestLogger(t))
+		r := chi.NewRouter()
+		r.Method(http.MethodPost, "/organizations/{orgID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver",
+			middleware.NewTestAuthMiddleware(http.HandlerFunc(handler.RedeliverWebhook), auth.Identity{UserID: "admin-user"}))
+		return r
+	}
+
+	t.Run("queues the delivery", func(t *testing.T) {
+		service := &mockWebhookService{
+			redeliverWebhookFunc: func(ctx context.Context, params services.RedeliverWebhookParams) (*models.WebhookDelivery, error) {
+				assert.Equal(t, "wh-1", params.WebhookID)
+				assert.Equal(t, "d-1", params.DeliveryID)
+				return &models.WebhookDelivery{ID: params.DeliveryID, WebhookID: params.WebhookID, Status: models.DeliveryPending}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/webhooks/wh-1/deliveries/d-1/redeliver", nil)
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusAccepted, res.Code)
+		var response api.WebhookDeliveryResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, models.DeliveryPending, response.Status)
+		assert.NotNil(t, response.NextAttemptAt)
+	})
+
+	t.Run("unknown delivery", func(t *testing.T) {
+		service := &mockWebhookService{
+			redeliverWebhookFunc: func(ctx context.Context, params services.RedeliverWebhookParams) (*models.WebhookDelivery, error) {
+				return nil, services.ErrWebhookDeliveryNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/organizations/org-1/webhooks/wh-1/deliveries/d-1/redeliver", nil)
+		res := httptest.NewRecorder()
+
+		newRouter(service).ServeHTTP(res, req)
+
+		api.AssertStatus(t, res, http.StatusNotFound)
+		api.AssertProblemBody(t, res, problem.CodeDeliveryNotFound, "webhook delivery not found")
+	})
+}
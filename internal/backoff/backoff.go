@@ -0,0 +1,20 @@
+// Package backoff computes retry delays for background work.
+package backoff
+
+import "time"
+
+// Exponential returns the delay after the given failed attempt (starting
+// at 1): base doubled for every earlier attempt, capped at max.
+func Exponential(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
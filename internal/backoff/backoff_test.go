@@ -0,0 +1,19 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/backoff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponential(t *testing.T) {
+	base, max := 30*time.Second, 10*time.Minute
+
+	assert.Equal(t, 30*time.Second, backoff.Exponential(1, base, max))
+	assert.Equal(t, time.Minute, backoff.Exponential(2, base, max))
+	assert.Equal(t, 4*time.Minute, backoff.Exponential(4, base, max))
+	assert.Equal(t, max, backoff.Exponential(6, base, max))
+	assert.Equal(t, max, backoff.Exponential(100, base, max))
+}
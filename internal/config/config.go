@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -23,6 +25,65 @@ type AppConfig struct {
 	Port                string `yaml:"port"`
 	DatabaseURL         string `yaml:"database_url"`
 	GoogleOAuthClientID string `yaml:"google_oauth_client_id"`
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key header are kept for replay.
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl"`
+	// AdminUserIDs lists the users allowed to operate the server through
+	// the /admin endpoints, such as inspecting background jobs.
+	AdminUserIDs []string `yaml:"admin_user_ids"`
+	// PublicURL is where users reach the server, used for links in emails.
+	PublicURL     string              `yaml:"public_url"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Storage       StorageConfig       `yaml:"storage"`
+	PublicCatalog PublicCatalogConfig `yaml:"public_catalog"`
+}
+
+// NotificationsConfig configures notification emails.
+type NotificationsConfig struct {
+	// From is the sender of notification emails.
+	From string `yaml:"from"`
+	// SMTPAddr is the mail server's host:port. Without it, emails are
+	// logged instead of sent.
+	SMTPAddr     string `yaml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username"`
+	// SMTPPassword can also be set with the SMTP_PASSWORD variable.
+	SMTPPassword string `yaml:"smtp_password"`
+	// UnsubscribeSecret signs the unsubscribe links in emails. It can also
+	// be set with the UNSUBSCRIBE_SECRET variable.
+	UnsubscribeSecret string `yaml:"unsubscribe_secret"`
+}
+
+// StorageConfig configures where uploaded files are kept.
+type StorageConfig struct {
+	// Driver is "filesystem" or "s3".
+	Driver string `yaml:"driver"`
+	// Path is the directory the filesystem driver keeps files in.
+	Path string `yaml:"path"`
+	// S3Endpoint is the URL of an S3-compatible service, such as MinIO.
+	S3Endpoint    string `yaml:"s3_endpoint"`
+	S3Region      string `yaml:"s3_region"`
+	S3Bucket      string `yaml:"s3_bucket"`
+	S3AccessKeyID string `yaml:"s3_access_key_id"`
+	// S3SecretAccessKey can also be set with the S3_SECRET_ACCESS_KEY
+	// variable.
+	S3SecretAccessKey string `yaml:"s3_secret_access_key"`
+	// SigningSecret signs download links. It can also be set with the
+	// STORAGE_SIGNING_SECRET variable.
+	SigningSecret string `yaml:"signing_secret"`
+}
+
+// PublicCatalogConfig configures the unauthenticated /public endpoints.
+type PublicCatalogConfig struct {
+	// RequestsPerMinute is how many requests each client IP address may
+	// make, in bursts of up to Burst requests.
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	Burst             int `yaml:"burst"`
+	// CacheMaxAge is how long browsers and CDNs may cache responses.
+	CacheMaxAge time.Duration `yaml:"cache_max_age"`
+	// TrustedProxies lists the address ranges of the load balancers in
+	// front of the server, such as "10.0.0.0/8". Requests from them are
+	// rate limited by the client in X-Forwarded-For instead.
+	TrustedProxies []netip.Prefix `yaml:"trusted_proxies"`
 }
 
 // file holds the structure of the entire YAML file.
@@ -77,6 +138,18 @@ func Load() (*AppConfig, error) {
 	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
 		appConfig.DatabaseURL = dbURL
 	}
+	if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+		appConfig.Notifications.SMTPPassword = password
+	}
+	if secret := os.Getenv("UNSUBSCRIBE_SECRET"); secret != "" {
+		appConfig.Notifications.UnsubscribeSecret = secret
+	}
+	if secret := os.Getenv("S3_SECRET_ACCESS_KEY"); secret != "" {
+		appConfig.Storage.S3SecretAccessKey = secret
+	}
+	if secret := os.Getenv("STORAGE_SIGNING_SECRET"); secret != "" {
+		appConfig.Storage.SigningSecret = secret
+	}
 
 	if appConfig.DatabaseURL == "" {
 		return nil, fmt.Errorf("database_url is a required config field")
@@ -84,6 +157,39 @@ func Load() (*AppConfig, error) {
 	if appConfig.GoogleOAuthClientID == "" {
 		return nil, fmt.Errorf("google_oauth_client_id is a required config field")
 	}
+	if appConfig.IdempotencyTTL <= 0 {
+		return nil, fmt.Errorf("idempotency_ttl must be a positive duration")
+	}
+	if appConfig.PublicURL == "" {
+		return nil, fmt.Errorf("public_url is a required config field")
+	}
+	if appConfig.Notifications.From == "" {
+		return nil, fmt.Errorf("notifications.from is a required config field")
+	}
+	if appConfig.Notifications.UnsubscribeSecret == "" {
+		return nil, fmt.Errorf("notifications.unsubscribe_secret is a required config field")
+	}
+	switch appConfig.Storage.Driver {
+	case "filesystem":
+		if appConfig.Storage.Path == "" {
+			return nil, fmt.Errorf("storage.path is required for the filesystem driver")
+		}
+	case "s3":
+		if appConfig.Storage.S3Endpoint == "" || appConfig.Storage.S3Bucket == "" {
+			return nil, fmt.Errorf("storage.s3_endpoint and storage.s3_bucket are required for the s3 driver")
+		}
+	default:
+		return nil, fmt.Errorf("storage.driver must be filesystem or s3")
+	}
+	if appConfig.Storage.SigningSecret == "" {
+		return nil, fmt.Errorf("storage.signing_secret is a required config field")
+	}
+	if appConfig.PublicCatalog.RequestsPerMinute <= 0 || appConfig.PublicCatalog.Burst <= 0 {
+		return nil, fmt.Errorf("public_catalog.requests_per_minute and public_catalog.burst must be positive")
+	}
+	if appConfig.PublicCatalog.CacheMaxAge < 0 {
+		return nil, fmt.Errorf("public_catalog.cache_max_age must not be negative")
+	}
 
 	return &appConfig, nil
 }
@@ -100,4 +206,75 @@ func merge(base *AppConfig, override AppConfig) {
 	if override.GoogleOAuthClientID != "" {
 		base.GoogleOAuthClientID = override.GoogleOAuthClientID
 	}
-}
\ No newline at end of file
+	if override.IdempotencyTTL != 0 {
+		base.IdempotencyTTL = override.IdempotencyTTL
+	}
+	if len(override.AdminUserIDs) > 0 {
+		base.AdminUserIDs = override.AdminUserIDs
+	}
+	if override.PublicURL != "" {
+		base.PublicURL = override.PublicURL
+	}
+	mergeNotifications(&base.Notifications, override.Notifications)
+	mergeStorage(&base.Storage, override.Storage)
+	mergePublicCatalog(&base.PublicCatalog, override.PublicCatalog)
+}
+
+func mergeNotifications(base *NotificationsConfig, override NotificationsConfig) {
+	if override.From != "" {
+		base.From = override.From
+	}
+	if override.SMTPAddr != "" {
+		base.SMTPAddr = override.SMTPAddr
+	}
+	if override.SMTPUsername != "" {
+		base.SMTPUsername = override.SMTPUsername
+	}
+	if override.SMTPPassword != "" {
+		base.SMTPPassword = override.SMTPPassword
+	}
+	if override.UnsubscribeSecret != "" {
+		base.UnsubscribeSecret = override.UnsubscribeSecret
+	}
+}
+
+func mergeStorage(base *StorageConfig, override StorageConfig) {
+	if override.Driver != "" {
+		base.Driver = override.Driver
+	}
+	if override.Path != "" {
+		base.Path = override.Path
+	}
+	if override.S3Endpoint != "" {
+		base.S3Endpoint = override.S3Endpoint
+	}
+	if override.S3Region != "" {
+		base.S3Region = override.S3Region
+	}
+	if override.S3Bucket != "" {
+		base.S3Bucket = override.S3Bucket
+	}
+	if override.S3AccessKeyID != "" {
+		base.S3AccessKeyID = override.S3AccessKeyID
+	}
+	if override.S3SecretAccessKey != "" {
+		base.S3SecretAccessKey = override.S3SecretAccessKey
+	}
+	if override.SigningSecret != "" {
+		base.SigningSecret = override.SigningSecret
+	}
+}
+func mergePublicCatalog(base *PublicCatalogConfig, override PublicCatalogConfig) {
+	if override.RequestsPerMinute != 0 {
+		base.RequestsPerMinute = override.RequestsPerMinute
+	}
+	if override.Burst != 0 {
+		base.Burst = override.Burst
+	}
+	if override.CacheMaxAge != 0 {
+		base.CacheMaxAge = override.CacheMaxAge
+	}
+	if len(override.TrustedProxies) > 0 {
+		base.TrustedProxies = override.TrustedProxies
+	}
+}
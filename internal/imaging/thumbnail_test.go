@@ -0,0 +1,87 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantW, wantH  int
+	}{
+		{"landscape", 800, 400, 200, 100},
+		{"portrait", 300, 900, 66, 200},
+		{"small images are kept", 120, 80, 120, 80},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.Set(x, y, color.RGBA{R: 200, G: 30, B: 30, A: 255})
+				}
+			}
+
+			thumb, err := imaging.Thumbnail(encodePNG(t, src), 200)
+			require.NoError(t, err)
+
+			img, err := jpeg.Decode(bytes.NewReader(thumb))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantW, img.Bounds().Dx())
+			assert.Equal(t, tt.wantH, img.Bounds().Dy())
+
+			r, g, b, _ := img.At(img.Bounds().Dx()/2, img.Bounds().Dy()/2).RGBA()
+			assert.InDelta(t, 200, r>>8, 8)
+			assert.InDelta(t, 30, g>>8, 8)
+			assert.InDelta(t, 30, b>>8, 8)
+		})
+	}
+}
+
+func TestThumbnail_TransparentBecomesWhite(t *testing.T) {
+	thumb, err := imaging.Thumbnail(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 10, 10))), 200)
+	require.NoError(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	r, g, b, _ := img.At(5, 5).RGBA()
+	assert.Equal(t, []uint32{0xff, 0xff, 0xff}, []uint32{r >> 8, g >> 8, b >> 8})
+}
+
+func TestThumbnail_Rejects(t *testing.T) {
+	t.Run("non-images", func(t *testing.T) {
+		_, err := imaging.Thumbnail([]byte("%PDF-1.7"), 200)
+		assert.Error(t, err)
+	})
+
+	t.Run("images declaring huge dimensions", func(t *testing.T) {
+		data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+		// Patch the IHDR width and height to 100000x100000 and fix up
+		// the chunk checksum.
+		copy(data[16:24], []byte{0, 1, 0x86, 0xa0, 0, 1, 0x86, 0xa0})
+		binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+		_, err := imaging.Thumbnail(data, 200)
+		assert.ErrorIs(t, err, imaging.ErrTooLarge)
+	})
+}
@@ -0,0 +1,54 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2025, 1, 15, 10, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2025, 1, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 8 * * 0", time.Date(2025, 1, 19, 8, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"5,35 10 * * *", time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+		{"10/20 * * * *", time.Date(2025, 1, 15, 10, 50, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match.
+		{"0 0 20 * 4", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := jobs.ParseSchedule(tt.spec)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@yearly"} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := jobs.ParseSchedule(spec)
+
+			assert.Error(t, err)
+		})
+	}
+}
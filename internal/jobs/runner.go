@@ -0,0 +1,414 @@
+// Package jobs runs background work outside the request path. Jobs are
+// stored in Postgres and claimed by any number of runners; a job is
+// retried with backoff until it succeeds or runs out of attempts, after
+// which it is kept as dead until an operator retries it.
+//
+// Handlers may run more than once for the same job, for example when a
+// runner dies before recording the outcome, so they must be idempotent.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/backoff"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+// ErrUnknownKind is returned when enqueuing a job without a handler.
+var ErrUnknownKind = errors.New("no handler registered for job kind")
+
+// Options tunes the runner. Zero values are replaced by the defaults from
+// DefaultOptions.
+type Options struct {
+	// Workers is the number of jobs run concurrently.
+	Workers int
+	// PollInterval is how often idle workers check for due jobs.
+	PollInterval time.Duration
+	// Lease is how long a claimed job may run. Its handler's context is
+	// cancelled when the lease ends, and the job can then be claimed again.
+	Lease time.Duration
+	// MaxAttempts is used for jobs enqueued without their own limit.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failed attempt. It doubles
+	// with every further attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// DrainTimeout is how long Run waits for running jobs after its context
+	// is cancelled before cancelling them too.
+	DrainTimeout time.Duration
+	// Retention is how long succeeded jobs are kept.
+	Retention time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Workers:      4,
+		PollInterval: time.Second,
+		Lease:        5 * time.Minute,
+		MaxAttempts:  10,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   time.Hour,
+		DrainTimeout: 30 * time.Second,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.Workers <= 0 {
+		o.Workers = d.Workers
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = d.PollInterval
+	}
+	if o.Lease <= 0 {
+		o.Lease = d.Lease
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = d.MaxAttempts
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = d.BaseBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = d.MaxBackoff
+	}
+	if o.DrainTimeout <= 0 {
+		o.DrainTimeout = d.DrainTimeout
+	}
+	if o.Retention <= 0 {
+		o.Retention = d.Retention
+	}
+	return o
+}
+
+// HandlerFunc runs one attempt of a job with its raw JSON payload.
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+// EnqueueOptions controls when and how often a job runs.
+type EnqueueOptions struct {
+	// RunAt delays the job; the zero value runs it as soon as possible.
+	RunAt time.Time
+	// MaxAttempts overrides Options.MaxAttempts for this job.
+	MaxAttempts int
+	// UniqueKey prevents enqueuing the same job twice.
+	UniqueKey string
+}
+
+type cronEntry struct {
+	name     string
+	schedule *Schedule
+	kind     string
+	payload  []byte
+}
+
+// Runner executes jobs with the handlers registered on it. Handlers and
+// cron entries must be registered before Run is called.
+type Runner struct {
+	repo     repositories.JobRepository
+	opts     Options
+	log      *slog.Logger
+	now      func() time.Time
+	handlers map[string]HandlerFunc
+	crons    []cronEntry
+}
+
+// purgeJobKind removes succeeded jobs older than Options.Retention.
+const purgeJobKind = "jobs.purge"
+
+func NewRunner(repo repositories.JobRepository, opts Options, log *slog.Logger) *Runner {
+	r := &Runner{
+		repo:     repo,
+		opts:     opts.withDefaults(),
+		log:      log.With(slog.String("component", "job_runner")),
+		now:      time.Now,
+		handlers: make(map[string]HandlerFunc),
+	}
+
+	Handle(r, purgeJobKind, func(ctx context.Context, _ struct{}) error {
+		n, err := r.repo.DeleteFinished(ctx, r.now().Add(-r.opts.Retention))
+		if err != nil {
+			return err
+		}
+		r.log.Info("Purged finished jobs", slog.Int("jobs", n))
+		return nil
+	})
+	// The built-in schedule is always valid.
+	_ = r.Cron("purge-finished-jobs", "@daily", purgeJobKind, struct{}{})
+
+	return r
+}
+
+// Register sets the handler for a job kind.
+func (r *Runner) Register(kind string, handler HandlerFunc) {
+	r.handlers[kind] = handler
+}
+
+// Handle registers a handler that receives the job payload decoded into T.
+// A payload that cannot be decoded fails the job permanently.
+func Handle[T any](r *Runner, kind string, handler func(ctx context.Context, args T) error) {
+	r.Register(kind, func(ctx context.Context, job *models.Job) error {
+		var args T
+		if err := json.Unmarshal(job.Payload, &args); err != nil {
+			return Permanent(fmt.Errorf("decode %s payload: %w", kind, err))
+		}
+		return handler(ctx, args)
+	})
+}
+
+// Cron enqueues a job of the given kind with args at every firing of spec,
+// evaluated in UTC. Each firing is enqueued once however many runners
+// share the queue. Firings missed while no runner was running are skipped.
+func (r *Runner) Cron(name, spec, kind string, args any) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	r.crons = append(r.crons, cronEntry{name: name, schedule: schedule, kind: kind, payload: payload})
+	return nil
+}
+
+// Enqueue stores a job of a registered kind with args as its payload.
+// Enqueuing a job whose unique key was already used returns the conflict
+// as repositories.ErrConflict.
+func (r *Runner) Enqueue(ctx context.Context, kind string, args any, opts EnqueueOptions) (*models.Job, error) {
+	if _, ok := r.handlers[kind]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	return r.enqueue(ctx, kind, payload, opts)
+}
+
+func (r *Runner) enqueue(ctx context.Context, kind string, payload []byte, opts EnqueueOptions) (*models.Job, error) {
+	if opts.RunAt.IsZero() {
+		opts.RunAt = r.now()
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = r.opts.MaxAttempts
+	}
+	return r.repo.Enqueue(ctx, &repositories.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     payload,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+		UniqueKey:   opts.UniqueKey,
+	})
+}
+
+// Run executes due jobs until ctx is cancelled. It then stops claiming
+// jobs and waits up to Options.DrainTimeout for running jobs to finish
+// before cancelling them, and returns once all of them have stopped.
+func (r *Runner) Run(ctx context.Context) {
+	kinds := slices.Sorted(maps.Keys(r.handlers))
+	r.log.Info("Job runner started", slog.Int("workers", r.opts.Workers), slog.Any("kinds", kinds))
+
+	// Running jobs outlive ctx so they can finish during the drain.
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.schedule(ctx)
+	}()
+
+	slots := make(chan struct{}, r.opts.Workers)
+	finished := make(chan struct{}, 1)
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		free := r.opts.Workers - len(slots)
+		if free > 0 {
+			jobs, err := r.repo.Claim(ctx, kinds, free, r.opts.Lease)
+			if err != nil && ctx.Err() == nil {
+				r.log.Error("Failed to claim jobs", slog.Any("error", err))
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r.execute(jobCtx, job)
+					<-slots
+					// Wake the loop to refill the slot; one pending
+					// signal is enough.
+					select {
+					case finished <- struct{}{}:
+					default:
+					}
+				}()
+			}
+			// A full batch suggests more jobs are due; claim again at once.
+			if len(jobs) == free {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-finished:
+		}
+	}
+
+	r.log.Info("Job runner draining", slog.Int("running", len(slots)), slog.Duration("timeout", r.opts.DrainTimeout))
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(r.opts.DrainTimeout):
+		r.log.Warn("Drain timed out, cancelling running jobs")
+		cancelJobs()
+		<-done
+	}
+
+	r.log.Info("Job runner stopped")
+}
+
+// execute runs one attempt of a claimed job and records its outcome.
+func (r *Runner) execute(ctx context.Context, job *models.Job) {
+	log := r.log.With(slog.String("job_id", job.ID), slog.String("kind", job.Kind), slog.Int("attempt", job.Attempts))
+
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Lease)
+	defer cancel()
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// The previous attempt used up the last one but its runner never
+		// recorded the outcome.
+		err = Permanent(errors.New("lease expired on the last attempt"))
+	} else {
+		err = r.call(ctx, job)
+	}
+
+	// Record the outcome even when the job was cancelled.
+	ctx = context.WithoutCancel(ctx)
+
+	if err == nil {
+		if err := r.repo.Complete(ctx, job.ID, job.Attempts); err != nil {
+			log.Error("Failed to record job success", slog.Any("error", err))
+			return
+		}
+		log.Info("Job succeeded")
+		return
+	}
+
+	params := &repositories.FailJobParams{ID: job.ID, Attempt: job.Attempts, Error: err.Error()}
+	var permanent *permanentError
+	if !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+		retryAt := r.now().Add(backoff.Exponential(job.Attempts, r.opts.BaseBackoff, r.opts.MaxBackoff))
+		params.RetryAt = &retryAt
+		log.Warn("Job failed, will retry", slog.Time("retry_at", retryAt), slog.Any("error", err))
+	} else {
+		log.Error("Job failed permanently", slog.Any("error", err))
+	}
+
+	if err := r.repo.Fail(ctx, params); err != nil {
+		log.Error("Failed to record job failure", slog.Any("error", err))
+	}
+}
+
+// call runs the handler, turning a panic into an error so one bad job
+// cannot take the runner down.
+func (r *Runner) call(ctx context.Context, job *models.Job) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+	}()
+
+	handler, ok := r.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("%w: %s", ErrUnknownKind, job.Kind))
+	}
+	return handler(ctx, job)
+}
+
+// schedule enqueues cron jobs as they come due until ctx is cancelled.
+func (r *Runner) schedule(ctx context.Context) {
+	if len(r.crons) == 0 {
+		return
+	}
+
+	next := make([]time.Time, len(r.crons))
+	for i, entry := range r.crons {
+		next[i] = entry.schedule.Next(r.now().UTC())
+	}
+
+	for {
+		wake := time.Time{}
+		for _, t := range next {
+			if !t.IsZero() && (wake.IsZero() || t.Before(wake)) {
+				wake = t
+			}
+		}
+		if wake.IsZero() {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(wake)):
+		}
+
+		now := r.now().UTC()
+		for i, entry := range r.crons {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			r.fire(ctx, entry, next[i])
+			next[i] = entry.schedule.Next(now)
+		}
+	}
+}
+
+func (r *Runner) fire(ctx context.Context, entry cronEntry, at time.Time) {
+	log := r.log.With(slog.String("cron", entry.name), slog.Time("at", at))
+
+	// Every runner fires the same entries; the unique key lets only the
+	// first one enqueue the job.
+	key := fmt.Sprintf("cron:%s:%s", entry.name, at.Format(time.RFC3339))
+	_, err := r.enqueue(ctx, entry.kind, entry.payload, EnqueueOptions{RunAt: at, UniqueKey: key})
+	if err != nil && !errors.Is(err, repositories.ErrConflict) {
+		log.Error("Failed to enqueue cron job", slog.Any("error", err))
+		return
+	}
+	log.Debug("Cron job enqueued")
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying; the job moves
+// straight to the dead state.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
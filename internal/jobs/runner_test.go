@@ -0,0 +1,339 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/jobs"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJobs is an in-memory job queue.
+type fakeJobs struct {
+	mu   sync.Mutex
+	jobs []*models.Job
+	keys map[string]bool
+	// fails records the FailJobParams of every failed attempt.
+	fails []repositories.FailJobParams
+}
+
+func (f *fakeJobs) Enqueue(ctx context.Context, params *repositories.EnqueueJobParams) (*models.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if params.UniqueKey != "" {
+		if f.keys[params.UniqueKey] {
+			return nil, repositories.ErrConflict
+		}
+		if f.keys == nil {
+			f.keys = map[string]bool{}
+		}
+		f.keys[params.UniqueKey] = true
+	}
+	job := &models.Job{
+		ID:          fmt.Sprintf("job-%d", len(f.jobs)+1),
+		Kind:        params.Kind,
+		Payload:     params.Payload,
+		Status:      models.JobPending,
+		MaxAttempts: params.MaxAttempts,
+		RunAt:       params.RunAt,
+		UniqueKey:   params.UniqueKey,
+	}
+	f.jobs = append(f.jobs, job)
+	return job, nil
+}
+
+func (f *fakeJobs) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*models.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []*models.Job
+	for _, job := range f.jobs {
+		if len(claimed) == limit {
+			break
+		}
+		if job.Status == models.JobPending && !job.RunAt.After(time.Now()) {
+			job.Status = models.JobRunning
+			job.Attempts++
+			copied := *job
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeJobs) Complete(ctx context.Context, id string, attempt int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.find(id).Status = models.JobSucceeded
+	return nil
+}
+
+func (f *fakeJobs) Fail(ctx context.Context, params *repositories.FailJobParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fails = append(f.fails, *params)
+	job := f.find(params.ID)
+	job.LastError = params.Error
+	if params.RetryAt == nil {
+		job.Status = models.JobDead
+	} else {
+		job.Status = models.JobPending
+		job.RunAt = *params.RetryAt
+	}
+	return nil
+}
+
+func (f *fakeJobs) GetByID(ctx context.Context, id string) (*models.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *f.find(id)
+	return &copied, nil
+}
+
+func (f *fakeJobs) List(ctx context.Context, params *repositories.ListJobsParams) ([]*models.Job, error) {
+	return nil, nil
+}
+
+func (f *fakeJobs) Retry(ctx context.Context, id string) (*models.Job, error) {
+	return nil, nil
+}
+
+func (f *fakeJobs) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeJobs) find(id string) *models.Job {
+	for _, job := range f.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	panic("unknown job " + id)
+}
+
+func (f *fakeJobs) status(t *testing.T, id string) models.JobStatus {
+	job, err := f.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	return job.Status
+}
+
+func (f *fakeJobs) failures() []repositories.FailJobParams {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]repositories.FailJobParams(nil), f.fails...)
+}
+
+// runUntil runs the runner until cond holds, then stops it and waits for
+// it to drain.
+func runUntil(t *testing.T, runner *jobs.Runner, cond func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(stopped)
+	}()
+
+	assert.Eventually(t, cond, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	<-stopped
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func TestRunner_RunsTypedHandlers(t *testing.T) {
+	repo := &fakeJobs{}
+	runner := jobs.NewRunner(repo, jobs.Options{PollInterval: 10 * time.Millisecond}, logger.NewTestLogger(t))
+
+	var mu sync.Mutex
+	var greeted []string
+	jobs.Handle(runner, "greet", func(ctx context.Context, args greeting) error {
+		mu.Lock()
+		defer mu.Unlock()
+		greeted = append(greeted, args.Name)
+		return nil
+	})
+
+	job, err := runner.Enqueue(context.Background(), "greet", greeting{Name: "ada"}, jobs.EnqueueOptions{})
+	require.NoError(t, err)
+
+	runUntil(t, runner, func() bool { return repo.status(t, job.ID) == models.JobSucceeded })
+
+	assert.Equal(t, []string{"ada"}, greeted)
+}
+
+func TestRunner_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	repo := &fakeJobs{}
+	opts := jobs.Options{PollInterval: 10 * time.Millisecond, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	runner := jobs.NewRunner(repo, opts, logger.NewTestLogger(t))
+
+	calls := 0
+	jobs.Handle(runner, "flaky", func(ctx context.Context, _ struct{}) error {
+		calls++
+		return errors.New("downstream unavailable")
+	})
+
+	job, err := runner.Enqueue(context.Background(), "flaky", struct{}{}, jobs.EnqueueOptions{MaxAttempts: 3})
+	require.NoError(t, err)
+
+	runUntil(t, runner, func() bool { return repo.status(t, job.ID) == models.JobDead })
+
+	assert.Equal(t, 3, calls)
+	require.Len(t, repo.fails, 3)
+	assert.NotNil(t, repo.fails[0].RetryAt)
+	assert.NotNil(t, repo.fails[1].RetryAt)
+	assert.Nil(t, repo.fails[2].RetryAt, "the last attempt moves the job to the dead state")
+	assert.Equal(t, "downstream unavailable", repo.fails[2].Error)
+}
+
+func TestRunner_DeadLettersPermanentFailures(t *testing.T) {
+	tests := map[string]struct {
+		payload any
+		handler func(ctx context.Context, args greeting) error
+	}{
+		"permanent error": {
+			payload: greeting{Name: "ada"},
+			handler: func(ctx context.Context, args greeting) error {
+				return jobs.Permanent(errors.New("user deleted"))
+			},
+		},
+		"undecodable payload": {
+			payload: []int{1, 2},
+			handler: func(ctx context.Context, args greeting) error { return nil },
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakeJobs{}
+			runner := jobs.NewRunner(repo, jobs.Options{PollInterval: 10 * time.Millisecond}, logger.NewTestLogger(t))
+			jobs.Handle(runner, "greet", tt.handler)
+
+			job, err := runner.Enqueue(context.Background(), "greet", tt.payload, jobs.EnqueueOptions{MaxAttempts: 5})
+			require.NoError(t, err)
+
+			runUntil(t, runner, func() bool { return repo.status(t, job.ID) == models.JobDead })
+			assert.Len(t, repo.failures(), 1, "no attempts are retried")
+		})
+	}
+
+	t.Run("a panic is retried like any failure", func(t *testing.T) {
+		repo := &fakeJobs{}
+		runner := jobs.NewRunner(repo, jobs.Options{PollInterval: 10 * time.Millisecond}, logger.NewTestLogger(t))
+		jobs.Handle(runner, "greet", func(ctx context.Context, args greeting) error { panic("boom") })
+
+		_, err := runner.Enqueue(context.Background(), "greet", greeting{}, jobs.EnqueueOptions{MaxAttempts: 5})
+		require.NoError(t, err)
+
+		runUntil(t, runner, func() bool { return len(repo.failures()) > 0 })
+
+		assert.NotNil(t, repo.failures()[0].RetryAt)
+		assert.Contains(t, repo.failures()[0].Error, "boom")
+	})
+}
+
+func TestRunner_DrainsRunningJobsOnShutdown(t *testing.T) {
+	repo := &fakeJobs{}
+	runner := jobs.NewRunner(repo, jobs.Options{PollInterval: 10 * time.Millisecond}, logger.NewTestLogger(t))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	jobs.Handle(runner, "slow", func(ctx context.Context, _ struct{}) error {
+		close(started)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	job, err := runner.Enqueue(context.Background(), "slow", struct{}{}, jobs.EnqueueOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(stopped)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-stopped:
+		t.Fatal("Run returned while a job was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-stopped
+
+	assert.Equal(t, models.JobSucceeded, repo.status(t, job.ID))
+}
+
+func TestRunner_CancelsJobsAfterDrainTimeout(t *testing.T) {
+	repo := &fakeJobs{}
+	runner := jobs.NewRunner(repo, jobs.Options{PollInterval: 10 * time.Millisecond, DrainTimeout: 20 * time.Millisecond}, logger.NewTestLogger(t))
+
+	started := make(chan struct{})
+	jobs.Handle(runner, "stuck", func(ctx context.Context, _ struct{}) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	job, err := runner.Enqueue(context.Background(), "stuck", struct{}{}, jobs.EnqueueOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(stopped)
+	}()
+
+	<-started
+	cancel()
+	<-stopped
+
+	// The cancelled attempt is recorded so the job runs again later.
+	assert.Equal(t, models.JobPending, repo.status(t, job.ID))
+}
+
+func TestRunner_Enqueue(t *testing.T) {
+	repo := &fakeJobs{}
+	runner := jobs.NewRunner(repo, jobs.Options{}, logger.NewTestLogger(t))
+	jobs.Handle(runner, "greet", func(ctx context.Context, args greeting) error { return nil })
+
+	t.Run("rejects kinds without a handler", func(t *testing.T) {
+		_, err := runner.Enqueue(context.Background(), "unknown", nil, jobs.EnqueueOptions{})
+
+		assert.ErrorIs(t, err, jobs.ErrUnknownKind)
+	})
+
+	t.Run("enqueues a unique key once", func(t *testing.T) {
+		_, err := runner.Enqueue(context.Background(), "greet", greeting{}, jobs.EnqueueOptions{UniqueKey: "welcome:u1"})
+		require.NoError(t, err)
+
+		_, err = runner.Enqueue(context.Background(), "greet", greeting{}, jobs.EnqueueOptions{UniqueKey: "welcome:u1"})
+		assert.ErrorIs(t, err, repositories.ErrConflict)
+	})
+
+	t.Run("applies the default attempt limit", func(t *testing.T) {
+		job, err := runner.Enqueue(context.Background(), "greet", greeting{}, jobs.EnqueueOptions{})
+		require.NoError(t, err)
+
+		assert.Equal(t, jobs.DefaultOptions().MaxAttempts, job.MaxAttempts)
+	})
+}
@@ -2,11 +2,11 @@ package middleware
 
 import (
 	"context"
-	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 )
@@ -32,14 +32,14 @@ func (am *AccessMiddleware) requireAccess(next http.Handler, check accessCheckFu
 		identity, err := auth.FromContext(r.Context())
 		if err != nil {
 			am.log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			problem.WriteError(w, r, err)
 			return
 		}
 
 		orgID := chi.URLParam(r, "orgID")
 		if orgID == "" {
 			am.log.Warn("Organization ID is required for access check", slog.String("orgID", orgID))
-			http.Error(w, "Bad Request: Missing organization ID", http.StatusBadRequest)
+			problem.WriteError(w, r, services.NewValidationError("orgID", "is required"))
 			return
 		}
 
@@ -48,13 +48,15 @@ func (am *AccessMiddleware) requireAccess(next http.Handler, check accessCheckFu
 			UserID: identity.UserID,
 		})
 		if err != nil {
-			if errors.Is(err, services.ErrUnauthorized) {
+			p := problem.FromError(err)
+			if p.Status == http.StatusForbidden {
 				am.log.Warn("Access denied for user", slog.String("user_id", identity.UserID), slog.String("org_id", orgID), slog.Any("error", err))
-				http.Error(w, forbiddenMsg, http.StatusForbidden)
+				p.Detail = forbiddenMsg
+				problem.Write(w, r, p)
 				return
 			}
 			am.log.Error("Failed to check access", slog.String("user_id", identity.UserID), slog.String("org_id", orgID), slog.Any("error", err))
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			problem.Write(w, r, p)
 			return
 		}
 
@@ -66,7 +68,7 @@ func (am *AccessMiddleware) RequireAdmin(next http.Handler) http.Handler {
 	return am.requireAccess(
 		next,
 		am.accessService.IsAdmin,
-		"Forbidden: You are not an admin of this organization",
+		"You are not an admin of this organization",
 	)
 }
 
@@ -74,6 +76,6 @@ func (am *AccessMiddleware) RequireMember(next http.Handler) http.Handler {
 	return am.requireAccess(
 		next,
 		am.accessService.IsMember,
-		"Forbidden: You are not a member of this organization",
+		"You are not a member of this organization",
 	)
 }
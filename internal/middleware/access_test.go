@@ -9,6 +9,7 @@ import (
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
 	"github.com/espennoreng/go-http-rental-server/internal/logger"
 	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -86,4 +87,6 @@ func TestAccessMiddleware_RequireAdmin_BlocksNonAdminUser(t *testing.T) {
 	}
 
 	assert.Contains(t, res.Body.String(), "Forbidden")
+	assert.Equal(t, problem.ContentType, res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), problem.CodeInsufficientRole)
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 )
 
@@ -17,13 +18,13 @@ func NewAuthMiddleware(log *slog.Logger, verifier auth.TokenVerifier, userServic
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
 				log.Error("Authorization header is missing")
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, "Authorization header required"))
 				return
 			}
 			parts := strings.Split(authHeader, " ")
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 				log.Error("Invalid Authorization header format")
-				http.Error(w, "Authorization header must be 'Bearer {token}'", http.StatusUnauthorized)
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, "Authorization header must be 'Bearer {token}'"))
 				return
 			}
 			tokenString := parts[1]
@@ -32,7 +33,7 @@ func NewAuthMiddleware(log *slog.Logger, verifier auth.TokenVerifier, userServic
 			payload, err := verifier.Verify(r.Context(), tokenString, audience)
 			if err != nil {
 				log.Error("Token verification failed", slog.Any("error", err))
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken, "Invalid token"))
 				return
 			}
 
@@ -43,7 +44,7 @@ func NewAuthMiddleware(log *slog.Logger, verifier auth.TokenVerifier, userServic
 			user, err := userService.FindOrCreateByGoogleID(r.Context(), googleID, email)
 			if err != nil {
 				log.Error("Failed to find or create user", slog.Any("error", err))
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				problem.WriteError(w, r, err)
 				return
 			}
 
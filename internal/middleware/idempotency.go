@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// memoryBodyBytes is how much of a keyed request body is held in
+	// memory; larger bodies, such as uploads, are spooled to a temporary
+	// file while they are fingerprinted.
+	memoryBodyBytes = 64 << 10
+)
+
+var (
+	errBodyTooLarge = errors.New("request body is too large")
+	errSpoolFailed  = errors.New("failed to spool request body")
+)
+
+// NewIdempotencyMiddleware makes POST requests that carry an Idempotency-Key
+// header safe to retry. The first response for a key, user and route is
+// stored and replayed for retries; reusing the key with a different body is
+// rejected. Server errors are not stored, so those requests can be retried.
+// It must run after the auth middleware.
+//
+// maxBodyBytes should be the largest body any wrapped route accepts, since
+// keyed requests with larger bodies are rejected before they reach the
+// route. Routes still apply their own limits.
+func NewIdempotencyMiddleware(idempotencyService services.IdempotencyService, maxBodyBytes int64, log *slog.Logger) func(http.Handler) http.Handler {
+	log = log.With(slog.String("component", "idempotency_middleware"))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := auth.FromContext(r.Context())
+			if err != nil {
+				log.Error("Failed to retrieve user ID from context", slog.Any("error", err))
+				problem.WriteError(w, r, err)
+				return
+			}
+
+			hash, body, cleanup, err := spoolBody(r.Body, maxBodyBytes)
+			switch {
+			case errors.Is(err, errBodyTooLarge):
+				problem.Write(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, "Request body is too large"))
+				return
+			case errors.Is(err, errSpoolFailed):
+				log.Error("Failed to buffer request body", slog.Any("error", err))
+				problem.WriteError(w, r, services.ErrInternalServer)
+				return
+			case err != nil:
+				log.Warn("Failed to read request body", slog.Any("error", err))
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidRequestBody, "Invalid request body"))
+				return
+			}
+			defer cleanup()
+			r.Body = body
+
+			req := services.IdempotentRequest{
+				UserID: identity.UserID,
+				Key:    key,
+				Method: r.Method,
+				Path:   r.URL.Path,
+			}
+			stored, err := idempotencyService.BeginRequest(r.Context(), services.BeginIdempotentRequestParams{
+				IdempotentRequest: req,
+				RequestHash:       hex.EncodeToString(hash),
+			})
+			if err != nil {
+				problem.WriteError(w, r, err)
+				return
+			}
+			if stored != nil {
+				for name, values := range stored.ResponseHeaders {
+					w.Header()[name] = values
+				}
+				w.Header().Set(HeaderIdempotentReplayed, "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.ResponseBody)
+				return
+			}
+
+			// The key is held until a response is stored. Release it on
+			// every other path, including panics, so retries are not
+			// stuck behind a request that will never complete.
+			completed := false
+			defer func() {
+				if !completed {
+					idempotencyService.ReleaseRequest(context.WithoutCancel(r.Context()), req)
+				}
+			}()
+
+			var captured bytes.Buffer
+			ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&captured)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			err = idempotencyService.CompleteRequest(context.WithoutCancel(r.Context()), services.CompleteIdempotentRequestParams{
+				IdempotentRequest: req,
+				StatusCode:        status,
+				ResponseHeaders:   w.Header().Clone(),
+				ResponseBody:      captured.Bytes(),
+			})
+			completed = err == nil
+		})
+	}
+}
+
+// spoolBody reads body, up to limit bytes, and returns its SHA-256 hash and
+// a reader that replays it. Bodies over memoryBodyBytes are replayed from a
+// temporary file, which cleanup removes.
+func spoolBody(body io.Reader, limit int64) (hash []byte, replay io.ReadCloser, cleanup func(), err error) {
+	hasher := sha256.New()
+	body = io.TeeReader(io.LimitReader(body, limit+1), hasher)
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, body, memoryBodyBytes+1); err != nil && err != io.EOF {
+		return nil, nil, nil, err
+	}
+	if int64(buf.Len()) <= memoryBodyBytes {
+		if int64(buf.Len()) > limit {
+			return nil, nil, nil, errBodyTooLarge
+		}
+		return hasher.Sum(nil), io.NopCloser(&buf), func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "idempotent-body-*")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %w", errSpoolFailed, err)
+	}
+	cleanup = func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	n, err := io.Copy(spoolWriter{file}, io.MultiReader(&buf, body))
+	if err == nil && n > limit {
+		err = errBodyTooLarge
+	}
+	if err == nil {
+		if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+			err = fmt.Errorf("%w: %w", errSpoolFailed, seekErr)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, err
+	}
+	// The server closes the request body it was given, so the file is
+	// closed by cleanup instead.
+	return hasher.Sum(nil), io.NopCloser(file), cleanup, nil
+}
+
+// spoolWriter marks write errors, so they are not mistaken for a client
+// sending a broken body.
+type spoolWriter struct {
+	file *os.File
+}
+
+func (w spoolWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", errSpoolFailed, err)
+	}
+	return n, err
+}
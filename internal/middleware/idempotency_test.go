@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIdempotencyService keeps keys in memory and mirrors the rules of the
+// real service closely enough to exercise the middleware end to end.
+type fakeIdempotencyService struct {
+	keys     map[services.IdempotentRequest]*models.IdempotencyKey
+	released int
+}
+
+func newFakeIdempotencyService() *fakeIdempotencyService {
+	return &fakeIdempotencyService{keys: map[services.IdempotentRequest]*models.IdempotencyKey{}}
+}
+
+func (f *fakeIdempotencyService) BeginRequest(ctx context.Context, params services.BeginIdempotentRequestParams) (*models.IdempotencyKey, error) {
+	stored, ok := f.keys[params.IdempotentRequest]
+	if !ok {
+		f.keys[params.IdempotentRequest] = &models.IdempotencyKey{RequestHash: params.RequestHash}
+		return nil, nil
+	}
+	if stored.RequestHash != params.RequestHash {
+		return nil, services.ErrIdempotencyKeyReused
+	}
+	if !stored.Completed() {
+		return nil, services.ErrIdempotentRequestInProgress
+	}
+	return stored, nil
+}
+
+func (f *fakeIdempotencyService) CompleteRequest(ctx context.Context, params services.CompleteIdempotentRequestParams) error {
+	stored := f.keys[params.IdempotentRequest]
+	stored.StatusCode = params.StatusCode
+	stored.ResponseHeaders = params.ResponseHeaders
+	stored.ResponseBody = params.ResponseBody
+	return nil
+}
+
+func (f *fakeIdempotencyService) ReleaseRequest(ctx context.Context, req services.IdempotentRequest) error {
+	delete(f.keys, req)
+	f.released++
+	return nil
+}
+
+func (f *fakeIdempotencyService) PurgeExpired(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	identity := auth.Identity{UserID: "user-001"}
+
+	const maxBodyBytes = 4 * memoryBodyBytes
+
+	newHandler := func(service services.IdempotencyService, next http.HandlerFunc) http.Handler {
+		idempotency := NewIdempotencyMiddleware(service, maxBodyBytes, logger.NewTestLogger(t))
+		return NewTestAuthMiddleware(idempotency(next), identity)
+	}
+	newRequest := func(key, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/organizations", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		return req
+	}
+
+	t.Run("should replay the first response for a retry", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		handler := newHandler(newFakeIdempotencyService(), func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"org-001"}`))
+		})
+
+		// Act
+		first := httptest.NewRecorder()
+		handler.ServeHTTP(first, newRequest("key-1", `{"name":"Acme"}`))
+		retry := httptest.NewRecorder()
+		handler.ServeHTTP(retry, newRequest("key-1", `{"name":"Acme"}`))
+
+		// Assert
+		assert.Equal(t, 1, calls, "the handler should only run once")
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		assert.Equal(t, "true", retry.Header().Get(HeaderIdempotentReplayed))
+		assert.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("should reject reuse of a key with a different body", func(t *testing.T) {
+		// Arrange
+		handler := newHandler(newFakeIdempotencyService(), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("key-1", `{"name":"Acme"}`))
+
+		// Act
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest("key-1", `{"name":"Other"}`))
+
+		// Assert
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), problem.CodeIdempotencyKeyReused)
+	})
+
+	t.Run("should pass the original body to the handler", func(t *testing.T) {
+		// Arrange
+		var received string
+		handler := newHandler(newFakeIdempotencyService(), func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			received = string(body)
+		})
+
+		// Act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("key-1", `{"name":"Acme"}`))
+
+		// Assert
+		assert.Equal(t, `{"name":"Acme"}`, received)
+	})
+
+	t.Run("should pass a body too large to keep in memory to the handler", func(t *testing.T) {
+		// Arrange
+		body := strings.Repeat("a", 2*memoryBodyBytes)
+		calls := 0
+		var received string
+		handler := newHandler(newFakeIdempotencyService(), func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			content, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			received = string(content)
+			w.WriteHeader(http.StatusCreated)
+		})
+
+		// Act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("key-1", body))
+		retry := httptest.NewRecorder()
+		handler.ServeHTTP(retry, newRequest("key-1", body))
+
+		// Assert
+		assert.Equal(t, body, received)
+		assert.Equal(t, 1, calls, "the handler should only run once")
+		assert.Equal(t, "true", retry.Header().Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("should reject a body over the limit", func(t *testing.T) {
+		// Arrange
+		service := newFakeIdempotencyService()
+		calls := 0
+		handler := newHandler(service, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		})
+
+		// Act
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest("key-1", strings.Repeat("a", maxBodyBytes+1)))
+
+		// Assert
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Contains(t, rr.Body.String(), problem.CodeRequestTooLarge)
+		assert.Zero(t, calls)
+		assert.Empty(t, service.keys)
+	})
+
+	t.Run("should not store server errors", func(t *testing.T) {
+		// Arrange
+		service := newFakeIdempotencyService()
+		calls := 0
+		handler := newHandler(service, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		// Act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("key-1", `{}`))
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("key-1", `{}`))
+
+		// Assert
+		assert.Equal(t, 2, calls, "a failed request should be retried, not replayed")
+		assert.Equal(t, 2, service.released)
+	})
+
+	t.Run("should release the key when the handler panics", func(t *testing.T) {
+		// Arrange
+		service := newFakeIdempotencyService()
+		handler := newHandler(service, func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})
+
+		// Act
+		assert.Panics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), newRequest("key-1", `{}`))
+		})
+
+		// Assert
+		assert.Equal(t, 1, service.released)
+		assert.Empty(t, service.keys)
+	})
+
+	t.Run("should ignore requests without a key", func(t *testing.T) {
+		// Arrange
+		service := newFakeIdempotencyService()
+		calls := 0
+		handler := newHandler(service, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		})
+
+		// Act
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("", `{}`))
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("", `{}`))
+
+		// Assert
+		assert.Equal(t, 2, calls)
+		assert.Empty(t, service.keys)
+	})
+}
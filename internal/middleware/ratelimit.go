@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+)
+
+// NewRateLimiter limits each client IP address to requestsPerMinute
+// requests, allowing bursts of up to burst requests. Requests over the
+// limit get 429 Too Many Requests with a Retry-After header.
+//
+// The client is the address the request came from, unless that is one of
+// trustedProxies, such as a load balancer. Then the client is read from
+// X-Forwarded-For, skipping any further trusted proxies. Without trusted
+// proxies, every client behind a proxy shares one limit.
+//
+// Limits are kept in memory, so each server instance counts separately.
+func NewRateLimiter(log *slog.Logger, requestsPerMinute, burst int, trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	limiter := &rateLimiter{
+		rate:           float64(requestsPerMinute) / 60,
+		burst:          float64(max(burst, 1)),
+		trustedProxies: trustedProxies,
+		buckets:        map[string]*tokenBucket{},
+		log:            log.With(slog.String("component", "rate_limiter")),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := limiter.clientIP(r)
+			wait := limiter.take(client, time.Now())
+			if wait > 0 {
+				seconds := int(math.Ceil(wait.Seconds()))
+				limiter.log.Warn("Rate limit exceeded", slog.String("client_ip", client), slog.String("path", r.URL.Path))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited,
+					fmt.Sprintf("too many requests; retry in %d seconds", seconds)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64
+
+	trustedProxies []netip.Prefix
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+
+	log *slog.Logger
+}
+
+// take spends a token from the client's bucket. It returns zero if the
+// request may proceed, or how long until a token is available.
+func (l *rateLimiter) take(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	if l.rate <= 0 {
+		return time.Minute
+	}
+	return time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep forgets clients whose buckets have refilled, since a new bucket
+// would be identical. It runs at most once a minute.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP returns the address of the client that sent the request.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	client := remote.Addr().Unmap()
+	if !l.trusted(client) {
+		return client.String()
+	}
+
+	// Each proxy appends the address it received the request from, so
+	// the client is the last address that was not added by a trusted
+	// proxy. Anything before it could have been sent by the client.
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !l.trusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (l *rateLimiter) trusted(addr netip.Addr) bool {
+	for _, proxy := range l.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.NewRateLimiter(logger.NewTestLogger(t), 60, 3, nil)(ok)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/public/oslo-ski-rental", nil)
+		req.RemoteAddr = remoteAddr
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	for i := range 3 {
+		res := request("203.0.113.7:40000")
+		require.Equal(t, http.StatusOK, res.Code, "request %d", i)
+	}
+
+	t.Run("blocks after the burst", func(t *testing.T) {
+		// A different port is the same client.
+		res := request("203.0.113.7:40001")
+
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "1", res.Header().Get("Retry-After"))
+		assert.Equal(t, problem.ContentType, res.Header().Get("Content-Type"))
+		assert.Contains(t, res.Body.String(), problem.CodeRateLimited)
+	})
+
+	t.Run("limits each client separately", func(t *testing.T) {
+		res := request("198.51.100.1:40000")
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
+
+func TestRateLimiter_TrustedProxies(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	handler := middleware.NewRateLimiter(logger.NewTestLogger(t), 60, 1, proxies)(ok)
+
+	request := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/public/oslo-ski-rental", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	t.Run("limits clients behind a trusted proxy separately", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:40000", "203.0.113.7"))
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:40000", "198.51.100.1"))
+		assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.2:40000", "203.0.113.7, 10.0.0.1"))
+	})
+
+	t.Run("ignores addresses a client added itself", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:40000", "192.0.2.1, 192.0.2.50"))
+		assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:40000", "192.0.2.2, 192.0.2.50"))
+	})
+
+	t.Run("ignores X-Forwarded-For from untrusted addresses", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("192.0.2.99:40000", "192.0.2.3"))
+		assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.99:40000", "192.0.2.4"))
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+)
+
+// NewRecoverer returns a middleware that recovers from panics, logs them with
+// a stack trace and responds with a problem+json 500 instead of plain text.
+func NewRecoverer(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					// Let net/http abort the connection as intended.
+					panic(rec)
+				}
+
+				log.Error("Recovered from panic",
+					slog.Any("panic", rec),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("stack", string(debug.Stack())),
+				)
+
+				if r.Header.Get("Connection") != "Upgrade" {
+					problem.Write(w, r, problem.New(http.StatusInternalServerError, problem.CodeInternal, "internal server error"))
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
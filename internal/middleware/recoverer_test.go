@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/middleware"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverer_RespondsWithProblem(t *testing.T) {
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	handler := middleware.NewRecoverer(logger.NewTestLogger(t))(panicking)
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, problem.ContentType, res.Header().Get("Content-Type"))
+
+	var body problem.Problem
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	assert.Equal(t, problem.CodeInternal, body.Code)
+	assert.NotContains(t, body.Detail, "boom")
+}
+
+func TestRecoverer_RepanicsOnAbortHandler(t *testing.T) {
+	aborting := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	handler := middleware.NewRecoverer(logger.NewTestLogger(t))(aborting)
+
+	req := httptest.NewRequest(http.MethodGet, "/abort", nil)
+	res := httptest.NewRecorder()
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(res, req)
+	})
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Attachment is a photo or document uploaded to an organization. Key and
+// ThumbnailKey address the blobs in the blob store; ThumbnailKey is empty
+// for files without a thumbnail.
+type Attachment struct {
+	ID           string
+	OrgID        string
+	Filename     string
+	ContentType  string
+	Size         int64
+	Key          string
+	ThumbnailKey string
+	CreatedBy    string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// VerificationStatus is how far a customer's identity has been checked.
+type VerificationStatus string
+
+const (
+	VerificationUnverified VerificationStatus = "unverified"
+	VerificationPending    VerificationStatus = "pending"
+	VerificationVerified   VerificationStatus = "verified"
+	VerificationRejected   VerificationStatus = "rejected"
+)
+
+// VerificationStatuses holds every valid VerificationStatus.
+var VerificationStatuses = map[VerificationStatus]bool{
+	VerificationUnverified: true,
+	VerificationPending:    true,
+	VerificationVerified:   true,
+	VerificationRejected:   true,
+}
+
+// Customer is a user who rents from an organization without being one of
+// its members. Username and Email are the user's. Address is the zero
+// value if the customer has not given one.
+type Customer struct {
+	ID                 string
+	OrgID              string
+	UserID             string
+	Username           string
+	Email              string
+	Phone              string
+	Address            Address
+	VerificationStatus VerificationStatus
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Version            int
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type EventType string
+
+const (
+	EventMemberAdded       EventType = "member.added"
+	EventMemberRoleChanged EventType = "member.role_changed"
+	EventMemberRemoved     EventType = "member.removed"
+)
+
+// EventTypes lists the events that can be subscribed to.
+var EventTypes = map[EventType]bool{
+	EventMemberAdded:       true,
+	EventMemberRoleChanged: true,
+	EventMemberRemoved:     true,
+}
+
+// Event is something that happened in an organization. Data is the
+// event-specific payload and must be JSON serializable; events read back
+// from storage carry it as json.RawMessage. Seq identifies the event in
+// the outbox and is zero until the event has been stored. Position orders
+// events by when they became visible and is zero until a dispatcher first
+// claims the event; readers resume from a position, never from a seq.
+type Event struct {
+	ID         string
+	Seq        int64
+	Position   int64
+	Type       EventType
+	OrgID      string
+	OccurredAt time.Time
+	Data       any
+}
+
+// MemberEventData is the payload of member events.
+type MemberEventData struct {
+	UserID  string `json:"user_id"`
+	Role    Role   `json:"role,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
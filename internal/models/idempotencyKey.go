@@ -0,0 +1,27 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyKey is the stored outcome of a request made with an
+// Idempotency-Key header. StatusCode is zero while the first request is
+// still being processed.
+type IdempotencyKey struct {
+	UserID          string
+	Key             string
+	Method          string
+	Path            string
+	RequestHash     string
+	StatusCode      int
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+// Completed reports whether a response has been stored for the key.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	// JobDead marks jobs that failed permanently or ran out of attempts.
+	JobDead JobStatus = "dead"
+)
+
+var JobStatuses = map[JobStatus]bool{
+	JobPending:   true,
+	JobRunning:   true,
+	JobSucceeded: true,
+	JobDead:      true,
+}
+
+// Job is a unit of background work. Payload holds the JSON arguments for
+// the handler registered for Kind.
+type Job struct {
+	ID          string
+	Kind        string
+	Payload     json.RawMessage
+	Status      JobStatus
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LockedUntil *time.Time
+	LastError   string
+	UniqueKey   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FinishedAt  *time.Time
+}
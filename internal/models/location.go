@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Location is a depot of an organization where rentals are picked up and
+// returned.
+type Location struct {
+	ID      string
+	OrgID   string
+	Name    string
+	Address Address
+	// TimeZone is an IANA time zone name, such as "Europe/Oslo". Opening
+	// hours are local times in this zone.
+	TimeZone     string
+	OpeningHours []OpeningHours
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Version      int
+}
+
+type Address struct {
+	Street     string `json:"street"`
+	PostalCode string `json:"postal_code,omitempty"`
+	City       string `json:"city"`
+	// Country is an ISO 3166-1 alpha-2 code, such as "NO".
+	Country string `json:"country"`
+}
+
+type Weekday string
+
+const (
+	Monday    Weekday = "monday"
+	Tuesday   Weekday = "tuesday"
+	Wednesday Weekday = "wednesday"
+	Thursday  Weekday = "thursday"
+	Friday    Weekday = "friday"
+	Saturday  Weekday = "saturday"
+	Sunday    Weekday = "sunday"
+)
+
+// Weekdays lists the valid weekdays.
+var Weekdays = map[Weekday]bool{
+	Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, Saturday: true, Sunday: true,
+}
+
+// OpeningHours is one period a location is open on a day of the week.
+// Opens and Closes are "15:04" clock times; Closes may be "24:00" for
+// midnight at the end of the day.
+type OpeningHours struct {
+	Day    Weekday `json:"day"`
+	Opens  string  `json:"opens"`
+	Closes string  `json:"closes"`
+}
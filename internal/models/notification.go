@@ -0,0 +1,26 @@
+package models
+
+// NotificationChannel is a way of reaching a user.
+type NotificationChannel string
+
+const (
+	NotificationEmail NotificationChannel = "email"
+)
+
+// NotificationChannels lists the channels notifications can be sent on.
+var NotificationChannels = map[NotificationChannel]bool{
+	NotificationEmail: true,
+}
+
+// NotificationPreferences are stored on the user. Channels missing from
+// Channels are enabled, so users are opted in until they unsubscribe.
+type NotificationPreferences struct {
+	Locale   string                       `json:"locale,omitempty"`
+	Channels map[NotificationChannel]bool `json:"channels,omitempty"`
+}
+
+// Enabled reports whether the user wants notifications on channel.
+func (p NotificationPreferences) Enabled(channel NotificationChannel) bool {
+	enabled, ok := p.Channels[channel]
+	return !ok || enabled
+}
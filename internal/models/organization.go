@@ -1,13 +1,24 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/money"
+)
 
 type Organization struct {
 	ID        string  
 	Name      string  
+	// Currency is what the organization prices and invoices in.
+	Currency  money.Currency
+	// Slug names the organization in public URLs; it is empty until set.
+	Slug string
+	// PublicCatalog reports whether the organization has opted in to the
+	// unauthenticated /public endpoints.
+	PublicCatalog bool
 	CreatedBy string  
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int
 }
 
-
@@ -8,4 +8,5 @@ type OrganizationUser struct {
 	UserID    string
 	CreatedAt time.Time
 	Role      Role
+	Version   int
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Terms is a published version of an organization's rental terms.
+// Versions are numbered from 1 and never change once published.
+// ContentHash is the hex SHA-256 of Body.
+type Terms struct {
+	ID          string
+	OrgID       string
+	Version     int
+	Title       string
+	Body        string
+	ContentHash string
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+// TermsAcceptance records that a user accepted a version of an
+// organization's terms, from which IP address, and the hash of the text
+// they accepted.
+type TermsAcceptance struct {
+	ID          string
+	TermsID     string
+	OrgID       string
+	Version     int
+	UserID      string
+	IPAddress   string
+	ContentHash string
+	AcceptedAt  time.Time
+}
@@ -13,6 +13,10 @@ type User struct {
 	Email     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// NotificationPreferences is empty for users loaded without it, such
+	// as by FindOrCreateByGoogleID.
+	NotificationPreferences NotificationPreferences
 }
 
 type CreateUserInput struct {
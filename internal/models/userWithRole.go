@@ -3,4 +3,7 @@ package models
 type UserWithRole struct {
 	User
 	Role Role 
+	// MembershipVersion is the version of the organization membership,
+	// not of the user.
+	MembershipVersion int
 }
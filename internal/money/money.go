@@ -0,0 +1,44 @@
+// Package money holds the ISO 4217 currencies organizations can price in.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownCurrency = errors.New("money: unknown currency")
+
+// Currency is an upper-case ISO 4217 currency code such as "NOK".
+type Currency string
+
+// exponents holds the number of minor unit digits of each supported
+// currency. Currencies not listed here are rejected.
+var exponents = map[Currency]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2,
+	"DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "INR": 2, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2,
+	"OMR": 3, "PLN": 2, "RON": 2, "SEK": 2, "SGD": 2, "TND": 3, "TRY": 2,
+	"USD": 2, "ZAR": 2,
+}
+
+// ParseCurrency returns the currency with the given code, in any case.
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(code))
+	if _, ok := exponents[c]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// Valid reports whether c is a supported currency.
+func (c Currency) Valid() bool {
+	_, ok := exponents[c]
+	return ok
+}
+
+// Exponent is the number of digits after the decimal point, 2 for NOK
+// and 0 for JPY.
+func (c Currency) Exponent() int {
+	return exponents[c]
+}
@@ -0,0 +1,19 @@
+package money_test
+
+import (
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrency(t *testing.T) {
+	c, err := money.ParseCurrency("nok")
+	require.NoError(t, err)
+	assert.Equal(t, money.Currency("NOK"), c)
+	assert.Equal(t, 2, c.Exponent())
+
+	_, err = money.ParseCurrency("XXX")
+	assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
@@ -0,0 +1,42 @@
+// Package notification renders notifications from templates and sends them
+// to users. Which notifications are sent, and to whom, is decided by the
+// notification service.
+package notification
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Message is a rendered email. HTML is optional.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are added to the message as is, e.g. List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender logs messages instead of sending them, for development.
+type LogSender struct {
+	log *slog.Logger
+}
+
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log.With(slog.String("component", "notification_log"))}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.log.InfoContext(ctx, "Notification sent",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("text", msg.Text),
+	)
+	return nil
+}
@@ -0,0 +1,163 @@
+package notification_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts mail on a local port and records what it gets. It
+// speaks just enough SMTP for net/smtp and offers neither STARTTLS nor AUTH.
+type fakeSMTPServer struct {
+	addr string
+
+	mu       sync.Mutex
+	from     string
+	rcpt     []string
+	messages []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeSMTPServer{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = cmd
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, cmd)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) received() (string, []string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.from, append([]string(nil), s.rcpt...), append([]string(nil), s.messages...)
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sender, err := notification.NewSMTPSender(notification.SMTPOptions{
+		Addr: server.addr,
+		From: "Rentals <no-reply@rentals.example>",
+	})
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), notification.Message{
+		To:      "ada@example.com",
+		Subject: "Du er lagt til i Verktøy",
+		Text:    "Hei ada,\n.\nMeld deg av: https://rentals.example/u\n",
+		HTML:    "<p>Hei ada</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://rentals.example/u>"},
+	})
+	require.NoError(t, err)
+
+	from, rcpt, messages := server.received()
+	assert.Equal(t, "MAIL FROM:<no-reply@rentals.example>", from)
+	assert.Equal(t, []string{"RCPT TO:<ada@example.com>"}, rcpt)
+	require.Len(t, messages, 1)
+
+	msg, err := mail.ReadMessage(strings.NewReader(messages[0]))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Du er lagt til i Verktøy", subject)
+	assert.Equal(t, "<ada@example.com>", msg.Header.Get("To"))
+	assert.Equal(t, "<https://rentals.example/u>", msg.Header.Get("List-Unsubscribe"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		bodies = append(bodies, strings.ReplaceAll(string(body), "\r\n", "\n"))
+	}
+	assert.Equal(t, []string{"Hei ada,\n.\nMeld deg av: https://rentals.example/u\n", "<p>Hei ada</p>"}, bodies)
+}
+
+func TestSMTPSender_RejectsInvalidRecipients(t *testing.T) {
+	sender, err := notification.NewSMTPSender(notification.SMTPOptions{Addr: "127.0.0.1:1", From: "no-reply@rentals.example"})
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), notification.Message{To: "ada@example.com\r\nBcc: eve@example.com"})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+// DefaultLocale is used for users without a locale and for kinds that have
+// no template in the user's locale.
+const DefaultLocale = "en"
+
+// Templates live in templates/<locale>/. Each kind has a text template
+// <kind>.txt, which must also define "subject", and may have an HTML
+// template <kind>.html, which defines "content" for the locale's
+// layout.html.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Data is what templates are rendered with.
+type Data struct {
+	Recipient    *models.User
+	Organization *models.Organization
+	// Details is the event's payload, e.g. models.MemberEventData.
+	Details        any
+	UnsubscribeURL string
+}
+
+type templateKey struct {
+	locale string
+	kind   string
+}
+
+// Templates renders notifications per kind and locale.
+type Templates struct {
+	text    map[templateKey]*texttemplate.Template
+	html    map[templateKey]*htmltemplate.Template
+	locales map[string]bool
+}
+
+// LoadTemplates parses the embedded templates. Every kind must have a
+// template in DefaultLocale.
+func LoadTemplates() (*Templates, error) {
+	return loadTemplates(templateFS)
+}
+
+func loadTemplates(fsys fs.FS) (*Templates, error) {
+	t := &Templates{
+		text:    map[templateKey]*texttemplate.Template{},
+		html:    map[templateKey]*htmltemplate.Template{},
+		locales: map[string]bool{},
+	}
+
+	dirs, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		locale := dir.Name()
+		t.locales[locale] = true
+
+		files, err := fs.Glob(fsys, path.Join("templates", locale, "*.txt"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			kind := strings.TrimSuffix(path.Base(file), ".txt")
+			tmpl, err := texttemplate.New(path.Base(file)).Option("missingkey=error").ParseFS(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			if tmpl.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s does not define a subject", file)
+			}
+			t.text[templateKey{locale, kind}] = tmpl
+		}
+
+		layout := path.Join("templates", locale, "layout.html")
+		files, err = fs.Glob(fsys, path.Join("templates", locale, "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file == layout {
+				continue
+			}
+			kind := strings.TrimSuffix(path.Base(file), ".html")
+			if _, ok := t.text[templateKey{locale, kind}]; !ok {
+				return nil, fmt.Errorf("%s has no text template", file)
+			}
+			tmpl, err := htmltemplate.New("layout.html").Option("missingkey=error").ParseFS(fsys, layout, file)
+			if err != nil {
+				return nil, err
+			}
+			t.html[templateKey{locale, kind}] = tmpl
+		}
+	}
+
+	for key := range t.text {
+		if _, ok := t.text[templateKey{DefaultLocale, key.kind}]; !ok {
+			return nil, fmt.Errorf("%s has no template in the default locale %s", key.kind, DefaultLocale)
+		}
+	}
+	return t, nil
+}
+
+// HasLocale reports whether there are templates in locale.
+func (t *Templates) HasLocale(locale string) bool {
+	return t.locales[locale]
+}
+
+// HasKind reports whether kind can be rendered.
+func (t *Templates) HasKind(kind string) bool {
+	_, ok := t.text[templateKey{DefaultLocale, kind}]
+	return ok
+}
+
+// Render renders kind in locale, falling back to DefaultLocale. The
+// returned message has no recipient.
+func (t *Templates) Render(kind, locale string, data Data) (Message, error) {
+	key := templateKey{locale, kind}
+	if _, ok := t.text[key]; !ok {
+		key.locale = DefaultLocale
+	}
+	text, ok := t.text[key]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for %s", kind)
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	msg := Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+	}
+
+	if html, ok := t.html[key]; ok {
+		var buf bytes.Buffer
+		if err := html.Execute(&buf, data); err != nil {
+			return Message{}, err
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
@@ -0,0 +1,67 @@
+package notification_test
+
+import (
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	templates, err := notification.LoadTemplates()
+	require.NoError(t, err)
+
+	data := notification.Data{
+		Recipient:      &models.User{Username: "ada"},
+		Organization:   &models.Organization{Name: "Tools <& Co>"},
+		Details:        models.MemberEventData{Role: models.RoleAdmin},
+		UnsubscribeURL: "https://rentals.example/notifications/unsubscribe?token=abc",
+	}
+
+	t.Run("renders every event type in every locale", func(t *testing.T) {
+		for eventType := range models.EventTypes {
+			for _, locale := range []string{"en", "nb"} {
+				msg, err := templates.Render(string(eventType), locale, data)
+				require.NoError(t, err, "%s/%s", locale, eventType)
+
+				assert.NotEmpty(t, msg.Subject)
+				assert.Contains(t, msg.Text, data.UnsubscribeURL)
+				assert.Contains(t, msg.HTML, "token=abc")
+			}
+		}
+	})
+
+	t.Run("uses the user's locale", func(t *testing.T) {
+		msg, err := templates.Render(string(models.EventMemberAdded), "nb", data)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Du er lagt til i Tools <& Co>", msg.Subject)
+		assert.Contains(t, msg.Text, "med rollen admin")
+	})
+
+	t.Run("escapes HTML but not text", func(t *testing.T) {
+		msg, err := templates.Render(string(models.EventMemberAdded), "en", data)
+		require.NoError(t, err)
+
+		assert.Contains(t, msg.Text, "Tools <& Co>")
+		assert.Contains(t, msg.HTML, "Tools &lt;&amp; Co&gt;")
+	})
+
+	t.Run("falls back to the default locale", func(t *testing.T) {
+		assert.False(t, templates.HasLocale("xx"))
+
+		msg, err := templates.Render(string(models.EventMemberRemoved), "xx", data)
+		require.NoError(t, err)
+
+		assert.Equal(t, "You were removed from Tools <& Co>", msg.Subject)
+	})
+
+	t.Run("fails for unknown kinds", func(t *testing.T) {
+		assert.False(t, templates.HasKind("booking.teleported"))
+
+		_, err := templates.Render("booking.teleported", "en", data)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,49 @@
+package notification
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeToken returns a token that lets its holder turn off channel
+// for userID without signing in. Tokens do not expire, because the links
+// in old emails must keep working; rotating secret revokes all of them.
+func UnsubscribeToken(secret, userID string, channel models.NotificationChannel) string {
+	payload := []byte(userID + ":" + string(channel))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(unsubscribeMAC(secret, payload))
+}
+
+// ParseUnsubscribeToken verifies a token produced by UnsubscribeToken.
+func ParseUnsubscribeToken(secret, token string) (userID string, channel models.NotificationChannel, err error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, unsubscribeMAC(secret, payload)) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	id, ch, ok := strings.Cut(string(payload), ":")
+	if !ok || id == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return id, models.NotificationChannel(ch), nil
+}
+
+func unsubscribeMAC(secret string, payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,40 @@
+package notification_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	token := notification.UnsubscribeToken("secret", "user-1", models.NotificationEmail)
+
+	userID, channel, err := notification.ParseUnsubscribeToken("secret", token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.Equal(t, models.NotificationEmail, channel)
+
+	otherPayload, _, _ := strings.Cut(notification.UnsubscribeToken("secret", "user-2", models.NotificationEmail), ".")
+	_, mac, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+	}{
+		{"wrong secret", "other", token},
+		{"malformed", "secret", "not-a-token"},
+		{"tampered payload", "secret", otherPayload + "." + mac},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := notification.ParseUnsubscribeToken(tt.secret, tt.token)
+			assert.ErrorIs(t, err, notification.ErrInvalidUnsubscribeToken)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package outbox publishes the events that services store in the
+// transactional outbox. Events are published at least once: a sink may see
+// an event again if the dispatcher stops before recording it as published,
+// or if another sink failed and the event is retried.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/backoff"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+// Sink receives published events. Because of redelivery, sinks must
+// tolerate seeing the same event ID twice.
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, event models.Event) error
+
+func (f SinkFunc) Publish(ctx context.Context, event models.Event) error {
+	return f(ctx, event)
+}
+
+// Options tunes the dispatcher. Zero values are replaced by the defaults
+// from DefaultOptions.
+type Options struct {
+	// PollInterval is how often the outbox is checked for new events.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of events claimed per poll.
+	BatchSize int
+	// Lease is how long claimed events stay hidden from other dispatchers.
+	Lease time.Duration
+	// BaseBackoff is the delay after the first failed attempt. It doubles
+	// with every further attempt up to MaxBackoff. Events are never
+	// dropped, so MaxBackoff bounds how long a recovered sink waits.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Lease:        time.Minute,
+		BaseBackoff:  5 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.PollInterval <= 0 {
+		o.PollInterval = d.PollInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = d.BatchSize
+	}
+	if o.Lease <= 0 {
+		o.Lease = d.Lease
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = d.BaseBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = d.MaxBackoff
+	}
+	return o
+}
+
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+// Dispatcher drains the outbox into its sinks. Several dispatchers may
+// share an outbox.
+type Dispatcher struct {
+	repo  repositories.OutboxRepository
+	sinks []namedSink
+	opts  Options
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+func NewDispatcher(repo repositories.OutboxRepository, opts Options, log *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		opts: opts.withDefaults(),
+		log:  log.With(slog.String("component", "outbox_dispatcher")),
+		now:  time.Now,
+	}
+}
+
+// Register adds a sink. Sinks must be registered before Run is called.
+func (d *Dispatcher) Register(name string, sink Sink) {
+	d.sinks = append(d.sinks, namedSink{name: name, sink: sink})
+}
+
+// Run publishes pending events every poll interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.log.Info("Outbox dispatcher started", slog.Duration("poll_interval", d.opts.PollInterval), slog.Int("sinks", len(d.sinks)))
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches come back so a backlog drains
+		// without waiting for the next tick.
+		for {
+			n, err := d.DispatchPending(ctx)
+			if err != nil || n < d.opts.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.log.Info("Outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending claims one batch of events, publishes each to every sink
+// and returns how many were claimed.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	entries, err := d.repo.ClaimUnpublished(ctx, d.opts.BatchSize, d.opts.Lease)
+	if err != nil {
+		d.log.Error("Failed to claim outbox events", slog.Any("error", err))
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		d.publish(ctx, entry)
+	}
+
+	return len(entries), nil
+}
+
+func (d *Dispatcher) publish(ctx context.Context, entry *repositories.OutboxEntry) {
+	event := entry.Event
+	log := d.log.With(slog.Int64("seq", event.Seq), slog.String("event_id", event.ID), slog.String("event_type", string(event.Type)))
+
+	var errs []error
+	for _, s := range d.sinks {
+		if err := s.sink.Publish(ctx, *event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+
+	// Record the outcome even if ctx was cancelled while publishing.
+	ctx = context.WithoutCancel(ctx)
+
+	if err := errors.Join(errs...); err != nil {
+		attempt := entry.Attempts + 1
+		next := d.now().Add(backoff.Exponential(attempt, d.opts.BaseBackoff, d.opts.MaxBackoff))
+		log.Warn("Failed to publish event, will retry", slog.Int("attempt", attempt), slog.Time("next_attempt_at", next), slog.Any("error", err))
+		if err := d.repo.RecordFailure(ctx, event.Seq, err.Error(), next); err != nil {
+			log.Error("Failed to record outbox failure", slog.Any("error", err))
+		}
+		return
+	}
+
+	if err := d.repo.MarkPublished(ctx, event.Seq); err != nil {
+		log.Error("Failed to mark outbox event published", slog.Any("error", err))
+		return
+	}
+
+	log.Debug("Event published")
+}
@@ -0,0 +1,147 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/outbox"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox is an in-memory outbox that records what the dispatcher does
+// with each claimed event.
+type fakeOutbox struct {
+	pending   []*repositories.OutboxEntry
+	published []int64
+	failures  map[int64]time.Time
+}
+
+func (o *fakeOutbox) Append(ctx context.Context, event *models.Event) error {
+	o.pending = append(o.pending, &repositories.OutboxEntry{Event: event})
+	return nil
+}
+
+func (o *fakeOutbox) ClaimUnpublished(ctx context.Context, limit int, lease time.Duration) ([]*repositories.OutboxEntry, error) {
+	if limit > len(o.pending) {
+		limit = len(o.pending)
+	}
+	claimed := o.pending[:limit]
+	o.pending = o.pending[limit:]
+	return claimed, nil
+}
+
+func (o *fakeOutbox) MarkPublished(ctx context.Context, seq int64) error {
+	o.published = append(o.published, seq)
+	return nil
+}
+
+func (o *fakeOutbox) RecordFailure(ctx context.Context, seq int64, reason string, nextAttemptAt time.Time) error {
+	if o.failures == nil {
+		o.failures = make(map[int64]time.Time)
+	}
+	o.failures[seq] = nextAttemptAt
+	return nil
+}
+
+func (o *fakeOutbox) GetBySeq(ctx context.Context, seq int64) (*models.Event, error) {
+	return nil, repositories.ErrNotFound
+}
+
+func (o *fakeOutbox) ListByOrganizationID(ctx context.Context, orgID string, afterPosition int64, limit int) ([]*models.Event, error) {
+	return nil, nil
+}
+
+func (o *fakeOutbox) LatestPosition(ctx context.Context, orgID string) (int64, error) {
+	return 0, nil
+}
+
+func newEntry(seq int64, attempts int) *repositories.OutboxEntry {
+	return &repositories.OutboxEntry{
+		Event: &models.Event{
+			ID:    fmt.Sprintf("event-%d", seq),
+			Seq:   seq,
+			Type:  models.EventMemberAdded,
+			OrgID: "org-1",
+			Data:  json.RawMessage(`{"user_id":"u1"}`),
+		},
+		Attempts: attempts,
+	}
+}
+
+func TestDispatcher_PublishesToEverySink(t *testing.T) {
+	repo := &fakeOutbox{pending: []*repositories.OutboxEntry{newEntry(1, 0), newEntry(2, 0)}}
+	dispatcher := outbox.NewDispatcher(repo, outbox.Options{}, logger.NewTestLogger(t))
+
+	var first, second []int64
+	dispatcher.Register("first", outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		first = append(first, event.Seq)
+		return nil
+	}))
+	dispatcher.Register("second", outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		second = append(second, event.Seq)
+		return nil
+	}))
+
+	n, err := dispatcher.DispatchPending(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int64{1, 2}, first)
+	assert.Equal(t, []int64{1, 2}, second)
+	assert.Equal(t, []int64{1, 2}, repo.published)
+}
+
+func TestDispatcher_RetriesWhenASinkFails(t *testing.T) {
+	repo := &fakeOutbox{pending: []*repositories.OutboxEntry{newEntry(1, 2)}}
+	opts := outbox.Options{BaseBackoff: time.Second, MaxBackoff: time.Minute}
+	dispatcher := outbox.NewDispatcher(repo, opts, logger.NewTestLogger(t))
+
+	delivered := 0
+	dispatcher.Register("ok", outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		delivered++
+		return nil
+	}))
+	dispatcher.Register("broken", outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		return errors.New("unavailable")
+	}))
+
+	before := time.Now()
+	_, err := dispatcher.DispatchPending(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered, "healthy sinks still see the event")
+	assert.Empty(t, repo.published)
+	require.Contains(t, repo.failures, int64(1))
+	// Third attempt: base doubled twice.
+	assert.WithinDuration(t, before.Add(4*time.Second), repo.failures[1], time.Second)
+}
+
+func TestBus(t *testing.T) {
+	bus := outbox.NewBus()
+	ctx := context.Background()
+
+	var received []string
+	unsubscribe := bus.Subscribe(outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		received = append(received, event.ID)
+		return nil
+	}))
+	bus.Subscribe(outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		return errors.New("subscriber failed")
+	}))
+
+	err := bus.Publish(ctx, models.Event{ID: "a"})
+	assert.Error(t, err, "a failing subscriber should fail the publish so it is retried")
+
+	unsubscribe()
+	_ = bus.Publish(ctx, models.Event{ID: "b"})
+
+	assert.Equal(t, []string{"a"}, received)
+}
@@ -0,0 +1,76 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+// LogSink logs every event, which is mostly useful in development.
+type LogSink struct {
+	log *slog.Logger
+}
+
+func NewLogSink(log *slog.Logger) *LogSink {
+	return &LogSink{log: log.With(slog.String("component", "event_log"))}
+}
+
+func (s *LogSink) Publish(ctx context.Context, event models.Event) error {
+	s.log.InfoContext(ctx, "Event published",
+		slog.Int64("seq", event.Seq),
+		slog.String("event_id", event.ID),
+		slog.String("event_type", string(event.Type)),
+		slog.String("org_id", event.OrgID),
+	)
+	return nil
+}
+
+// Bus fans events out to in-process subscribers.
+type Bus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]Sink
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]Sink)}
+}
+
+// Subscribe registers sink for every event published from now on and
+// returns a function that removes it again.
+func (b *Bus) Subscribe(sink Sink) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sink
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish calls every subscriber and returns their joined errors, so a
+// failing subscriber gets the event again on retry.
+func (b *Bus) Publish(ctx context.Context, event models.Event) error {
+	b.mu.RLock()
+	subscribers := make([]Sink, 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := s.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
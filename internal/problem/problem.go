@@ -0,0 +1,180 @@
+// Package problem renders errors as RFC 7807 problem details so that every
+// error leaving the server, whether from a handler, a middleware or a
+// recovered panic, has the same shape.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const ContentType = "application/problem+json"
+
+// Stable, machine-readable error codes. Clients should branch on these
+// rather than on the human-readable detail.
+const (
+	CodeInvalidInput          = "invalid_input"
+	CodeInvalidRequestBody    = "invalid_request_body"
+	CodeUnauthenticated       = "unauthenticated"
+	CodeInvalidToken          = "invalid_token"
+	CodeForbidden             = "forbidden"
+	CodeInsufficientRole      = "insufficient_role"
+	CodeNotOrganizationMember = "not_organization_member"
+	CodeUserNotFound          = "user_not_found"
+	CodeOrganizationNotFound  = "organization_not_found"
+	CodeUserConflict          = "user_conflict"
+	CodeOrganizationConflict  = "organization_conflict"
+	CodeDuplicateInput        = "duplicate_input"
+	CodeMembershipExists      = "membership_exists"
+	CodeMembershipNotFound    = "membership_not_found"
+	CodeWebhookNotFound       = "webhook_not_found"
+	CodeDeliveryNotFound      = "webhook_delivery_not_found"
+	CodeJobNotFound           = "job_not_found"
+	CodeJobNotRetryable       = "job_not_retryable"
+	CodeAttachmentNotFound    = "attachment_not_found"
+	CodeInvalidDownloadLink   = "invalid_download_link"
+	CodeLocationNotFound      = "location_not_found"
+	CodeLocationConflict      = "location_conflict"
+	CodeTermsNotFound         = "terms_not_found"
+	CodeTermsNotAccepted      = "terms_not_accepted"
+	CodeTermsOutdated         = "terms_outdated"
+	CodeCustomerNotFound      = "customer_not_found"
+	CodeCustomerExists        = "customer_exists"
+	CodeCatalogNotFound       = "catalog_not_found"
+	CodeRateLimited           = "rate_limited"
+	CodeVersionMismatch       = "version_mismatch"
+	CodePreconditionRequired  = "precondition_required"
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"
+	CodeRequestInProgress     = "request_in_progress"
+	CodeRequestTooLarge       = "request_too_large"
+	CodeUnsupportedMediaType  = "unsupported_media_type"
+	CodeNotFound              = "not_found"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeInternal              = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details document extended with a stable
+// error code, field-level validation errors and the request ID.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	Errors    []services.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// New creates a Problem for the given status, code and detail.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// mapping describes how a sentinel error is presented to clients.
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// mappings is checked in order, so more specific errors must come first.
+var mappings = []mapping{
+	{services.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound},
+	{services.ErrOrganizationNotFound, http.StatusNotFound, CodeOrganizationNotFound},
+	{services.ErrOrganizationUserNotFound, http.StatusNotFound, CodeMembershipNotFound},
+	{services.ErrWebhookNotFound, http.StatusNotFound, CodeWebhookNotFound},
+	{services.ErrWebhookDeliveryNotFound, http.StatusNotFound, CodeDeliveryNotFound},
+	{services.ErrJobNotFound, http.StatusNotFound, CodeJobNotFound},
+	{services.ErrJobNotRetryable, http.StatusConflict, CodeJobNotRetryable},
+	{services.ErrAttachmentNotFound, http.StatusNotFound, CodeAttachmentNotFound},
+	{services.ErrAttachmentTooLarge, http.StatusRequestEntityTooLarge, CodeRequestTooLarge},
+	{services.ErrUnsupportedAttachmentType, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+	{services.ErrInvalidDownloadLink, http.StatusForbidden, CodeInvalidDownloadLink},
+	{services.ErrLocationNotFound, http.StatusNotFound, CodeLocationNotFound},
+	{services.ErrLocationNameTaken, http.StatusConflict, CodeLocationConflict},
+	{services.ErrTermsNotFound, http.StatusNotFound, CodeTermsNotFound},
+	{services.ErrTermsAcceptanceNotFound, http.StatusNotFound, CodeTermsNotAccepted},
+	{services.ErrTermsOutdated, http.StatusConflict, CodeTermsOutdated},
+	{services.ErrCustomerNotFound, http.StatusNotFound, CodeCustomerNotFound},
+	{services.ErrCustomerExists, http.StatusConflict, CodeCustomerExists},
+	{services.ErrCatalogNotFound, http.StatusNotFound, CodeCatalogNotFound},
+	{services.ErrVersionMismatch, http.StatusPreconditionFailed, CodeVersionMismatch},
+	{services.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused},
+	{services.ErrIdempotentRequestInProgress, http.StatusConflict, CodeRequestInProgress},
+	{services.ErrOrganizationWithDuplicateDetailsExists, http.StatusConflict, CodeOrganizationConflict},
+	{services.ErrUserWithDuplicateDetailsExists, http.StatusConflict, CodeUserConflict},
+	{services.ErrUserAlreadyHasARoleInOrganization, http.StatusConflict, CodeMembershipExists},
+	{services.ErrDuplicateInput, http.StatusConflict, CodeDuplicateInput},
+	{services.ErrUserNotPartOfOrganization, http.StatusForbidden, CodeNotOrganizationMember},
+	{services.ErrUnauthorized, http.StatusForbidden, CodeInsufficientRole},
+	{services.ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{services.ErrInvalidInput, http.StatusBadRequest, CodeInvalidInput},
+	{auth.ErrUnauthorized, http.StatusUnauthorized, CodeUnauthenticated},
+}
+
+// FromError converts err into a Problem. Known sentinel errors keep their
+// message as the detail; anything else becomes a generic 500 so internal
+// details never leak to clients. A Problem in err's chain is copied, so
+// the result can be changed without affecting err.
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		cp := *p
+		return &cp
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			p := New(m.status, m.code, err.Error())
+			var validationErr *services.ValidationError
+			if errors.As(err, &validationErr) {
+				p.Errors = validationErr.Fields
+			}
+			return p
+		}
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// Write sends p as an application/problem+json response. The request's
+// instance and request ID are filled in on a copy, so p itself can be
+// shared between requests.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	cp := *p
+	if cp.Instance == "" {
+		cp.Instance = r.URL.Path
+	}
+	if cp.RequestID == "" {
+		cp.RequestID = middleware.GetReqID(r.Context())
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(cp.Status)
+
+	if err := json.NewEncoder(w).Encode(&cp); err != nil {
+		log.Printf("failed to encode problem response: %v", err)
+	}
+}
+
+// WriteError converts err with FromError and writes it.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, FromError(err))
+}
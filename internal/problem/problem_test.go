@@ -0,0 +1,136 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/auth"
+	"github.com/espennoreng/go-http-rental-server/internal/problem"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"invalid input", services.ErrInvalidInput, http.StatusBadRequest, problem.CodeInvalidInput},
+		{"user not found", services.ErrUserNotFound, http.StatusNotFound, problem.CodeUserNotFound},
+		{"organization not found", services.ErrOrganizationNotFound, http.StatusNotFound, problem.CodeOrganizationNotFound},
+		{"duplicate organization", services.ErrOrganizationWithDuplicateDetailsExists, http.StatusConflict, problem.CodeOrganizationConflict},
+		{"duplicate membership", services.ErrUserAlreadyHasARoleInOrganization, http.StatusConflict, problem.CodeMembershipExists},
+		{"not a member", services.ErrUserNotPartOfOrganization, http.StatusForbidden, problem.CodeNotOrganizationMember},
+		{"insufficient role", services.ErrUnauthorized, http.StatusForbidden, problem.CodeInsufficientRole},
+		{"forbidden", services.ErrForbidden, http.StatusForbidden, problem.CodeForbidden},
+		{"version mismatch", services.ErrVersionMismatch, http.StatusPreconditionFailed, problem.CodeVersionMismatch},
+		{"idempotency key reused", services.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused},
+		{"webhook not found", services.ErrWebhookNotFound, http.StatusNotFound, problem.CodeWebhookNotFound},
+		{"webhook delivery not found", services.ErrWebhookDeliveryNotFound, http.StatusNotFound, problem.CodeDeliveryNotFound},
+		{"job not found", services.ErrJobNotFound, http.StatusNotFound, problem.CodeJobNotFound},
+		{"job not retryable", services.ErrJobNotRetryable, http.StatusConflict, problem.CodeJobNotRetryable},
+		{"attachment not found", services.ErrAttachmentNotFound, http.StatusNotFound, problem.CodeAttachmentNotFound},
+		{"attachment too large", services.ErrAttachmentTooLarge, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge},
+		{"unsupported attachment type", services.ErrUnsupportedAttachmentType, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType},
+		{"invalid download link", services.ErrInvalidDownloadLink, http.StatusForbidden, problem.CodeInvalidDownloadLink},
+		{"location not found", services.ErrLocationNotFound, http.StatusNotFound, problem.CodeLocationNotFound},
+		{"location name taken", services.ErrLocationNameTaken, http.StatusConflict, problem.CodeLocationConflict},
+		{"terms not found", services.ErrTermsNotFound, http.StatusNotFound, problem.CodeTermsNotFound},
+		{"terms not accepted", services.ErrTermsAcceptanceNotFound, http.StatusNotFound, problem.CodeTermsNotAccepted},
+		{"terms outdated", services.ErrTermsOutdated, http.StatusConflict, problem.CodeTermsOutdated},
+		{"customer not found", services.ErrCustomerNotFound, http.StatusNotFound, problem.CodeCustomerNotFound},
+		{"customer exists", services.ErrCustomerExists, http.StatusConflict, problem.CodeCustomerExists},
+		{"catalog not found", services.ErrCatalogNotFound, http.StatusNotFound, problem.CodeCatalogNotFound},
+		{"request in progress", services.ErrIdempotentRequestInProgress, http.StatusConflict, problem.CodeRequestInProgress},
+		{"missing identity", auth.ErrUnauthorized, http.StatusUnauthorized, problem.CodeUnauthenticated},
+		{"wrapped sentinel", fmt.Errorf("lookup: %w", services.ErrUserNotFound), http.StatusNotFound, problem.CodeUserNotFound},
+		{"internal", services.ErrInternalServer, http.StatusInternalServerError, problem.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := problem.FromError(tt.err)
+
+			assert.Equal(t, tt.expectedStatus, p.Status)
+			assert.Equal(t, tt.expectedCode, p.Code)
+			assert.Equal(t, http.StatusText(tt.expectedStatus), p.Title)
+		})
+	}
+}
+
+func TestFromError_CopiesProblems(t *testing.T) {
+	shared := problem.New(http.StatusForbidden, problem.CodeForbidden, "forbidden")
+
+	p := problem.FromError(fmt.Errorf("check: %w", shared))
+	p.Detail = "You cannot manage this organization"
+
+	assert.Equal(t, problem.CodeForbidden, p.Code)
+	assert.Equal(t, "forbidden", shared.Detail)
+}
+
+func TestFromError_HidesUnknownErrors(t *testing.T) {
+	p := problem.FromError(errors.New("pq: connection refused on 10.0.0.3"))
+
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Equal(t, problem.CodeInternal, p.Code)
+	assert.Equal(t, "internal server error", p.Detail)
+}
+
+func TestFromError_IncludesFieldErrors(t *testing.T) {
+	var errs services.ValidationError
+	errs.Add("username", "is required")
+	errs.Add("email", "is required")
+
+	p := problem.FromError(errs.Err())
+
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, problem.CodeInvalidInput, p.Code)
+	assert.Equal(t, []services.FieldError{
+		{Field: "username", Message: "is required"},
+		{Field: "email", Message: "is required"},
+	}, p.Errors)
+}
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/organizations/org-1", nil)
+	res := httptest.NewRecorder()
+
+	problem.Write(res, req, problem.New(http.StatusNotFound, problem.CodeOrganizationNotFound, "organization not found"))
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, problem.ContentType, res.Header().Get("Content-Type"))
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	assert.Equal(t, "about:blank", body["type"])
+	assert.Equal(t, "Not Found", body["title"])
+	assert.Equal(t, float64(http.StatusNotFound), body["status"])
+	assert.Equal(t, "organization not found", body["detail"])
+	assert.Equal(t, "/organizations/org-1", body["instance"])
+	assert.Equal(t, problem.CodeOrganizationNotFound, body["code"])
+	assert.NotContains(t, body, "errors")
+}
+
+func TestWrite_DoesNotModifyProblem(t *testing.T) {
+	p := problem.New(http.StatusPreconditionRequired, problem.CodePreconditionRequired, "If-Match header is required")
+
+	for _, path := range []string{"/organizations/org-1", "/organizations/org-2"} {
+		req := httptest.NewRequest(http.MethodPatch, path, nil)
+		res := httptest.NewRecorder()
+
+		problem.Write(res, req, p)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, path, body["instance"])
+	}
+	assert.Empty(t, p.Instance)
+	assert.Empty(t, p.RequestID)
+}
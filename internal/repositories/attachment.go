@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+type CreateAttachmentParams struct {
+	OrgID        string
+	Filename     string
+	ContentType  string
+	Size         int64
+	Key          string
+	ThumbnailKey string
+	CreatedBy    string
+}
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, params *CreateAttachmentParams) (*models.Attachment, error)
+	GetByID(ctx context.Context, orgID, id string) (*models.Attachment, error)
+	// GetByIDAnyOrganization looks an attachment up without an
+	// organization, for serving signed download links.
+	GetByIDAnyOrganization(ctx context.Context, id string) (*models.Attachment, error)
+	Delete(ctx context.Context, orgID, id string) error
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+type CreateCustomerParams struct {
+	OrgID   string
+	UserID  string
+	Phone   string
+	Address models.Address
+}
+
+// UpdateCustomerProfileParams replaces a customer's contact details.
+// Version is the version the caller expects to replace, or AnyVersion.
+type UpdateCustomerProfileParams struct {
+	OrgID   string
+	ID      string
+	Phone   string
+	Address models.Address
+	Version int
+}
+
+type UpdateCustomerVerificationParams struct {
+	OrgID   string
+	ID      string
+	Status  models.VerificationStatus
+	Version int
+}
+
+type CustomerRepository interface {
+	// Create returns ErrConflict if the user is already a customer of the
+	// organization, and ErrNotFound if the organization does not exist.
+	Create(ctx context.Context, params *CreateCustomerParams) (*models.Customer, error)
+	GetByID(ctx context.Context, orgID, id string) (*models.Customer, error)
+	GetByUserID(ctx context.Context, orgID, userID string) (*models.Customer, error)
+	ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Customer, error)
+	UpdateProfile(ctx context.Context, params *UpdateCustomerProfileParams) (*models.Customer, error)
+	UpdateVerification(ctx context.Context, params *UpdateCustomerVerificationParams) (*models.Customer, error)
+}
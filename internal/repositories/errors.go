@@ -3,6 +3,11 @@ package repositories
 import "errors"
 
 var (
-	ErrNotFound = errors.New("not found")
-	ErrConflict = errors.New("conflict")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrVersionMismatch = errors.New("version mismatch")
 )
+
+// AnyVersion disables the optimistic concurrency check on updates and
+// deletes that take an expected version.
+const AnyVersion = 0
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+// IdempotencyKeyID identifies a stored request: the same key may be reused
+// by different users or on different routes.
+type IdempotencyKeyID struct {
+	UserID string
+	Key    string
+	Method string
+	Path   string
+}
+
+type ReserveIdempotencyKeyParams struct {
+	IdempotencyKeyID
+	RequestHash string
+	ExpiresAt   time.Time
+	// LockedUntil is when a request that has not completed gives up the
+	// key to a retry.
+	LockedUntil time.Time
+}
+
+type CompleteIdempotencyKeyParams struct {
+	IdempotencyKeyID
+	StatusCode      int
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+}
+
+type IdempotencyKeyRepository interface {
+	// Reserve claims the key for a new request. If the key is already held
+	// by a completed request that has not expired, or by an incomplete one
+	// whose lock has not run out, the existing record is returned with
+	// reserved set to false.
+	Reserve(ctx context.Context, params *ReserveIdempotencyKeyParams) (key *models.IdempotencyKey, reserved bool, err error)
+	Complete(ctx context.Context, params *CompleteIdempotencyKeyParams) error
+	Release(ctx context.Context, id IdempotencyKeyID) error
+	// DeleteExpired removes keys that expired before the given time and
+	// returns how many were removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
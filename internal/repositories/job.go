@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+type EnqueueJobParams struct {
+	Kind        string
+	Payload     []byte
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey, if set, makes Enqueue a no-op for a key already used.
+	UniqueKey string
+}
+
+type ListJobsParams struct {
+	// Status and Kind filter the jobs when set.
+	Status models.JobStatus
+	Kind   string
+	Limit  int
+}
+
+type FailJobParams struct {
+	ID      string
+	Attempt int
+	Error   string
+	// RetryAt schedules another attempt; nil moves the job to the dead state.
+	RetryAt *time.Time
+}
+
+type JobRepository interface {
+	// Enqueue stores a pending job. It returns ErrConflict if params has a
+	// unique key that an earlier job already used.
+	Enqueue(ctx context.Context, params *EnqueueJobParams) (*models.Job, error)
+
+	// Claim leases up to limit due jobs of the given kinds, oldest first,
+	// and counts the attempt. Jobs whose lease expired while running are
+	// claimed again.
+	Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*models.Job, error)
+	// Complete and Fail record the outcome of an attempt. They return
+	// ErrConflict if the job was claimed again since that attempt started.
+	Complete(ctx context.Context, id string, attempt int) error
+	Fail(ctx context.Context, params *FailJobParams) error
+
+	GetByID(ctx context.Context, id string) (*models.Job, error)
+	// List returns jobs newest first.
+	List(ctx context.Context, params *ListJobsParams) ([]*models.Job, error)
+	// Retry moves a dead job back to pending with a fresh set of attempts.
+	// It returns ErrNotFound for unknown jobs and ErrConflict for jobs that
+	// are not dead.
+	Retry(ctx context.Context, id string) (*models.Job, error)
+	// DeleteFinished removes succeeded jobs that finished before the given
+	// time and returns how many were removed.
+	DeleteFinished(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+type CreateLocationParams struct {
+	OrgID        string
+	Name         string
+	Address      models.Address
+	TimeZone     string
+	OpeningHours []models.OpeningHours
+}
+
+// UpdateLocationParams replaces a location's details. Version is the
+// version the caller expects to replace, or AnyVersion.
+type UpdateLocationParams struct {
+	OrgID        string
+	ID           string
+	Name         string
+	Address      models.Address
+	TimeZone     string
+	OpeningHours []models.OpeningHours
+	Version      int
+}
+
+type LocationRepository interface {
+	// Create returns ErrConflict if the organization already has a
+	// location with the same name.
+	Create(ctx context.Context, params *CreateLocationParams) (*models.Location, error)
+	GetByID(ctx context.Context, orgID, id string) (*models.Location, error)
+	ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Location, error)
+	Update(ctx context.Context, params *UpdateLocationParams) (*models.Location, error)
+	// Delete removes a location; pass AnyVersion to skip the version check.
+	Delete(ctx context.Context, orgID, id string, version int) error
+}
@@ -4,14 +4,30 @@ import (
 	"context"
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/money"
 )
 
 type CreateOrganizationParams struct {
-	Name      string `json:"name"`
-	CreatedBy string `json:"created_by"`
+	Name      string         `json:"name"`
+	Currency  money.Currency `json:"currency"`
+	Slug      string         `json:"slug"`
+	CreatedBy string         `json:"created_by"`
+}
+
+type UpdateOrganizationParams struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Currency is left unchanged if empty.
+	Currency money.Currency `json:"currency"`
+	// Slug and PublicCatalog are left unchanged if empty or nil.
+	Slug          string `json:"slug"`
+	PublicCatalog *bool  `json:"public_catalog"`
+	Version       int    `json:"version"`
 }
 
 type OrganizationRepository interface {
 	Create(ctx context.Context, params *CreateOrganizationParams) (*models.Organization, error)
 	GetByID(ctx context.Context, id string) (*models.Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Organization, error)
+	Update(ctx context.Context, params *UpdateOrganizationParams) (*models.Organization, error)
 }
@@ -25,7 +25,15 @@ type OrganizationUserRepository interface {
 	Create(ctx context.Context, input *CreateOrganizationUserParams) (*models.OrganizationUser, error)
 	GetByID(ctx context.Context, orgID string, userID string) (*models.OrganizationUser, error)
 	GetUsersByOrganizationID(ctx context.Context, orgID string) ([]*models.UserWithRole, error)
-	Delete(ctx context.Context, orgID string, userID string) error
-	UpdateRole(ctx context.Context, orgID string, userID string, newRole models.Role) error
+	StreamUsersByOrganizationID(ctx context.Context, orgID string, fn func(user *models.UserWithRole) error) error
+	Delete(ctx context.Context, orgID string, userID string, version int) error
+	UpdateRole(ctx context.Context, orgID string, userID string, newRole models.Role, version int) (*models.OrganizationUser, error)
 	AreUsersInSameOrg(ctx context.Context, params *AreUsersInSameOrgParams) (bool, error)
+	// WithinTransaction runs fn against a repository whose writes are
+	// committed together, or not at all if fn returns an error. Nested
+	// calls roll back only their own writes.
+	WithinTransaction(ctx context.Context, fn func(repo OrganizationUserRepository) error) error
+	// Outbox returns the outbox sharing this repository's transaction, so
+	// events are stored atomically with the change they describe.
+	Outbox() OutboxRepository
 }
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+// OutboxEntry is an unpublished event leased to a dispatcher.
+type OutboxEntry struct {
+	Event    *models.Event
+	Attempts int
+}
+
+type OutboxRepository interface {
+	// Append stores an event. Called on a repository bound to a
+	// transaction, the event is only kept if the transaction commits.
+	Append(ctx context.Context, event *models.Event) error
+
+	// ClaimUnpublished leases up to limit due events, oldest first, so
+	// that concurrent dispatchers never publish the same event at once.
+	// Claimed events without a position are given one; positions are
+	// assigned in commit order, so no later claim can assign a lower one.
+	ClaimUnpublished(ctx context.Context, limit int, lease time.Duration) ([]*OutboxEntry, error)
+	MarkPublished(ctx context.Context, seq int64) error
+	// RecordFailure keeps the event unpublished until nextAttemptAt.
+	RecordFailure(ctx context.Context, seq int64, reason string, nextAttemptAt time.Time) error
+
+	GetBySeq(ctx context.Context, seq int64) (*models.Event, error)
+	// ListByOrganizationID returns up to limit events of an organization
+	// after the position afterPosition, in position order, whether
+	// published or not. Events that have not been claimed yet have no
+	// position and are not listed.
+	ListByOrganizationID(ctx context.Context, orgID string, afterPosition int64, limit int) ([]*models.Event, error)
+	// LatestPosition returns the highest position of the organization's
+	// events, or zero if none has one.
+	LatestPosition(ctx context.Context, orgID string) (int64, error)
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AttachmentRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewAttachmentRepository(db *pgxpool.Pool, log *slog.Logger) *AttachmentRepository {
+	return &AttachmentRepository{
+		db:  db,
+		log: log.With("component", "attachment_repository"),
+	}
+}
+
+var _ repositories.AttachmentRepository = (*AttachmentRepository)(nil)
+
+const attachmentColumns = `id, organization_id, filename, content_type, size, storage_key, COALESCE(thumbnail_key, ''), created_by, created_at`
+
+func (r *AttachmentRepository) Create(ctx context.Context, params *repositories.CreateAttachmentParams) (*models.Attachment, error) {
+	query := `
+		INSERT INTO attachments (organization_id, filename, content_type, size, storage_key, thumbnail_key, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING ` + attachmentColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("key", params.Key))
+
+	attachment, err := scanAttachment(r.db.QueryRow(ctx, query, params.OrgID, params.Filename, params.ContentType, params.Size, params.Key, params.ThumbnailKey, params.CreatedBy))
+	if err != nil {
+		r.log.Error("Failed to create attachment", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Attachment created successfully", slog.String("attachment_id", attachment.ID), slog.String("org_id", attachment.OrgID))
+
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, orgID, id string) (*models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE organization_id = $1 AND id = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("attachment_id", id))
+
+	return r.get(ctx, query, id, orgID, id)
+}
+
+func (r *AttachmentRepository) GetByIDAnyOrganization(ctx context.Context, id string) (*models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE id = $1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("attachment_id", id))
+
+	return r.get(ctx, query, id, id)
+}
+
+func (r *AttachmentRepository) get(ctx context.Context, query, id string, args ...any) (*models.Attachment, error) {
+	attachment, err := scanAttachment(r.db.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Attachment not found", slog.String("attachment_id", id))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve attachment", slog.Any("error", err))
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, orgID, id string) error {
+	query := `
+		DELETE FROM attachments
+		WHERE organization_id = $1 AND id = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("attachment_id", id))
+
+	tag, err := r.db.Exec(ctx, query, orgID, id)
+	if err != nil {
+		r.log.Error("Failed to delete attachment", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.log.Warn("Attachment to delete not found", slog.String("attachment_id", id))
+		return repositories.ErrNotFound
+	}
+
+	r.log.Info("Attachment deleted successfully", slog.String("attachment_id", id))
+
+	return nil
+}
+
+func scanAttachment(row pgx.Row) (*models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.OrgID, &a.Filename, &a.ContentType, &a.Size, &a.Key, &a.ThumbnailKey, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresAttachmentRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *repositories.CreateAttachmentParams {
+		th.ResetDB(t)
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: user.ID,
+		})
+		require.NoError(t, err)
+		return &repositories.CreateAttachmentParams{
+			OrgID:       org.ID,
+			Filename:    "contract.pdf",
+			ContentType: "application/pdf",
+			Size:        1234,
+			Key:         org.ID + "/" + uuid.New().String(),
+			CreatedBy:   user.ID,
+		}
+	}
+
+	t.Run("Create and get", func(t *testing.T) {
+		params := setup(t)
+
+		created, err := th.attachmentRepo.Create(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, params.Key, created.Key)
+		require.Empty(t, created.ThumbnailKey)
+
+		got, err := th.attachmentRepo.GetByID(ctx, params.OrgID, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, created, got)
+
+		got, err = th.attachmentRepo.GetByIDAnyOrganization(ctx, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, created, got)
+	})
+
+	t.Run("Get from another organization", func(t *testing.T) {
+		params := setup(t)
+		created, err := th.attachmentRepo.Create(ctx, params)
+		require.NoError(t, err)
+
+		_, err = th.attachmentRepo.GetByID(ctx, uuid.New().String(), created.ID)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		params := setup(t)
+		params.ThumbnailKey = params.Key + "-thumb"
+		created, err := th.attachmentRepo.Create(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, params.ThumbnailKey, created.ThumbnailKey)
+
+		require.NoError(t, th.attachmentRepo.Delete(ctx, params.OrgID, created.ID))
+		require.ErrorIs(t, th.attachmentRepo.Delete(ctx, params.OrgID, created.ID), repositories.ErrNotFound)
+
+		_, err = th.attachmentRepo.GetByIDAnyOrganization(ctx, created.ID)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+}
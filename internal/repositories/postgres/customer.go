@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CustomerRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewCustomerRepository(db *pgxpool.Pool, log *slog.Logger) *CustomerRepository {
+	return &CustomerRepository{
+		db:  db,
+		log: log.With("component", "customer_repository"),
+	}
+}
+
+var _ repositories.CustomerRepository = (*CustomerRepository)(nil)
+
+// customerColumns selects a customer as c joined with its user as u.
+const customerColumns = `c.id, c.organization_id, c.user_id, u.username, u.email, c.phone, c.street, c.postal_code, c.city, c.country,
+	c.verification_status, c.created_at, c.updated_at, c.version`
+
+func (r *CustomerRepository) Create(ctx context.Context, params *repositories.CreateCustomerParams) (*models.Customer, error) {
+	query := `
+		WITH c AS (
+			INSERT INTO customers (organization_id, user_id, phone, street, postal_code, city, country)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING *
+		)
+		SELECT ` + customerColumns + `
+		FROM c
+		JOIN users u ON u.id = c.user_id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("user_id", params.UserID))
+
+	customer, err := scanCustomer(r.db.QueryRow(ctx, query,
+		params.OrgID, params.UserID, params.Phone,
+		params.Address.Street, params.Address.PostalCode, params.Address.City, params.Address.Country,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
+			r.log.Warn("User is already a customer of the organization", slog.String("org_id", params.OrgID), slog.String("user_id", params.UserID))
+			return nil, repositories.ErrConflict
+		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // Foreign key violation
+			r.log.Warn("User or organization for customer does not exist", slog.Any("error", err))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to create customer", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Customer created successfully", slog.String("customer_id", customer.ID), slog.String("org_id", customer.OrgID))
+
+	return customer, nil
+}
+
+func (r *CustomerRepository) GetByID(ctx context.Context, orgID, id string) (*models.Customer, error) {
+	return r.get(ctx, "c.id", orgID, id)
+}
+
+func (r *CustomerRepository) GetByUserID(ctx context.Context, orgID, userID string) (*models.Customer, error) {
+	return r.get(ctx, "c.user_id", orgID, userID)
+}
+
+// get returns the organization's customer whose column equals value.
+func (r *CustomerRepository) get(ctx context.Context, column, orgID, value string) (*models.Customer, error) {
+	query := `
+		SELECT ` + customerColumns + `
+		FROM customers c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.organization_id = $1 AND ` + column + ` = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String(column, value))
+
+	customer, err := scanCustomer(r.db.QueryRow(ctx, query, orgID, value))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Customer not found", slog.String("org_id", orgID), slog.String(column, value))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve customer", slog.Any("error", err))
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+func (r *CustomerRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Customer, error) {
+	query := `
+		SELECT ` + customerColumns + `
+		FROM customers c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.organization_id = $1
+		ORDER BY u.username, c.id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		r.log.Error("Failed to list customers", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []*models.Customer{}
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			r.log.Error("Failed to scan customer", slog.Any("error", err))
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate customers", slog.Any("error", err))
+		return nil, err
+	}
+
+	return customers, nil
+}
+
+// UpdateProfile replaces a customer's contact details. The version check
+// is part of the UPDATE statement; pass repositories.AnyVersion to skip it.
+func (r *CustomerRepository) UpdateProfile(ctx context.Context, params *repositories.UpdateCustomerProfileParams) (*models.Customer, error) {
+	query := `
+		WITH c AS (
+			UPDATE customers
+			SET phone = $3, street = $4, postal_code = $5, city = $6, country = $7, updated_at = NOW(), version = version + 1
+			WHERE organization_id = $1 AND id = $2 AND ($8 = 0 OR version = $8)
+			RETURNING *
+		)
+		SELECT ` + customerColumns + `
+		FROM c
+		JOIN users u ON u.id = c.user_id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("customer_id", params.ID), slog.Int("version", params.Version))
+
+	customer, err := scanCustomer(r.db.QueryRow(ctx, query,
+		params.OrgID, params.ID, params.Phone,
+		params.Address.Street, params.Address.PostalCode, params.Address.City, params.Address.Country,
+		params.Version,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missingOrStale(ctx, params.OrgID, params.ID)
+		}
+		r.log.Error("Failed to update customer profile", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Customer profile updated successfully", slog.String("customer_id", customer.ID), slog.Int("version", customer.Version))
+
+	return customer, nil
+}
+
+// UpdateVerification sets a customer's verification status. The version
+// check is part of the UPDATE statement; pass repositories.AnyVersion to
+// skip it.
+func (r *CustomerRepository) UpdateVerification(ctx context.Context, params *repositories.UpdateCustomerVerificationParams) (*models.Customer, error) {
+	query := `
+		WITH c AS (
+			UPDATE customers
+			SET verification_status = $3, updated_at = NOW(), version = version + 1
+			WHERE organization_id = $1 AND id = $2 AND ($4 = 0 OR version = $4)
+			RETURNING *
+		)
+		SELECT ` + customerColumns + `
+		FROM c
+		JOIN users u ON u.id = c.user_id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("customer_id", params.ID), slog.String("status", string(params.Status)))
+
+	customer, err := scanCustomer(r.db.QueryRow(ctx, query, params.OrgID, params.ID, params.Status, params.Version))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missingOrStale(ctx, params.OrgID, params.ID)
+		}
+		r.log.Error("Failed to update customer verification", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Customer verification updated successfully", slog.String("customer_id", customer.ID), slog.String("status", string(customer.VerificationStatus)))
+
+	return customer, nil
+}
+
+// missingOrStale explains why a version-guarded statement matched no rows:
+// either the customer does not exist or its version has moved on.
+func (r *CustomerRepository) missingOrStale(ctx context.Context, orgID, id string) error {
+	var exists bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE organization_id = $1 AND id = $2)", orgID, id).Scan(&exists)
+	if err != nil {
+		r.log.Error("Failed to check if customer exists", slog.Any("error", err))
+		return err
+	}
+	if exists {
+		r.log.Warn("Customer version mismatch", slog.String("customer_id", id))
+		return repositories.ErrVersionMismatch
+	}
+	r.log.Warn("Customer not found", slog.String("customer_id", id))
+	return repositories.ErrNotFound
+}
+
+func scanCustomer(row pgx.Row) (*models.Customer, error) {
+	var c models.Customer
+	err := row.Scan(&c.ID, &c.OrgID, &c.UserID, &c.Username, &c.Email, &c.Phone,
+		&c.Address.Street, &c.Address.PostalCode, &c.Address.City, &c.Address.Country,
+		&c.VerificationStatus, &c.CreatedAt, &c.UpdatedAt, &c.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,96 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresCustomerRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (orgID, userID string) {
+		th.ResetDB(t)
+		owner, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: owner.ID,
+		})
+		require.NoError(t, err)
+		renter, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "Jane Doe",
+			Email:    "jane@example.com",
+		})
+		require.NoError(t, err)
+		return org.ID, renter.ID
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		orgID, userID := setup(t)
+
+		customer, err := th.customerRepo.Create(ctx, &repositories.CreateCustomerParams{
+			OrgID:   orgID,
+			UserID:  userID,
+			Phone:   "+4791234567",
+			Address: models.Address{Street: "Storgata 1", PostalCode: "0155", City: "Oslo", Country: "NO"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Jane Doe", customer.Username)
+		require.Equal(t, "jane@example.com", customer.Email)
+		require.Equal(t, models.VerificationUnverified, customer.VerificationStatus)
+		require.Equal(t, 1, customer.Version)
+
+		got, err := th.customerRepo.GetByUserID(ctx, orgID, userID)
+		require.NoError(t, err)
+		require.Equal(t, customer, got)
+
+		_, err = th.customerRepo.Create(ctx, &repositories.CreateCustomerParams{OrgID: orgID, UserID: userID})
+		require.ErrorIs(t, err, repositories.ErrConflict)
+
+		_, err = th.customerRepo.Create(ctx, &repositories.CreateCustomerParams{OrgID: uuid.New().String(), UserID: userID})
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		orgID, userID := setup(t)
+
+		customer, err := th.customerRepo.Create(ctx, &repositories.CreateCustomerParams{OrgID: orgID, UserID: userID})
+		require.NoError(t, err)
+
+		customer, err = th.customerRepo.UpdateProfile(ctx, &repositories.UpdateCustomerProfileParams{
+			OrgID: orgID, ID: customer.ID, Phone: "+4798765432", Version: customer.Version,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "+4798765432", customer.Phone)
+		require.Equal(t, 2, customer.Version)
+
+		_, err = th.customerRepo.UpdateVerification(ctx, &repositories.UpdateCustomerVerificationParams{
+			OrgID: orgID, ID: customer.ID, Status: models.VerificationVerified, Version: 1,
+		})
+		require.ErrorIs(t, err, repositories.ErrVersionMismatch)
+
+		customer, err = th.customerRepo.UpdateVerification(ctx, &repositories.UpdateCustomerVerificationParams{
+			OrgID: orgID, ID: customer.ID, Status: models.VerificationVerified, Version: repositories.AnyVersion,
+		})
+		require.NoError(t, err)
+		require.Equal(t, models.VerificationVerified, customer.VerificationStatus)
+
+		_, err = th.customerRepo.UpdateProfile(ctx, &repositories.UpdateCustomerProfileParams{
+			OrgID: orgID, ID: uuid.New().String(), Version: repositories.AnyVersion,
+		})
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+
+		customers, err := th.customerRepo.ListByOrganizationID(ctx, orgID)
+		require.NoError(t, err)
+		require.Len(t, customers, 1)
+	})
+}
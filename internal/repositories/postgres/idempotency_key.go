@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyKeyRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewIdempotencyKeyRepository(db *pgxpool.Pool, log *slog.Logger) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{
+		db:  db,
+		log: log.With("component", "idempotency_key_repository"),
+	}
+}
+
+var _ repositories.IdempotencyKeyRepository = (*IdempotencyKeyRepository)(nil)
+
+const idempotencyKeyColumns = `user_id, idempotency_key, method, path, request_hash, COALESCE(status_code, 0), response_headers, response_body, created_at, expires_at`
+
+// Reserve inserts the key, or takes over a row whose window has expired or
+// whose request never completed within its lock. The ON CONFLICT clause
+// makes the claim atomic, so two concurrent requests with the same key
+// cannot both be reserved.
+func (r *IdempotencyKeyRepository) Reserve(ctx context.Context, params *repositories.ReserveIdempotencyKeyParams) (*models.IdempotencyKey, bool, error) {
+	log := r.log.With(slog.String("user_id", params.UserID), slog.String("method", params.Method), slog.String("path", params.Path))
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash, expires_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, idempotency_key, method, path) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at,
+			locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.locked_until <= NOW())
+		RETURNING ` + idempotencyKeyColumns
+
+	log.Debug("Executing database query", slog.String("query", query))
+
+	key, err := scanIdempotencyKey(r.db.QueryRow(ctx, query, params.UserID, params.Key, params.Method, params.Path, params.RequestHash, params.ExpiresAt, params.LockedUntil))
+	if err == nil {
+		log.Debug("Idempotency key reserved")
+		return key, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Error("Failed to reserve idempotency key", slog.Any("error", err))
+		return nil, false, err
+	}
+
+	// The key is held by a live request; return it so the caller can
+	// replay its response or report that it is still in progress.
+	selectQuery := `
+		SELECT ` + idempotencyKeyColumns + `
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND method = $3 AND path = $4
+	`
+	key, err = scanIdempotencyKey(r.db.QueryRow(ctx, selectQuery, params.UserID, params.Key, params.Method, params.Path))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Released between the two statements; the client may retry.
+			log.Warn("Idempotency key released while being reserved")
+			return nil, false, repositories.ErrConflict
+		}
+		log.Error("Failed to retrieve idempotency key", slog.Any("error", err))
+		return nil, false, err
+	}
+
+	return key, false, nil
+}
+
+// Complete stores the response for a reserved key.
+func (r *IdempotencyKeyRepository) Complete(ctx context.Context, params *repositories.CompleteIdempotencyKeyParams) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $1, response_headers = $2, response_body = $3
+		WHERE user_id = $4 AND idempotency_key = $5 AND method = $6 AND path = $7
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int("status_code", params.StatusCode))
+
+	tag, err := r.db.Exec(ctx, query, params.StatusCode, params.ResponseHeaders, params.ResponseBody, params.UserID, params.Key, params.Method, params.Path)
+	if err != nil {
+		r.log.Error("Failed to complete idempotency key", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.log.Warn("Idempotency key to complete not found", slog.String("user_id", params.UserID))
+		return repositories.ErrNotFound
+	}
+
+	return nil
+}
+
+// Release deletes a reserved key so the request can be retried.
+func (r *IdempotencyKeyRepository) Release(ctx context.Context, id repositories.IdempotencyKeyID) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND method = $3 AND path = $4
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query))
+
+	if _, err := r.db.Exec(ctx, query, id.UserID, id.Key, id.Method, id.Path); err != nil {
+		r.log.Error("Failed to release idempotency key", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *IdempotencyKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Time("before", before))
+
+	tag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		r.log.Error("Failed to delete expired idempotency keys", slog.Any("error", err))
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func scanIdempotencyKey(row pgx.Row) (*models.IdempotencyKey, error) {
+	var key models.IdempotencyKey
+	err := row.Scan(&key.UserID, &key.Key, &key.Method, &key.Path, &key.RequestHash, &key.StatusCode, &key.ResponseHeaders, &key.ResponseBody, &key.CreatedAt, &key.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
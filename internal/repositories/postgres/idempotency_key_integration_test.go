@@ -0,0 +1,165 @@
+package postgres_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresIdempotencyKeyRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	newKey := func(t *testing.T) repositories.IdempotencyKeyID {
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+		return repositories.IdempotencyKeyID{
+			UserID: user.ID,
+			Key:    "key-001",
+			Method: http.MethodPost,
+			Path:   "/organizations",
+		}
+	}
+
+	t.Run("Reserve and replay", func(t *testing.T) {
+		th.ResetDB(t)
+		id := newKey(t)
+
+		key, reserved, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.True(t, reserved)
+		require.False(t, key.Completed())
+
+		// A second reservation sees the in-progress request
+		key, reserved, err = th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.False(t, reserved)
+		require.False(t, key.Completed())
+
+		err = th.idempotencyKeyRepo.Complete(ctx, &repositories.CompleteIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			StatusCode:       http.StatusCreated,
+			ResponseHeaders:  http.Header{"Content-Type": {"application/json"}},
+			ResponseBody:     []byte(`{"id":"org-001"}`),
+		})
+		require.NoError(t, err)
+
+		key, reserved, err = th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.False(t, reserved)
+		require.Equal(t, http.StatusCreated, key.StatusCode)
+		require.Equal(t, "application/json", key.ResponseHeaders.Get("Content-Type"))
+		require.Equal(t, `{"id":"org-001"}`, string(key.ResponseBody))
+	})
+
+	t.Run("Reserve takes over an expired key", func(t *testing.T) {
+		th.ResetDB(t)
+		id := newKey(t)
+
+		_, reserved, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(-time.Minute),
+		})
+		require.NoError(t, err)
+		require.True(t, reserved)
+
+		key, reserved, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-b",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.True(t, reserved)
+		require.Equal(t, "hash-b", key.RequestHash)
+	})
+
+	t.Run("Reserve takes over a key whose lock ran out", func(t *testing.T) {
+		th.ResetDB(t)
+		id := newKey(t)
+
+		// The first request never completes.
+		_, reserved, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(-time.Second),
+		})
+		require.NoError(t, err)
+		require.True(t, reserved)
+
+		key, reserved, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.True(t, reserved)
+		require.False(t, key.Completed())
+
+		// A completed request is replayed even after its lock ran out.
+		require.NoError(t, th.idempotencyKeyRepo.Complete(ctx, &repositories.CompleteIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			StatusCode:       http.StatusCreated,
+		}))
+		_, err = th.dbpool.Exec(ctx, `UPDATE idempotency_keys SET locked_until = NOW() - INTERVAL '1 second'`)
+		require.NoError(t, err)
+		key, reserved, err = th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.False(t, reserved)
+		require.Equal(t, http.StatusCreated, key.StatusCode)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		th.ResetDB(t)
+		id := newKey(t)
+
+		_, _, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-a",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, th.idempotencyKeyRepo.Release(ctx, id))
+
+		_, reserved, err := th.idempotencyKeyRepo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+			IdempotencyKeyID: id,
+			RequestHash:      "hash-b",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			LockedUntil:      time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		require.True(t, reserved)
+	})
+}
@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewJobRepository(db *pgxpool.Pool, log *slog.Logger) *JobRepository {
+	return &JobRepository{
+		db:  db,
+		log: log.With("component", "job_repository"),
+	}
+}
+
+var _ repositories.JobRepository = (*JobRepository)(nil)
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, locked_until, COALESCE(last_error, ''), COALESCE(unique_key, ''), created_at, updated_at, finished_at`
+
+func (r *JobRepository) Enqueue(ctx context.Context, params *repositories.EnqueueJobParams) (*models.Job, error) {
+	log := r.log.With(slog.String("kind", params.Kind), slog.String("unique_key", params.UniqueKey))
+
+	query := `
+		INSERT INTO jobs (kind, payload, run_at, max_attempts, unique_key)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (unique_key) DO NOTHING
+		RETURNING ` + jobColumns
+
+	log.Debug("Executing database query", slog.String("query", query))
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, params.Kind, params.Payload, params.RunAt, params.MaxAttempts, params.UniqueKey))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Debug("Job with the same unique key already enqueued")
+			return nil, repositories.ErrConflict
+		}
+		log.Error("Failed to enqueue job", slog.Any("error", err))
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (r *JobRepository) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*models.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'running',
+			attempts = attempts + 1,
+			locked_until = NOW() + make_interval(secs => $3),
+			updated_at = NOW()
+		WHERE id IN (
+			SELECT id
+			FROM jobs
+			WHERE kind = ANY($1)
+				AND ((status = 'pending' AND run_at <= NOW())
+					OR (status = 'running' AND locked_until <= NOW()))
+			ORDER BY run_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int("limit", limit))
+
+	rows, err := r.db.Query(ctx, query, kinds, limit, lease.Seconds())
+	if err != nil {
+		r.log.Error("Failed to claim jobs", slog.Any("error", err))
+		return nil, err
+	}
+
+	return r.collectJobs(rows)
+}
+
+func (r *JobRepository) Complete(ctx context.Context, id string, attempt int) error {
+	query := `
+		UPDATE jobs
+		SET status = 'succeeded', locked_until = NULL, last_error = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("job_id", id))
+
+	tag, err := r.db.Exec(ctx, query, id, attempt)
+	if err != nil {
+		r.log.Error("Failed to complete job", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repositories.ErrConflict
+	}
+
+	return nil
+}
+
+func (r *JobRepository) Fail(ctx context.Context, params *repositories.FailJobParams) error {
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			run_at = COALESCE($4, run_at),
+			finished_at = CASE WHEN $4::timestamptz IS NULL THEN NOW() END,
+			locked_until = NULL,
+			last_error = $3,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("job_id", params.ID))
+
+	tag, err := r.db.Exec(ctx, query, params.ID, params.Attempt, params.Error, params.RetryAt)
+	if err != nil {
+		r.log.Error("Failed to record job failure", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repositories.ErrConflict
+	}
+
+	return nil
+}
+
+func (r *JobRepository) GetByID(ctx context.Context, id string) (*models.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("job_id", id))
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to get job", slog.Any("error", err))
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (r *JobRepository) List(ctx context.Context, params *repositories.ListJobsParams) ([]*models.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("status", string(params.Status)), slog.String("kind", params.Kind))
+
+	rows, err := r.db.Query(ctx, query, string(params.Status), params.Kind, params.Limit)
+	if err != nil {
+		r.log.Error("Failed to list jobs", slog.Any("error", err))
+		return nil, err
+	}
+
+	return r.collectJobs(rows)
+}
+
+func (r *JobRepository) Retry(ctx context.Context, id string) (*models.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_at = NOW(), finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+		RETURNING ` + jobColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("job_id", id))
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, id))
+	if err == nil {
+		return job, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		r.log.Error("Failed to retry job", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Tell an unknown job apart from one that is not dead.
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, repositories.ErrConflict
+}
+
+func (r *JobRepository) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM jobs WHERE status = 'succeeded' AND finished_at < $1`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Time("before", before))
+
+	tag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		r.log.Error("Failed to delete finished jobs", slog.Any("error", err))
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *JobRepository) collectJobs(rows pgx.Rows) ([]*models.Job, error) {
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			r.log.Error("Failed to scan job", slog.Any("error", err))
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate jobs", slog.Any("error", err))
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func scanJob(row pgx.Row) (*models.Job, error) {
+	var job models.Job
+	var payload []byte
+	err := row.Scan(&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt, &job.LockedUntil,
+		&job.LastError, &job.UniqueKey, &job.CreatedAt, &job.UpdatedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	return &job, nil
+}
@@ -0,0 +1,158 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresJobRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	enqueue := func(t *testing.T, kind string, runAt time.Time) *models.Job {
+		job, err := th.jobRepo.Enqueue(ctx, &repositories.EnqueueJobParams{
+			Kind:        kind,
+			Payload:     []byte(`{"n":1}`),
+			RunAt:       runAt,
+			MaxAttempts: 3,
+		})
+		require.NoError(t, err)
+		return job
+	}
+
+	t.Run("Unique keys are enqueued once", func(t *testing.T) {
+		th.ResetDB(t)
+		params := &repositories.EnqueueJobParams{Kind: "greet", Payload: []byte(`{}`), RunAt: time.Now(), MaxAttempts: 3, UniqueKey: "cron:greet:1"}
+
+		job, err := th.jobRepo.Enqueue(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, models.JobPending, job.Status)
+
+		_, err = th.jobRepo.Enqueue(ctx, params)
+		require.ErrorIs(t, err, repositories.ErrConflict)
+	})
+
+	t.Run("Claim leases due jobs of the given kinds", func(t *testing.T) {
+		th.ResetDB(t)
+		due := enqueue(t, "greet", time.Now().Add(-time.Second))
+		enqueue(t, "greet", time.Now().Add(time.Hour))
+		enqueue(t, "other", time.Now().Add(-time.Second))
+
+		claimed, err := th.jobRepo.Claim(ctx, []string{"greet"}, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, due.ID, claimed[0].ID)
+		require.Equal(t, models.JobRunning, claimed[0].Status)
+		require.Equal(t, 1, claimed[0].Attempts)
+		require.NotNil(t, claimed[0].LockedUntil)
+
+		again, err := th.jobRepo.Claim(ctx, []string{"greet"}, 10, time.Minute)
+		require.NoError(t, err)
+		require.Empty(t, again, "a leased job is not claimed twice")
+	})
+
+	t.Run("Expired leases are reclaimed and fence the old attempt", func(t *testing.T) {
+		th.ResetDB(t)
+		enqueue(t, "greet", time.Now())
+
+		first, err := th.jobRepo.Claim(ctx, []string{"greet"}, 1, time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, first, 1)
+		time.Sleep(10 * time.Millisecond)
+
+		second, err := th.jobRepo.Claim(ctx, []string{"greet"}, 1, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, second, 1)
+		require.Equal(t, 2, second[0].Attempts)
+
+		require.ErrorIs(t, th.jobRepo.Complete(ctx, first[0].ID, 1), repositories.ErrConflict)
+		require.NoError(t, th.jobRepo.Complete(ctx, second[0].ID, 2))
+
+		job, err := th.jobRepo.GetByID(ctx, second[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, models.JobSucceeded, job.Status)
+		require.NotNil(t, job.FinishedAt)
+	})
+
+	t.Run("Fail schedules a retry or moves the job to dead", func(t *testing.T) {
+		th.ResetDB(t)
+		enqueue(t, "greet", time.Now())
+		claimed, err := th.jobRepo.Claim(ctx, []string{"greet"}, 1, time.Minute)
+		require.NoError(t, err)
+		id := claimed[0].ID
+
+		retryAt := time.Now().Add(-time.Second)
+		require.NoError(t, th.jobRepo.Fail(ctx, &repositories.FailJobParams{ID: id, Attempt: 1, Error: "boom", RetryAt: &retryAt}))
+
+		job, err := th.jobRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, models.JobPending, job.Status)
+		require.Equal(t, "boom", job.LastError)
+
+		claimed, err = th.jobRepo.Claim(ctx, []string{"greet"}, 1, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.NoError(t, th.jobRepo.Fail(ctx, &repositories.FailJobParams{ID: id, Attempt: 2, Error: "gave up"}))
+
+		job, err = th.jobRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, models.JobDead, job.Status)
+		require.NotNil(t, job.FinishedAt)
+	})
+
+	t.Run("Only dead jobs can be retried", func(t *testing.T) {
+		th.ResetDB(t)
+		job := enqueue(t, "greet", time.Now())
+
+		_, err := th.jobRepo.Retry(ctx, job.ID)
+		require.ErrorIs(t, err, repositories.ErrConflict)
+
+		_, err = th.jobRepo.Retry(ctx, uuid.New().String())
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+
+		_, err = th.jobRepo.Claim(ctx, []string{"greet"}, 1, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, th.jobRepo.Fail(ctx, &repositories.FailJobParams{ID: job.ID, Attempt: 1, Error: "boom"}))
+
+		retried, err := th.jobRepo.Retry(ctx, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.JobPending, retried.Status)
+	})
+
+	t.Run("List filters by status and kind", func(t *testing.T) {
+		th.ResetDB(t)
+		enqueue(t, "greet", time.Now())
+		enqueue(t, "other", time.Now())
+
+		jobs, err := th.jobRepo.List(ctx, &repositories.ListJobsParams{Status: models.JobPending, Kind: "greet", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		require.Equal(t, "greet", jobs[0].Kind)
+
+		jobs, err = th.jobRepo.List(ctx, &repositories.ListJobsParams{Status: models.JobDead, Limit: 10})
+		require.NoError(t, err)
+		require.Empty(t, jobs)
+	})
+
+	t.Run("DeleteFinished removes old finished jobs", func(t *testing.T) {
+		th.ResetDB(t)
+		done := enqueue(t, "greet", time.Now())
+		pending := enqueue(t, "greet", time.Now().Add(time.Hour))
+		_, err := th.jobRepo.Claim(ctx, []string{"greet"}, 1, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, th.jobRepo.Complete(ctx, done.ID, 1))
+
+		deleted, err := th.jobRepo.DeleteFinished(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		_, err = th.jobRepo.GetByID(ctx, pending.ID)
+		require.NoError(t, err)
+	})
+}
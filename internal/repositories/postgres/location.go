@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LocationRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewLocationRepository(db *pgxpool.Pool, log *slog.Logger) *LocationRepository {
+	return &LocationRepository{
+		db:  db,
+		log: log.With("component", "location_repository"),
+	}
+}
+
+var _ repositories.LocationRepository = (*LocationRepository)(nil)
+
+const locationColumns = `id, organization_id, name, street, postal_code, city, country, time_zone, opening_hours, created_at, updated_at, version`
+
+func (r *LocationRepository) Create(ctx context.Context, params *repositories.CreateLocationParams) (*models.Location, error) {
+	query := `
+		INSERT INTO locations (organization_id, name, street, postal_code, city, country, time_zone, opening_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + locationColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("name", params.Name))
+
+	location, err := scanLocation(r.db.QueryRow(ctx, query,
+		params.OrgID, params.Name, params.Address.Street, params.Address.PostalCode, params.Address.City, params.Address.Country,
+		params.TimeZone, openingHoursValue(params.OpeningHours),
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
+			r.log.Warn("Location with the same name already exists", slog.String("org_id", params.OrgID), slog.String("name", params.Name))
+			return nil, repositories.ErrConflict
+		}
+		r.log.Error("Failed to create location", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Location created successfully", slog.String("location_id", location.ID), slog.String("org_id", location.OrgID))
+
+	return location, nil
+}
+
+func (r *LocationRepository) GetByID(ctx context.Context, orgID, id string) (*models.Location, error) {
+	query := `
+		SELECT ` + locationColumns + `
+		FROM locations
+		WHERE organization_id = $1 AND id = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("location_id", id))
+
+	location, err := scanLocation(r.db.QueryRow(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Location not found", slog.String("location_id", id))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve location", slog.Any("error", err))
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (r *LocationRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Location, error) {
+	query := `
+		SELECT ` + locationColumns + `
+		FROM locations
+		WHERE organization_id = $1
+		ORDER BY name, id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		r.log.Error("Failed to list locations", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []*models.Location{}
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			r.log.Error("Failed to scan location", slog.Any("error", err))
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate locations", slog.Any("error", err))
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// Update replaces a location's details. The version check is part of the
+// UPDATE statement; pass repositories.AnyVersion to skip it.
+func (r *LocationRepository) Update(ctx context.Context, params *repositories.UpdateLocationParams) (*models.Location, error) {
+	query := `
+		UPDATE locations
+		SET name = $3, street = $4, postal_code = $5, city = $6, country = $7, time_zone = $8, opening_hours = $9,
+			updated_at = NOW(), version = version + 1
+		WHERE organization_id = $1 AND id = $2 AND ($10 = 0 OR version = $10)
+		RETURNING ` + locationColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("location_id", params.ID), slog.Int("version", params.Version))
+
+	location, err := scanLocation(r.db.QueryRow(ctx, query,
+		params.OrgID, params.ID, params.Name, params.Address.Street, params.Address.PostalCode, params.Address.City, params.Address.Country,
+		params.TimeZone, openingHoursValue(params.OpeningHours), params.Version,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missingOrStale(ctx, params.OrgID, params.ID)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
+			r.log.Warn("Location with the same name already exists", slog.String("org_id", params.OrgID), slog.String("name", params.Name))
+			return nil, repositories.ErrConflict
+		}
+		r.log.Error("Failed to update location", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Location updated successfully", slog.String("location_id", location.ID), slog.Int("version", location.Version))
+
+	return location, nil
+}
+
+// Delete removes a location. The version check is part of the DELETE
+// statement; pass repositories.AnyVersion to skip it.
+func (r *LocationRepository) Delete(ctx context.Context, orgID, id string, version int) error {
+	query := `
+		DELETE FROM locations
+		WHERE organization_id = $1 AND id = $2 AND ($3 = 0 OR version = $3)
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("location_id", id), slog.Int("version", version))
+
+	tag, err := r.db.Exec(ctx, query, orgID, id, version)
+	if err != nil {
+		r.log.Error("Failed to delete location", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return r.missingOrStale(ctx, orgID, id)
+	}
+
+	r.log.Info("Location deleted successfully", slog.String("location_id", id))
+
+	return nil
+}
+
+// missingOrStale explains why a version-guarded statement matched no rows:
+// either the location does not exist or its version has moved on.
+func (r *LocationRepository) missingOrStale(ctx context.Context, orgID, id string) error {
+	var exists bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM locations WHERE organization_id = $1 AND id = $2)", orgID, id).Scan(&exists)
+	if err != nil {
+		r.log.Error("Failed to check if location exists", slog.Any("error", err))
+		return err
+	}
+	if exists {
+		r.log.Warn("Location version mismatch", slog.String("location_id", id))
+		return repositories.ErrVersionMismatch
+	}
+	r.log.Warn("Location not found", slog.String("location_id", id))
+	return repositories.ErrNotFound
+}
+
+func scanLocation(row pgx.Row) (*models.Location, error) {
+	var l models.Location
+	err := row.Scan(&l.ID, &l.OrgID, &l.Name, &l.Address.Street, &l.Address.PostalCode, &l.Address.City, &l.Address.Country,
+		&l.TimeZone, &l.OpeningHours, &l.CreatedAt, &l.UpdatedAt, &l.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// openingHoursValue stores missing opening hours as an empty array rather
+// than JSON null.
+func openingHoursValue(hours []models.OpeningHours) []models.OpeningHours {
+	if hours == nil {
+		return []models.OpeningHours{}
+	}
+	return hours
+}
@@ -0,0 +1,98 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLocationRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *repositories.CreateLocationParams {
+		th.ResetDB(t)
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: user.ID,
+		})
+		require.NoError(t, err)
+		return &repositories.CreateLocationParams{
+			OrgID:    org.ID,
+			Name:     "Oslo depot",
+			Address:  models.Address{Street: "Karl Johans gate 1", PostalCode: "0154", City: "Oslo", Country: "NO"},
+			TimeZone: "Europe/Oslo",
+			OpeningHours: []models.OpeningHours{
+				{Day: models.Monday, Opens: "08:00", Closes: "16:00"},
+			},
+		}
+	}
+
+	t.Run("Create, get and list", func(t *testing.T) {
+		params := setup(t)
+
+		created, err := th.locationRepo.Create(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, 1, created.Version)
+		require.Equal(t, params.Address, created.Address)
+		require.Equal(t, params.OpeningHours, created.OpeningHours)
+
+		got, err := th.locationRepo.GetByID(ctx, params.OrgID, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, created, got)
+
+		list, err := th.locationRepo.ListByOrganizationID(ctx, params.OrgID)
+		require.NoError(t, err)
+		require.Equal(t, []*models.Location{created}, list)
+	})
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		params := setup(t)
+		_, err := th.locationRepo.Create(ctx, params)
+		require.NoError(t, err)
+
+		_, err = th.locationRepo.Create(ctx, params)
+		require.ErrorIs(t, err, repositories.ErrConflict)
+	})
+
+	t.Run("Update checks the version", func(t *testing.T) {
+		params := setup(t)
+		created, err := th.locationRepo.Create(ctx, params)
+		require.NoError(t, err)
+
+		update := &repositories.UpdateLocationParams{
+			OrgID:    params.OrgID,
+			ID:       created.ID,
+			Name:     "Bergen depot",
+			Address:  models.Address{Street: "Bryggen 1", City: "Bergen", Country: "NO"},
+			TimeZone: "Europe/Oslo",
+			Version:  created.Version,
+		}
+		updated, err := th.locationRepo.Update(ctx, update)
+		require.NoError(t, err)
+		require.Equal(t, "Bergen depot", updated.Name)
+		require.Equal(t, 2, updated.Version)
+		require.Empty(t, updated.OpeningHours)
+
+		_, err = th.locationRepo.Update(ctx, update)
+		require.ErrorIs(t, err, repositories.ErrVersionMismatch)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		params := setup(t)
+		created, err := th.locationRepo.Create(ctx, params)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, th.locationRepo.Delete(ctx, params.OrgID, created.ID, created.Version+1), repositories.ErrVersionMismatch)
+		require.NoError(t, th.locationRepo.Delete(ctx, params.OrgID, created.ID, created.Version))
+		require.ErrorIs(t, th.locationRepo.Delete(ctx, params.OrgID, created.ID, repositories.AnyVersion), repositories.ErrNotFound)
+	})
+}
@@ -78,6 +78,14 @@ type TestHelper struct {
 	orgRepo  *repoPostgres.OrganizationRepository
 	userRepo *repoPostgres.UserRepository
 	orgUserRepo *repoPostgres.OrganizationUserRepository
+	idempotencyKeyRepo *repoPostgres.IdempotencyKeyRepository
+	webhookRepo *repoPostgres.WebhookRepository
+	outboxRepo *repoPostgres.OutboxRepository
+	jobRepo *repoPostgres.JobRepository
+	attachmentRepo *repoPostgres.AttachmentRepository
+	locationRepo *repoPostgres.LocationRepository
+	termsRepo *repoPostgres.TermsRepository
+	customerRepo *repoPostgres.CustomerRepository
 }
 
 func SetupTestHelper(t *testing.T) *TestHelper {
@@ -90,6 +98,14 @@ func SetupTestHelper(t *testing.T) *TestHelper {
 		orgRepo: repoPostgres.NewOrganizationRepository(dbpool, logger.NewTestLogger(t)),
 		userRepo: repoPostgres.NewUserRepository(dbpool, logger.NewTestLogger(t)),
 		orgUserRepo: repoPostgres.NewOrganizationUserRepository(dbpool, logger.NewTestLogger(t)),
+		idempotencyKeyRepo: repoPostgres.NewIdempotencyKeyRepository(dbpool, logger.NewTestLogger(t)),
+		webhookRepo: repoPostgres.NewWebhookRepository(dbpool, logger.NewTestLogger(t)),
+		outboxRepo: repoPostgres.NewOutboxRepository(dbpool, logger.NewTestLogger(t)),
+		jobRepo: repoPostgres.NewJobRepository(dbpool, logger.NewTestLogger(t)),
+		attachmentRepo: repoPostgres.NewAttachmentRepository(dbpool, logger.NewTestLogger(t)),
+		locationRepo: repoPostgres.NewLocationRepository(dbpool, logger.NewTestLogger(t)),
+		termsRepo: repoPostgres.NewTermsRepository(dbpool, logger.NewTestLogger(t)),
+		customerRepo: repoPostgres.NewCustomerRepository(dbpool, logger.NewTestLogger(t)),
 	}
 }
 
@@ -99,4 +115,8 @@ func (th *TestHelper) ResetDB(t *testing.T) {
 	require.NoError(t, err)
 	_, err = th.dbpool.Exec(ctx, "TRUNCATE users RESTART IDENTITY CASCADE")
 	require.NoError(t, err)
+	_, err = th.dbpool.Exec(ctx, "TRUNCATE outbox_events RESTART IDENTITY")
+	require.NoError(t, err)
+	_, err = th.dbpool.Exec(ctx, "TRUNCATE jobs")
+	require.NoError(t, err)
 }
\ No newline at end of file
@@ -7,6 +7,7 @@ import (
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -25,6 +26,9 @@ func NewOrganizationRepository(db *pgxpool.Pool, log *slog.Logger) *Organization
 
 var _ repositories.OrganizationRepository = (*OrganizationRepository)(nil)
 
+// organizationColumns is the column list scanOrganization expects.
+const organizationColumns = `id, name, currency, COALESCE(slug, ''), public_catalog, created_by, created_at, updated_at, version`
+
 func (r *OrganizationRepository) Create(ctx context.Context, params *repositories.CreateOrganizationParams) (*models.Organization, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -34,15 +38,13 @@ func (r *OrganizationRepository) Create(ctx context.Context, params *repositorie
 	defer tx.Rollback(ctx)
 
 	createOrgQuery := `
-		INSERT INTO organizations (name, created_by)
-		VALUES ($1, $2)
-		RETURNING id, name, created_by, created_at, updated_at
-	`
+		INSERT INTO organizations (name, currency, slug, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING ` + organizationColumns
 
 	r.log.Debug("Executing database query", slog.String("query", createOrgQuery), slog.Any("params", params))
 
-	var org models.Organization
-	err = r.db.QueryRow(ctx, createOrgQuery, params.Name, params.CreatedBy).Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+	org, err := scanOrganization(r.db.QueryRow(ctx, createOrgQuery, params.Name, params.Currency, params.Slug, params.CreatedBy))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
@@ -71,27 +73,104 @@ func (r *OrganizationRepository) Create(ctx context.Context, params *repositorie
 
 	r.log.Info("Organization created successfully", slog.String("org_id", org.ID), slog.String("name", org.Name), slog.String("created_by", org.CreatedBy))
 
-	return &org, nil
+	return org, nil
 }
 
 func (r *OrganizationRepository) GetByID(ctx context.Context, id string) (*models.Organization, error) {
-	query := `
-		SELECT id, name, created_by, created_at, updated_at
-		FROM organizations
-		WHERE id = $1
-	`
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE id = $1`
 
 	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", id))
 
-	row := r.db.QueryRow(ctx, query, id)
-
-	var org models.Organization
-	if err := row.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt); err != nil {
+	org, err := scanOrganization(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Organization not found", slog.String("org_id", id))
+			return nil, repositories.ErrNotFound
+		}
 		r.log.Error("Failed to retrieve organization by ID", slog.Any("error", err))
 		return nil, err
 	}
 
 	r.log.Info("Organization retrieved successfully", slog.String("org_id", org.ID))
 
+	return org, nil
+}
+
+func (r *OrganizationRepository) GetBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE slug = $1`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("slug", slug))
+
+	org, err := scanOrganization(r.db.QueryRow(ctx, query, slug))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Organization not found", slog.String("slug", slug))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve organization by slug", slog.Any("error", err))
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// Update changes an organization's details. The version check is part of the
+// UPDATE statement, so concurrent writers cannot both succeed.
+func (r *OrganizationRepository) Update(ctx context.Context, params *repositories.UpdateOrganizationParams) (*models.Organization, error) {
+	query := `
+		UPDATE organizations
+		SET name = $1,
+			currency = COALESCE(NULLIF($4, ''), currency),
+			slug = COALESCE(NULLIF($5, ''), slug),
+			public_catalog = COALESCE($6, public_catalog),
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $2 AND ($3 = 0 OR version = $3)
+		RETURNING ` + organizationColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Any("params", params))
+
+	org, err := scanOrganization(r.db.QueryRow(ctx, query, params.Name, params.ID, params.Version, params.Currency, params.Slug, params.PublicCatalog))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missingOrStale(ctx, params.ID)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
+			r.log.Warn("Organization with the same name already exists", slog.Any("error", err))
+			return nil, repositories.ErrConflict
+		}
+		r.log.Error("Failed to update organization", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Organization updated successfully", slog.String("org_id", org.ID), slog.Int("version", org.Version))
+
+	return org, nil
+}
+
+// missingOrStale explains why a version-guarded statement matched no rows:
+// either the organization does not exist or its version has moved on.
+func (r *OrganizationRepository) missingOrStale(ctx context.Context, id string) error {
+	var exists bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM organizations WHERE id = $1)", id).Scan(&exists)
+	if err != nil {
+		r.log.Error("Failed to check if organization exists", slog.Any("error", err))
+		return err
+	}
+	if exists {
+		r.log.Warn("Organization version mismatch", slog.String("org_id", id))
+		return repositories.ErrVersionMismatch
+	}
+	r.log.Warn("Organization not found", slog.String("org_id", id))
+	return repositories.ErrNotFound
+}
+
+func scanOrganization(row pgx.Row) (*models.Organization, error) {
+	var org models.Organization
+	err := row.Scan(&org.ID, &org.Name, &org.Currency, &org.Slug, &org.PublicCatalog, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt, &org.Version)
+	if err != nil {
+		return nil, err
+	}
 	return &org, nil
 }
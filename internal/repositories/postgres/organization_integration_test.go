@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/espennoreng/go-http-rental-server/internal/money"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -51,6 +52,70 @@ func TestPostgresOrganizationRepository(t *testing.T) {
 		require.NotNil(t, org)
 	})
 
+	t.Run("Update_Currency", func(t *testing.T) {
+		th.ResetDB(t)
+
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "johndoe@example.com",
+		})
+		require.NoError(t, err)
+
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Organization",
+			Currency:  "NOK",
+			CreatedBy: user.ID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, money.Currency("NOK"), org.Currency)
+
+		// An empty currency keeps the current one.
+		org, err = th.orgRepo.Update(ctx, &repositories.UpdateOrganizationParams{ID: org.ID, Name: "Renamed", Version: org.Version})
+		require.NoError(t, err)
+		require.Equal(t, money.Currency("NOK"), org.Currency)
+
+		org, err = th.orgRepo.Update(ctx, &repositories.UpdateOrganizationParams{ID: org.ID, Name: "Renamed", Currency: "EUR", Version: org.Version})
+		require.NoError(t, err)
+		require.Equal(t, money.Currency("EUR"), org.Currency)
+	})
+
+	t.Run("Slug_And_PublicCatalog", func(t *testing.T) {
+		th.ResetDB(t)
+
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "johndoe@example.com",
+		})
+		require.NoError(t, err)
+
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{Name: "Test Organization", CreatedBy: user.ID})
+		require.NoError(t, err)
+		require.Empty(t, org.Slug)
+		require.False(t, org.PublicCatalog)
+
+		publish := true
+		org, err = th.orgRepo.Update(ctx, &repositories.UpdateOrganizationParams{ID: org.ID, Name: org.Name, Slug: "test-org", PublicCatalog: &publish, Version: org.Version})
+		require.NoError(t, err)
+		require.Equal(t, "test-org", org.Slug)
+		require.True(t, org.PublicCatalog)
+
+		// An empty slug and a nil flag keep the current values.
+		org, err = th.orgRepo.Update(ctx, &repositories.UpdateOrganizationParams{ID: org.ID, Name: "Renamed", Version: org.Version})
+		require.NoError(t, err)
+		require.Equal(t, "test-org", org.Slug)
+		require.True(t, org.PublicCatalog)
+
+		got, err := th.orgRepo.GetBySlug(ctx, "test-org")
+		require.NoError(t, err)
+		require.Equal(t, org, got)
+
+		_, err = th.orgRepo.GetBySlug(ctx, "other-org")
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+
+		_, err = th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{Name: "Other Organization", Slug: "test-org", CreatedBy: user.ID})
+		require.ErrorIs(t, err, repositories.ErrConflict)
+	})
+
 	t.Run("GetByID_NotFound", func(t *testing.T) {
 		th.ResetDB(t)
 
@@ -8,41 +8,88 @@ import (
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type OrganizationUserRepository struct {
-	db *pgxpool.Pool
+	// Exactly one of pool and tx is set: tx for a repository bound to a
+	// transaction.
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+	db   dbtx
 	log *slog.Logger
 }
 
 func NewOrganizationUserRepository(db *pgxpool.Pool, log *slog.Logger) *OrganizationUserRepository {
 	return &OrganizationUserRepository{
-		db: db,
+		pool: db,
+		db:   db,
 		log: log.With("component", "organization_user_repository"),
 	}
 }
 
 var _ repositories.OrganizationUserRepository = (*OrganizationUserRepository)(nil)
 
+// WithinTransaction runs fn with a repository bound to a single transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+// Calls on a repository that is already bound to a transaction use a
+// savepoint, so a failed inner call does not abort the outer transaction.
+func (r *OrganizationUserRepository) WithinTransaction(ctx context.Context, fn func(repo repositories.OrganizationUserRepository) error) error {
+	var (
+		tx  pgx.Tx
+		err error
+	)
+	if r.tx != nil {
+		tx, err = r.tx.Begin(ctx)
+	} else {
+		tx, err = r.pool.Begin(ctx)
+	}
+	if err != nil {
+		r.log.Error("Failed to begin transaction", slog.Any("error", err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&OrganizationUserRepository{tx: tx, db: tx, log: r.log}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.log.Error("Failed to commit transaction", slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+// Outbox returns an outbox that writes through the repository's
+// transaction, if it is bound to one.
+func (r *OrganizationUserRepository) Outbox() repositories.OutboxRepository {
+	return newOutboxRepository(r.db, r.log)
+}
+
 func (r *OrganizationUserRepository) Create(ctx context.Context, params *repositories.CreateOrganizationUserParams) (*models.OrganizationUser, error) {
 	query := `
 		INSERT INTO organization_users (organization_id, user_id, created_at, role)
 		VALUES ($1, $2, $3, $4)
-		RETURNING organization_id, user_id, created_at, role
+		RETURNING organization_id, user_id, created_at, role, version
 	`
 
 	r.log.Debug("Executing database query", slog.String("query", query), slog.Any("params", params))
 
 	var orgUser models.OrganizationUser
-	err := r.db.QueryRow(ctx, query, params.OrgID, params.UserID, time.Now(), params.Role).Scan(&orgUser.OrgID, &orgUser.UserID, &orgUser.CreatedAt, &orgUser.Role)
+	err := r.db.QueryRow(ctx, query, params.OrgID, params.UserID, time.Now(), params.Role).Scan(&orgUser.OrgID, &orgUser.UserID, &orgUser.CreatedAt, &orgUser.Role, &orgUser.Version)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
 			r.log.Warn("Organization user already exists", slog.Any("error", err))
 			return nil, repositories.ErrConflict
 		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // Foreign key violation
+			r.log.Warn("User or organization for organization user does not exist", slog.Any("error", err))
+			return nil, repositories.ErrNotFound
+		}
 		r.log.Error("Failed to create organization user", slog.Any("error", err))
 		return nil, err
 	}
@@ -54,7 +101,7 @@ func (r *OrganizationUserRepository) Create(ctx context.Context, params *reposit
 
 func (r *OrganizationUserRepository) GetByID(ctx context.Context, orgID string, userID string) (*models.OrganizationUser, error) {
 	query := `
-		SELECT organization_id, user_id, created_at, role
+		SELECT organization_id, user_id, created_at, role, version
 		FROM organization_users
 		WHERE organization_id = $1 AND user_id = $2
 	`
@@ -62,8 +109,13 @@ func (r *OrganizationUserRepository) GetByID(ctx context.Context, orgID string,
 	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("user_id", userID))
 
 	var orgUser models.OrganizationUser
-	err := r.db.QueryRow(ctx, query, orgID, userID).Scan(&orgUser.OrgID, &orgUser.UserID, &orgUser.CreatedAt, &orgUser.Role)
+	err := r.db.QueryRow(ctx, query, orgID, userID).Scan(&orgUser.OrgID, &orgUser.UserID, &orgUser.CreatedAt, &orgUser.Role, &orgUser.Version)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Callers treat a nil membership as "not part of the organization".
+			r.log.Info("Organization user not found", slog.String("org_id", orgID), slog.String("user_id", userID))
+			return nil, nil
+		}
 		r.log.Error("Failed to retrieve organization user by ID", slog.Any("error", err))
 		return nil, err
 	}
@@ -75,7 +127,7 @@ func (r *OrganizationUserRepository) GetByID(ctx context.Context, orgID string,
 
 func (r *OrganizationUserRepository) GetUsersByOrganizationID(ctx context.Context, orgID string) ([]*models.UserWithRole, error) {
 	query := `
-		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, ou.role
+		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, ou.role, ou.version
 		FROM users u
 		JOIN organization_users ou ON ou.user_id = u.id
 		WHERE ou.organization_id = $1
@@ -93,7 +145,7 @@ func (r *OrganizationUserRepository) GetUsersByOrganizationID(ctx context.Contex
 	orgUsersWithRole := make([]*models.UserWithRole, 0)
 	for rows.Next() {
 		var orgUser models.UserWithRole
-		if err := rows.Scan(&orgUser.User.ID, &orgUser.User.Username, &orgUser.User.Email, &orgUser.User.CreatedAt, &orgUser.User.UpdatedAt, &orgUser.Role); err != nil {
+		if err := rows.Scan(&orgUser.User.ID, &orgUser.User.Username, &orgUser.User.Email, &orgUser.User.CreatedAt, &orgUser.User.UpdatedAt, &orgUser.Role, &orgUser.MembershipVersion); err != nil {
 			r.log.Error("Failed to scan organization user row", slog.Any("error", err))
 			return nil, err
 		}
@@ -108,42 +160,119 @@ func (r *OrganizationUserRepository) GetUsersByOrganizationID(ctx context.Contex
 	return orgUsersWithRole, nil
 }
 
-func (r *OrganizationUserRepository) Delete(ctx context.Context, orgID string, userID string) error {
+// StreamUsersByOrganizationID calls fn for each member of an organization
+// as rows are read, so large organizations are never held in memory. It
+// stops at the first error returned by fn.
+func (r *OrganizationUserRepository) StreamUsersByOrganizationID(ctx context.Context, orgID string, fn func(user *models.UserWithRole) error) error {
+	query := `
+		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, ou.role, ou.version
+		FROM users u
+		JOIN organization_users ou ON ou.user_id = u.id
+		WHERE ou.organization_id = $1
+		ORDER BY ou.created_at, u.id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		r.log.Error("Failed to stream users by organization ID", slog.Any("error", err))
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var orgUser models.UserWithRole
+		if err := rows.Scan(&orgUser.User.ID, &orgUser.User.Username, &orgUser.User.Email, &orgUser.User.CreatedAt, &orgUser.User.UpdatedAt, &orgUser.Role, &orgUser.MembershipVersion); err != nil {
+			r.log.Error("Failed to scan organization user row", slog.Any("error", err))
+			return err
+		}
+		if err := fn(&orgUser); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Error occurred while streaming organization users", slog.Any("error", err))
+		return err
+	}
+
+	r.log.Info("Users streamed successfully for organization", slog.String("org_id", orgID), slog.Int("user_count", count))
+	return nil
+}
+
+// Delete removes a membership. The version check is part of the DELETE
+// statement; pass repositories.AnyVersion to skip it.
+func (r *OrganizationUserRepository) Delete(ctx context.Context, orgID string, userID string, version int) error {
 	query := `
 		DELETE FROM organization_users
-		WHERE organization_id = $1 AND user_id = $2
+		WHERE organization_id = $1 AND user_id = $2 AND ($3 = 0 OR version = $3)
 	`
 
-	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("user_id", userID))
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("user_id", userID), slog.Int("version", version))
 
-	_, err := r.db.Exec(ctx, query, orgID, userID)
+	tag, err := r.db.Exec(ctx, query, orgID, userID, version)
 	if err != nil {
 		r.log.Error("Failed to delete organization user", slog.Any("error", err))
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return r.missingOrStale(ctx, orgID, userID)
+	}
 
 	r.log.Info("Organization user deleted successfully", slog.String("org_id", orgID), slog.String("user_id", userID))
 
 	return nil
 }
 
-func (r *OrganizationUserRepository) UpdateRole(ctx context.Context, orgID string, userID string, role models.Role) error {
+// UpdateRole changes a member's role. The version check is part of the
+// UPDATE statement; pass repositories.AnyVersion to skip it.
+func (r *OrganizationUserRepository) UpdateRole(ctx context.Context, orgID string, userID string, role models.Role, version int) (*models.OrganizationUser, error) {
 	query := `
 		UPDATE organization_users
-		SET role = $1
-		WHERE organization_id = $2 AND user_id = $3
+		SET role = $1, version = version + 1
+		WHERE organization_id = $2 AND user_id = $3 AND ($4 = 0 OR version = $4)
+		RETURNING organization_id, user_id, created_at, role, version
 	`
 
-	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("user_id", userID), slog.String("role", string(role)))
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("user_id", userID), slog.String("role", string(role)), slog.Int("version", version))
 
-	_, err := r.db.Exec(ctx, query, role, orgID, userID)
+	var orgUser models.OrganizationUser
+	err := r.db.QueryRow(ctx, query, role, orgID, userID, version).Scan(&orgUser.OrgID, &orgUser.UserID, &orgUser.CreatedAt, &orgUser.Role, &orgUser.Version)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.missingOrStale(ctx, orgID, userID)
+		}
 		r.log.Error("Failed to update organization user role", slog.Any("error", err))
-		return err
+		return nil, err
 	}
 
-	r.log.Info("Organization user role updated successfully", slog.String("org_id", orgID), slog.String("user_id", userID), slog.String("role", string(role)))
-	return nil
+	r.log.Info("Organization user role updated successfully", slog.String("org_id", orgID), slog.String("user_id", userID), slog.String("role", string(role)), slog.Int("version", orgUser.Version))
+	return &orgUser, nil
+}
+
+// missingOrStale explains why a version-guarded statement matched no rows:
+// either the membership does not exist or its version has moved on.
+func (r *OrganizationUserRepository) missingOrStale(ctx context.Context, orgID string, userID string) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM organization_users
+			WHERE organization_id = $1 AND user_id = $2
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, orgID, userID).Scan(&exists); err != nil {
+		r.log.Error("Failed to check if organization user exists", slog.Any("error", err))
+		return err
+	}
+	if exists {
+		r.log.Warn("Organization user version mismatch", slog.String("org_id", orgID), slog.String("user_id", userID))
+		return repositories.ErrVersionMismatch
+	}
+	r.log.Warn("Organization user not found", slog.String("org_id", orgID), slog.String("user_id", userID))
+	return repositories.ErrNotFound
 }
 
 func (r *OrganizationUserRepository) AreUsersInSameOrg(ctx context.Context, params *repositories.AreUsersInSameOrgParams) (bool, error) {
@@ -217,7 +217,7 @@ func TestPostgresOrganizationUserRepository(t *testing.T) {
 		require.NotNil(t, orgUser)
 
 		// Delete the organization-user relationship
-		err = th.orgUserRepo.Delete(ctx, org.ID, user.ID)
+		err = th.orgUserRepo.Delete(ctx, org.ID, user.ID, orgUser.Version)
 		require.NoError(t, err)
 		// Verify that the relationship no longer exists
 		orgUsers, err := th.orgUserRepo.GetUsersByOrganizationID(ctx, org.ID)
@@ -257,8 +257,12 @@ func TestPostgresOrganizationUserRepository(t *testing.T) {
 		require.Equal(t, org.ID, orgUser.OrgID)
 		require.Equal(t, user.ID, orgUser.UserID)
 		// Change the role of the organization user
-		err = th.orgUserRepo.UpdateRole(ctx, org.ID, user.ID, models.RoleAdmin)
+		updated, err := th.orgUserRepo.UpdateRole(ctx, org.ID, user.ID, models.RoleAdmin, orgUser.Version)
 		require.NoError(t, err)
+		require.Equal(t, orgUser.Version+1, updated.Version, "should bump the version")
+		// A second update with the stale version must be rejected
+		_, err = th.orgUserRepo.UpdateRole(ctx, org.ID, user.ID, models.RoleMember, orgUser.Version)
+		require.ErrorIs(t, err, repositories.ErrVersionMismatch)
 		// Verify that the role has been changed
 		orgUsers, err := th.orgUserRepo.GetUsersByOrganizationID(ctx, org.ID)
 		require.NoError(t, err)
@@ -368,5 +372,136 @@ func TestPostgresOrganizationUserRepository(t *testing.T) {
 		require.True(t, OK)
 	})
 
+	t.Run("WithinTransaction rolls back on error", func(t *testing.T) {
+		th.ResetDB(t)
+
+		creator, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "Jane Doe",
+			Email:    "jane@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: creator.ID,
+		})
+		require.NoError(t, err)
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+
+		err = th.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+			_, err := repo.Create(ctx, &repositories.CreateOrganizationUserParams{
+				OrgID:  org.ID,
+				UserID: user.ID,
+				Role:   models.RoleMember,
+			})
+			require.NoError(t, err)
+			// Adding the creator again violates the unique constraint
+			_, err = repo.Create(ctx, &repositories.CreateOrganizationUserParams{
+				OrgID:  org.ID,
+				UserID: creator.ID,
+				Role:   models.RoleMember,
+			})
+			return err
+		})
+		require.ErrorIs(t, err, repositories.ErrConflict)
+
+		orgUser, err := th.orgUserRepo.GetByID(ctx, org.ID, user.ID)
+		require.NoError(t, err)
+		require.Nil(t, orgUser, "the first insert should have been rolled back")
+	})
+
+	t.Run("Create for unknown user", func(t *testing.T) {
+		th.ResetDB(t)
 
+		creator, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "Jane Doe",
+			Email:    "jane@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: creator.ID,
+		})
+		require.NoError(t, err)
+
+		_, err = th.orgUserRepo.Create(ctx, &repositories.CreateOrganizationUserParams{
+			OrgID:  org.ID,
+			UserID: uuid.New().String(),
+			Role:   models.RoleMember,
+		})
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("Nested WithinTransaction rolls back only the savepoint", func(t *testing.T) {
+		th.ResetDB(t)
+
+		creator, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "Jane Doe",
+			Email:    "jane@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: creator.ID,
+		})
+		require.NoError(t, err)
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+
+		err = th.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+			innerErr := repo.WithinTransaction(ctx, func(inner repositories.OrganizationUserRepository) error {
+				_, err := inner.Create(ctx, &repositories.CreateOrganizationUserParams{
+					OrgID:  org.ID,
+					UserID: creator.ID,
+					Role:   models.RoleMember,
+				})
+				return err
+			})
+			require.ErrorIs(t, innerErr, repositories.ErrConflict)
+
+			// The outer transaction is still usable after the failed savepoint
+			_, err := repo.Create(ctx, &repositories.CreateOrganizationUserParams{
+				OrgID:  org.ID,
+				UserID: user.ID,
+				Role:   models.RoleMember,
+			})
+			return err
+		})
+		require.NoError(t, err)
+
+		orgUser, err := th.orgUserRepo.GetByID(ctx, org.ID, user.ID)
+		require.NoError(t, err)
+		require.NotNil(t, orgUser)
+	})
+
+	t.Run("StreamUsersByOrganizationID", func(t *testing.T) {
+		th.ResetDB(t)
+
+		creator, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "Jane Doe",
+			Email:    "jane@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: creator.ID,
+		})
+		require.NoError(t, err)
+
+		var streamed []*models.UserWithRole
+		err = th.orgUserRepo.StreamUsersByOrganizationID(ctx, org.ID, func(user *models.UserWithRole) error {
+			streamed = append(streamed, user)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, streamed, 1)
+		require.Equal(t, creator.ID, streamed[0].ID)
+		require.Equal(t, models.RoleAdmin, streamed[0].Role)
+	})
 }
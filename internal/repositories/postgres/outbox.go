@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository struct {
+	db  dbtx
+	log *slog.Logger
+}
+
+func NewOutboxRepository(db *pgxpool.Pool, log *slog.Logger) *OutboxRepository {
+	return newOutboxRepository(db, log)
+}
+
+// newOutboxRepository creates an outbox on db, which may be a transaction.
+func newOutboxRepository(db dbtx, log *slog.Logger) *OutboxRepository {
+	return &OutboxRepository{
+		db:  db,
+		log: log.With("component", "outbox_repository"),
+	}
+}
+
+var _ repositories.OutboxRepository = (*OutboxRepository)(nil)
+
+func (r *OutboxRepository) Append(ctx context.Context, event *models.Event) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		r.log.Error("Failed to encode event payload", slog.String("event_type", string(event.Type)), slog.Any("error", err))
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox_events (id, organization_id, event_type, payload, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING seq
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("event_id", event.ID), slog.String("event_type", string(event.Type)))
+
+	if err := r.db.QueryRow(ctx, query, event.ID, event.OrgID, string(event.Type), payload, event.OccurredAt).Scan(&event.Seq); err != nil {
+		r.log.Error("Failed to append event to outbox", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
+
+// outboxPositionLock is the advisory lock key that serializes position
+// assignment.
+const outboxPositionLock = 0x6f7574626f78 // "outbox"
+
+// ClaimUnpublished pushes next_attempt_at of the claimed rows past the
+// lease. A dispatcher that stops before publishing leaves the events to be
+// claimed again once the lease runs out, which is what makes delivery
+// at-least-once across restarts.
+//
+// Claims hold an advisory lock until they commit. A claim therefore only
+// takes positions after every earlier claim is visible, so positions
+// become visible in increasing order.
+func (r *OutboxRepository) ClaimUnpublished(ctx context.Context, limit int, lease time.Duration) ([]*repositories.OutboxEntry, error) {
+	query := `
+		WITH due AS (
+			SELECT seq, position
+			FROM outbox_events
+			WHERE published_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY seq
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), positioned AS (
+			SELECT seq, COALESCE(position, nextval('outbox_events_position_seq')) AS position
+			FROM due
+			ORDER BY seq
+		)
+		UPDATE outbox_events o
+		SET next_attempt_at = NOW() + make_interval(secs => $2), position = p.position
+		FROM positioned p
+		WHERE o.seq = p.seq
+		RETURNING o.seq, o.position, o.id, o.organization_id, o.event_type, o.payload, o.occurred_at, o.attempts
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int("limit", limit))
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.log.Error("Failed to begin transaction", slog.Any("error", err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(outboxPositionLock)); err != nil {
+		r.log.Error("Failed to lock outbox positions", slog.Any("error", err))
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		r.log.Error("Failed to claim outbox events", slog.Any("error", err))
+		return nil, err
+	}
+
+	entries := []*repositories.OutboxEntry{}
+	for rows.Next() {
+		var event models.Event
+		var payload []byte
+		entry := &repositories.OutboxEntry{Event: &event}
+		if err := rows.Scan(&event.Seq, &event.Position, &event.ID, &event.OrgID, &event.Type, &payload, &event.OccurredAt, &entry.Attempts); err != nil {
+			rows.Close()
+			r.log.Error("Failed to scan outbox event", slog.Any("error", err))
+			return nil, err
+		}
+		event.Data = json.RawMessage(payload)
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate outbox events", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.log.Error("Failed to commit outbox claim", slog.Any("error", err))
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING does not preserve the subquery's order.
+	slices.SortFunc(entries, func(a, b *repositories.OutboxEntry) int {
+		return cmp.Compare(a.Event.Seq, b.Event.Seq)
+	})
+
+	return entries, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, seq int64) error {
+	query := `
+		UPDATE outbox_events
+		SET published_at = NOW(), attempts = attempts + 1, last_error = NULL
+		WHERE seq = $1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int64("seq", seq))
+
+	tag, err := r.db.Exec(ctx, query, seq)
+	if err != nil {
+		r.log.Error("Failed to mark outbox event published", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repositories.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) RecordFailure(ctx context.Context, seq int64, reason string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		WHERE seq = $1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int64("seq", seq))
+
+	tag, err := r.db.Exec(ctx, query, seq, reason, nextAttemptAt)
+	if err != nil {
+		r.log.Error("Failed to record outbox failure", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repositories.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) GetBySeq(ctx context.Context, seq int64) (*models.Event, error) {
+	query := `
+		SELECT seq, COALESCE(position, 0), id, organization_id, event_type, payload, occurred_at
+		FROM outbox_events
+		WHERE seq = $1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int64("seq", seq))
+
+	event, err := scanOutboxEvent(r.db.QueryRow(ctx, query, seq))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to get outbox event", slog.Any("error", err))
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (r *OutboxRepository) ListByOrganizationID(ctx context.Context, orgID string, afterPosition int64, limit int) ([]*models.Event, error) {
+	query := `
+		SELECT seq, position, id, organization_id, event_type, payload, occurred_at
+		FROM outbox_events
+		WHERE organization_id = $1 AND position > $2
+		ORDER BY position
+		LIMIT $3
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.Int64("after_position", afterPosition))
+
+	rows, err := r.db.Query(ctx, query, orgID, afterPosition, limit)
+	if err != nil {
+		r.log.Error("Failed to list outbox events", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.Event{}
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			r.log.Error("Failed to scan outbox event", slog.Any("error", err))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate outbox events", slog.Any("error", err))
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) LatestPosition(ctx context.Context, orgID string) (int64, error) {
+	query := `
+		SELECT COALESCE(MAX(position), 0)
+		FROM outbox_events
+		WHERE organization_id = $1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	var position int64
+	if err := r.db.QueryRow(ctx, query, orgID).Scan(&position); err != nil {
+		r.log.Error("Failed to get latest outbox position", slog.Any("error", err))
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func scanOutboxEvent(row pgx.Row) (*models.Event, error) {
+	var event models.Event
+	var payload []byte
+	if err := row.Scan(&event.Seq, &event.Position, &event.ID, &event.OrgID, &event.Type, &payload, &event.OccurredAt); err != nil {
+		return nil, err
+	}
+	event.Data = json.RawMessage(payload)
+	return &event, nil
+}
@@ -0,0 +1,182 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	repoPostgres "github.com/espennoreng/go-http-rental-server/internal/repositories/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresOutboxRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	newEvent := func() *models.Event {
+		return &models.Event{
+			ID:         uuid.New().String(),
+			Type:       models.EventMemberAdded,
+			OrgID:      uuid.New().String(),
+			OccurredAt: time.Now(),
+			Data:       models.MemberEventData{UserID: "u1", Role: models.RoleMember},
+		}
+	}
+
+	t.Run("Events follow their transaction", func(t *testing.T) {
+		th.ResetDB(t)
+
+		committed := newEvent()
+		err := th.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+			return repo.Outbox().Append(ctx, committed)
+		})
+		require.NoError(t, err)
+		require.NotZero(t, committed.Seq)
+
+		errRollback := errors.New("rollback")
+		err = th.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+			require.NoError(t, repo.Outbox().Append(ctx, newEvent()))
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		entries, err := th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, committed.ID, entries[0].Event.ID)
+		require.JSONEq(t, `{"user_id":"u1","role":"member"}`, string(entries[0].Event.Data.(json.RawMessage)))
+	})
+
+	t.Run("Claimed events are leased until published", func(t *testing.T) {
+		th.ResetDB(t)
+		require.NoError(t, th.outboxRepo.Append(ctx, newEvent()))
+		require.NoError(t, th.outboxRepo.Append(ctx, newEvent()))
+
+		entries, err := th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Less(t, entries[0].Event.Seq, entries[1].Event.Seq)
+
+		again, err := th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Empty(t, again)
+
+		require.NoError(t, th.outboxRepo.MarkPublished(ctx, entries[0].Event.Seq))
+		// An expired lease makes the unpublished event claimable again.
+		require.NoError(t, th.outboxRepo.RecordFailure(ctx, entries[1].Event.Seq, "sink down", time.Now().Add(-time.Second)))
+
+		again, err = th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, again, 1)
+		require.Equal(t, entries[1].Event.Seq, again[0].Event.Seq)
+		require.Equal(t, 1, again[0].Attempts)
+	})
+
+	t.Run("Events are listed per organization after a position", func(t *testing.T) {
+		th.ResetDB(t)
+		first, second, other := newEvent(), newEvent(), newEvent()
+		second.OrgID = first.OrgID
+		for _, event := range []*models.Event{first, other, second} {
+			require.NoError(t, th.outboxRepo.Append(ctx, event))
+		}
+
+		// Events get a position, and are listed, once they are claimed.
+		events, err := th.outboxRepo.ListByOrganizationID(ctx, first.OrgID, 0, 10)
+		require.NoError(t, err)
+		require.Empty(t, events)
+		_, err = th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+
+		events, err = th.outboxRepo.ListByOrganizationID(ctx, first.OrgID, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, first.ID, events[0].ID)
+		require.Equal(t, second.ID, events[1].ID)
+		require.Less(t, events[0].Position, events[1].Position)
+
+		events, err = th.outboxRepo.ListByOrganizationID(ctx, first.OrgID, events[0].Position, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, second.Seq, events[0].Seq)
+
+		latest, err := th.outboxRepo.LatestPosition(ctx, first.OrgID)
+		require.NoError(t, err)
+		require.Equal(t, events[0].Position, latest)
+
+		event, err := th.outboxRepo.GetBySeq(ctx, other.Seq)
+		require.NoError(t, err)
+		require.Equal(t, other.ID, event.ID)
+
+		_, err = th.outboxRepo.GetBySeq(ctx, other.Seq+100)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("Positions follow commit order", func(t *testing.T) {
+		th.ResetDB(t)
+		early, late := newEvent(), newEvent()
+		late.OrgID = early.OrgID
+
+		// early takes the lower seq but commits after late.
+		tx, err := th.dbpool.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+		var earlySeq int64
+		require.NoError(t, tx.QueryRow(ctx, `
+			INSERT INTO outbox_events (id, organization_id, event_type, payload, occurred_at)
+			VALUES ($1, $2, $3, '{}', NOW())
+			RETURNING seq
+		`, early.ID, early.OrgID, string(early.Type)).Scan(&earlySeq))
+		require.NoError(t, th.outboxRepo.Append(ctx, late))
+		require.Less(t, earlySeq, late.Seq)
+
+		_, err = th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		seen, err := th.outboxRepo.ListByOrganizationID(ctx, early.OrgID, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, seen, 1)
+		require.Equal(t, late.ID, seen[0].ID)
+
+		require.NoError(t, tx.Commit(ctx))
+		_, err = th.outboxRepo.ClaimUnpublished(ctx, 10, time.Minute)
+		require.NoError(t, err)
+
+		// A reader resuming after late still receives early.
+		events, err := th.outboxRepo.ListByOrganizationID(ctx, early.OrgID, seen[0].Position, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, early.ID, events[0].ID)
+	})
+
+	t.Run("Notified events reach listeners", func(t *testing.T) {
+		th.ResetDB(t)
+		notifier := repoPostgres.NewOutboxNotifier(th.dbpool, logger.NewTestLogger(t))
+
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		received := make(chan models.Event, 1)
+		go notifier.Listen(listenCtx, func(ctx context.Context, event models.Event) error {
+			received <- event
+			return nil
+		})
+
+		event := newEvent()
+		require.NoError(t, th.outboxRepo.Append(ctx, event))
+
+		// The listener may not be connected yet, so keep notifying.
+		require.Eventually(t, func() bool {
+			require.NoError(t, notifier.Publish(ctx, *event))
+			select {
+			case got := <-received:
+				return got.ID == event.ID
+			case <-time.After(100 * time.Millisecond):
+				return false
+			}
+		}, 10*time.Second, 200*time.Millisecond)
+	})
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/backoff"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// outboxChannel is the LISTEN/NOTIFY channel carrying the sequence numbers
+// of published outbox events.
+const outboxChannel = "outbox_events"
+
+// OutboxNotifier relays published outbox events to every server instance.
+// The instance whose dispatcher publishes an event notifies the others
+// through Postgres, and each instance loads the event and hands it to its
+// own in-process subscribers.
+type OutboxNotifier struct {
+	pool *pgxpool.Pool
+	repo *OutboxRepository
+	log  *slog.Logger
+}
+
+func NewOutboxNotifier(pool *pgxpool.Pool, log *slog.Logger) *OutboxNotifier {
+	return &OutboxNotifier{
+		pool: pool,
+		repo: newOutboxRepository(pool, log),
+		log:  log.With(slog.String("component", "outbox_notifier")),
+	}
+}
+
+// Publish notifies all listening instances, including this one, that event
+// was published. Only the sequence number is sent because notification
+// payloads are limited in size.
+func (n *OutboxNotifier) Publish(ctx context.Context, event models.Event) error {
+	if _, err := n.pool.Exec(ctx, "SELECT pg_notify($1, $2)", outboxChannel, strconv.FormatInt(event.Seq, 10)); err != nil {
+		n.log.Error("Failed to notify listeners", slog.Int64("seq", event.Seq), slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+// Listen calls publish for every notified event until ctx is cancelled,
+// reconnecting with backoff when the connection is lost. Events notified
+// while disconnected are not replayed; consumers that need them resume from
+// the outbox by position.
+func (n *OutboxNotifier) Listen(ctx context.Context, publish func(context.Context, models.Event) error) {
+	n.log.Info("Outbox notifier listening", slog.String("channel", outboxChannel))
+
+	for attempt := 1; ; attempt++ {
+		err := n.listen(ctx, publish, func() { attempt = 0 })
+		if ctx.Err() != nil {
+			n.log.Info("Outbox notifier stopped")
+			return
+		}
+
+		delay := backoff.Exponential(attempt, time.Second, 30*time.Second)
+		n.log.Warn("Outbox notifier disconnected, reconnecting", slog.Duration("delay", delay), slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			n.log.Info("Outbox notifier stopped")
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// listen holds one connection until it fails. connected is called once
+// the connection is listening.
+func (n *OutboxNotifier) listen(ctx context.Context, publish func(context.Context, models.Event) error, connected func()) error {
+	pooled, err := n.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is taken out of the pool and closed afterwards so
+	// that no other caller inherits the LISTEN.
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+outboxChannel); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		seq, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			n.log.Error("Ignoring malformed outbox notification", slog.String("payload", notification.Payload))
+			continue
+		}
+
+		event, err := n.repo.GetBySeq(ctx, seq)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			n.log.Error("Failed to load notified outbox event", slog.Int64("seq", seq), slog.Any("error", err))
+			continue
+		}
+
+		if err := publish(ctx, *event); err != nil {
+			n.log.Warn("Failed to relay outbox event", slog.Int64("seq", seq), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TermsRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewTermsRepository(db *pgxpool.Pool, log *slog.Logger) *TermsRepository {
+	return &TermsRepository{
+		db:  db,
+		log: log.With("component", "terms_repository"),
+	}
+}
+
+var _ repositories.TermsRepository = (*TermsRepository)(nil)
+
+const termsColumns = `id, organization_id, version, title, body, content_hash, created_by, created_at`
+
+const termsAcceptanceColumns = `a.id, a.terms_id, t.organization_id, t.version, a.user_id, a.ip_address, a.content_hash, a.accepted_at`
+
+// Create publishes terms as the organization's next version. The
+// organization row is locked while the version is chosen, so concurrent
+// publishers get consecutive versions instead of a conflict.
+func (r *TermsRepository) Create(ctx context.Context, params *repositories.CreateTermsParams) (*models.Terms, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.log.Error("Failed to begin transaction for terms creation", slog.Any("error", err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	lockQuery := "SELECT 1 FROM organizations WHERE id = $1 FOR UPDATE"
+	r.log.Debug("Executing database query", slog.String("query", lockQuery), slog.String("org_id", params.OrgID))
+	var exists int
+	if err := tx.QueryRow(ctx, lockQuery, params.OrgID).Scan(&exists); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Organization not found", slog.String("org_id", params.OrgID))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to lock organization for terms creation", slog.Any("error", err))
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO terms (organization_id, version, title, body, content_hash, created_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM terms
+		WHERE organization_id = $1
+		RETURNING ` + termsColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("title", params.Title))
+
+	terms, err := scanTerms(tx.QueryRow(ctx, query, params.OrgID, params.Title, params.Body, params.ContentHash, params.CreatedBy))
+	if err != nil {
+		r.log.Error("Failed to create terms", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.log.Error("Failed to commit transaction for terms creation", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Terms created successfully", slog.String("terms_id", terms.ID), slog.String("org_id", terms.OrgID), slog.Int("version", terms.Version))
+
+	return terms, nil
+}
+
+func (r *TermsRepository) GetLatest(ctx context.Context, orgID string) (*models.Terms, error) {
+	query := `
+		SELECT ` + termsColumns + `
+		FROM terms
+		WHERE organization_id = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	terms, err := scanTerms(r.db.QueryRow(ctx, query, orgID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Organization has no terms", slog.String("org_id", orgID))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve current terms", slog.Any("error", err))
+		return nil, err
+	}
+
+	return terms, nil
+}
+
+func (r *TermsRepository) GetByVersion(ctx context.Context, orgID string, version int) (*models.Terms, error) {
+	query := `
+		SELECT ` + termsColumns + `
+		FROM terms
+		WHERE organization_id = $1 AND version = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.Int("version", version))
+
+	terms, err := scanTerms(r.db.QueryRow(ctx, query, orgID, version))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Terms not found", slog.String("org_id", orgID), slog.Int("version", version))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve terms", slog.Any("error", err))
+		return nil, err
+	}
+
+	return terms, nil
+}
+
+func (r *TermsRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Terms, error) {
+	query := `
+		SELECT ` + termsColumns + `
+		FROM terms
+		WHERE organization_id = $1
+		ORDER BY version DESC
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		r.log.Error("Failed to list terms", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*models.Terms{}
+	for rows.Next() {
+		terms, err := scanTerms(rows)
+		if err != nil {
+			r.log.Error("Failed to scan terms", slog.Any("error", err))
+			return nil, err
+		}
+		versions = append(versions, terms)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate terms", slog.Any("error", err))
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (r *TermsRepository) CreateAcceptance(ctx context.Context, params *repositories.CreateTermsAcceptanceParams) (*models.TermsAcceptance, error) {
+	query := `
+		WITH a AS (
+			INSERT INTO terms_acceptances (terms_id, user_id, ip_address, content_hash)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		)
+		SELECT ` + termsAcceptanceColumns + `
+		FROM a
+		JOIN terms t ON t.id = a.terms_id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("terms_id", params.TermsID), slog.String("user_id", params.UserID))
+
+	acceptance, err := scanTermsAcceptance(r.db.QueryRow(ctx, query, params.TermsID, params.UserID, params.IPAddress, params.ContentHash))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
+			r.log.Warn("User has already accepted the terms", slog.String("terms_id", params.TermsID), slog.String("user_id", params.UserID))
+			return nil, repositories.ErrConflict
+		}
+		r.log.Error("Failed to create terms acceptance", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Terms accepted successfully", slog.String("terms_id", acceptance.TermsID), slog.String("user_id", acceptance.UserID))
+
+	return acceptance, nil
+}
+
+func (r *TermsRepository) GetAcceptance(ctx context.Context, termsID, userID string) (*models.TermsAcceptance, error) {
+	query := `
+		SELECT ` + termsAcceptanceColumns + `
+		FROM terms_acceptances a
+		JOIN terms t ON t.id = a.terms_id
+		WHERE a.terms_id = $1 AND a.user_id = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("terms_id", termsID), slog.String("user_id", userID))
+
+	acceptance, err := scanTermsAcceptance(r.db.QueryRow(ctx, query, termsID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Terms acceptance not found", slog.String("terms_id", termsID), slog.String("user_id", userID))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve terms acceptance", slog.Any("error", err))
+		return nil, err
+	}
+
+	return acceptance, nil
+}
+
+func scanTerms(row pgx.Row) (*models.Terms, error) {
+	var t models.Terms
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Version, &t.Title, &t.Body, &t.ContentHash, &t.CreatedBy, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func scanTermsAcceptance(row pgx.Row) (*models.TermsAcceptance, error) {
+	var a models.TermsAcceptance
+	if err := row.Scan(&a.ID, &a.TermsID, &a.OrgID, &a.Version, &a.UserID, &a.IPAddress, &a.ContentHash, &a.AcceptedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,105 @@
+package postgres_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresTermsRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (orgID, userID string) {
+		th.ResetDB(t)
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: user.ID,
+		})
+		require.NoError(t, err)
+		return org.ID, user.ID
+	}
+
+	t.Run("Versions", func(t *testing.T) {
+		orgID, userID := setup(t)
+
+		_, err := th.termsRepo.GetLatest(ctx, orgID)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+
+		v1, err := th.termsRepo.Create(ctx, &repositories.CreateTermsParams{OrgID: orgID, Title: "Terms", Body: "v1", ContentHash: "h1", CreatedBy: userID})
+		require.NoError(t, err)
+		require.Equal(t, 1, v1.Version)
+
+		v2, err := th.termsRepo.Create(ctx, &repositories.CreateTermsParams{OrgID: orgID, Title: "Terms", Body: "v2", ContentHash: "h2", CreatedBy: userID})
+		require.NoError(t, err)
+		require.Equal(t, 2, v2.Version)
+
+		latest, err := th.termsRepo.GetLatest(ctx, orgID)
+		require.NoError(t, err)
+		require.Equal(t, v2, latest)
+
+		got, err := th.termsRepo.GetByVersion(ctx, orgID, 1)
+		require.NoError(t, err)
+		require.Equal(t, v1, got)
+
+		list, err := th.termsRepo.ListByOrganizationID(ctx, orgID)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		require.Equal(t, 2, list[0].Version)
+	})
+
+	t.Run("Concurrent publishing", func(t *testing.T) {
+		orgID, userID := setup(t)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 5)
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := th.termsRepo.Create(ctx, &repositories.CreateTermsParams{OrgID: orgID, Title: "Terms", Body: "body", ContentHash: "h", CreatedBy: userID})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		latest, err := th.termsRepo.GetLatest(ctx, orgID)
+		require.NoError(t, err)
+		require.Equal(t, 5, latest.Version)
+	})
+
+	t.Run("Acceptances", func(t *testing.T) {
+		orgID, userID := setup(t)
+
+		terms, err := th.termsRepo.Create(ctx, &repositories.CreateTermsParams{OrgID: orgID, Title: "Terms", Body: "v1", ContentHash: "h1", CreatedBy: userID})
+		require.NoError(t, err)
+
+		_, err = th.termsRepo.GetAcceptance(ctx, terms.ID, userID)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+
+		params := &repositories.CreateTermsAcceptanceParams{TermsID: terms.ID, UserID: userID, IPAddress: "203.0.113.7", ContentHash: "h1"}
+		acceptance, err := th.termsRepo.CreateAcceptance(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, orgID, acceptance.OrgID)
+		require.Equal(t, 1, acceptance.Version)
+
+		_, err = th.termsRepo.CreateAcceptance(ctx, params)
+		require.ErrorIs(t, err, repositories.ErrConflict)
+
+		got, err := th.termsRepo.GetAcceptance(ctx, terms.ID, userID)
+		require.NoError(t, err)
+		require.Equal(t, acceptance, got)
+	})
+}
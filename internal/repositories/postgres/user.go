@@ -57,7 +57,7 @@ func (r *UserRepository) Create(ctx context.Context, user *repositories.CreateUs
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
 
 	query := `
-		SELECT id, username, email, created_at, updated_at
+		SELECT id, username, email, created_at, updated_at, notification_preferences
 		FROM users
 		WHERE id = $1
 	`
@@ -67,7 +67,11 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 	row := r.db.QueryRow(ctx, query, id)
 
 	var user models.User
-	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.NotificationPreferences); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("User not found", slog.String("user_id", id))
+			return nil, repositories.ErrNotFound
+		}
 		r.log.Error("Failed to retrieve user by ID", slog.Any("error", err))
 		return nil, err
 	}
@@ -145,3 +149,29 @@ func (r *UserRepository) FindOrCreateByGoogleID(ctx context.Context, googleID, e
 	return &user, tx.Commit(ctx)
 }
 
+
+func (r *UserRepository) UpdateNotificationPreferences(ctx context.Context, id string, prefs models.NotificationPreferences) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET notification_preferences = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, username, email, created_at, updated_at, notification_preferences
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("user_id", id))
+
+	var user models.User
+	err := r.db.QueryRow(ctx, query, id, prefs).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.NotificationPreferences)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("User not found", slog.String("user_id", id))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to update notification preferences", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Notification preferences updated", slog.String("user_id", user.ID))
+
+	return &user, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/espennoreng/go-http-rental-server/internal/models"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -67,7 +68,35 @@ func TestPostgresUserRepository(t *testing.T) {
 
 		randomID := uuid.New().String()
 		_, err := th.userRepo.GetByID(ctx, randomID)
-		require.Error(t, err)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("UpdateNotificationPreferences", func(t *testing.T) {
+		th.ResetDB(t)
+
+		newUser, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "johndoe@example.com",
+		})
+		require.NoError(t, err)
+
+		user, err := th.userRepo.GetByID(ctx, newUser.ID)
+		require.NoError(t, err)
+		require.True(t, user.NotificationPreferences.Enabled(models.NotificationEmail), "users are opted in by default")
+
+		prefs := models.NotificationPreferences{
+			Locale:   "nb",
+			Channels: map[models.NotificationChannel]bool{models.NotificationEmail: false},
+		}
+		_, err = th.userRepo.UpdateNotificationPreferences(ctx, newUser.ID, prefs)
+		require.NoError(t, err)
+
+		user, err = th.userRepo.GetByID(ctx, newUser.ID)
+		require.NoError(t, err)
+		require.Equal(t, prefs, user.NotificationPreferences)
+
+		_, err = th.userRepo.UpdateNotificationPreferences(ctx, uuid.New().String(), prefs)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
 	})
 
 	t.Run("FindOrCreateByGoogleID", func(t *testing.T) {
@@ -0,0 +1,328 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepository struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewWebhookRepository(db *pgxpool.Pool, log *slog.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:  db,
+		log: log.With("component", "webhook_repository"),
+	}
+}
+
+var _ repositories.WebhookRepository = (*WebhookRepository)(nil)
+
+const webhookColumns = `id, organization_id, url, secret, events, description, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.delivered_at, d.created_at`
+
+func (r *WebhookRepository) Create(ctx context.Context, params *repositories.CreateWebhookParams) (*models.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (organization_id, url, secret, events, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + webhookColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("url", params.URL))
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, params.OrgID, params.URL, params.Secret, eventTypeStrings(params.Events), params.Description, params.CreatedBy))
+	if err != nil {
+		r.log.Error("Failed to create webhook", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Webhook created successfully", slog.String("webhook_id", webhook.ID), slog.String("org_id", webhook.OrgID))
+
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, orgID, id string) (*models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE organization_id = $1 AND id = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("webhook_id", id))
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Webhook not found", slog.String("webhook_id", id))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to retrieve webhook", slog.Any("error", err))
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (r *WebhookRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE organization_id = $1
+		ORDER BY created_at, id
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID))
+
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		r.log.Error("Failed to list webhooks", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			r.log.Error("Failed to scan webhook", slog.Any("error", err))
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate webhooks", slog.Any("error", err))
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, orgID, id string) error {
+	query := `
+		DELETE FROM webhooks
+		WHERE organization_id = $1 AND id = $2
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("webhook_id", id))
+
+	tag, err := r.db.Exec(ctx, query, orgID, id)
+	if err != nil {
+		r.log.Error("Failed to delete webhook", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.log.Warn("Webhook to delete not found", slog.String("webhook_id", id))
+		return repositories.ErrNotFound
+	}
+
+	r.log.Info("Webhook deleted successfully", slog.String("webhook_id", id))
+
+	return nil
+}
+
+// EnqueueDeliveries fans the event out to the subscribed webhooks in a single
+// statement. The (webhook_id, event_id) constraint makes it safe to enqueue
+// the same event twice.
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, params *repositories.EnqueueDeliveriesParams) (int, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $3, $2, $4
+		FROM webhooks
+		WHERE organization_id = $1 AND $2 = ANY(events)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", params.OrgID), slog.String("event_type", string(params.EventType)))
+
+	tag, err := r.db.Exec(ctx, query, params.OrgID, string(params.EventType), params.EventID, params.Payload)
+	if err != nil {
+		r.log.Error("Failed to enqueue webhook deliveries", slog.Any("error", err))
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, orgID, webhookID string, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE w.organization_id = $1 AND d.webhook_id = $2
+		ORDER BY d.created_at DESC, d.id
+		LIMIT $3
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("org_id", orgID), slog.String("webhook_id", webhookID))
+
+	rows, err := r.db.Query(ctx, query, orgID, webhookID, limit)
+	if err != nil {
+		r.log.Error("Failed to list webhook deliveries", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			r.log.Error("Failed to scan webhook delivery", slog.Any("error", err))
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate webhook deliveries", slog.Any("error", err))
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) Redeliver(ctx context.Context, orgID, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND w.organization_id = $1 AND d.webhook_id = $2 AND d.id = $3
+		RETURNING ` + webhookDeliveryColumns
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("delivery_id", deliveryID))
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRow(ctx, query, orgID, webhookID, deliveryID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log.Warn("Webhook delivery to redeliver not found", slog.String("delivery_id", deliveryID))
+			return nil, repositories.ErrNotFound
+		}
+		r.log.Error("Failed to redeliver webhook delivery", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.log.Info("Webhook delivery queued for redelivery", slog.String("delivery_id", deliveryID))
+
+	return delivery, nil
+}
+
+// ClaimDueDeliveries pushes next_attempt_at of the claimed rows past the
+// lease. SKIP LOCKED keeps concurrent dispatchers off each other's rows, and
+// a dispatcher that dies mid-delivery only delays the retry until the lease
+// runs out.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*repositories.ClaimedDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns + `, w.url, w.secret`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.Int("limit", limit))
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		r.log.Error("Failed to claim webhook deliveries", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	claimed := []*repositories.ClaimedDelivery{}
+	for rows.Next() {
+		var c repositories.ClaimedDelivery
+		var d models.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt, &c.URL, &c.Secret)
+		if err != nil {
+			r.log.Error("Failed to scan claimed webhook delivery", slog.Any("error", err))
+			return nil, err
+		}
+		c.Delivery = &d
+		claimed = append(claimed, &c)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to iterate claimed webhook deliveries", slog.Any("error", err))
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (r *WebhookRepository) RecordDeliveryAttempt(ctx context.Context, params *repositories.RecordDeliveryAttemptParams) error {
+	status := models.DeliveryPending
+	switch {
+	case params.Succeeded:
+		status = models.DeliverySucceeded
+	case params.NextAttemptAt == nil:
+		status = models.DeliveryFailed
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = attempts + 1,
+			last_attempt_at = NOW(),
+			last_status_code = NULLIF($3, 0),
+			last_error = NULLIF($4, ''),
+			next_attempt_at = COALESCE($5, next_attempt_at),
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() ELSE NULL END
+		WHERE id = $1
+	`
+
+	r.log.Debug("Executing database query", slog.String("query", query), slog.String("delivery_id", params.ID), slog.String("status", string(status)))
+
+	tag, err := r.db.Exec(ctx, query, params.ID, string(status), params.StatusCode, params.Error, params.NextAttemptAt)
+	if err != nil {
+		r.log.Error("Failed to record webhook delivery attempt", slog.Any("error", err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.log.Warn("Webhook delivery to record not found", slog.String("delivery_id", params.ID))
+		return repositories.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var events []string
+	err := row.Scan(&webhook.ID, &webhook.OrgID, &webhook.URL, &webhook.Secret, &events, &webhook.Description, &webhook.CreatedBy, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = make([]models.EventType, len(events))
+	for i, e := range events {
+		webhook.Events[i] = models.EventType(e)
+	}
+	return &webhook, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func eventTypeStrings(events []models.EventType) []string {
+	s := make([]string, len(events))
+	for i, e := range events {
+		s[i] = string(e)
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresWebhookRepository(t *testing.T) {
+	th := SetupTestHelper(t)
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*models.Organization, *models.Webhook) {
+		th.ResetDB(t)
+		user, err := th.userRepo.Create(ctx, &repositories.CreateUserParams{
+			Username: "John Doe",
+			Email:    "john@example.com",
+		})
+		require.NoError(t, err)
+		org, err := th.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
+			Name:      "Test Org",
+			CreatedBy: user.ID,
+		})
+		require.NoError(t, err)
+		webhook, err := th.webhookRepo.Create(ctx, &repositories.CreateWebhookParams{
+			OrgID:     org.ID,
+			URL:       "https://example.com/hooks",
+			Secret:    "whsec_test",
+			Events:    []models.EventType{models.EventMemberAdded},
+			CreatedBy: user.ID,
+		})
+		require.NoError(t, err)
+		return org, webhook
+	}
+
+	enqueue := func(t *testing.T, orgID string, eventType models.EventType) int {
+		n, err := th.webhookRepo.EnqueueDeliveries(ctx, &repositories.EnqueueDeliveriesParams{
+			OrgID:     orgID,
+			EventID:   uuid.New().String(),
+			EventType: eventType,
+			Payload:   []byte(`{}`),
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("Enqueue only for subscribed webhooks", func(t *testing.T) {
+		org, _ := setup(t)
+
+		require.Equal(t, 1, enqueue(t, org.ID, models.EventMemberAdded))
+		require.Equal(t, 0, enqueue(t, org.ID, models.EventMemberRemoved))
+	})
+
+	t.Run("Claimed deliveries are leased", func(t *testing.T) {
+		org, webhook := setup(t)
+		enqueue(t, org.ID, models.EventMemberAdded)
+
+		claimed, err := th.webhookRepo.ClaimDueDeliveries(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, webhook.URL, claimed[0].URL)
+		require.Equal(t, webhook.Secret, claimed[0].Secret)
+
+		claimed, err = th.webhookRepo.ClaimDueDeliveries(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Empty(t, claimed, "a leased delivery must not be claimed again")
+	})
+
+	t.Run("Record failure, then redeliver", func(t *testing.T) {
+		org, webhook := setup(t)
+		enqueue(t, org.ID, models.EventMemberAdded)
+
+		claimed, err := th.webhookRepo.ClaimDueDeliveries(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		id := claimed[0].Delivery.ID
+
+		err = th.webhookRepo.RecordDeliveryAttempt(ctx, &repositories.RecordDeliveryAttemptParams{
+			ID:         id,
+			StatusCode: 500,
+			Error:      "unexpected status 500",
+		})
+		require.NoError(t, err)
+
+		deliveries, err := th.webhookRepo.ListDeliveries(ctx, org.ID, webhook.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, models.DeliveryFailed, deliveries[0].Status)
+		require.Equal(t, 1, deliveries[0].Attempts)
+		require.Equal(t, 500, deliveries[0].LastStatusCode)
+
+		delivery, err := th.webhookRepo.Redeliver(ctx, org.ID, webhook.ID, id)
+		require.NoError(t, err)
+		require.Equal(t, models.DeliveryPending, delivery.Status)
+		require.Equal(t, 0, delivery.Attempts)
+
+		claimed, err = th.webhookRepo.ClaimDueDeliveries(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+	})
+
+	t.Run("Redeliver from another organization", func(t *testing.T) {
+		org, webhook := setup(t)
+		enqueue(t, org.ID, models.EventMemberAdded)
+		deliveries, err := th.webhookRepo.ListDeliveries(ctx, org.ID, webhook.ID, 10)
+		require.NoError(t, err)
+
+		_, err = th.webhookRepo.Redeliver(ctx, uuid.New().String(), webhook.ID, deliveries[0].ID)
+		require.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+type CreateTermsParams struct {
+	OrgID       string
+	Title       string
+	Body        string
+	ContentHash string
+	CreatedBy   string
+}
+
+type CreateTermsAcceptanceParams struct {
+	TermsID     string
+	UserID      string
+	IPAddress   string
+	ContentHash string
+}
+
+type TermsRepository interface {
+	// Create publishes terms as the organization's next version.
+	Create(ctx context.Context, params *CreateTermsParams) (*models.Terms, error)
+	// GetLatest returns the organization's current terms, or ErrNotFound
+	// if it has never published any.
+	GetLatest(ctx context.Context, orgID string) (*models.Terms, error)
+	GetByVersion(ctx context.Context, orgID string, version int) (*models.Terms, error)
+	// ListByOrganizationID returns every version, newest first.
+	ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Terms, error)
+	// CreateAcceptance returns ErrConflict if the user has already
+	// accepted the terms.
+	CreateAcceptance(ctx context.Context, params *CreateTermsAcceptanceParams) (*models.TermsAcceptance, error)
+	GetAcceptance(ctx context.Context, termsID, userID string) (*models.TermsAcceptance, error)
+}
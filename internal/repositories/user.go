@@ -15,4 +15,7 @@ type UserRepository interface {
 	Create(ctx context.Context, params *CreateUserParams) (*models.User, error)
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	FindOrCreateByGoogleID(ctx context.Context, googleID, email string) (*models.User, error)
+	// UpdateNotificationPreferences replaces the user's preferences and
+	// returns ErrNotFound if the user does not exist.
+	UpdateNotificationPreferences(ctx context.Context, id string, prefs models.NotificationPreferences) (*models.User, error)
 }
\ No newline at end of file
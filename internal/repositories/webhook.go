@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+)
+
+type CreateWebhookParams struct {
+	OrgID       string
+	URL         string
+	Secret      string
+	Events      []models.EventType
+	Description string
+	CreatedBy   string
+}
+
+type EnqueueDeliveriesParams struct {
+	OrgID     string
+	EventID   string
+	EventType models.EventType
+	Payload   []byte
+}
+
+// ClaimedDelivery is a delivery leased to a dispatcher together with the
+// endpoint it must be sent to.
+type ClaimedDelivery struct {
+	Delivery *models.WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// RecordDeliveryAttemptParams stores the outcome of an attempt. A failed
+// attempt with a nil NextAttemptAt marks the delivery as failed for good.
+type RecordDeliveryAttemptParams struct {
+	ID            string
+	Succeeded     bool
+	StatusCode    int
+	Error         string
+	NextAttemptAt *time.Time
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, params *CreateWebhookParams) (*models.Webhook, error)
+	GetByID(ctx context.Context, orgID, id string) (*models.Webhook, error)
+	ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Webhook, error)
+	Delete(ctx context.Context, orgID, id string) error
+
+	// EnqueueDeliveries queues the event for every webhook of the
+	// organization subscribed to its type and returns how many were queued.
+	EnqueueDeliveries(ctx context.Context, params *EnqueueDeliveriesParams) (int, error)
+	ListDeliveries(ctx context.Context, orgID, webhookID string, limit int) ([]*models.WebhookDelivery, error)
+	// Redeliver puts a delivery back in the queue with a fresh retry budget.
+	Redeliver(ctx context.Context, orgID, webhookID, deliveryID string) (*models.WebhookDelivery, error)
+
+	// ClaimDueDeliveries leases up to limit due deliveries for lease so
+	// that concurrent dispatchers never send the same delivery twice.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*ClaimedDelivery, error)
+	RecordDeliveryAttempt(ctx context.Context, params *RecordDeliveryAttemptParams) error
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/espennoreng/go-http-rental-server/internal/imaging"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/storage"
+	"github.com/google/uuid"
+)
+
+// MaxAttachmentSize bounds the size of an uploaded file.
+const MaxAttachmentSize = 10 << 20
+
+// AttachmentContentTypes are the file types that can be uploaded. The type
+// is sniffed from the content; the client's Content-Type is not trusted.
+var AttachmentContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": true,
+}
+
+const (
+	thumbnailSize        = 320
+	thumbnailContentType = "image/jpeg"
+	maxFilenameLength    = 255
+	defaultLinkTTL       = 15 * time.Minute
+)
+
+type AttachmentOptions struct {
+	// BaseURL is the server's public URL, used for download links.
+	BaseURL string
+	// SigningSecret signs download links.
+	SigningSecret string
+	// LinkTTL is how long download links stay valid. It defaults to 15
+	// minutes.
+	LinkTTL time.Duration
+}
+
+type attachmentService struct {
+	repo          repositories.AttachmentRepository
+	blobs         storage.BlobStore
+	accessService AccessService
+	opts          AttachmentOptions
+	log           *slog.Logger
+}
+
+// NewAttachmentService creates a service that stores uploaded files in
+// blobs and hands out signed links to download them.
+func NewAttachmentService(repo repositories.AttachmentRepository, blobs storage.BlobStore, accessService AccessService, opts AttachmentOptions, log *slog.Logger) *attachmentService {
+	if opts.LinkTTL <= 0 {
+		opts.LinkTTL = defaultLinkTTL
+	}
+	return &attachmentService{
+		repo:          repo,
+		blobs:         blobs,
+		accessService: accessService,
+		opts:          opts,
+		log:           log.With(slog.String("component", "attachment_service")),
+	}
+}
+
+var _ AttachmentService = (*attachmentService)(nil)
+
+func (s *attachmentService) UploadAttachment(ctx context.Context, params UploadAttachmentParams) (*SignedAttachment, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to upload attachment, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	validationErr := &ValidationError{}
+	if !validFilename(params.Filename) {
+		validationErr.Add("filename", "must be 1 to 255 characters without slashes or control characters")
+	}
+	if len(params.Content) == 0 {
+		validationErr.Add("body", "must not be empty")
+	}
+	if err := validationErr.Err(); err != nil {
+		log.Warn("Invalid input for attachment", slog.Any("error", err))
+		return nil, err
+	}
+	if len(params.Content) > MaxAttachmentSize {
+		log.Warn("Attachment is too large", slog.Int("size", len(params.Content)))
+		return nil, ErrAttachmentTooLarge
+	}
+
+	contentType, _, _ := strings.Cut(http.DetectContentType(params.Content), ";")
+	if !AttachmentContentTypes[contentType] {
+		log.Warn("Unsupported attachment type", slog.String("content_type", contentType))
+		return nil, ErrUnsupportedAttachmentType
+	}
+
+	var thumbnail []byte
+	if strings.HasPrefix(contentType, "image/") {
+		var err error
+		if thumbnail, err = imaging.Thumbnail(params.Content, thumbnailSize); err != nil {
+			log.Warn("Failed to make thumbnail", slog.Any("error", err))
+			if errors.Is(err, imaging.ErrTooLarge) {
+				return nil, NewValidationError("body", "image dimensions are too large")
+			}
+			return nil, NewValidationError("body", "is not a valid image")
+		}
+	}
+
+	// Blobs are stored before the row that refers to them, so a failure
+	// leaves at worst an unreferenced blob, never a dangling row.
+	key := params.OrgID + "/" + uuid.NewString()
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(params.Content), int64(len(params.Content)), contentType); err != nil {
+		log.Error("Failed to store attachment", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+	var thumbnailKey string
+	if thumbnail != nil {
+		thumbnailKey = key + "-thumbnail"
+		if err := s.blobs.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailContentType); err != nil {
+			log.Error("Failed to store thumbnail", slog.Any("error", err))
+			s.deleteBlobs(ctx, log, key)
+			return nil, ErrInternalServer
+		}
+	}
+
+	attachment, err := s.repo.Create(ctx, &repositories.CreateAttachmentParams{
+		OrgID:        params.OrgID,
+		Filename:     params.Filename,
+		ContentType:  contentType,
+		Size:         int64(len(params.Content)),
+		Key:          key,
+		ThumbnailKey: thumbnailKey,
+		CreatedBy:    params.ActingUserID,
+	})
+	if err != nil {
+		log.Error("Failed to create attachment", slog.Any("error", err))
+		s.deleteBlobs(ctx, log, key, thumbnailKey)
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Attachment uploaded successfully", slog.String("attachment_id", attachment.ID), slog.String("content_type", contentType))
+
+	return s.sign(attachment, time.Now()), nil
+}
+
+func (s *attachmentService) GetAttachment(ctx context.Context, params AttachmentParams) (*SignedAttachment, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("attachment_id", params.AttachmentID))
+
+	attachment, err := s.getAttachment(ctx, log, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sign(attachment, time.Now()), nil
+}
+
+// DeleteAttachment deletes an attachment. Admins can delete any of the
+// organization's attachments; members only their own.
+func (s *attachmentService) DeleteAttachment(ctx context.Context, params AttachmentParams) error {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("attachment_id", params.AttachmentID))
+
+	attachment, err := s.getAttachment(ctx, log, params)
+	if err != nil {
+		return err
+	}
+	if attachment.CreatedBy != params.ActingUserID {
+		if err := s.accessService.IsAdmin(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+			log.Warn("Failed to delete attachment, probably due to insufficient permissions", slog.Any("error", err))
+			return err
+		}
+	}
+
+	if err := s.repo.Delete(ctx, params.OrgID, params.AttachmentID); err != nil {
+		return mapAttachmentError(log, err, "Failed to delete attachment")
+	}
+	s.deleteBlobs(ctx, log, attachment.Key, attachment.ThumbnailKey)
+
+	log.Info("Attachment deleted successfully")
+
+	return nil
+}
+
+// OpenAttachment checks a signed download link and opens the file it
+// points to. The caller must close the returned content.
+func (s *attachmentService) OpenAttachment(ctx context.Context, params OpenAttachmentParams) (*AttachmentContent, error) {
+	log := s.log.With(slog.String("attachment_id", params.AttachmentID), slog.String("variant", string(params.Variant)))
+
+	if !s.validSignature(params, time.Now()) {
+		log.Warn("Invalid or expired download link")
+		return nil, ErrInvalidDownloadLink
+	}
+
+	attachment, err := s.repo.GetByIDAnyOrganization(ctx, params.AttachmentID)
+	if err != nil {
+		return nil, mapAttachmentError(log, err, "Failed to retrieve attachment")
+	}
+
+	key := attachment.Key
+	filename := attachment.Filename
+	if params.Variant == AttachmentThumbnail {
+		if attachment.ThumbnailKey == "" {
+			log.Warn("Attachment has no thumbnail")
+			return nil, ErrAttachmentNotFound
+		}
+		key = attachment.ThumbnailKey
+		filename = strings.TrimSuffix(filename, extension(filename)) + "-thumbnail.jpg"
+	}
+
+	body, info, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("Attachment blob is missing", slog.String("key", key))
+			return nil, ErrAttachmentNotFound
+		}
+		log.Error("Failed to open attachment blob", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return &AttachmentContent{
+		Filename:    filename,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		Body:        body,
+	}, nil
+}
+
+func (s *attachmentService) getAttachment(ctx context.Context, log *slog.Logger, params AttachmentParams) (*models.Attachment, error) {
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Attachment access denied, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+	if err := uuid.Validate(params.AttachmentID); err != nil {
+		log.Warn("Invalid attachment ID provided")
+		return nil, NewValidationError("attachmentID", "must be a valid UUID")
+	}
+
+	attachment, err := s.repo.GetByID(ctx, params.OrgID, params.AttachmentID)
+	if err != nil {
+		return nil, mapAttachmentError(log, err, "Failed to retrieve attachment")
+	}
+	return attachment, nil
+}
+
+// deleteBlobs removes blobs that are no longer referenced. Failures are
+// only logged; an orphaned blob wastes space but breaks nothing.
+func (s *attachmentService) deleteBlobs(ctx context.Context, log *slog.Logger, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Error("Failed to delete attachment blob", slog.String("key", key), slog.Any("error", err))
+		}
+	}
+}
+
+func (s *attachmentService) sign(attachment *models.Attachment, now time.Time) *SignedAttachment {
+	expires := now.Add(s.opts.LinkTTL).Truncate(time.Second)
+	signed := &SignedAttachment{
+		Attachment: attachment,
+		URL:        s.downloadURL(attachment.ID, AttachmentOriginal, expires),
+		ExpiresAt:  expires,
+	}
+	if attachment.ThumbnailKey != "" {
+		signed.ThumbnailURL = s.downloadURL(attachment.ID, AttachmentThumbnail, expires)
+	}
+	return signed
+}
+
+func (s *attachmentService) downloadURL(id string, variant AttachmentVariant, expires time.Time) string {
+	query := url.Values{}
+	if variant != AttachmentOriginal {
+		query.Set("variant", string(variant))
+	}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.signature(id, variant, expires.Unix()))
+	return strings.TrimSuffix(s.opts.BaseURL, "/") + "/files/" + url.PathEscape(id) + "?" + query.Encode()
+}
+
+func (s *attachmentService) validSignature(params OpenAttachmentParams, now time.Time) bool {
+	if params.Variant != AttachmentOriginal && params.Variant != AttachmentThumbnail {
+		return false
+	}
+	if !now.Before(time.Unix(params.Expires, 0)) {
+		return false
+	}
+	want := s.signature(params.AttachmentID, params.Variant, params.Expires)
+	return hmac.Equal([]byte(params.Signature), []byte(want))
+}
+
+func (s *attachmentService) signature(id string, variant AttachmentVariant, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.opts.SigningSecret))
+	mac.Write([]byte(id + "\n" + string(variant) + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validFilename(name string) bool {
+	if name == "" || len(name) > maxFilenameLength || name == "." || name == ".." || !utf8.ValidString(name) {
+		return false
+	}
+	return !strings.ContainsFunc(name, func(r rune) bool {
+		return r == '/' || r == '\\' || unicode.IsControl(r)
+	})
+}
+
+func extension(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i > 0 {
+		return name[i:]
+	}
+	return ""
+}
+
+func mapAttachmentError(log *slog.Logger, err error, msg string) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		log.Warn("Attachment not found")
+		return ErrAttachmentNotFound
+	}
+	log.Error(msg, slog.Any("error", err))
+	return ErrInternalServer
+}
@@ -0,0 +1,330 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/espennoreng/go-http-rental-server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAttachmentRepository keeps attachments in memory.
+type fakeAttachmentRepository struct {
+	attachments map[string]*models.Attachment
+}
+
+func (f *fakeAttachmentRepository) Create(ctx context.Context, params *repositories.CreateAttachmentParams) (*models.Attachment, error) {
+	a := &models.Attachment{
+		ID:           uuid.New().String(),
+		OrgID:        params.OrgID,
+		Filename:     params.Filename,
+		ContentType:  params.ContentType,
+		Size:         params.Size,
+		Key:          params.Key,
+		ThumbnailKey: params.ThumbnailKey,
+		CreatedBy:    params.CreatedBy,
+		CreatedAt:    time.Now(),
+	}
+	f.attachments[a.ID] = a
+	return a, nil
+}
+
+func (f *fakeAttachmentRepository) GetByID(ctx context.Context, orgID, id string) (*models.Attachment, error) {
+	a, ok := f.attachments[id]
+	if !ok || a.OrgID != orgID {
+		return nil, repositories.ErrNotFound
+	}
+	return a, nil
+}
+
+func (f *fakeAttachmentRepository) GetByIDAnyOrganization(ctx context.Context, id string) (*models.Attachment, error) {
+	a, ok := f.attachments[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return a, nil
+}
+
+func (f *fakeAttachmentRepository) Delete(ctx context.Context, orgID, id string) error {
+	if _, err := f.GetByID(ctx, orgID, id); err != nil {
+		return err
+	}
+	delete(f.attachments, id)
+	return nil
+}
+
+type attachmentTest struct {
+	service  services.AttachmentService
+	repo     *fakeAttachmentRepository
+	blobs    storage.BlobStore
+	access   *mockAccessService
+	orgID    string
+	adminID  string
+	memberID string
+}
+
+func newAttachmentTest(t *testing.T) *attachmentTest {
+	t.Helper()
+	blobs, err := storage.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	at := &attachmentTest{
+		repo:     &fakeAttachmentRepository{attachments: map[string]*models.Attachment{}},
+		blobs:    blobs,
+		orgID:    uuid.New().String(),
+		adminID:  uuid.New().String(),
+		memberID: uuid.New().String(),
+	}
+	at.access = &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != at.adminID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+		IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.OrgID != at.orgID || (params.UserID != at.adminID && params.UserID != at.memberID) {
+				return services.ErrUserNotPartOfOrganization
+			}
+			return nil
+		},
+	}
+	at.service = services.NewAttachmentService(at.repo, blobs, at.access, services.AttachmentOptions{
+		BaseURL:       "https://rental.example.com/",
+		SigningSecret: "test-secret",
+	}, logger.NewTestLogger(t))
+	return at
+}
+
+func (at *attachmentTest) upload(t *testing.T, userID, filename string, content []byte) *services.SignedAttachment {
+	t.Helper()
+	signed, err := at.service.UploadAttachment(context.Background(), services.UploadAttachmentParams{
+		OrgID:        at.orgID,
+		ActingUserID: userID,
+		Filename:     filename,
+		Content:      content,
+	})
+	require.NoError(t, err)
+	return signed
+}
+
+// openParams turns a signed download URL back into the parameters the
+// handler would pass on.
+func openParams(t *testing.T, rawURL string) services.OpenAttachmentParams {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	variant := services.AttachmentVariant(u.Query().Get("variant"))
+	if variant == "" {
+		variant = services.AttachmentOriginal
+	}
+	return services.OpenAttachmentParams{
+		AttachmentID: strings.TrimPrefix(u.Path, "/files/"),
+		Variant:      variant,
+		Expires:      expires,
+		Signature:    u.Query().Get("signature"),
+	}
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+var testPDF = []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\ntrailer\n<<>>\n%%EOF\n")
+
+func TestAttachmentService_UploadAttachment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("image gets a thumbnail", func(t *testing.T) {
+		at := newAttachmentTest(t)
+
+		signed := at.upload(t, at.memberID, "bike.png", testPNG(t, 1000, 500))
+
+		assert.Equal(t, "image/png", signed.ContentType)
+		assert.NotEmpty(t, signed.ThumbnailKey)
+		assert.True(t, strings.HasPrefix(signed.URL, "https://rental.example.com/files/"+signed.ID+"?"))
+		assert.Contains(t, signed.ThumbnailURL, "variant=thumbnail")
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), signed.ExpiresAt, time.Minute)
+
+		_, info, err := at.blobs.Get(ctx, signed.ThumbnailKey)
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", info.ContentType)
+	})
+
+	t.Run("document has no thumbnail", func(t *testing.T) {
+		at := newAttachmentTest(t)
+
+		signed := at.upload(t, at.memberID, "contract.pdf", testPDF)
+
+		assert.Equal(t, "application/pdf", signed.ContentType)
+		assert.Equal(t, int64(len(testPDF)), signed.Size)
+		assert.Empty(t, signed.ThumbnailKey)
+		assert.Empty(t, signed.ThumbnailURL)
+	})
+
+	tests := []struct {
+		name     string
+		userID   func(at *attachmentTest) string
+		filename string
+		content  []byte
+		wantErr  error
+	}{
+		{"not a member", func(at *attachmentTest) string { return uuid.New().String() }, "a.pdf", testPDF, services.ErrUserNotPartOfOrganization},
+		{"missing filename", nil, "", testPDF, services.ErrInvalidInput},
+		{"filename with path", nil, "../a.pdf", testPDF, services.ErrInvalidInput},
+		{"empty file", nil, "a.pdf", nil, services.ErrInvalidInput},
+		{"too large", nil, "a.pdf", append(bytes.Clone(testPDF), make([]byte, services.MaxAttachmentSize)...), services.ErrAttachmentTooLarge},
+		{"unsupported type", nil, "a.txt", []byte("hello, world"), services.ErrUnsupportedAttachmentType},
+		{"corrupt image", nil, "a.png", testPNG(t, 10, 10)[:40], services.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newAttachmentTest(t)
+			userID := at.memberID
+			if tt.userID != nil {
+				userID = tt.userID(at)
+			}
+
+			_, err := at.service.UploadAttachment(ctx, services.UploadAttachmentParams{
+				OrgID:        at.orgID,
+				ActingUserID: userID,
+				Filename:     tt.filename,
+				Content:      tt.content,
+			})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, at.repo.attachments)
+		})
+	}
+}
+
+func TestAttachmentService_OpenAttachment(t *testing.T) {
+	ctx := context.Background()
+	at := newAttachmentTest(t)
+	content := testPNG(t, 400, 400)
+	signed := at.upload(t, at.memberID, "bike.png", content)
+
+	read := func(t *testing.T, params services.OpenAttachmentParams) *services.AttachmentContent {
+		t.Helper()
+		opened, err := at.service.OpenAttachment(ctx, params)
+		require.NoError(t, err)
+		t.Cleanup(func() { opened.Body.Close() })
+		return opened
+	}
+
+	t.Run("original", func(t *testing.T) {
+		opened := read(t, openParams(t, signed.URL))
+
+		body, err := io.ReadAll(opened.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content, body)
+		assert.Equal(t, "bike.png", opened.Filename)
+		assert.Equal(t, "image/png", opened.ContentType)
+	})
+
+	t.Run("thumbnail", func(t *testing.T) {
+		opened := read(t, openParams(t, signed.ThumbnailURL))
+
+		assert.Equal(t, "bike-thumbnail.jpg", opened.Filename)
+		assert.Equal(t, "image/jpeg", opened.ContentType)
+	})
+
+	tamper := []struct {
+		name   string
+		modify func(p *services.OpenAttachmentParams)
+	}{
+		{"wrong signature", func(p *services.OpenAttachmentParams) { p.Signature = "x" + p.Signature[1:] }},
+		{"extended expiry", func(p *services.OpenAttachmentParams) { p.Expires += 3600 }},
+		{"other variant", func(p *services.OpenAttachmentParams) { p.Variant = services.AttachmentThumbnail }},
+		{"unknown variant", func(p *services.OpenAttachmentParams) { p.Variant = "large" }},
+		{"other attachment", func(p *services.OpenAttachmentParams) { p.AttachmentID = uuid.New().String() }},
+	}
+
+	for _, tt := range tamper {
+		t.Run(tt.name, func(t *testing.T) {
+			params := openParams(t, signed.URL)
+			tt.modify(&params)
+
+			_, err := at.service.OpenAttachment(ctx, params)
+			assert.ErrorIs(t, err, services.ErrInvalidDownloadLink)
+		})
+	}
+
+	t.Run("expired link", func(t *testing.T) {
+		// Expiry is rounded down to the second, so a nanosecond TTL makes
+		// links that have already expired.
+		shortLived := services.NewAttachmentService(at.repo, at.blobs, at.access, services.AttachmentOptions{
+			SigningSecret: "test-secret",
+			LinkTTL:       time.Nanosecond,
+		}, logger.NewTestLogger(t))
+		expired, err := shortLived.GetAttachment(ctx, services.AttachmentParams{OrgID: at.orgID, ActingUserID: at.memberID, AttachmentID: signed.ID})
+		require.NoError(t, err)
+
+		_, err = at.service.OpenAttachment(ctx, openParams(t, expired.URL))
+		assert.ErrorIs(t, err, services.ErrInvalidDownloadLink)
+	})
+}
+
+func TestAttachmentService_DeleteAttachment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("uploader can delete", func(t *testing.T) {
+		at := newAttachmentTest(t)
+		signed := at.upload(t, at.memberID, "bike.png", testPNG(t, 10, 10))
+
+		err := at.service.DeleteAttachment(ctx, services.AttachmentParams{OrgID: at.orgID, ActingUserID: at.memberID, AttachmentID: signed.ID})
+		require.NoError(t, err)
+
+		assert.Empty(t, at.repo.attachments)
+		_, _, err = at.blobs.Get(ctx, signed.Key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		_, _, err = at.blobs.Get(ctx, signed.ThumbnailKey)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
+		_, err = at.service.OpenAttachment(ctx, openParams(t, signed.URL))
+		assert.ErrorIs(t, err, services.ErrAttachmentNotFound)
+	})
+
+	t.Run("admin can delete others' attachments", func(t *testing.T) {
+		at := newAttachmentTest(t)
+		signed := at.upload(t, at.memberID, "contract.pdf", testPDF)
+
+		err := at.service.DeleteAttachment(ctx, services.AttachmentParams{OrgID: at.orgID, ActingUserID: at.adminID, AttachmentID: signed.ID})
+		require.NoError(t, err)
+	})
+
+	t.Run("members cannot delete others' attachments", func(t *testing.T) {
+		at := newAttachmentTest(t)
+		signed := at.upload(t, at.adminID, "contract.pdf", testPDF)
+
+		err := at.service.DeleteAttachment(ctx, services.AttachmentParams{OrgID: at.orgID, ActingUserID: at.memberID, AttachmentID: signed.ID})
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+		assert.Len(t, at.repo.attachments, 1)
+	})
+
+	t.Run("unknown attachment", func(t *testing.T) {
+		at := newAttachmentTest(t)
+
+		err := at.service.DeleteAttachment(ctx, services.AttachmentParams{OrgID: at.orgID, ActingUserID: at.adminID, AttachmentID: uuid.New().String()})
+		assert.ErrorIs(t, err, services.ErrAttachmentNotFound)
+	})
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+)
+
+type customerService struct {
+	repo          repositories.CustomerRepository
+	accessService AccessService
+	log           *slog.Logger
+}
+
+// NewCustomerService creates a service that manages the external
+// customers of organizations.
+func NewCustomerService(repo repositories.CustomerRepository, accessService AccessService, log *slog.Logger) *customerService {
+	return &customerService{
+		repo:          repo,
+		accessService: accessService,
+		log:           log.With(slog.String("component", "customer_service")),
+	}
+}
+
+var _ CustomerService = (*customerService)(nil)
+
+// RegisterCustomer makes the acting user a customer of the organization.
+// It needs no membership: customers are outsiders by design.
+func (s *customerService) RegisterCustomer(ctx context.Context, params RegisterCustomerParams) (*models.Customer, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := uuid.Validate(params.OrgID); err != nil {
+		log.Warn("Invalid organization ID provided")
+		return nil, NewValidationError("orgID", "must be a valid UUID")
+	}
+	profile, err := normalizeCustomerProfile(params.CustomerProfile)
+	if err != nil {
+		log.Warn("Invalid input for customer", slog.Any("error", err))
+		return nil, err
+	}
+
+	customer, err := s.repo.Create(ctx, &repositories.CreateCustomerParams{
+		OrgID:   params.OrgID,
+		UserID:  params.ActingUserID,
+		Phone:   profile.Phone,
+		Address: profile.Address,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrConflict):
+			log.Warn("User is already a customer")
+			return nil, ErrCustomerExists
+		case errors.Is(err, repositories.ErrNotFound):
+			log.Warn("Organization not found")
+			return nil, ErrOrganizationNotFound
+		}
+		log.Error("Failed to register customer", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Customer registered successfully", slog.String("customer_id", customer.ID))
+
+	return customer, nil
+}
+
+func (s *customerService) GetOwnCustomer(ctx context.Context, params OwnCustomerParams) (*models.Customer, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := uuid.Validate(params.OrgID); err != nil {
+		log.Warn("Invalid organization ID provided")
+		return nil, NewValidationError("orgID", "must be a valid UUID")
+	}
+
+	customer, err := s.repo.GetByUserID(ctx, params.OrgID, params.ActingUserID)
+	if err != nil {
+		return nil, mapCustomerError(log, err, "Failed to retrieve customer")
+	}
+
+	return customer, nil
+}
+
+func (s *customerService) UpdateOwnCustomer(ctx context.Context, params UpdateOwnCustomerParams) (*models.Customer, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.Int("version", params.Version),
+	)
+
+	profile, err := normalizeCustomerProfile(params.CustomerProfile)
+	if err != nil {
+		log.Warn("Invalid input for customer update", slog.Any("error", err))
+		return nil, err
+	}
+
+	current, err := s.GetOwnCustomer(ctx, params.OwnCustomerParams)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := s.repo.UpdateProfile(ctx, &repositories.UpdateCustomerProfileParams{
+		OrgID:   params.OrgID,
+		ID:      current.ID,
+		Phone:   profile.Phone,
+		Address: profile.Address,
+		Version: params.Version,
+	})
+	if err != nil {
+		return nil, mapCustomerError(log, err, "Failed to update customer")
+	}
+
+	log.Info("Customer profile updated successfully", slog.Int("new_version", customer.Version))
+
+	return customer, nil
+}
+
+func (s *customerService) ListCustomers(ctx context.Context, params ListCustomersParams) ([]*models.Customer, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to list customers, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	customers, err := s.repo.ListByOrganizationID(ctx, params.OrgID)
+	if err != nil {
+		log.Error("Failed to list customers", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return customers, nil
+}
+
+func (s *customerService) GetCustomer(ctx context.Context, params CustomerParams) (*models.Customer, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("customer_id", params.CustomerID))
+
+	if err := s.authorizeCustomer(ctx, log, params, s.accessService.IsMember); err != nil {
+		return nil, err
+	}
+
+	customer, err := s.repo.GetByID(ctx, params.OrgID, params.CustomerID)
+	if err != nil {
+		return nil, mapCustomerError(log, err, "Failed to retrieve customer")
+	}
+
+	return customer, nil
+}
+
+func (s *customerService) SetCustomerVerification(ctx context.Context, params SetCustomerVerificationParams) (*models.Customer, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.String("customer_id", params.CustomerID),
+		slog.String("status", string(params.Status)),
+	)
+
+	if err := s.authorizeCustomer(ctx, log, params.CustomerParams, s.accessService.IsAdmin); err != nil {
+		return nil, err
+	}
+	if !models.VerificationStatuses[params.Status] {
+		log.Warn("Invalid verification status")
+		return nil, NewValidationError("status", "must be unverified, pending, verified or rejected")
+	}
+
+	customer, err := s.repo.UpdateVerification(ctx, &repositories.UpdateCustomerVerificationParams{
+		OrgID:   params.OrgID,
+		ID:      params.CustomerID,
+		Status:  params.Status,
+		Version: params.Version,
+	})
+	if err != nil {
+		return nil, mapCustomerError(log, err, "Failed to update customer verification")
+	}
+
+	log.Info("Customer verification updated successfully", slog.Int("new_version", customer.Version))
+
+	return customer, nil
+}
+
+func (s *customerService) authorizeCustomer(ctx context.Context, log *slog.Logger, params CustomerParams, check func(context.Context, OrgAccessParams) error) error {
+	if err := check(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Customer access denied, probably due to insufficient permissions", slog.Any("error", err))
+		return err
+	}
+	if err := uuid.Validate(params.CustomerID); err != nil {
+		log.Warn("Invalid customer ID provided")
+		return NewValidationError("customerID", "must be a valid UUID")
+	}
+	return nil
+}
+
+// normalizeCustomerProfile validates a profile and returns it with
+// whitespace trimmed, the phone number in E.164 form and the country in
+// upper case.
+func normalizeCustomerProfile(profile CustomerProfile) (CustomerProfile, error) {
+	profile.Phone = normalizePhone(profile.Phone)
+	profile.Address.Street = strings.TrimSpace(profile.Address.Street)
+	profile.Address.PostalCode = strings.TrimSpace(profile.Address.PostalCode)
+	profile.Address.City = strings.TrimSpace(profile.Address.City)
+	profile.Address.Country = strings.ToUpper(strings.TrimSpace(profile.Address.Country))
+
+	validationErr := &ValidationError{}
+	if profile.Phone != "" && !isE164(profile.Phone) {
+		validationErr.Add("phone", "must be an international number, such as +47 912 34 567")
+	}
+	if profile.Address != (models.Address{}) {
+		if profile.Address.Street == "" {
+			validationErr.Add("address.street", "is required")
+		}
+		if profile.Address.City == "" {
+			validationErr.Add("address.city", "is required")
+		}
+		if !isCountryCode(profile.Address.Country) {
+			validationErr.Add("address.country", "must be an ISO 3166-1 alpha-2 country code")
+		}
+	}
+	return profile, validationErr.Err()
+}
+
+// normalizePhone removes the spaces, dashes, dots and parentheses people
+// write phone numbers with.
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
+
+// isE164 reports whether phone is "+" followed by 8 to 15 digits, the
+// first of which is not 0.
+func isE164(phone string) bool {
+	digits, ok := strings.CutPrefix(phone, "+")
+	if !ok || len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func mapCustomerError(log *slog.Logger, err error, msg string) error {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		log.Warn("Customer not found")
+		return ErrCustomerNotFound
+	case errors.Is(err, repositories.ErrVersionMismatch):
+		log.Warn("Customer was modified concurrently")
+		return ErrVersionMismatch
+	}
+	log.Error(msg, slog.Any("error", err))
+	return ErrInternalServer
+}
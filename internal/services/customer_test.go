@@ -0,0 +1,196 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCustomerRepository keeps the customers of a single organization in
+// memory, keyed by user ID.
+type fakeCustomerRepository struct {
+	customers map[string]*models.Customer
+}
+
+func (f *fakeCustomerRepository) Create(ctx context.Context, params *repositories.CreateCustomerParams) (*models.Customer, error) {
+	if _, ok := f.customers[params.UserID]; ok {
+		return nil, repositories.ErrConflict
+	}
+	customer := &models.Customer{
+		ID: uuid.New().String(), OrgID: params.OrgID, UserID: params.UserID,
+		Phone: params.Phone, Address: params.Address,
+		VerificationStatus: models.VerificationUnverified, Version: 1,
+	}
+	f.customers[params.UserID] = customer
+	return customer, nil
+}
+
+func (f *fakeCustomerRepository) GetByID(ctx context.Context, orgID, id string) (*models.Customer, error) {
+	for _, customer := range f.customers {
+		if customer.ID == id {
+			return customer, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (f *fakeCustomerRepository) GetByUserID(ctx context.Context, orgID, userID string) (*models.Customer, error) {
+	customer, ok := f.customers[userID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return customer, nil
+}
+
+func (f *fakeCustomerRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Customer, error) {
+	customers := make([]*models.Customer, 0, len(f.customers))
+	for _, customer := range f.customers {
+		customers = append(customers, customer)
+	}
+	return customers, nil
+}
+
+func (f *fakeCustomerRepository) UpdateProfile(ctx context.Context, params *repositories.UpdateCustomerProfileParams) (*models.Customer, error) {
+	customer, err := f.update(params.ID, params.Version)
+	if err != nil {
+		return nil, err
+	}
+	customer.Phone, customer.Address = params.Phone, params.Address
+	return customer, nil
+}
+
+func (f *fakeCustomerRepository) UpdateVerification(ctx context.Context, params *repositories.UpdateCustomerVerificationParams) (*models.Customer, error) {
+	customer, err := f.update(params.ID, params.Version)
+	if err != nil {
+		return nil, err
+	}
+	customer.VerificationStatus = params.Status
+	return customer, nil
+}
+
+func (f *fakeCustomerRepository) update(id string, version int) (*models.Customer, error) {
+	customer, err := f.GetByID(context.Background(), "", id)
+	if err != nil {
+		return nil, err
+	}
+	if version != repositories.AnyVersion && version != customer.Version {
+		return nil, repositories.ErrVersionMismatch
+	}
+	customer.Version++
+	return customer, nil
+}
+
+func TestCustomerService(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+	memberUserID := uuid.New().String()
+	customerUserID := uuid.New().String()
+
+	repo := &fakeCustomerRepository{customers: map[string]*models.Customer{}}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != adminUserID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+		IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != adminUserID && params.UserID != memberUserID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+	}
+	service := services.NewCustomerService(repo, accessService, logger.NewTestLogger(t))
+
+	own := services.OwnCustomerParams{OrgID: orgID, ActingUserID: customerUserID}
+
+	t.Run("rejects an invalid profile", func(t *testing.T) {
+		_, err := service.RegisterCustomer(ctx, services.RegisterCustomerParams{
+			OrgID:        orgID,
+			ActingUserID: customerUserID,
+			CustomerProfile: services.CustomerProfile{
+				Phone:   "912 34 567",
+				Address: models.Address{City: "Oslo", Country: "Norway"},
+			},
+		})
+		var validationErr *services.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 3)
+	})
+
+	customer, err := service.RegisterCustomer(ctx, services.RegisterCustomerParams{
+		OrgID:        orgID,
+		ActingUserID: customerUserID,
+		CustomerProfile: services.CustomerProfile{
+			Phone:   " +47 (912) 34-567 ",
+			Address: models.Address{Street: "Storgata 1", PostalCode: "0155", City: "Oslo", Country: "no"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "+4791234567", customer.Phone)
+	assert.Equal(t, "NO", customer.Address.Country)
+	assert.Equal(t, models.VerificationUnverified, customer.VerificationStatus)
+
+	t.Run("registers only once", func(t *testing.T) {
+		_, err := service.RegisterCustomer(ctx, services.RegisterCustomerParams{OrgID: orgID, ActingUserID: customerUserID})
+		assert.ErrorIs(t, err, services.ErrCustomerExists)
+	})
+
+	t.Run("updates own profile", func(t *testing.T) {
+		updated, err := service.UpdateOwnCustomer(ctx, services.UpdateOwnCustomerParams{
+			OwnCustomerParams: own,
+			CustomerProfile:   services.CustomerProfile{Phone: "+4798765432"},
+			Version:           customer.Version,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "+4798765432", updated.Phone)
+		assert.Equal(t, models.Address{}, updated.Address)
+
+		_, err = service.UpdateOwnCustomer(ctx, services.UpdateOwnCustomerParams{OwnCustomerParams: own, Version: 1})
+		assert.ErrorIs(t, err, services.ErrVersionMismatch)
+	})
+
+	t.Run("non-customers have no profile", func(t *testing.T) {
+		_, err := service.GetOwnCustomer(ctx, services.OwnCustomerParams{OrgID: orgID, ActingUserID: memberUserID})
+		assert.ErrorIs(t, err, services.ErrCustomerNotFound)
+	})
+
+	t.Run("customers cannot see other customers", func(t *testing.T) {
+		_, err := service.ListCustomers(ctx, services.ListCustomersParams{OrgID: orgID, ActingUserID: customerUserID})
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+
+		customers, err := service.ListCustomers(ctx, services.ListCustomersParams{OrgID: orgID, ActingUserID: memberUserID})
+		require.NoError(t, err)
+		assert.Len(t, customers, 1)
+	})
+
+	t.Run("only admins verify", func(t *testing.T) {
+		params := services.SetCustomerVerificationParams{
+			CustomerParams: services.CustomerParams{OrgID: orgID, ActingUserID: memberUserID, CustomerID: customer.ID},
+			Status:         models.VerificationVerified,
+			Version:        repositories.AnyVersion,
+		}
+		_, err := service.SetCustomerVerification(ctx, params)
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+
+		params.ActingUserID = adminUserID
+		verified, err := service.SetCustomerVerification(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, models.VerificationVerified, verified.VerificationStatus)
+
+		params.Status = "approved"
+		_, err = service.SetCustomerVerification(ctx, params)
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+}
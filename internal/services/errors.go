@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
@@ -16,5 +18,86 @@ var (
 	ErrUnauthorized                           = errors.New("unauthorized")
 	ErrForbidden                              = errors.New("forbidden")
 	ErrUserAlreadyHasARoleInOrganization = errors.New("user already has a role in the organization")
+	ErrOrganizationUserNotFound          = errors.New("organization user not found")
+	ErrVersionMismatch                   = errors.New("resource has been modified since it was retrieved")
+	ErrIdempotencyKeyReused              = errors.New("idempotency key was already used for a different request")
+	ErrIdempotentRequestInProgress       = errors.New("a request with this idempotency key is still being processed")
+	ErrWebhookNotFound                   = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound           = errors.New("webhook delivery not found")
+	ErrJobNotFound                       = errors.New("job not found")
+	ErrJobNotRetryable                   = errors.New("only dead jobs can be retried")
+	ErrAttachmentNotFound                = errors.New("attachment not found")
+	ErrAttachmentTooLarge                = errors.New("attachment is too large")
+	ErrUnsupportedAttachmentType         = errors.New("attachments must be JPEG, PNG or GIF images or PDF documents")
+	ErrInvalidDownloadLink               = errors.New("download link is invalid or has expired")
+	ErrLocationNotFound                  = errors.New("location not found")
+	ErrLocationNameTaken                 = errors.New("organization already has a location with this name")
+	ErrTermsNotFound                     = errors.New("terms not found")
+	ErrTermsAcceptanceNotFound           = errors.New("terms have not been accepted")
+	ErrTermsOutdated                     = errors.New("terms have changed; accept the current version")
+	ErrCustomerNotFound                  = errors.New("customer not found")
+	ErrCustomerExists                    = errors.New("user is already a customer of the organization")
+	ErrCatalogNotFound                   = errors.New("catalog not found")
 )
-	
\ No newline at end of file
+
+// FieldError describes why a single input field was rejected. Line is set
+// when the input came from a file, such as a CSV import.
+type FieldError struct {
+	Line    int    `json:"line,omitempty"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError carries field-level details for rejected input.
+// It matches ErrInvalidInput with errors.Is, so existing checks keep working.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError creates a ValidationError for a single field.
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// Add records another rejected field.
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns e if any fields were rejected, or nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + " " + f.Message
+		if f.Line > 0 {
+			messages[i] = fmt.Sprintf("line %d: %s", f.Line, messages[i])
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// BatchOperationError reports which operation caused an atomic batch to be
+// rolled back. It unwraps to the operation's error.
+type BatchOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return fmt.Sprintf("operations[%d]: %v", e.Index, e.Err)
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
+}
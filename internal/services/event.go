@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/outbox"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+// eventBufferSize is how many live events a subscriber may fall behind
+// before its subscription is cut off.
+const eventBufferSize = 64
+
+// EventSource delivers published events to in-process subscribers.
+type EventSource interface {
+	Subscribe(sink outbox.Sink) (unsubscribe func())
+}
+
+type eventService struct {
+	outboxRepo    repositories.OutboxRepository
+	source        EventSource
+	accessService AccessService
+	log           *slog.Logger
+}
+
+// NewEventService creates a service that lets organization members read
+// and follow their organization's events.
+func NewEventService(outboxRepo repositories.OutboxRepository, source EventSource, accessService AccessService, log *slog.Logger) *eventService {
+	return &eventService{
+		outboxRepo:    outboxRepo,
+		source:        source,
+		accessService: accessService,
+		log:           log.With(slog.String("component", "event_service")),
+	}
+}
+
+var _ EventService = (*eventService)(nil)
+
+func (s *eventService) ListEvents(ctx context.Context, params ListEventsParams) ([]*models.Event, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to list events, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+	if params.AfterPosition < 0 {
+		log.Warn("Invalid event position provided", slog.Int64("after_position", params.AfterPosition))
+		return nil, NewValidationError("afterPosition", "must not be negative")
+	}
+
+	events, err := s.outboxRepo.ListByOrganizationID(ctx, params.OrgID, params.AfterPosition, MaxListedEvents)
+	if err != nil {
+		log.Error("Failed to list events", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return events, nil
+}
+
+func (s *eventService) SubscribeEvents(ctx context.Context, params SubscribeEventsParams) (*EventSubscription, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to subscribe to events, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	sub := &EventSubscription{events: make(chan models.Event, eventBufferSize)}
+	sub.unsubscribe = s.source.Subscribe(outbox.SinkFunc(func(ctx context.Context, event models.Event) error {
+		if event.OrgID == params.OrgID {
+			sub.deliver(event)
+		}
+		return nil
+	}))
+
+	// Read the start after subscribing, so every event positioned after
+	// it is also delivered live.
+	start, err := s.outboxRepo.LatestPosition(ctx, params.OrgID)
+	if err != nil {
+		sub.Close()
+		log.Error("Failed to read the latest event position", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+	sub.start = start
+
+	log.Debug("Subscribed to events", slog.Int64("start", start))
+
+	return sub, nil
+}
+
+// EventSubscription receives the live events of one organization. A
+// subscriber that falls too far behind is cut off rather than allowed to
+// hold up publishing: Events is closed and Lagged reports true, and the
+// subscriber should catch up with ListEvents from the last event it handled.
+type EventSubscription struct {
+	events      chan models.Event
+	unsubscribe func()
+	start       int64
+
+	mu     sync.Mutex
+	closed bool
+	lagged bool
+}
+
+// NewEventSubscription returns a subscription starting after position
+// start and fed by the returned send function, for callers that provide
+// events themselves, such as tests.
+func NewEventSubscription(start int64, buffer int) (*EventSubscription, func(models.Event)) {
+	sub := &EventSubscription{events: make(chan models.Event, buffer), unsubscribe: func() {}, start: start}
+	return sub, sub.deliver
+}
+
+func (s *EventSubscription) Events() <-chan models.Event {
+	return s.events
+}
+
+// Start is the latest event position when the subscription began. Every
+// event with a higher position is delivered live.
+func (s *EventSubscription) Start() int64 {
+	return s.start
+}
+
+// Lagged reports whether the subscription was cut off for falling behind.
+func (s *EventSubscription) Lagged() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lagged
+}
+
+func (s *EventSubscription) Close() {
+	s.unsubscribe()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *EventSubscription) deliver(event models.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.lagged = true
+		s.closeLocked()
+	}
+}
+
+func (s *EventSubscription) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
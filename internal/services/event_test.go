@@ -0,0 +1,119 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/outbox"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventService_ListEvents(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New().String()
+	memberID := uuid.New().String()
+
+	repo := &mockOutboxRepository{events: []models.Event{
+		{Seq: 1, Position: 1, OrgID: orgID, Type: models.EventMemberAdded},
+		{Seq: 2, Position: 2, OrgID: uuid.New().String(), Type: models.EventMemberAdded},
+		{Seq: 3, Position: 3, OrgID: orgID, Type: models.EventMemberRemoved},
+	}}
+	accessService := &mockAccessService{
+		IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != memberID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+	}
+	service := services.NewEventService(repo, outbox.NewBus(), accessService, logger.NewTestLogger(t))
+
+	t.Run("returns the organization's events after the given position", func(t *testing.T) {
+		events, err := service.ListEvents(ctx, services.ListEventsParams{OrgID: orgID, ActingUserID: memberID, AfterPosition: 1})
+
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, int64(3), events[0].Position)
+	})
+
+	t.Run("requires membership", func(t *testing.T) {
+		_, err := service.ListEvents(ctx, services.ListEventsParams{OrgID: orgID, ActingUserID: uuid.New().String()})
+
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+	})
+
+	t.Run("rejects a negative position", func(t *testing.T) {
+		_, err := service.ListEvents(ctx, services.ListEventsParams{OrgID: orgID, ActingUserID: memberID, AfterPosition: -1})
+
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+}
+
+func TestEventService_SubscribeEvents(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New().String()
+
+	accessService := &mockAccessService{
+		IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error { return nil },
+	}
+	bus := outbox.NewBus()
+	repo := &mockOutboxRepository{events: []models.Event{{Seq: 4, Position: 7, OrgID: orgID}}}
+	service := services.NewEventService(repo, bus, accessService, logger.NewTestLogger(t))
+
+	t.Run("starts at the latest position", func(t *testing.T) {
+		sub, err := service.SubscribeEvents(ctx, services.SubscribeEventsParams{OrgID: orgID, ActingUserID: uuid.New().String()})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		assert.Equal(t, int64(7), sub.Start())
+	})
+
+	t.Run("delivers only the organization's events", func(t *testing.T) {
+		sub, err := service.SubscribeEvents(ctx, services.SubscribeEventsParams{OrgID: orgID, ActingUserID: uuid.New().String()})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		require.NoError(t, bus.Publish(ctx, models.Event{Seq: 1, OrgID: uuid.New().String()}))
+		require.NoError(t, bus.Publish(ctx, models.Event{Seq: 2, OrgID: orgID}))
+
+		event := <-sub.Events()
+		assert.Equal(t, int64(2), event.Seq)
+		assert.Empty(t, sub.Events())
+	})
+
+	t.Run("cuts off a subscriber that falls behind", func(t *testing.T) {
+		sub, err := service.SubscribeEvents(ctx, services.SubscribeEventsParams{OrgID: orgID, ActingUserID: uuid.New().String()})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		// Publishing never blocks on a slow subscriber.
+		for seq := int64(1); seq <= 1000; seq++ {
+			require.NoError(t, bus.Publish(ctx, models.Event{Seq: seq, OrgID: orgID}))
+		}
+
+		received := 0
+		for range sub.Events() {
+			received++
+		}
+		assert.Less(t, received, 1000)
+		assert.True(t, sub.Lagged())
+	})
+
+	t.Run("closing stops delivery", func(t *testing.T) {
+		sub, err := service.SubscribeEvents(ctx, services.SubscribeEventsParams{OrgID: orgID, ActingUserID: uuid.New().String()})
+		require.NoError(t, err)
+
+		sub.Close()
+		require.NoError(t, bus.Publish(ctx, models.Event{Seq: 1, OrgID: orgID}))
+
+		_, ok := <-sub.Events()
+		assert.False(t, ok)
+		assert.False(t, sub.Lagged())
+	})
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+// maxIdempotencyKeyLength bounds client-supplied keys; UUIDs fit comfortably.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLock is how long a request may hold its key without storing
+// a response. A retry after that assumes the request crashed or timed out
+// and takes the key over. It is well above the time any request takes.
+const idempotencyLock = 2 * time.Minute
+
+type idempotencyService struct {
+	repo repositories.IdempotencyKeyRepository
+	ttl  time.Duration
+	log  *slog.Logger
+}
+
+// NewIdempotencyService creates a service that remembers responses for ttl.
+func NewIdempotencyService(repo repositories.IdempotencyKeyRepository, ttl time.Duration, log *slog.Logger) *idempotencyService {
+	return &idempotencyService{
+		repo: repo,
+		ttl:  ttl,
+		log:  log.With(slog.String("component", "idempotency_service")),
+	}
+}
+
+var _ IdempotencyService = (*idempotencyService)(nil)
+
+func (s *idempotencyService) BeginRequest(ctx context.Context, params BeginIdempotentRequestParams) (*models.IdempotencyKey, error) {
+	log := s.log.With(
+		slog.String("user_id", params.UserID),
+		slog.String("method", params.Method),
+		slog.String("path", params.Path),
+	)
+
+	if params.Key == "" || len(params.Key) > maxIdempotencyKeyLength {
+		log.Warn("Invalid input: idempotency key has an invalid length", slog.Int("length", len(params.Key)))
+		return nil, NewValidationError("Idempotency-Key", "must be between 1 and 255 characters")
+	}
+	if params.UserID == "" {
+		log.Error("Invalid input: user ID is required")
+		return nil, ErrInvalidInput
+	}
+
+	now := time.Now()
+	stored, reserved, err := s.repo.Reserve(ctx, &repositories.ReserveIdempotencyKeyParams{
+		IdempotencyKeyID: toIdempotencyKeyID(params.IdempotentRequest),
+		RequestHash:      params.RequestHash,
+		ExpiresAt:        now.Add(s.ttl),
+		LockedUntil:      now.Add(idempotencyLock),
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return nil, ErrIdempotentRequestInProgress
+		}
+		log.Error("Failed to reserve idempotency key", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	if stored.RequestHash != params.RequestHash {
+		log.Warn("Idempotency key reused with a different request body")
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !stored.Completed() {
+		log.Warn("Idempotent request retried while still in progress")
+		return nil, ErrIdempotentRequestInProgress
+	}
+
+	log.Info("Replaying stored response for idempotency key", slog.Int("status_code", stored.StatusCode))
+
+	return stored, nil
+}
+
+func (s *idempotencyService) CompleteRequest(ctx context.Context, params CompleteIdempotentRequestParams) error {
+	err := s.repo.Complete(ctx, &repositories.CompleteIdempotencyKeyParams{
+		IdempotencyKeyID: toIdempotencyKeyID(params.IdempotentRequest),
+		StatusCode:       params.StatusCode,
+		ResponseHeaders:  params.ResponseHeaders,
+		ResponseBody:     params.ResponseBody,
+	})
+	if err != nil {
+		s.log.Error("Failed to store response for idempotency key", slog.String("user_id", params.UserID), slog.Any("error", err))
+		return ErrInternalServer
+	}
+	return nil
+}
+
+func (s *idempotencyService) ReleaseRequest(ctx context.Context, req IdempotentRequest) error {
+	if err := s.repo.Release(ctx, toIdempotencyKeyID(req)); err != nil {
+		s.log.Error("Failed to release idempotency key", slog.String("user_id", req.UserID), slog.Any("error", err))
+		return ErrInternalServer
+	}
+	return nil
+}
+
+func (s *idempotencyService) PurgeExpired(ctx context.Context) (int, error) {
+	n, err := s.repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		s.log.Error("Failed to purge expired idempotency keys", slog.Any("error", err))
+		return 0, ErrInternalServer
+	}
+
+	s.log.Info("Expired idempotency keys purged", slog.Int("keys", n))
+
+	return n, nil
+}
+
+func toIdempotencyKeyID(req IdempotentRequest) repositories.IdempotencyKeyID {
+	return repositories.IdempotencyKeyID{
+		UserID: req.UserID,
+		Key:    req.Key,
+		Method: req.Method,
+		Path:   req.Path,
+	}
+}
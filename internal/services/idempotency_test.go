@@ -0,0 +1,152 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+type mockIdempotencyKeyRepository struct {
+	ReserveFunc       func(ctx context.Context, params *repositories.ReserveIdempotencyKeyParams) (*models.IdempotencyKey, bool, error)
+	CompleteFunc      func(ctx context.Context, params *repositories.CompleteIdempotencyKeyParams) error
+	ReleaseFunc       func(ctx context.Context, id repositories.IdempotencyKeyID) error
+	DeleteExpiredFunc func(ctx context.Context, before time.Time) (int, error)
+}
+
+func (m *mockIdempotencyKeyRepository) Reserve(ctx context.Context, params *repositories.ReserveIdempotencyKeyParams) (*models.IdempotencyKey, bool, error) {
+	return m.ReserveFunc(ctx, params)
+}
+
+func (m *mockIdempotencyKeyRepository) Complete(ctx context.Context, params *repositories.CompleteIdempotencyKeyParams) error {
+	return m.CompleteFunc(ctx, params)
+}
+
+func (m *mockIdempotencyKeyRepository) Release(ctx context.Context, id repositories.IdempotencyKeyID) error {
+	return m.ReleaseFunc(ctx, id)
+}
+
+func (m *mockIdempotencyKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return m.DeleteExpiredFunc(ctx, before)
+}
+
+func TestIdempotencyService_BeginRequest(t *testing.T) {
+	params := services.BeginIdempotentRequestParams{
+		IdempotentRequest: services.IdempotentRequest{
+			UserID: "user-001",
+			Key:    "key-001",
+			Method: "POST",
+			Path:   "/organizations",
+		},
+		RequestHash: "hash-a",
+	}
+
+	tests := []struct {
+		name         string
+		params       func() services.BeginIdempotentRequestParams
+		stored       *models.IdempotencyKey
+		reserved     bool
+		repoErr      error
+		expectReplay bool
+		expectedErr  error
+	}{
+		{
+			name:     "new key is reserved",
+			reserved: true,
+		},
+		{
+			name:         "completed request is replayed",
+			stored:       &models.IdempotencyKey{RequestHash: "hash-a", StatusCode: 201},
+			expectReplay: true,
+		},
+		{
+			name:        "different body is rejected",
+			stored:      &models.IdempotencyKey{RequestHash: "hash-b", StatusCode: 201},
+			expectedErr: services.ErrIdempotencyKeyReused,
+		},
+		{
+			name:        "request still in progress",
+			stored:      &models.IdempotencyKey{RequestHash: "hash-a"},
+			expectedErr: services.ErrIdempotentRequestInProgress,
+		},
+		{
+			name:        "key released concurrently",
+			repoErr:     repositories.ErrConflict,
+			expectedErr: services.ErrIdempotentRequestInProgress,
+		},
+		{
+			name:        "repository failure",
+			repoErr:     errors.New("db down"),
+			expectedErr: services.ErrInternalServer,
+		},
+		{
+			name: "key too long",
+			params: func() services.BeginIdempotentRequestParams {
+				p := params
+				p.Key = strings.Repeat("k", 256)
+				return p
+			},
+			expectedErr: services.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockIdempotencyKeyRepository{
+				ReserveFunc: func(ctx context.Context, p *repositories.ReserveIdempotencyKeyParams) (*models.IdempotencyKey, bool, error) {
+					require.Equal(t, params.RequestHash, p.RequestHash)
+					require.WithinDuration(t, time.Now().Add(time.Hour), p.ExpiresAt, time.Minute)
+					require.True(t, p.LockedUntil.After(time.Now()) && p.LockedUntil.Before(p.ExpiresAt))
+					return tt.stored, tt.reserved, tt.repoErr
+				},
+			}
+			s := services.NewIdempotencyService(repo, time.Hour, logger.NewTestLogger(t))
+
+			p := params
+			if tt.params != nil {
+				p = tt.params()
+			}
+			replay, err := s.BeginRequest(context.Background(), p)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectReplay {
+				require.Equal(t, tt.stored, replay)
+			} else {
+				require.Nil(t, replay)
+			}
+		})
+	}
+}
+
+func TestIdempotencyService_PurgeExpired(t *testing.T) {
+	repo := &mockIdempotencyKeyRepository{
+		DeleteExpiredFunc: func(ctx context.Context, before time.Time) (int, error) {
+			require.WithinDuration(t, time.Now(), before, time.Minute)
+			return 3, nil
+		},
+	}
+	s := services.NewIdempotencyService(repo, time.Hour, logger.NewTestLogger(t))
+
+	n, err := s.PurgeExpired(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	repo.DeleteExpiredFunc = func(ctx context.Context, before time.Time) (int, error) {
+		return 0, errors.New("db down")
+	}
+	_, err = s.PurgeExpired(context.Background())
+
+	require.ErrorIs(t, err, services.ErrInternalServer)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+)
+
+type jobService struct {
+	repo      repositories.JobRepository
+	operators map[string]bool
+	log       *slog.Logger
+}
+
+// NewJobService creates a service for the users in operatorIDs to inspect
+// and retry background jobs.
+func NewJobService(repo repositories.JobRepository, operatorIDs []string, log *slog.Logger) *jobService {
+	operators := make(map[string]bool, len(operatorIDs))
+	for _, id := range operatorIDs {
+		operators[id] = true
+	}
+	return &jobService{
+		repo:      repo,
+		operators: operators,
+		log:       log.With(slog.String("component", "job_service")),
+	}
+}
+
+var _ JobService = (*jobService)(nil)
+
+func (s *jobService) ListJobs(ctx context.Context, params ListJobsParams) ([]*models.Job, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID))
+
+	if err := s.authorize(log, params.ActingUserID); err != nil {
+		return nil, err
+	}
+	if params.Status != "" && !models.JobStatuses[params.Status] {
+		log.Warn("Invalid job status filter", slog.String("status", string(params.Status)))
+		return nil, NewValidationError("status", "must be one of pending, running, succeeded or dead")
+	}
+
+	jobs, err := s.repo.List(ctx, &repositories.ListJobsParams{
+		Status: params.Status,
+		Kind:   params.Kind,
+		Limit:  MaxListedJobs,
+	})
+	if err != nil {
+		log.Error("Failed to list jobs", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return jobs, nil
+}
+
+func (s *jobService) GetJob(ctx context.Context, params JobParams) (*models.Job, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("job_id", params.JobID))
+
+	if err := s.authorizeJob(log, params); err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.GetByID(ctx, params.JobID)
+	if err != nil {
+		return nil, mapJobError(log, err, "Failed to retrieve job")
+	}
+
+	return job, nil
+}
+
+func (s *jobService) RetryJob(ctx context.Context, params JobParams) (*models.Job, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("job_id", params.JobID))
+
+	if err := s.authorizeJob(log, params); err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.Retry(ctx, params.JobID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			log.Warn("Job is not dead and cannot be retried")
+			return nil, ErrJobNotRetryable
+		}
+		return nil, mapJobError(log, err, "Failed to retry job")
+	}
+
+	log.Info("Job queued for retry", slog.String("kind", job.Kind))
+
+	return job, nil
+}
+
+func (s *jobService) authorize(log *slog.Logger, userID string) error {
+	if !s.operators[userID] {
+		log.Warn("Job access denied, user is not an operator")
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *jobService) authorizeJob(log *slog.Logger, params JobParams) error {
+	if err := s.authorize(log, params.ActingUserID); err != nil {
+		return err
+	}
+	if err := uuid.Validate(params.JobID); err != nil {
+		log.Warn("Invalid job ID provided")
+		return NewValidationError("jobID", "must be a valid UUID")
+	}
+	return nil
+}
+
+func mapJobError(log *slog.Logger, err error, msg string) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		log.Warn("Job not found")
+		return ErrJobNotFound
+	}
+	log.Error(msg, slog.Any("error", err))
+	return ErrInternalServer
+}
@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockJobRepository struct {
+	GetByIDFunc func(ctx context.Context, id string) (*models.Job, error)
+	ListFunc    func(ctx context.Context, params *repositories.ListJobsParams) ([]*models.Job, error)
+	RetryFunc   func(ctx context.Context, id string) (*models.Job, error)
+}
+
+func (m *mockJobRepository) Enqueue(ctx context.Context, params *repositories.EnqueueJobParams) (*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockJobRepository) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockJobRepository) Complete(ctx context.Context, id string, attempt int) error {
+	return nil
+}
+
+func (m *mockJobRepository) Fail(ctx context.Context, params *repositories.FailJobParams) error {
+	return nil
+}
+
+func (m *mockJobRepository) GetByID(ctx context.Context, id string) (*models.Job, error) {
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockJobRepository) List(ctx context.Context, params *repositories.ListJobsParams) ([]*models.Job, error) {
+	return m.ListFunc(ctx, params)
+}
+
+func (m *mockJobRepository) Retry(ctx context.Context, id string) (*models.Job, error) {
+	return m.RetryFunc(ctx, id)
+}
+
+func (m *mockJobRepository) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func TestJobService(t *testing.T) {
+	ctx := context.Background()
+	operatorID := uuid.New().String()
+	jobID := uuid.New().String()
+
+	repo := &mockJobRepository{
+		ListFunc: func(ctx context.Context, params *repositories.ListJobsParams) ([]*models.Job, error) {
+			assert.Equal(t, models.JobDead, params.Status)
+			assert.Equal(t, services.MaxListedJobs, params.Limit)
+			return []*models.Job{{ID: jobID, Status: models.JobDead}}, nil
+		},
+		GetByIDFunc: func(ctx context.Context, id string) (*models.Job, error) {
+			return nil, repositories.ErrNotFound
+		},
+	}
+	service := services.NewJobService(repo, []string{operatorID}, logger.NewTestLogger(t))
+
+	t.Run("lists jobs for operators", func(t *testing.T) {
+		jobs, err := service.ListJobs(ctx, services.ListJobsParams{ActingUserID: operatorID, Status: models.JobDead})
+
+		require.NoError(t, err)
+		assert.Len(t, jobs, 1)
+	})
+
+	t.Run("forbids other users", func(t *testing.T) {
+		_, err := service.ListJobs(ctx, services.ListJobsParams{ActingUserID: uuid.New().String()})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+
+		_, err = service.RetryJob(ctx, services.JobParams{ActingUserID: uuid.New().String(), JobID: jobID})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+
+	t.Run("rejects an unknown status filter", func(t *testing.T) {
+		_, err := service.ListJobs(ctx, services.ListJobsParams{ActingUserID: operatorID, Status: "exploded"})
+
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+
+	t.Run("maps a missing job", func(t *testing.T) {
+		_, err := service.GetJob(ctx, services.JobParams{ActingUserID: operatorID, JobID: jobID})
+
+		assert.ErrorIs(t, err, services.ErrJobNotFound)
+	})
+
+	t.Run("only retries dead jobs", func(t *testing.T) {
+		repo.RetryFunc = func(ctx context.Context, id string) (*models.Job, error) {
+			return nil, repositories.ErrConflict
+		}
+
+		_, err := service.RetryJob(ctx, services.JobParams{ActingUserID: operatorID, JobID: jobID})
+
+		assert.ErrorIs(t, err, services.ErrJobNotRetryable)
+	})
+
+	t.Run("retries a dead job", func(t *testing.T) {
+		repo.RetryFunc = func(ctx context.Context, id string) (*models.Job, error) {
+			assert.Equal(t, jobID, id)
+			return &models.Job{ID: id, Status: models.JobPending}, nil
+		}
+
+		job, err := service.RetryJob(ctx, services.JobParams{ActingUserID: operatorID, JobID: jobID})
+
+		require.NoError(t, err)
+		assert.Equal(t, models.JobPending, job.Status)
+	})
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // Validate time zones on hosts without a zoneinfo database.
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/google/uuid"
+)
+
+const maxLocationNameLength = 100
+
+type locationService struct {
+	repo          repositories.LocationRepository
+	accessService AccessService
+	log           *slog.Logger
+}
+
+// NewLocationService creates a service that manages the locations of
+// organizations.
+func NewLocationService(repo repositories.LocationRepository, accessService AccessService, log *slog.Logger) *locationService {
+	return &locationService{
+		repo:          repo,
+		accessService: accessService,
+		log:           log.With(slog.String("component", "location_service")),
+	}
+}
+
+var _ LocationService = (*locationService)(nil)
+
+func (s *locationService) CreateLocation(ctx context.Context, params CreateLocationParams) (*models.Location, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsAdmin(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to create location, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	details, err := normalizeLocationDetails(params.LocationDetails)
+	if err != nil {
+		log.Warn("Invalid input for location", slog.Any("error", err))
+		return nil, err
+	}
+
+	location, err := s.repo.Create(ctx, &repositories.CreateLocationParams{
+		OrgID:        params.OrgID,
+		Name:         details.Name,
+		Address:      details.Address,
+		TimeZone:     details.TimeZone,
+		OpeningHours: details.OpeningHours,
+	})
+	if err != nil {
+		return nil, mapLocationError(log, err, "Failed to create location")
+	}
+
+	log.Info("Location created successfully", slog.String("location_id", location.ID))
+
+	return location, nil
+}
+
+func (s *locationService) ListLocations(ctx context.Context, params ListLocationsParams) ([]*models.Location, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to list locations, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	locations, err := s.repo.ListByOrganizationID(ctx, params.OrgID)
+	if err != nil {
+		log.Error("Failed to list locations", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return locations, nil
+}
+
+func (s *locationService) GetLocation(ctx context.Context, params LocationParams) (*models.Location, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("location_id", params.LocationID))
+
+	if err := s.authorizeLocation(ctx, log, params, s.accessService.IsMember); err != nil {
+		return nil, err
+	}
+
+	location, err := s.repo.GetByID(ctx, params.OrgID, params.LocationID)
+	if err != nil {
+		return nil, mapLocationError(log, err, "Failed to retrieve location")
+	}
+
+	return location, nil
+}
+
+func (s *locationService) UpdateLocation(ctx context.Context, params UpdateLocationParams) (*models.Location, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.String("location_id", params.LocationID),
+		slog.Int("version", params.Version),
+	)
+
+	if err := s.authorizeLocation(ctx, log, params.LocationParams, s.accessService.IsAdmin); err != nil {
+		return nil, err
+	}
+
+	details, err := normalizeLocationDetails(params.LocationDetails)
+	if err != nil {
+		log.Warn("Invalid input for location update", slog.Any("error", err))
+		return nil, err
+	}
+
+	location, err := s.repo.Update(ctx, &repositories.UpdateLocationParams{
+		OrgID:        params.OrgID,
+		ID:           params.LocationID,
+		Name:         details.Name,
+		Address:      details.Address,
+		TimeZone:     details.TimeZone,
+		OpeningHours: details.OpeningHours,
+		Version:      params.Version,
+	})
+	if err != nil {
+		return nil, mapLocationError(log, err, "Failed to update location")
+	}
+
+	log.Info("Location updated successfully", slog.Int("new_version", location.Version))
+
+	return location, nil
+}
+
+func (s *locationService) DeleteLocation(ctx context.Context, params DeleteLocationParams) error {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.String("location_id", params.LocationID),
+		slog.Int("version", params.Version),
+	)
+
+	if err := s.authorizeLocation(ctx, log, params.LocationParams, s.accessService.IsAdmin); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, params.OrgID, params.LocationID, params.Version); err != nil {
+		return mapLocationError(log, err, "Failed to delete location")
+	}
+
+	log.Info("Location deleted successfully")
+
+	return nil
+}
+
+func (s *locationService) authorizeLocation(ctx context.Context, log *slog.Logger, params LocationParams, check func(context.Context, OrgAccessParams) error) error {
+	if err := check(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Location access denied, probably due to insufficient permissions", slog.Any("error", err))
+		return err
+	}
+	if err := uuid.Validate(params.LocationID); err != nil {
+		log.Warn("Invalid location ID provided")
+		return NewValidationError("locationID", "must be a valid UUID")
+	}
+	return nil
+}
+
+// normalizeLocationDetails validates a location and returns it with
+// surrounding whitespace trimmed, the country in upper case and the
+// opening hours sorted by day and time.
+func normalizeLocationDetails(details LocationDetails) (LocationDetails, error) {
+	details.Name = strings.TrimSpace(details.Name)
+	details.Address.Street = strings.TrimSpace(details.Address.Street)
+	details.Address.PostalCode = strings.TrimSpace(details.Address.PostalCode)
+	details.Address.City = strings.TrimSpace(details.Address.City)
+	details.Address.Country = strings.ToUpper(strings.TrimSpace(details.Address.Country))
+
+	validationErr := &ValidationError{}
+	if details.Name == "" || len(details.Name) > maxLocationNameLength {
+		validationErr.Add("name", fmt.Sprintf("must be 1 to %d characters", maxLocationNameLength))
+	}
+	if details.Address.Street == "" {
+		validationErr.Add("address.street", "is required")
+	}
+	if details.Address.City == "" {
+		validationErr.Add("address.city", "is required")
+	}
+	if !isCountryCode(details.Address.Country) {
+		validationErr.Add("address.country", "must be an ISO 3166-1 alpha-2 country code")
+	}
+	if _, err := time.LoadLocation(details.TimeZone); err != nil || details.TimeZone == "" || details.TimeZone == "Local" {
+		validationErr.Add("time_zone", "must be an IANA time zone name, such as Europe/Oslo")
+	}
+
+	hours := make([]models.OpeningHours, len(details.OpeningHours))
+	copy(hours, details.OpeningHours)
+	for _, h := range hours {
+		opens, okOpens := parseClock(h.Opens)
+		closes, okCloses := parseClock(h.Closes)
+		switch {
+		case !models.Weekdays[h.Day]:
+			validationErr.Add("opening_hours", "contains unknown day "+string(h.Day))
+		case !okOpens || !okCloses || opens == 24*60:
+			validationErr.Add("opening_hours", "times must be HH:MM, from 00:00 to 24:00")
+		case closes <= opens:
+			validationErr.Add("opening_hours", fmt.Sprintf("period on %s must close after it opens", h.Day))
+		}
+	}
+	if err := validationErr.Err(); err != nil {
+		return details, err
+	}
+
+	// Fixed-width clock times sort correctly as strings.
+	sort.SliceStable(hours, func(i, j int) bool {
+		if hours[i].Day != hours[j].Day {
+			return weekdayIndex(hours[i].Day) < weekdayIndex(hours[j].Day)
+		}
+		return hours[i].Opens < hours[j].Opens
+	})
+	for i := 1; i < len(hours); i++ {
+		if hours[i].Day == hours[i-1].Day && hours[i].Opens < hours[i-1].Closes {
+			return details, NewValidationError("opening_hours", "periods on "+string(hours[i].Day)+" overlap")
+		}
+	}
+	details.OpeningHours = hours
+
+	return details, nil
+}
+
+// parseClock parses an "HH:MM" time of day into minutes since midnight.
+// "24:00" is accepted as the end of the day.
+func parseClock(s string) (int, bool) {
+	if s == "24:00" {
+		return 24 * 60, true
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil || len(s) != len("15:04") {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func weekdayIndex(day models.Weekday) int {
+	switch day {
+	case models.Monday:
+		return 0
+	case models.Tuesday:
+		return 1
+	case models.Wednesday:
+		return 2
+	case models.Thursday:
+		return 3
+	case models.Friday:
+		return 4
+	case models.Saturday:
+		return 5
+	default:
+		return 6
+	}
+}
+
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+func mapLocationError(log *slog.Logger, err error, msg string) error {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		log.Warn("Location not found")
+		return ErrLocationNotFound
+	case errors.Is(err, repositories.ErrVersionMismatch):
+		log.Warn("Location was modified concurrently")
+		return ErrVersionMismatch
+	case errors.Is(err, repositories.ErrConflict):
+		log.Warn("Location name is already taken")
+		return ErrLocationNameTaken
+	}
+	log.Error(msg, slog.Any("error", err))
+	return ErrInternalServer
+}
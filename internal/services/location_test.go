@@ -0,0 +1,195 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLocationRepository struct {
+	CreateFunc func(ctx context.Context, params *repositories.CreateLocationParams) (*models.Location, error)
+	UpdateFunc func(ctx context.Context, params *repositories.UpdateLocationParams) (*models.Location, error)
+	DeleteFunc func(ctx context.Context, orgID, id string, version int) error
+}
+
+func (m *mockLocationRepository) Create(ctx context.Context, params *repositories.CreateLocationParams) (*models.Location, error) {
+	return m.CreateFunc(ctx, params)
+}
+
+func (m *mockLocationRepository) GetByID(ctx context.Context, orgID, id string) (*models.Location, error) {
+	return nil, repositories.ErrNotFound
+}
+
+func (m *mockLocationRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Location, error) {
+	return nil, nil
+}
+
+func (m *mockLocationRepository) Update(ctx context.Context, params *repositories.UpdateLocationParams) (*models.Location, error) {
+	return m.UpdateFunc(ctx, params)
+}
+
+func (m *mockLocationRepository) Delete(ctx context.Context, orgID, id string, version int) error {
+	return m.DeleteFunc(ctx, orgID, id, version)
+}
+
+func validLocationDetails() services.LocationDetails {
+	return services.LocationDetails{
+		Name:     "Oslo depot",
+		Address:  models.Address{Street: "Karl Johans gate 1", PostalCode: "0154", City: "Oslo", Country: "NO"},
+		TimeZone: "Europe/Oslo",
+		OpeningHours: []models.OpeningHours{
+			{Day: models.Monday, Opens: "08:00", Closes: "16:00"},
+		},
+	}
+}
+
+func TestLocationService_CreateLocation(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+
+	var created *repositories.CreateLocationParams
+	repo := &mockLocationRepository{
+		CreateFunc: func(ctx context.Context, params *repositories.CreateLocationParams) (*models.Location, error) {
+			if params.Name == "Taken" {
+				return nil, repositories.ErrConflict
+			}
+			created = params
+			return &models.Location{ID: uuid.New().String(), OrgID: params.OrgID, Name: params.Name, Version: 1}, nil
+		},
+	}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != adminUserID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+	}
+	service := services.NewLocationService(repo, accessService, logger.NewTestLogger(t))
+
+	t.Run("normalizes the location", func(t *testing.T) {
+		details := validLocationDetails()
+		details.Name = "  Oslo depot "
+		details.Address.Country = "no"
+		details.OpeningHours = []models.OpeningHours{
+			{Day: models.Saturday, Opens: "10:00", Closes: "14:00"},
+			{Day: models.Monday, Opens: "13:00", Closes: "18:00"},
+			{Day: models.Monday, Opens: "08:00", Closes: "12:00"},
+			{Day: models.Sunday, Opens: "00:00", Closes: "24:00"},
+		}
+
+		_, err := service.CreateLocation(ctx, services.CreateLocationParams{OrgID: orgID, ActingUserID: adminUserID, LocationDetails: details})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Oslo depot", created.Name)
+		assert.Equal(t, "NO", created.Address.Country)
+		assert.Equal(t, []models.OpeningHours{
+			{Day: models.Monday, Opens: "08:00", Closes: "12:00"},
+			{Day: models.Monday, Opens: "13:00", Closes: "18:00"},
+			{Day: models.Saturday, Opens: "10:00", Closes: "14:00"},
+			{Day: models.Sunday, Opens: "00:00", Closes: "24:00"},
+		}, created.OpeningHours)
+	})
+
+	tests := []struct {
+		name      string
+		userID    string
+		modify    func(d *services.LocationDetails)
+		wantErr   error
+		wantField string
+	}{
+		{"not an admin", uuid.New().String(), func(d *services.LocationDetails) {}, services.ErrUnauthorized, ""},
+		{"missing name", adminUserID, func(d *services.LocationDetails) { d.Name = " " }, services.ErrInvalidInput, "name"},
+		{"missing street", adminUserID, func(d *services.LocationDetails) { d.Address.Street = "" }, services.ErrInvalidInput, "address.street"},
+		{"invalid country", adminUserID, func(d *services.LocationDetails) { d.Address.Country = "Norway" }, services.ErrInvalidInput, "address.country"},
+		{"unknown time zone", adminUserID, func(d *services.LocationDetails) { d.TimeZone = "Europe/Atlantis" }, services.ErrInvalidInput, "time_zone"},
+		{"local time zone", adminUserID, func(d *services.LocationDetails) { d.TimeZone = "Local" }, services.ErrInvalidInput, "time_zone"},
+		{"unknown day", adminUserID, func(d *services.LocationDetails) { d.OpeningHours[0].Day = "funday" }, services.ErrInvalidInput, "opening_hours"},
+		{"malformed time", adminUserID, func(d *services.LocationDetails) { d.OpeningHours[0].Opens = "8:00" }, services.ErrInvalidInput, "opening_hours"},
+		{"closes before opening", adminUserID, func(d *services.LocationDetails) { d.OpeningHours[0].Closes = "07:00" }, services.ErrInvalidInput, "opening_hours"},
+		{"overlapping periods", adminUserID, func(d *services.LocationDetails) {
+			d.OpeningHours = append(d.OpeningHours, models.OpeningHours{Day: models.Monday, Opens: "15:00", Closes: "20:00"})
+		}, services.ErrInvalidInput, "opening_hours"},
+		{"duplicate name", adminUserID, func(d *services.LocationDetails) { d.Name = "Taken" }, services.ErrLocationNameTaken, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := validLocationDetails()
+			tt.modify(&details)
+
+			_, err := service.CreateLocation(ctx, services.CreateLocationParams{OrgID: orgID, ActingUserID: tt.userID, LocationDetails: details})
+			require.ErrorIs(t, err, tt.wantErr)
+
+			if tt.wantField != "" {
+				var validationErr *services.ValidationError
+				require.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, tt.wantField, validationErr.Fields[0].Field)
+			}
+		})
+	}
+}
+
+func TestLocationService_UpdateLocation(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+	locationID := uuid.New().String()
+
+	repo := &mockLocationRepository{
+		UpdateFunc: func(ctx context.Context, params *repositories.UpdateLocationParams) (*models.Location, error) {
+			if params.Version != 3 {
+				return nil, repositories.ErrVersionMismatch
+			}
+			return &models.Location{ID: params.ID, Name: params.Name, Version: 4}, nil
+		},
+		DeleteFunc: func(ctx context.Context, orgID, id string, version int) error {
+			return repositories.ErrNotFound
+		},
+	}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error { return nil },
+	}
+	service := services.NewLocationService(repo, accessService, logger.NewTestLogger(t))
+
+	update := func(locationID string, version int) (*models.Location, error) {
+		return service.UpdateLocation(ctx, services.UpdateLocationParams{
+			LocationParams:  services.LocationParams{OrgID: orgID, ActingUserID: adminUserID, LocationID: locationID},
+			LocationDetails: validLocationDetails(),
+			Version:         version,
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		location, err := update(locationID, 3)
+		require.NoError(t, err)
+		assert.Equal(t, 4, location.Version)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		_, err := update(locationID, 2)
+		assert.ErrorIs(t, err, services.ErrVersionMismatch)
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		_, err := update("not-a-uuid", 3)
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+
+	t.Run("delete unknown location", func(t *testing.T) {
+		err := service.DeleteLocation(ctx, services.DeleteLocationParams{
+			LocationParams: services.LocationParams{OrgID: orgID, ActingUserID: adminUserID, LocationID: locationID},
+		})
+		assert.ErrorIs(t, err, services.ErrLocationNotFound)
+	})
+}
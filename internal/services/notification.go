@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/jobs"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/notification"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+// NotificationJobKind is the background job that delivers one notification.
+const NotificationJobKind = "notifications.send"
+
+// NotificationJob is the payload of a NotificationJobKind job.
+type NotificationJob struct {
+	EventID   string                     `json:"event_id"`
+	EventType models.EventType           `json:"event_type"`
+	OrgID     string                     `json:"org_id"`
+	UserID    string                     `json:"user_id"`
+	Channel   models.NotificationChannel `json:"channel"`
+	Data      json.RawMessage            `json:"data"`
+}
+
+// JobEnqueuer queues background jobs. It is implemented by *jobs.Runner.
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, kind string, args any, opts jobs.EnqueueOptions) (*models.Job, error)
+}
+
+type NotificationOptions struct {
+	// BaseURL is the server's public URL, used for unsubscribe links.
+	BaseURL string
+	// UnsubscribeSecret signs unsubscribe links.
+	UnsubscribeSecret string
+}
+
+type notificationService struct {
+	userRepo  repositories.UserRepository
+	orgRepo   repositories.OrganizationRepository
+	jobs      JobEnqueuer
+	templates *notification.Templates
+	sender    notification.Sender
+	opts      NotificationOptions
+	log       *slog.Logger
+}
+
+// NewNotificationService creates a service that turns published events
+// into notification jobs, delivers them and manages user preferences.
+func NewNotificationService(
+	userRepo repositories.UserRepository,
+	orgRepo repositories.OrganizationRepository,
+	jobs JobEnqueuer,
+	templates *notification.Templates,
+	sender notification.Sender,
+	opts NotificationOptions,
+	log *slog.Logger,
+) *notificationService {
+	return &notificationService{
+		userRepo:  userRepo,
+		orgRepo:   orgRepo,
+		jobs:      jobs,
+		templates: templates,
+		sender:    sender,
+		opts:      opts,
+		log:       log.With(slog.String("component", "notification_service")),
+	}
+}
+
+var _ NotificationService = (*notificationService)(nil)
+
+// Publish queues a notification job for everyone the event concerns. It
+// is an outbox sink; the job's unique key makes redelivered events a no-op.
+func (s *notificationService) Publish(ctx context.Context, event models.Event) error {
+	if !s.templates.HasKind(string(event.Type)) {
+		return nil
+	}
+	log := s.log.With(slog.String("event_id", event.ID), slog.String("event_type", string(event.Type)))
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Error("Failed to encode event data", slog.Any("error", err))
+		return err
+	}
+	recipients, err := notificationRecipients(event.Type, data)
+	if err != nil {
+		log.Error("Failed to decode event data", slog.Any("error", err))
+		return err
+	}
+
+	for _, userID := range recipients {
+		for channel := range models.NotificationChannels {
+			_, err := s.jobs.Enqueue(ctx, NotificationJobKind, NotificationJob{
+				EventID:   event.ID,
+				EventType: event.Type,
+				OrgID:     event.OrgID,
+				UserID:    userID,
+				Channel:   channel,
+				Data:      data,
+			}, jobs.EnqueueOptions{
+				UniqueKey: fmt.Sprintf("notification:%s:%s:%s", event.ID, userID, channel),
+			})
+			if err != nil && !errors.Is(err, repositories.ErrConflict) {
+				log.Error("Failed to queue notification", slog.String("user_id", userID), slog.Any("error", err))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// notificationRecipients returns the users to notify about an event.
+func notificationRecipients(eventType models.EventType, data json.RawMessage) ([]string, error) {
+	switch eventType {
+	case models.EventMemberAdded, models.EventMemberRoleChanged, models.EventMemberRemoved:
+		var member models.MemberEventData
+		if err := json.Unmarshal(data, &member); err != nil {
+			return nil, err
+		}
+		return []string{member.UserID}, nil
+	}
+	return nil, nil
+}
+
+// Deliver renders and sends one notification. It is the handler for
+// NotificationJobKind jobs. Users who have turned the channel off since
+// the job was queued are skipped.
+func (s *notificationService) Deliver(ctx context.Context, job NotificationJob) error {
+	log := s.log.With(
+		slog.String("event_id", job.EventID),
+		slog.String("user_id", job.UserID),
+		slog.String("channel", string(job.Channel)),
+	)
+
+	if job.Channel != models.NotificationEmail {
+		return jobs.Permanent(fmt.Errorf("unsupported notification channel %q", job.Channel))
+	}
+
+	user, err := s.userRepo.GetByID(ctx, job.UserID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		log.Info("Skipping notification for deleted user")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.NotificationPreferences.Enabled(job.Channel) {
+		log.Debug("Skipping notification, channel is turned off")
+		return nil
+	}
+
+	org, err := s.orgRepo.GetByID(ctx, job.OrgID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		log.Info("Skipping notification for deleted organization")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var details any = job.Data
+	switch job.EventType {
+	case models.EventMemberAdded, models.EventMemberRoleChanged, models.EventMemberRemoved:
+		var member models.MemberEventData
+		if err := json.Unmarshal(job.Data, &member); err != nil {
+			return jobs.Permanent(err)
+		}
+		details = member
+	}
+
+	unsubscribeURL := s.unsubscribeURL(user.ID, job.Channel)
+	msg, err := s.templates.Render(string(job.EventType), user.NotificationPreferences.Locale, notification.Data{
+		Recipient:      user,
+		Organization:   org,
+		Details:        details,
+		UnsubscribeURL: unsubscribeURL,
+	})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	msg.To = user.Email
+	msg.Headers = map[string]string{
+		// RFC 8058 one-click unsubscribe.
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+
+	if err := s.sender.Send(ctx, msg); err != nil {
+		log.Warn("Failed to send notification", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Notification sent", slog.String("event_type", string(job.EventType)))
+	return nil
+}
+
+func (s *notificationService) unsubscribeURL(userID string, channel models.NotificationChannel) string {
+	token := notification.UnsubscribeToken(s.opts.UnsubscribeSecret, userID, channel)
+	return strings.TrimSuffix(s.opts.BaseURL, "/") + "/notifications/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+func (s *notificationService) GetNotificationPreferences(ctx context.Context, actingUserID string) (*models.NotificationPreferences, error) {
+	log := s.log.With(slog.String("acting_user_id", actingUserID))
+
+	user, err := s.userRepo.GetByID(ctx, actingUserID)
+	if err != nil {
+		return nil, mapNotificationUserError(log, err)
+	}
+
+	return s.effectivePreferences(user.NotificationPreferences), nil
+}
+
+func (s *notificationService) UpdateNotificationPreferences(ctx context.Context, params UpdateNotificationPreferencesParams) (*models.NotificationPreferences, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID))
+
+	validationErr := &ValidationError{}
+	if params.Locale != "" && !s.templates.HasLocale(params.Locale) {
+		validationErr.Add("locale", "is not supported")
+	}
+	for channel := range params.Channels {
+		if !models.NotificationChannels[channel] {
+			validationErr.Add("channels", "contains unknown channel "+string(channel))
+		}
+	}
+	if err := validationErr.Err(); err != nil {
+		log.Warn("Invalid notification preferences", slog.Any("error", err))
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, params.ActingUserID)
+	if err != nil {
+		return nil, mapNotificationUserError(log, err)
+	}
+
+	prefs := user.NotificationPreferences
+	if params.Locale != "" {
+		prefs.Locale = params.Locale
+	}
+	prefs.Channels = mergeChannels(prefs.Channels, params.Channels)
+
+	user, err = s.userRepo.UpdateNotificationPreferences(ctx, user.ID, prefs)
+	if err != nil {
+		return nil, mapNotificationUserError(log, err)
+	}
+
+	log.Info("Notification preferences updated")
+	return s.effectivePreferences(user.NotificationPreferences), nil
+}
+
+func (s *notificationService) Unsubscribe(ctx context.Context, token string) error {
+	userID, channel, err := notification.ParseUnsubscribeToken(s.opts.UnsubscribeSecret, token)
+	if err != nil || !models.NotificationChannels[channel] {
+		s.log.Warn("Invalid unsubscribe token")
+		return NewValidationError("token", "is invalid")
+	}
+	log := s.log.With(slog.String("user_id", userID), slog.String("channel", string(channel)))
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return mapNotificationUserError(log, err)
+	}
+	if !user.NotificationPreferences.Enabled(channel) {
+		return nil
+	}
+
+	prefs := user.NotificationPreferences
+	prefs.Channels = mergeChannels(prefs.Channels, map[models.NotificationChannel]bool{channel: false})
+	if _, err := s.userRepo.UpdateNotificationPreferences(ctx, user.ID, prefs); err != nil {
+		return mapNotificationUserError(log, err)
+	}
+
+	log.Info("User unsubscribed from notifications")
+	return nil
+}
+
+func (s *notificationService) effectivePreferences(prefs models.NotificationPreferences) *models.NotificationPreferences {
+	effective := &models.NotificationPreferences{
+		Locale:   prefs.Locale,
+		Channels: make(map[models.NotificationChannel]bool, len(models.NotificationChannels)),
+	}
+	if effective.Locale == "" {
+		effective.Locale = notification.DefaultLocale
+	}
+	for channel := range models.NotificationChannels {
+		effective.Channels[channel] = prefs.Enabled(channel)
+	}
+	return effective
+}
+
+func mergeChannels(current, changes map[models.NotificationChannel]bool) map[models.NotificationChannel]bool {
+	merged := make(map[models.NotificationChannel]bool, len(current)+len(changes))
+	for channel, enabled := range current {
+		merged[channel] = enabled
+	}
+	for channel, enabled := range changes {
+		merged[channel] = enabled
+	}
+	return merged
+}
+
+func mapNotificationUserError(log *slog.Logger, err error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		log.Warn("User not found")
+		return ErrUserNotFound
+	}
+	log.Error("Failed to access notification preferences", slog.Any("error", err))
+	return ErrInternalServer
+}
@@ -0,0 +1,199 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/jobs"
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/notification"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEnqueuer struct {
+	jobs []services.NotificationJob
+	keys map[string]bool
+}
+
+func (f *fakeEnqueuer) Enqueue(ctx context.Context, kind string, args any, opts jobs.EnqueueOptions) (*models.Job, error) {
+	if f.keys[opts.UniqueKey] {
+		return nil, repositories.ErrConflict
+	}
+	if f.keys == nil {
+		f.keys = map[string]bool{}
+	}
+	f.keys[opts.UniqueKey] = true
+	f.jobs = append(f.jobs, args.(services.NotificationJob))
+	return &models.Job{Kind: kind}, nil
+}
+
+type fakeSender struct {
+	sent []notification.Message
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg notification.Message) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// inMemoryUsers backs mockUserRepository with a single user.
+func inMemoryUsers(user *models.User) *mockUserRepository {
+	return &mockUserRepository{
+		getByIDFunc: func(ctx context.Context, id string) (*models.User, error) {
+			if id != user.ID {
+				return nil, repositories.ErrNotFound
+			}
+			copied := *user
+			return &copied, nil
+		},
+		updateNotificationPreferencesFunc: func(ctx context.Context, id string, prefs models.NotificationPreferences) (*models.User, error) {
+			user.NotificationPreferences = prefs
+			copied := *user
+			return &copied, nil
+		},
+	}
+}
+
+// notifier is the notification service including the methods used by the
+// outbox and the job runner.
+type notifier interface {
+	services.NotificationService
+	Publish(ctx context.Context, event models.Event) error
+	Deliver(ctx context.Context, job services.NotificationJob) error
+}
+
+func newNotificationService(t *testing.T, user *models.User) (notifier, *fakeEnqueuer, *fakeSender) {
+	templates, err := notification.LoadTemplates()
+	require.NoError(t, err)
+
+	orgRepo := &mockOrganizationRepository{
+		GetOrganizationByIDFunc: func(ctx context.Context, id string) (*models.Organization, error) {
+			return &models.Organization{ID: id, Name: "Tool Library"}, nil
+		},
+	}
+	enqueuer := &fakeEnqueuer{}
+	sender := &fakeSender{}
+	service := services.NewNotificationService(inMemoryUsers(user), orgRepo, enqueuer, templates, sender, services.NotificationOptions{
+		BaseURL:           "https://rentals.example/",
+		UnsubscribeSecret: "secret",
+	}, logger.NewTestLogger(t))
+
+	return service, enqueuer, sender
+}
+
+func TestNotificationService_PublishAndDeliver(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: "user-1", Username: "ada", Email: "ada@example.com"}
+	service, enqueuer, sender := newNotificationService(t, user)
+
+	event := models.Event{
+		ID:    "event-1",
+		Type:  models.EventMemberAdded,
+		OrgID: "org-1",
+		// Events read back from the outbox carry raw JSON.
+		Data: json.RawMessage(`{"user_id":"user-1","role":"member"}`),
+	}
+
+	require.NoError(t, service.Publish(ctx, event))
+	require.NoError(t, service.Publish(ctx, event), "redelivered events are not queued twice")
+	require.Len(t, enqueuer.jobs, 1)
+	job := enqueuer.jobs[0]
+	assert.Equal(t, "user-1", job.UserID)
+	assert.Equal(t, models.NotificationEmail, job.Channel)
+
+	require.NoError(t, service.Deliver(ctx, job))
+
+	require.Len(t, sender.sent, 1)
+	msg := sender.sent[0]
+	assert.Equal(t, "ada@example.com", msg.To)
+	assert.Equal(t, "You were added to Tool Library", msg.Subject)
+	assert.Contains(t, msg.Text, "as member")
+	assert.True(t, strings.HasPrefix(msg.Headers["List-Unsubscribe"], "<https://rentals.example/notifications/unsubscribe?token="))
+}
+
+func TestNotificationService_IgnoresEventsWithoutTemplates(t *testing.T) {
+	service, enqueuer, _ := newNotificationService(t, &models.User{ID: "user-1"})
+
+	require.NoError(t, service.Publish(context.Background(), models.Event{ID: "event-1", Type: "booking.teleported"}))
+
+	assert.Empty(t, enqueuer.jobs)
+}
+
+func TestNotificationService_Preferences(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: "user-1", Username: "ada", Email: "ada@example.com"}
+	service, _, sender := newNotificationService(t, user)
+
+	t.Run("defaults to every channel in the default locale", func(t *testing.T) {
+		prefs, err := service.GetNotificationPreferences(ctx, "user-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "en", prefs.Locale)
+		assert.Equal(t, map[models.NotificationChannel]bool{models.NotificationEmail: true}, prefs.Channels)
+	})
+
+	t.Run("rejects unknown locales and channels", func(t *testing.T) {
+		_, err := service.UpdateNotificationPreferences(ctx, services.UpdateNotificationPreferencesParams{
+			ActingUserID: "user-1",
+			Locale:       "xx",
+			Channels:     map[models.NotificationChannel]bool{"pigeon": true},
+		})
+
+		var validationErr *services.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 2)
+	})
+
+	t.Run("sends in the user's locale", func(t *testing.T) {
+		prefs, err := service.UpdateNotificationPreferences(ctx, services.UpdateNotificationPreferencesParams{ActingUserID: "user-1", Locale: "nb"})
+		require.NoError(t, err)
+		assert.Equal(t, "nb", prefs.Locale)
+
+		require.NoError(t, service.Deliver(ctx, services.NotificationJob{
+			EventType: models.EventMemberRemoved, OrgID: "org-1", UserID: "user-1", Channel: models.NotificationEmail,
+			Data: json.RawMessage(`{"user_id":"user-1"}`),
+		}))
+
+		require.NotEmpty(t, sender.sent)
+		assert.Equal(t, "Du er fjernet fra Tool Library", sender.sent[len(sender.sent)-1].Subject)
+	})
+
+	t.Run("unsubscribe link turns the channel off", func(t *testing.T) {
+		require.NoError(t, service.Deliver(ctx, services.NotificationJob{
+			EventType: models.EventMemberAdded, OrgID: "org-1", UserID: "user-1", Channel: models.NotificationEmail,
+			Data: json.RawMessage(`{"user_id":"user-1","role":"admin"}`),
+		}))
+		link := strings.Trim(sender.sent[len(sender.sent)-1].Headers["List-Unsubscribe"], "<>")
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Unsubscribe(ctx, u.Query().Get("token")))
+
+		prefs, err := service.GetNotificationPreferences(ctx, "user-1")
+		require.NoError(t, err)
+		assert.False(t, prefs.Channels[models.NotificationEmail])
+		assert.Equal(t, "nb", prefs.Locale, "other preferences are kept")
+
+		sent := len(sender.sent)
+		require.NoError(t, service.Deliver(ctx, services.NotificationJob{
+			EventType: models.EventMemberAdded, OrgID: "org-1", UserID: "user-1", Channel: models.NotificationEmail,
+			Data: json.RawMessage(`{"user_id":"user-1","role":"admin"}`),
+		}))
+		assert.Len(t, sender.sent, sent, "unsubscribed users are skipped")
+	})
+
+	t.Run("rejects forged unsubscribe tokens", func(t *testing.T) {
+		token := notification.UnsubscribeToken("guessed", "user-1", models.NotificationEmail)
+
+		err := service.Unsubscribe(ctx, token)
+
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+}
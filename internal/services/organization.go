@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/money"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
 )
 
+// DefaultCurrency is the currency of organizations created without one.
+const DefaultCurrency money.Currency = "NOK"
+
 type organizationService struct {
 	orgRepo repositories.OrganizationRepository
 	log *slog.Logger
@@ -37,11 +42,26 @@ func (s *organizationService) CreateOrganization(ctx context.Context, params Cre
 		log.Error("Invalid input: created by is required")
 		return nil, ErrInvalidInput
 	}
+	currency := DefaultCurrency
+	if params.Currency != "" {
+		var err error
+		if currency, err = money.ParseCurrency(params.Currency); err != nil {
+			log.Warn("Invalid input: unsupported currency", slog.String("currency", params.Currency))
+			return nil, NewValidationError("currency", "is not a supported ISO 4217 currency code")
+		}
+	}
+	slug := strings.ToLower(strings.TrimSpace(params.Slug))
+	if slug != "" && !isSlug(slug) {
+		log.Warn("Invalid input: malformed slug", slog.String("slug", params.Slug))
+		return nil, NewValidationError("slug", slugRule)
+	}
 
 	log.Info("Creating new organization")
 
 	newOrganization, err := s.orgRepo.Create(ctx, &repositories.CreateOrganizationParams{
 		Name:      params.Name,
+		Currency:  currency,
+		Slug:      slug,
 		CreatedBy: params.CreatedBy,
 	})
 
@@ -71,6 +91,10 @@ func (s *organizationService) GetOrganizationByID(ctx context.Context, params Ge
 
 	organization, err := s.orgRepo.GetByID(ctx, params.ID)
 	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Warn("Organization not found")
+			return nil, ErrOrganizationNotFound
+		}
 		log.Error("Failed to retrieve organization", slog.Any("error", err))
 		return nil, ErrInternalServer
 	}
@@ -79,3 +103,95 @@ func (s *organizationService) GetOrganizationByID(ctx context.Context, params Ge
 
 	return organization, nil
 }
+
+// UpdateOrganization changes an organization's details if it is still at the
+// expected version. Admin access is enforced by the access middleware.
+func (s *organizationService) UpdateOrganization(ctx context.Context, params UpdateOrganizationParams) (*models.Organization, error) {
+	log := s.log.With(
+		slog.String("org_id", params.ID),
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.Int("version", params.Version),
+	)
+
+	var errs ValidationError
+	if params.ID == "" {
+		errs.Add("id", "is required")
+	}
+	if params.Name == "" {
+		errs.Add("name", "is required")
+	}
+	var currency money.Currency
+	if params.Currency != "" {
+		var err error
+		if currency, err = money.ParseCurrency(params.Currency); err != nil {
+			errs.Add("currency", "is not a supported ISO 4217 currency code")
+		}
+	}
+	slug := strings.ToLower(strings.TrimSpace(params.Slug))
+	if slug != "" && !isSlug(slug) {
+		errs.Add("slug", slugRule)
+	}
+	if err := errs.Err(); err != nil {
+		log.Error("Invalid input for organization update", slog.Any("error", err))
+		return nil, err
+	}
+
+	// A public catalog is reached through the slug, so publishing without
+	// one would make it unreachable.
+	if params.PublicCatalog != nil && *params.PublicCatalog && slug == "" {
+		current, err := s.GetOrganizationByID(ctx, GetOrganizationByIDParams{ID: params.ID})
+		if err != nil {
+			return nil, err
+		}
+		if current.Slug == "" {
+			log.Warn("Invalid input: public catalog without a slug")
+			return nil, NewValidationError("slug", "is required to publish a public catalog")
+		}
+	}
+
+	log.Info("Updating organization")
+
+	organization, err := s.orgRepo.Update(ctx, &repositories.UpdateOrganizationParams{
+		ID:       params.ID,
+		Name:     params.Name,
+		Currency:      currency,
+		Slug:          slug,
+		PublicCatalog: params.PublicCatalog,
+		Version:       params.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrNotFound):
+			log.Warn("Organization not found")
+			return nil, ErrOrganizationNotFound
+		case errors.Is(err, repositories.ErrVersionMismatch):
+			log.Warn("Organization was modified concurrently")
+			return nil, ErrVersionMismatch
+		case errors.Is(err, repositories.ErrConflict):
+			log.Warn("Organization already exists", slog.Any("error", err))
+			return nil, ErrOrganizationWithDuplicateDetailsExists
+		}
+		log.Error("Failed to update organization", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Organization updated successfully", slog.Int("new_version", organization.Version))
+
+	return organization, nil
+}
+
+const slugRule = "must be 3 to 63 lowercase letters, digits and single hyphens, such as oslo-ski-rental"
+
+// isSlug reports whether slug can name an organization in a URL: 3 to 63
+// characters of a-z and 0-9, with single hyphens between them.
+func isSlug(slug string) bool {
+	if len(slug) < 3 || len(slug) > 63 || slug[0] == '-' || slug[len(slug)-1] == '-' || strings.Contains(slug, "--") {
+		return false
+	}
+	for _, r := range slug {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
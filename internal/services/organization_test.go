@@ -6,14 +6,17 @@ import (
 
 	"github.com/espennoreng/go-http-rental-server/internal/logger"
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/money"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
 	"github.com/espennoreng/go-http-rental-server/internal/services"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockOrganizationRepository struct {
-	CreateOrganizationFunc  func(ctx context.Context, input repositories.CreateOrganizationParams) (*models.Organization, error)
-	GetOrganizationByIDFunc func(ctx context.Context, id string) (*models.Organization, error)
+	CreateOrganizationFunc    func(ctx context.Context, input repositories.CreateOrganizationParams) (*models.Organization, error)
+	GetOrganizationByIDFunc   func(ctx context.Context, id string) (*models.Organization, error)
+	GetOrganizationBySlugFunc func(ctx context.Context, slug string) (*models.Organization, error)
+	UpdateOrganizationFunc    func(ctx context.Context, params repositories.UpdateOrganizationParams) (*models.Organization, error)
 }
 
 func (m *mockOrganizationRepository) Create(ctx context.Context, params *repositories.CreateOrganizationParams) (*models.Organization, error) {
@@ -24,6 +27,14 @@ func (m *mockOrganizationRepository) GetByID(ctx context.Context, id string) (*m
 	return m.GetOrganizationByIDFunc(ctx, id)
 }
 
+func (m *mockOrganizationRepository) GetBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	return m.GetOrganizationBySlugFunc(ctx, slug)
+}
+
+func (m *mockOrganizationRepository) Update(ctx context.Context, params *repositories.UpdateOrganizationParams) (*models.Organization, error) {
+	return m.UpdateOrganizationFunc(ctx, *params)
+}
+
 func TestOrganizationService_CreateOrganization(t *testing.T) {
 	mockRepo := &mockOrganizationRepository{
 		CreateOrganizationFunc: func(ctx context.Context, input repositories.CreateOrganizationParams) (*models.Organization, error) {
@@ -45,6 +56,53 @@ func TestOrganizationService_CreateOrganization(t *testing.T) {
 		assert.NotNil(t, org)
 		assert.Equal(t, "Test Organization", org.Name)
 	})
+
+	t.Run("defaults and normalizes the currency", func(t *testing.T) {
+		var currencies []money.Currency
+		mockRepo.CreateOrganizationFunc = func(ctx context.Context, input repositories.CreateOrganizationParams) (*models.Organization, error) {
+			currencies = append(currencies, input.Currency)
+			return &models.Organization{ID: "1", Name: input.Name, Currency: input.Currency}, nil
+		}
+
+		for _, currency := range []string{"", "sek"} {
+			_, err := service.CreateOrganization(context.Background(), services.CreateOrganizationParams{
+				Name:      "Test Organization",
+				Currency:  currency,
+				CreatedBy: "user-001",
+			})
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, []money.Currency{services.DefaultCurrency, "SEK"}, currencies)
+	})
+
+	t.Run("normalizes and validates the slug", func(t *testing.T) {
+		var slug string
+		mockRepo.CreateOrganizationFunc = func(ctx context.Context, input repositories.CreateOrganizationParams) (*models.Organization, error) {
+			slug = input.Slug
+			return &models.Organization{ID: "1", Name: input.Name, Slug: input.Slug}, nil
+		}
+
+		_, err := service.CreateOrganization(context.Background(), services.CreateOrganizationParams{Name: "Test Organization", Slug: " Oslo-Ski-Rental ", CreatedBy: "user-001"})
+		assert.NoError(t, err)
+		assert.Equal(t, "oslo-ski-rental", slug)
+
+		for _, invalid := range []string{"os", "-oslo", "oslo--ski", "oslo_ski", "oslo ski"} {
+			_, err := service.CreateOrganization(context.Background(), services.CreateOrganizationParams{Name: "Test Organization", Slug: invalid, CreatedBy: "user-001"})
+			var validationErr *services.ValidationError
+			assert.ErrorAs(t, err, &validationErr, invalid)
+		}
+	})
+
+	t.Run("unknown currency", func(t *testing.T) {
+		_, err := service.CreateOrganization(context.Background(), services.CreateOrganizationParams{
+			Name:      "Test Organization",
+			Currency:  "NOKK",
+			CreatedBy: "user-001",
+		})
+
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
 }
 func TestOrganizationService_GetOrganizationByID(t *testing.T) {
 	mockRepo := &mockOrganizationRepository{
@@ -62,3 +120,40 @@ func TestOrganizationService_GetOrganizationByID(t *testing.T) {
 		assert.Equal(t, "Test Organization", org.Name)
 	})
 }
+
+func TestOrganizationService_UpdateOrganization_PublicCatalog(t *testing.T) {
+	current := &models.Organization{ID: "1", Name: "Test Organization", Version: 1}
+	mockRepo := &mockOrganizationRepository{
+		GetOrganizationByIDFunc: func(ctx context.Context, id string) (*models.Organization, error) {
+			return current, nil
+		},
+		UpdateOrganizationFunc: func(ctx context.Context, params repositories.UpdateOrganizationParams) (*models.Organization, error) {
+			updated := *current
+			if params.Slug != "" {
+				updated.Slug = params.Slug
+			}
+			if params.PublicCatalog != nil {
+				updated.PublicCatalog = *params.PublicCatalog
+			}
+			updated.Version++
+			return &updated, nil
+		},
+	}
+
+	service := services.NewOrganizationService(mockRepo, logger.NewTestLogger(t))
+	publish := true
+
+	t.Run("needs a slug", func(t *testing.T) {
+		_, err := service.UpdateOrganization(context.Background(), services.UpdateOrganizationParams{ID: "1", Name: "Test Organization", PublicCatalog: &publish})
+
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("with a slug", func(t *testing.T) {
+		org, err := service.UpdateOrganization(context.Background(), services.UpdateOrganizationParams{ID: "1", Name: "Test Organization", Slug: "test-org", PublicCatalog: &publish})
+		assert.NoError(t, err)
+		assert.True(t, org.PublicCatalog)
+		assert.Equal(t, "test-org", org.Slug)
+	})
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
 	"github.com/espennoreng/go-http-rental-server/internal/repositories"
@@ -17,6 +19,8 @@ type organizationUserService struct {
 }
 
 // NewOrganizationUserService initializes a new organizationUserService.
+// Every membership change is stored in the outbox in the same transaction
+// as the change itself.
 func NewOrganizationUserService(orgUserRepo repositories.OrganizationUserRepository, accessService AccessService) *organizationUserService {
 	return &organizationUserService{
 		orgUserRepo:   orgUserRepo,
@@ -47,35 +51,13 @@ func (s *organizationUserService) CreateOrganizationUser(ctx context.Context, pa
 		return nil, err
 	}
 
-	if !models.ValidRoles[params.Role] {
-		log.Error("Invalid role provided for organization user")
-		return nil, ErrInvalidInput
-	}
-
-	if err := uuid.Validate(params.UserID); err != nil || params.UserID == "" {
-		log.Error("Invalid user ID provided for organization user")
-		return nil, ErrInvalidInput
-	}
-
-	if err := uuid.Validate(params.OrgID); err != nil || params.OrgID == "" {
-		log.Error("Invalid organization ID provided for organization user")
-		return nil, ErrInvalidInput
-	}
-
-	log.Info("Creating new organization user")
-
-	newOrgUser, err := s.orgUserRepo.Create(ctx, &repositories.CreateOrganizationUserParams{
-		OrgID:  params.OrgID,
-		UserID: params.UserID,
-		Role:   params.Role,
+	var newOrgUser *models.OrganizationUser
+	err = s.inTransaction(ctx, log, func(tx *organizationUserService) error {
+		newOrgUser, err = tx.addMember(ctx, log, params.OrgID, params.UserID, params.Role)
+		return err
 	})
 	if err != nil {
-		if errors.Is(err, repositories.ErrConflict) {
-			log.Warn("Organization user already exists", slog.Any("error", err))
-			return nil, ErrUserAlreadyHasARoleInOrganization
-		}
-		log.Error("Failed to create organization user", slog.Any("error", err))
-		return nil, ErrInternalServer
+		return nil, err
 	}
 
 	log.Info("Organization user created successfully", slog.String("org_user_id", newOrgUser.ID))
@@ -109,13 +91,55 @@ func (s *organizationUserService) GetUsersByOrganizationID(ctx context.Context,
 	return users, nil
 }
 
-// UpdateRole updates a user's role within an organization.
-func (s *organizationUserService) UpdateUserRole(ctx context.Context, params UpdateUserRoleParams) error {
+// GetOrganizationUser retrieves a single membership within an organization.
+func (s *organizationUserService) GetOrganizationUser(ctx context.Context, params GetOrganizationUserParams) (*models.OrganizationUser, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.String("user_id", params.UserID),
+	)
+
+	err := s.accessService.IsMember(ctx, OrgAccessParams{
+		OrgID:  params.OrgID,
+		UserID: params.ActingUserID,
+	})
+
+	if err != nil {
+		log.Warn("Failed to retrieve organization user, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err := uuid.Validate(params.UserID); err != nil || params.UserID == "" {
+		log.Error("Invalid user ID provided for organization user")
+		return nil, ErrInvalidInput
+	}
+
+	log.Info("Fetching organization user")
+
+	orgUser, err := s.orgUserRepo.GetByID(ctx, params.OrgID, params.UserID)
+	if err != nil {
+		log.Error("Failed to fetch organization user", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+	if orgUser == nil {
+		log.Warn("Organization user not found")
+		return nil, ErrOrganizationUserNotFound
+	}
+
+	log.Info("Organization user retrieved successfully")
+
+	return orgUser, nil
+}
+
+// UpdateRole updates a user's role within an organization if the membership
+// is still at the expected version.
+func (s *organizationUserService) UpdateUserRole(ctx context.Context, params UpdateUserRoleParams) (*models.OrganizationUser, error) {
 	log := s.log.With(
 		slog.String("acting_user_id", params.ActingUserID),
 		slog.String("org_id", params.OrgID),
 		slog.String("user_id", params.UserID),
 		slog.String("role", string(params.Role)),
+		slog.Int("version", params.Version),
 	)
 
 	log.Info("Updating user role in organization")
@@ -127,33 +151,31 @@ func (s *organizationUserService) UpdateUserRole(ctx context.Context, params Upd
 
 	if err != nil {
 		log.Warn("Failed to update user role, probably due to insufficient permissions", slog.Any("error", err))
-		return err
-	}
-
-	if !models.ValidRoles[params.Role] {
-		log.Warn("Invalid role provided for organization user")
-		return ErrInvalidInput
+		return nil, err
 	}
 
-	log.Info("Updating user role in organization")
-
-	err = s.orgUserRepo.UpdateRole(ctx, params.OrgID, params.UserID, params.Role)
+	var orgUser *models.OrganizationUser
+	err = s.inTransaction(ctx, log, func(tx *organizationUserService) error {
+		orgUser, err = tx.changeRole(ctx, log, params.OrgID, params.UserID, params.Role, params.Version)
+		return err
+	})
 	if err != nil {
-		log.Error("Failed to update user role in organization", slog.Any("error", err))
-		return ErrInternalServer
+		return nil, err
 	}
 
-	log.Info("User role updated successfully in organization")
+	log.Info("User role updated successfully in organization", slog.Int("new_version", orgUser.Version))
 
-	return nil
+	return orgUser, nil
 }
 
-// DeleteUserFromOrganization removes a user from an organization.
+// DeleteUserFromOrganization removes a user from an organization if the
+// membership is still at the expected version.
 func (s *organizationUserService) DeleteUserFromOrganization(ctx context.Context, params DeleteOrganizationUserParams) error {
 	log := s.log.With(
 		slog.String("acting_user_id", params.ActingUserID),
 		slog.String("org_id", params.OrgID),
 		slog.String("user_id_to_delete", params.UserIDToDelete),
+		slog.Int("version", params.Version),
 	)
 
 	log.Info("Deleting user from organization")
@@ -168,13 +190,368 @@ func (s *organizationUserService) DeleteUserFromOrganization(ctx context.Context
 		return err
 	}
 
-	err = s.orgUserRepo.Delete(ctx, params.OrgID, params.UserIDToDelete)
+	err = s.inTransaction(ctx, log, func(tx *organizationUserService) error {
+		return tx.removeMember(ctx, log, params.OrgID, params.UserIDToDelete, params.Version)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("User deleted successfully from organization", slog.String("user_id_deleted", params.UserIDToDelete))
+
+	return nil
+}
+
+// BatchOrganizationUsers applies several membership changes for an admin.
+// In atomic mode the operations share one transaction and the first failure
+// is returned as a *BatchOperationError with nothing applied. Otherwise
+// every operation is attempted and its outcome reported in its result.
+func (s *organizationUserService) BatchOrganizationUsers(ctx context.Context, params BatchOrganizationUsersParams) ([]BatchOperationResult, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.Int("operation_count", len(params.Operations)),
+		slog.Bool("atomic", params.Atomic),
+	)
+
+	log.Info("Applying batch of organization user operations")
+
+	err := s.accessService.IsAdmin(ctx, OrgAccessParams{
+		OrgID:  params.OrgID,
+		UserID: params.ActingUserID,
+	})
+
 	if err != nil {
+		log.Warn("Failed to apply batch, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	if len(params.Operations) == 0 || len(params.Operations) > MaxBatchOperations {
+		log.Warn("Invalid number of operations in batch")
+		return nil, NewValidationError("operations", fmt.Sprintf("must contain between 1 and %d operations", MaxBatchOperations))
+	}
+
+	if !params.Atomic {
+		results := make([]BatchOperationResult, len(params.Operations))
+		for i, op := range params.Operations {
+			// Each operation commits on its own, together with its event.
+			err := s.inTransaction(ctx, log, func(tx *organizationUserService) error {
+				results[i] = tx.applyOperation(ctx, log, params.OrgID, op)
+				return results[i].Err
+			})
+			if err != nil {
+				results[i] = BatchOperationResult{Operation: op, Err: err}
+			}
+		}
+		log.Info("Batch of organization user operations applied")
+		return results, nil
+	}
+
+	results := make([]BatchOperationResult, 0, len(params.Operations))
+	err = s.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+		txService := s.withRepo(repo)
+		for i, op := range params.Operations {
+			result := txService.applyOperation(ctx, log, params.OrgID, op)
+			if result.Err != nil {
+				return &BatchOperationError{Index: i, Err: result.Err}
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		var opErr *BatchOperationError
+		if errors.As(err, &opErr) {
+			log.Warn("Batch of organization user operations rolled back", slog.Any("error", err))
+			return nil, err
+		}
+		log.Error("Failed to apply batch of organization user operations", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Batch of organization user operations committed")
+
+	return results, nil
+}
+
+// errRollback discards a transaction that completed without a failure,
+// such as a dry run.
+var errRollback = errors.New("rollback")
+
+// ImportOrganizationUsers adds the rows as members in one transaction. Each
+// row runs in its own savepoint so every rejected row can be reported, and
+// the transaction is only committed if no row was rejected and this is not
+// a dry run.
+func (s *organizationUserService) ImportOrganizationUsers(ctx context.Context, params ImportOrganizationUsersParams) (*ImportReport, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.Int("row_count", len(params.Rows)),
+		slog.Bool("dry_run", params.DryRun),
+	)
+
+	log.Info("Importing organization users")
+
+	err := s.accessService.IsAdmin(ctx, OrgAccessParams{
+		OrgID:  params.OrgID,
+		UserID: params.ActingUserID,
+	})
+
+	if err != nil {
+		log.Warn("Failed to import organization users, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	if len(params.Rows) == 0 || len(params.Rows) > MaxImportRows {
+		log.Warn("Invalid number of rows in import")
+		return nil, NewValidationError("rows", fmt.Sprintf("must contain between 1 and %d rows", MaxImportRows))
+	}
+
+	report := &ImportReport{DryRun: params.DryRun, Rows: len(params.Rows)}
+
+	err = s.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+		for _, row := range params.Rows {
+			rowLog := log.With(slog.Int("line", row.Line))
+			err := repo.WithinTransaction(ctx, func(rowRepo repositories.OrganizationUserRepository) error {
+				_, err := s.withRepo(rowRepo).addMember(ctx, rowLog, params.OrgID, row.UserID, row.Role)
+				return err
+			})
+			if errors.Is(err, ErrInternalServer) {
+				return err
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, importRowErrors(row.Line, err)...)
+				continue
+			}
+			report.Imported++
+		}
+
+		if params.DryRun || len(report.Errors) > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		log.Error("Failed to import organization users", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	if !params.DryRun && len(report.Errors) > 0 {
+		log.Warn("Import rejected", slog.Int("rejected_fields", len(report.Errors)))
+		return nil, &ValidationError{Fields: report.Errors}
+	}
+
+	log.Info("Organization users imported", slog.Int("imported", report.Imported))
+
+	return report, nil
+}
+
+// importRowErrors attributes the error for one imported row to its line.
+func importRowErrors(line int, err error) []FieldError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		fields := make([]FieldError, len(validationErr.Fields))
+		for i, f := range validationErr.Fields {
+			fields[i] = FieldError{Line: line, Field: f.Field, Message: f.Message}
+		}
+		return fields
+	}
+	return []FieldError{{Line: line, Field: "user_id", Message: err.Error()}}
+}
+
+func (s *organizationUserService) ExportOrganizationUsers(ctx context.Context, params ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error {
+	log := s.log.With(slog.String("org_id", params.OrgID), slog.String("acting_user_id", params.ActingUserID))
+
+	err := s.accessService.IsMember(ctx, OrgAccessParams{
+		OrgID:  params.OrgID,
+		UserID: params.ActingUserID,
+	})
+
+	if err != nil {
+		log.Warn("Failed to export organization users, probably due to insufficient permissions", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Exporting organization users")
+
+	var fnErr error
+	err = s.orgUserRepo.StreamUsersByOrganizationID(ctx, params.OrgID, func(user *models.UserWithRole) error {
+		fnErr = fn(user)
+		return fnErr
+	})
+	if err != nil {
+		if fnErr != nil {
+			// The consumer stopped the export, e.g. because the client went away.
+			log.Warn("Export of organization users stopped", slog.Any("error", fnErr))
+			return fnErr
+		}
+		log.Error("Failed to export organization users", slog.Any("error", err))
+		return ErrInternalServer
+	}
+
+	return nil
+}
+
+// withRepo returns a copy of s that uses repo, e.g. one bound to a
+// transaction.
+func (s *organizationUserService) withRepo(repo repositories.OrganizationUserRepository) *organizationUserService {
+	return &organizationUserService{orgUserRepo: repo, accessService: s.accessService, log: s.log}
+}
+
+// inTransaction runs fn with a service bound to a single transaction, so a
+// change and its outbox event are committed together. Errors from fn are
+// returned as is; failing to commit is an internal error.
+func (s *organizationUserService) inTransaction(ctx context.Context, log *slog.Logger, fn func(tx *organizationUserService) error) error {
+	var fnErr error
+	err := s.orgUserRepo.WithinTransaction(ctx, func(repo repositories.OrganizationUserRepository) error {
+		fnErr = fn(s.withRepo(repo))
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		log.Error("Failed to commit organization user change", slog.Any("error", err))
+		return ErrInternalServer
+	}
+	return nil
+}
+
+// recordEvent stores a membership event in the outbox. Callers run it in
+// the transaction of the change so the event is published if and only if
+// the change is committed.
+func (s *organizationUserService) recordEvent(ctx context.Context, log *slog.Logger, eventType models.EventType, orgID string, data models.MemberEventData) error {
+	err := s.orgUserRepo.Outbox().Append(ctx, &models.Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OrgID:      orgID,
+		OccurredAt: time.Now(),
+		Data:       data,
+	})
+	if err != nil {
+		log.Error("Failed to record membership event", slog.String("event_type", string(eventType)), slog.Any("error", err))
+		return ErrInternalServer
+	}
+	return nil
+}
+
+func memberEventData(orgUser *models.OrganizationUser) models.MemberEventData {
+	return models.MemberEventData{UserID: orgUser.UserID, Role: orgUser.Role, Version: orgUser.Version}
+}
+
+func (s *organizationUserService) applyOperation(ctx context.Context, log *slog.Logger, orgID string, op BatchOperation) BatchOperationResult {
+	log = log.With(slog.String("op", string(op.Type)), slog.String("user_id", op.UserID))
+	result := BatchOperationResult{Operation: op}
+
+	switch op.Type {
+	case BatchOperationAdd:
+		result.OrgUser, result.Err = s.addMember(ctx, log, orgID, op.UserID, op.Role)
+	case BatchOperationUpdateRole:
+		result.OrgUser, result.Err = s.changeRole(ctx, log, orgID, op.UserID, op.Role, op.Version)
+	case BatchOperationRemove:
+		result.Err = s.removeMember(ctx, log, orgID, op.UserID, op.Version)
+	default:
+		log.Warn("Unknown batch operation")
+		result.Err = NewValidationError("op", "must be one of add, update_role, remove")
+	}
+
+	return result
+}
+
+// addMember validates and creates a membership. Access checks are the
+// caller's responsibility.
+func (s *organizationUserService) addMember(ctx context.Context, log *slog.Logger, orgID, userID string, role models.Role) (*models.OrganizationUser, error) {
+	if !models.ValidRoles[role] {
+		log.Error("Invalid role provided for organization user")
+		return nil, NewValidationError("role", "must be one of admin, member")
+	}
+
+	if err := uuid.Validate(userID); err != nil || userID == "" {
+		log.Error("Invalid user ID provided for organization user")
+		return nil, NewValidationError("user_id", "must be a valid UUID")
+	}
+
+	if err := uuid.Validate(orgID); err != nil || orgID == "" {
+		log.Error("Invalid organization ID provided for organization user")
+		return nil, NewValidationError("orgID", "must be a valid UUID")
+	}
+
+	log.Info("Creating new organization user")
+
+	newOrgUser, err := s.orgUserRepo.Create(ctx, &repositories.CreateOrganizationUserParams{
+		OrgID:  orgID,
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			log.Warn("Organization user already exists", slog.Any("error", err))
+			return nil, ErrUserAlreadyHasARoleInOrganization
+		}
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Warn("User to add to organization does not exist", slog.Any("error", err))
+			return nil, ErrUserNotFound
+		}
+		log.Error("Failed to create organization user", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	if err := s.recordEvent(ctx, log, models.EventMemberAdded, orgID, memberEventData(newOrgUser)); err != nil {
+		return nil, err
+	}
+
+	return newOrgUser, nil
+}
+
+// changeRole validates and applies a version-guarded role change.
+func (s *organizationUserService) changeRole(ctx context.Context, log *slog.Logger, orgID, userID string, role models.Role, version int) (*models.OrganizationUser, error) {
+	if !models.ValidRoles[role] {
+		log.Warn("Invalid role provided for organization user")
+		return nil, ErrInvalidInput
+	}
+
+	log.Info("Updating user role in organization")
+
+	orgUser, err := s.orgUserRepo.UpdateRole(ctx, orgID, userID, role, version)
+	if err != nil {
+		if mapped := mapVersionedWriteError(err); mapped != nil {
+			log.Warn("Failed to update user role in organization", slog.Any("error", err))
+			return nil, mapped
+		}
+		log.Error("Failed to update user role in organization", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	if err := s.recordEvent(ctx, log, models.EventMemberRoleChanged, orgID, memberEventData(orgUser)); err != nil {
+		return nil, err
+	}
+
+	return orgUser, nil
+}
+
+// removeMember applies a version-guarded membership removal.
+func (s *organizationUserService) removeMember(ctx context.Context, log *slog.Logger, orgID, userID string, version int) error {
+	err := s.orgUserRepo.Delete(ctx, orgID, userID, version)
+	if err != nil {
+		if mapped := mapVersionedWriteError(err); mapped != nil {
+			log.Warn("Failed to delete user from organization", slog.Any("error", err))
+			return mapped
+		}
 		log.Error("Failed to delete user from organization", slog.Any("error", err))
 		return ErrInternalServer
 	}
 
-	log.Info("User deleted successfully from organization", slog.String("user_id_deleted", params.UserIDToDelete))
+	return s.recordEvent(ctx, log, models.EventMemberRemoved, orgID, models.MemberEventData{UserID: userID})
+}
 
+// mapVersionedWriteError translates the repository errors of a
+// version-guarded membership write, returning nil for unexpected errors.
+func mapVersionedWriteError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		return ErrOrganizationUserNotFound
+	case errors.Is(err, repositories.ErrVersionMismatch):
+		return ErrVersionMismatch
+	}
 	return nil
-}
\ No newline at end of file
+}
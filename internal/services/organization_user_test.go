@@ -2,7 +2,9 @@ package services_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/espennoreng/go-http-rental-server/internal/logger"
 	"github.com/espennoreng/go-http-rental-server/internal/models"
@@ -15,10 +17,14 @@ import (
 type mockOrganizationUserRepository struct {
 	CreateOrganizationUserFunc   func(ctx context.Context, input repositories.CreateOrganizationUserParams) (*models.OrganizationUser, error)
 	GetUsersByOrganizationIDFunc func(ctx context.Context, orgID string) ([]*models.UserWithRole, error)
-	UpdateUserRoleFunc           func(ctx context.Context, orgID, userID string, newRole models.Role) error
-	DeleteOrganizationUserFunc   func(ctx context.Context, orgID, userID string) error
+	StreamUsersFunc              func(ctx context.Context, orgID string, fn func(user *models.UserWithRole) error) error
+	UpdateUserRoleFunc           func(ctx context.Context, orgID, userID string, newRole models.Role, version int) (*models.OrganizationUser, error)
+	DeleteOrganizationUserFunc   func(ctx context.Context, orgID, userID string, version int) error
 	GetByIDFunc                  func(ctx context.Context, orgID, userID string) (*models.OrganizationUser, error)
 	AreUsersInSameOrgFunc        func(ctx context.Context, params *repositories.AreUsersInSameOrgParams) (bool, error)
+	// transactions counts calls to WithinTransaction.
+	transactions int
+	outbox       mockOutboxRepository
 }
 
 func (m *mockOrganizationUserRepository) Create(ctx context.Context, input *repositories.CreateOrganizationUserParams) (*models.OrganizationUser, error) {
@@ -29,12 +35,16 @@ func (m *mockOrganizationUserRepository) GetUsersByOrganizationID(ctx context.Co
 	return m.GetUsersByOrganizationIDFunc(ctx, orgID)
 }
 
-func (m *mockOrganizationUserRepository) UpdateRole(ctx context.Context, orgID, userID string, newRole models.Role) error {
-	return m.UpdateUserRoleFunc(ctx, orgID, userID, newRole)
+func (m *mockOrganizationUserRepository) StreamUsersByOrganizationID(ctx context.Context, orgID string, fn func(user *models.UserWithRole) error) error {
+	return m.StreamUsersFunc(ctx, orgID, fn)
 }
 
-func (m *mockOrganizationUserRepository) Delete(ctx context.Context, orgID, userID string) error {
-	return m.DeleteOrganizationUserFunc(ctx, orgID, userID)
+func (m *mockOrganizationUserRepository) UpdateRole(ctx context.Context, orgID, userID string, newRole models.Role, version int) (*models.OrganizationUser, error) {
+	return m.UpdateUserRoleFunc(ctx, orgID, userID, newRole, version)
+}
+
+func (m *mockOrganizationUserRepository) Delete(ctx context.Context, orgID, userID string, version int) error {
+	return m.DeleteOrganizationUserFunc(ctx, orgID, userID, version)
 }
 func (m *mockOrganizationUserRepository) GetByID(ctx context.Context, orgID, userID string) (*models.OrganizationUser, error) {
 	return m.GetByIDFunc(ctx, orgID, userID)
@@ -44,6 +54,65 @@ func (m *mockOrganizationUserRepository) AreUsersInSameOrg(ctx context.Context,
 	return m.AreUsersInSameOrgFunc(ctx, params)
 }
 
+func (m *mockOrganizationUserRepository) WithinTransaction(ctx context.Context, fn func(repo repositories.OrganizationUserRepository) error) error {
+	m.transactions++
+	return fn(m)
+}
+
+func (m *mockOrganizationUserRepository) Outbox() repositories.OutboxRepository {
+	return &m.outbox
+}
+
+// mockOutboxRepository records appended events.
+type mockOutboxRepository struct {
+	events    []models.Event
+	appendErr error
+}
+
+func (m *mockOutboxRepository) Append(ctx context.Context, event *models.Event) error {
+	if m.appendErr != nil {
+		return m.appendErr
+	}
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func (m *mockOutboxRepository) ClaimUnpublished(ctx context.Context, limit int, lease time.Duration) ([]*repositories.OutboxEntry, error) {
+	return nil, nil
+}
+
+func (m *mockOutboxRepository) MarkPublished(ctx context.Context, seq int64) error {
+	return nil
+}
+
+func (m *mockOutboxRepository) RecordFailure(ctx context.Context, seq int64, reason string, nextAttemptAt time.Time) error {
+	return nil
+}
+
+func (m *mockOutboxRepository) GetBySeq(ctx context.Context, seq int64) (*models.Event, error) {
+	return nil, repositories.ErrNotFound
+}
+
+func (m *mockOutboxRepository) ListByOrganizationID(ctx context.Context, orgID string, afterPosition int64, limit int) ([]*models.Event, error) {
+	var events []*models.Event
+	for i := range m.events {
+		if m.events[i].OrgID == orgID && m.events[i].Position > afterPosition && len(events) < limit {
+			events = append(events, &m.events[i])
+		}
+	}
+	return events, nil
+}
+
+func (m *mockOutboxRepository) LatestPosition(ctx context.Context, orgID string) (int64, error) {
+	var latest int64
+	for _, event := range m.events {
+		if event.OrgID == orgID {
+			latest = max(latest, event.Position)
+		}
+	}
+	return latest, nil
+}
+
 type mockAccessService struct {
 	IsAdminFunc  func(ctx context.Context, params services.OrgAccessParams) error
 	IsMemberFunc func(ctx context.Context, params services.OrgAccessParams) error
@@ -57,7 +126,6 @@ func (m *mockAccessService) IsMember(ctx context.Context, params services.OrgAcc
 	return m.IsMemberFunc(ctx, params)
 }
 
-
 func TestOrganizationUserService_Create(t *testing.T) {
 	ctx := context.Background()
 
@@ -119,6 +187,41 @@ func TestOrganizationUserService_Create(t *testing.T) {
 		assert.Equal(t, models.RoleMember, orgUser.Role)
 	})
 
+	t.Run("records member.added in the outbox", func(t *testing.T) {
+		orgID := uuid.New().String()
+		mockRepo.outbox = mockOutboxRepository{}
+
+		_, err := service.CreateOrganizationUser(ctx, services.CreateOrganizationUserParams{
+			ActingUserID: adminUserID,
+			OrgID:        orgID,
+			UserID:       memberUserID,
+			Role:         models.RoleMember,
+		})
+
+		assert.NoError(t, err)
+		if assert.Len(t, mockRepo.outbox.events, 1) {
+			event := mockRepo.outbox.events[0]
+			assert.Equal(t, models.EventMemberAdded, event.Type)
+			assert.Equal(t, orgID, event.OrgID)
+			assert.NotEmpty(t, event.ID)
+			assert.Equal(t, models.MemberEventData{UserID: memberUserID, Role: models.RoleMember}, event.Data)
+		}
+	})
+
+	t.Run("fails if the event cannot be recorded", func(t *testing.T) {
+		mockRepo.outbox = mockOutboxRepository{appendErr: errors.New("outbox unavailable")}
+		defer func() { mockRepo.outbox = mockOutboxRepository{} }()
+
+		_, err := service.CreateOrganizationUser(ctx, services.CreateOrganizationUserParams{
+			ActingUserID: adminUserID,
+			OrgID:        uuid.New().String(),
+			UserID:       memberUserID,
+			Role:         models.RoleMember,
+		})
+
+		assert.ErrorIs(t, err, services.ErrInternalServer)
+	})
+
 	t.Run("invalid organization ID", func(t *testing.T) {
 		_, err := service.CreateOrganizationUser(ctx, services.CreateOrganizationUserParams{
 			ActingUserID: adminUserID,
@@ -271,8 +374,8 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 	ctx := context.Background()
 
 	mockRepo := &mockOrganizationUserRepository{
-		UpdateUserRoleFunc: func(ctx context.Context, orgID string, userID string, newRole models.Role) error {
-			return nil
+		UpdateUserRoleFunc: func(ctx context.Context, orgID string, userID string, newRole models.Role, version int) (*models.OrganizationUser, error) {
+			return &models.OrganizationUser{OrgID: orgID, UserID: userID, Role: newRole, Version: version + 1}, nil
 		},
 		GetByIDFunc: func(ctx context.Context, orgID string, userID string) (*models.OrganizationUser, error) {
 			return &models.OrganizationUser{
@@ -286,7 +389,7 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 	service := services.NewOrganizationUserService(mockRepo, accessService)
 
 	t.Run("successful role update", func(t *testing.T) {
-		err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
+		_, err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
 			OrgID:        uuid.New().String(),
 			ActingUserID: uuid.New().String(),
 			UserID:       uuid.New().String(),
@@ -296,7 +399,7 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 	})
 
 	t.Run("invalid organization ID", func(t *testing.T) {
-		err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
+		_, err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
 			OrgID:        "invalid-id",
 			ActingUserID: uuid.New().String(),
 			UserID:       uuid.New().String(),
@@ -306,7 +409,7 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 	})
 
 	t.Run("invalid user ID", func(t *testing.T) {
-		err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
+		_, err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
 			OrgID:        uuid.New().String(),
 			ActingUserID: "invalid-id",
 			UserID:       uuid.New().String(),
@@ -316,7 +419,7 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 	})
 
 	t.Run("invalid role", func(t *testing.T) {
-		err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
+		_, err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
 			OrgID:        uuid.New().String(),
 			ActingUserID: uuid.New().String(),
 			UserID:       uuid.New().String(),
@@ -329,7 +432,7 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 		mockRepo.GetByIDFunc = func(ctx context.Context, orgID string, userID string) (*models.OrganizationUser, error) {
 			return nil, nil // Simulating that the user is not part of the organization
 		}
-		err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
+		_, err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
 			OrgID:        uuid.New().String(),
 			ActingUserID: uuid.New().String(),
 			UserID:       uuid.New().String(),
@@ -347,7 +450,7 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 				Role:   models.RoleMember,
 			}, nil
 		}
-		err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
+		_, err := service.UpdateUserRole(ctx, services.UpdateUserRoleParams{
 			OrgID:        uuid.New().String(),
 			ActingUserID: uuid.New().String(),
 			UserID:       uuid.New().String(),
@@ -358,3 +461,261 @@ func TestOrganizationUserService_UpdateUserRole(t *testing.T) {
 	})
 
 }
+
+func TestOrganizationUserService_BatchOrganizationUsers(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+	newUserID := uuid.New().String()
+	existingUserID := uuid.New().String()
+
+	newRepo := func() *mockOrganizationUserRepository {
+		return &mockOrganizationUserRepository{
+			CreateOrganizationUserFunc: func(ctx context.Context, input repositories.CreateOrganizationUserParams) (*models.OrganizationUser, error) {
+				if input.UserID == existingUserID {
+					return nil, repositories.ErrConflict
+				}
+				return &models.OrganizationUser{OrgID: input.OrgID, UserID: input.UserID, Role: input.Role, Version: 1}, nil
+			},
+			UpdateUserRoleFunc: func(ctx context.Context, orgID, userID string, newRole models.Role, version int) (*models.OrganizationUser, error) {
+				return &models.OrganizationUser{OrgID: orgID, UserID: userID, Role: newRole, Version: version + 1}, nil
+			},
+			DeleteOrganizationUserFunc: func(ctx context.Context, orgID, userID string, version int) error {
+				return nil
+			},
+		}
+	}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != adminUserID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+	}
+
+	operations := []services.BatchOperation{
+		{Type: services.BatchOperationAdd, UserID: newUserID, Role: models.RoleMember},
+		{Type: services.BatchOperationAdd, UserID: existingUserID, Role: models.RoleMember},
+		{Type: services.BatchOperationRemove, UserID: existingUserID, Version: 2},
+	}
+
+	t.Run("atomic batch stops at the first failure", func(t *testing.T) {
+		repo := newRepo()
+		removed := false
+		repo.DeleteOrganizationUserFunc = func(ctx context.Context, orgID, userID string, version int) error {
+			removed = true
+			return nil
+		}
+		service := services.NewOrganizationUserService(repo, accessService)
+
+		results, err := service.BatchOrganizationUsers(ctx, services.BatchOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Atomic:       true,
+			Operations:   operations,
+		})
+
+		assert.Nil(t, results)
+		var opErr *services.BatchOperationError
+		assert.ErrorAs(t, err, &opErr)
+		assert.Equal(t, 1, opErr.Index)
+		assert.ErrorIs(t, err, services.ErrUserAlreadyHasARoleInOrganization)
+		assert.Equal(t, 1, repo.transactions)
+		assert.False(t, removed, "operations after the failure should not run")
+	})
+
+	t.Run("partial batch reports each outcome", func(t *testing.T) {
+		repo := newRepo()
+		service := services.NewOrganizationUserService(repo, accessService)
+
+		results, err := service.BatchOrganizationUsers(ctx, services.BatchOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Operations:   operations,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 3)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, newUserID, results[0].OrgUser.UserID)
+		assert.ErrorIs(t, results[1].Err, services.ErrUserAlreadyHasARoleInOrganization)
+		assert.NoError(t, results[2].Err)
+		assert.Equal(t, 3, repo.transactions, "each operation should commit on its own")
+		if assert.Len(t, repo.outbox.events, 2) {
+			assert.Equal(t, models.EventMemberAdded, repo.outbox.events[0].Type)
+			assert.Equal(t, models.EventMemberRemoved, repo.outbox.events[1].Type)
+			assert.Equal(t, orgID, repo.outbox.events[0].OrgID)
+		}
+	})
+
+	t.Run("invalid operations are reported per item", func(t *testing.T) {
+		service := services.NewOrganizationUserService(newRepo(), accessService)
+
+		results, err := service.BatchOrganizationUsers(ctx, services.BatchOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Operations: []services.BatchOperation{
+				{Type: services.BatchOperationUpdateRole, UserID: newUserID, Role: "owner"},
+				{Type: "promote", UserID: newUserID},
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.ErrorIs(t, results[0].Err, services.ErrInvalidInput)
+		assert.ErrorIs(t, results[1].Err, services.ErrInvalidInput)
+	})
+
+	t.Run("non-admin is rejected", func(t *testing.T) {
+		service := services.NewOrganizationUserService(newRepo(), accessService)
+
+		_, err := service.BatchOrganizationUsers(ctx, services.BatchOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: uuid.New().String(),
+			Operations:   operations,
+		})
+
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+	})
+
+	t.Run("too many operations", func(t *testing.T) {
+		service := services.NewOrganizationUserService(newRepo(), accessService)
+
+		_, err := service.BatchOrganizationUsers(ctx, services.BatchOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Operations:   make([]services.BatchOperation, services.MaxBatchOperations+1),
+		})
+
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+}
+
+func TestOrganizationUserService_ImportOrganizationUsers(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+	existingUserID := uuid.New().String()
+
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			return nil
+		},
+	}
+	newRepo := func() *mockOrganizationUserRepository {
+		return &mockOrganizationUserRepository{
+			CreateOrganizationUserFunc: func(ctx context.Context, input repositories.CreateOrganizationUserParams) (*models.OrganizationUser, error) {
+				if input.UserID == existingUserID {
+					return nil, repositories.ErrConflict
+				}
+				return &models.OrganizationUser{OrgID: input.OrgID, UserID: input.UserID, Role: input.Role}, nil
+			},
+		}
+	}
+
+	rows := []services.ImportMemberRow{
+		{Line: 2, UserID: uuid.New().String(), Role: models.RoleMember},
+		{Line: 3, UserID: "not-a-uuid", Role: models.RoleMember},
+		{Line: 4, UserID: existingUserID, Role: models.RoleAdmin},
+		{Line: 5, UserID: uuid.New().String(), Role: "owner"},
+	}
+
+	t.Run("dry run reports every rejected row", func(t *testing.T) {
+		repo := newRepo()
+		service := services.NewOrganizationUserService(repo, accessService)
+
+		report, err := service.ImportOrganizationUsers(ctx, services.ImportOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Rows:         rows,
+			DryRun:       true,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, report.Rows)
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, []services.FieldError{
+			{Line: 3, Field: "user_id", Message: "must be a valid UUID"},
+			{Line: 4, Field: "user_id", Message: services.ErrUserAlreadyHasARoleInOrganization.Error()},
+			{Line: 5, Field: "role", Message: "must be one of admin, member"},
+		}, report.Errors)
+		assert.Equal(t, 5, repo.transactions, "one transaction plus a savepoint per row")
+	})
+
+	t.Run("import with rejected rows fails as a whole", func(t *testing.T) {
+		service := services.NewOrganizationUserService(newRepo(), accessService)
+
+		report, err := service.ImportOrganizationUsers(ctx, services.ImportOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Rows:         rows,
+		})
+
+		assert.Nil(t, report)
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 3)
+	})
+
+	t.Run("valid import", func(t *testing.T) {
+		service := services.NewOrganizationUserService(newRepo(), accessService)
+
+		report, err := service.ImportOrganizationUsers(ctx, services.ImportOrganizationUsersParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			Rows:         rows[:1],
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Imported)
+		assert.Empty(t, report.Errors)
+	})
+}
+
+func TestOrganizationUserService_ExportOrganizationUsers(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New().String()
+
+	repo := &mockOrganizationUserRepository{
+		StreamUsersFunc: func(ctx context.Context, orgID string, fn func(user *models.UserWithRole) error) error {
+			for i := 0; i < 3; i++ {
+				if err := fn(&models.UserWithRole{Role: models.RoleMember}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	t.Run("streams members", func(t *testing.T) {
+		service := services.NewOrganizationUserService(repo, &mockAccessService{
+			IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error { return nil },
+		})
+
+		count := 0
+		err := service.ExportOrganizationUsers(ctx, services.ExportOrganizationUsersParams{OrgID: orgID}, func(user *models.UserWithRole) error {
+			count++
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("non-member is rejected", func(t *testing.T) {
+		service := services.NewOrganizationUserService(repo, &mockAccessService{
+			IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+				return services.ErrUserNotPartOfOrganization
+			},
+		})
+
+		err := service.ExportOrganizationUsers(ctx, services.ExportOrganizationUsersParams{OrgID: orgID}, func(user *models.UserWithRole) error {
+			t.Fatal("no member should be streamed")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, services.ErrUserNotPartOfOrganization)
+	})
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+type publicCatalogService struct {
+	orgRepo      repositories.OrganizationRepository
+	locationRepo repositories.LocationRepository
+	log          *slog.Logger
+}
+
+// NewPublicCatalogService creates a service that serves the public
+// catalogs of the organizations that have opted in to one.
+func NewPublicCatalogService(orgRepo repositories.OrganizationRepository, locationRepo repositories.LocationRepository, log *slog.Logger) *publicCatalogService {
+	return &publicCatalogService{
+		orgRepo:      orgRepo,
+		locationRepo: locationRepo,
+		log:          log.With(slog.String("component", "public_catalog_service")),
+	}
+}
+
+var _ PublicCatalogService = (*publicCatalogService)(nil)
+
+func (s *publicCatalogService) GetStorefront(ctx context.Context, params GetStorefrontParams) (*Storefront, error) {
+	slug := strings.ToLower(params.Slug)
+	log := s.log.With(slog.String("slug", slug))
+
+	// Malformed slugs cannot exist, so they need no database round trip.
+	if !isSlug(slug) {
+		log.Debug("Malformed catalog slug")
+		return nil, ErrCatalogNotFound
+	}
+
+	org, err := s.orgRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Debug("No organization with this slug")
+			return nil, ErrCatalogNotFound
+		}
+		log.Error("Failed to retrieve organization by slug", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+	// Organizations that have not opted in look the same as unknown slugs,
+	// so anonymous callers cannot probe which slugs are taken.
+	if !org.PublicCatalog {
+		log.Debug("Organization has no public catalog", slog.String("org_id", org.ID))
+		return nil, ErrCatalogNotFound
+	}
+
+	locations, err := s.locationRepo.ListByOrganizationID(ctx, org.ID)
+	if err != nil {
+		log.Error("Failed to list locations", slog.Any("error", err), slog.String("org_id", org.ID))
+		return nil, ErrInternalServer
+	}
+
+	return &Storefront{Organization: org, Locations: locations}, nil
+}
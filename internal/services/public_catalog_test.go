@@ -0,0 +1,54 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicCatalogService_GetStorefront(t *testing.T) {
+	ctx := context.Background()
+
+	orgs := map[string]*models.Organization{
+		"oslo-ski-rental": {ID: "org-1", Name: "Oslo Ski Rental", Slug: "oslo-ski-rental", PublicCatalog: true},
+		"private-rental":  {ID: "org-2", Name: "Private Rental", Slug: "private-rental"},
+	}
+	var lookups int
+	orgRepo := &mockOrganizationRepository{
+		GetOrganizationBySlugFunc: func(ctx context.Context, slug string) (*models.Organization, error) {
+			lookups++
+			org, ok := orgs[slug]
+			if !ok {
+				return nil, repositories.ErrNotFound
+			}
+			return org, nil
+		},
+	}
+	service := services.NewPublicCatalogService(orgRepo, &mockLocationRepository{}, logger.NewTestLogger(t))
+
+	t.Run("opted in", func(t *testing.T) {
+		storefront, err := service.GetStorefront(ctx, services.GetStorefrontParams{Slug: "Oslo-Ski-Rental"})
+		require.NoError(t, err)
+		assert.Equal(t, "org-1", storefront.Organization.ID)
+	})
+
+	t.Run("unknown and private catalogs look the same", func(t *testing.T) {
+		for _, slug := range []string{"no-such-rental", "private-rental"} {
+			_, err := service.GetStorefront(ctx, services.GetStorefrontParams{Slug: slug})
+			assert.ErrorIs(t, err, services.ErrCatalogNotFound, slug)
+		}
+	})
+
+	t.Run("malformed slugs skip the lookup", func(t *testing.T) {
+		lookups = 0
+		_, err := service.GetStorefront(ctx, services.GetStorefrontParams{Slug: "../etc"})
+		assert.ErrorIs(t, err, services.ErrCatalogNotFound)
+		assert.Zero(t, lookups)
+	})
+}
@@ -2,10 +2,18 @@ package services
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"time"
 
 	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
 )
 
+// AnyVersion can be passed as an expected version to skip the optimistic
+// concurrency check, e.g. for "If-Match: *".
+const AnyVersion = repositories.AnyVersion
+
 type CreateUserParams struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
@@ -23,7 +31,11 @@ type UserService interface {
 }
 
 type CreateOrganizationParams struct {
-	Name      string `json:"name"`
+	Name string `json:"name"`
+	// Currency is an ISO 4217 code; it defaults to DefaultCurrency.
+	Currency string `json:"currency"`
+	// Slug names the organization in public URLs; it is optional.
+	Slug      string `json:"slug"`
 	CreatedBy string `json:"created_by"`
 }
 
@@ -31,9 +43,24 @@ type GetOrganizationByIDParams struct {
 	ID string `json:"id"`
 }
 
+type UpdateOrganizationParams struct {
+	ID           string `json:"id"`
+	ActingUserID string `json:"acting_user_id"`
+	Name         string `json:"name"`
+	// Currency is an ISO 4217 code; it is left unchanged if empty.
+	Currency string `json:"currency"`
+	// Slug is left unchanged if empty.
+	Slug string `json:"slug"`
+	// PublicCatalog opts the organization in to or out of the public
+	// catalog. It is left unchanged if nil, and publishing needs a slug.
+	PublicCatalog *bool `json:"public_catalog"`
+	Version       int   `json:"version"`
+}
+
 type OrganizationService interface {
 	CreateOrganization(ctx context.Context, params CreateOrganizationParams) (*models.Organization, error)
 	GetOrganizationByID(ctx context.Context, params GetOrganizationByIDParams) (*models.Organization, error)
+	UpdateOrganization(ctx context.Context, params UpdateOrganizationParams) (*models.Organization, error)
 }
 
 type CreateOrganizationUserParams struct {
@@ -48,24 +75,109 @@ type GetUsersByOrganizationIDParams struct {
 	ActingUserID string
 }
 
+type GetOrganizationUserParams struct {
+	OrgID        string
+	ActingUserID string
+	UserID       string
+}
+
 type UpdateUserRoleParams struct {
 	OrgID        string
 	ActingUserID string
 	UserID      string
 	Role         models.Role
+	Version      int
 }
 
 type DeleteOrganizationUserParams struct {
 	OrgID          string
 	ActingUserID   string
 	UserIDToDelete string
+	Version        int
+}
+
+// MaxBatchOperations bounds the size of a membership batch.
+const MaxBatchOperations = 100
+
+type BatchOperationType string
+
+const (
+	BatchOperationAdd        BatchOperationType = "add"
+	BatchOperationUpdateRole BatchOperationType = "update_role"
+	BatchOperationRemove     BatchOperationType = "remove"
+)
+
+// BatchOperation is a single membership change. Role is used by add and
+// update_role; Version guards update_role and remove like If-Match does,
+// with AnyVersion skipping the check.
+type BatchOperation struct {
+	Type    BatchOperationType
+	UserID  string
+	Role    models.Role
+	Version int
+}
+
+type BatchOrganizationUsersParams struct {
+	OrgID        string
+	ActingUserID string
+	Atomic       bool
+	Operations   []BatchOperation
+}
+
+// BatchOperationResult is the outcome of one operation. OrgUser is set for
+// successful adds and role updates; Err is set when the operation failed.
+type BatchOperationResult struct {
+	Operation BatchOperation
+	OrgUser   *models.OrganizationUser
+	Err       error
+}
+
+// MaxImportRows bounds the size of a member import.
+const MaxImportRows = 1000
+
+// ImportMemberRow is one row of a member import. Line is its line number in
+// the source file and is used to report errors.
+type ImportMemberRow struct {
+	Line   int
+	UserID string
+	Role   models.Role
+}
+
+type ImportOrganizationUsersParams struct {
+	OrgID        string
+	ActingUserID string
+	Rows         []ImportMemberRow
+	DryRun       bool
+}
+
+// ImportReport summarizes a member import. In a dry run Imported is the
+// number of rows that would have been imported and Errors lists the rows
+// that would have been rejected.
+type ImportReport struct {
+	DryRun   bool
+	Rows     int
+	Imported int
+	Errors   []FieldError
+}
+
+type ExportOrganizationUsersParams struct {
+	OrgID        string
+	ActingUserID string
 }
 
 type OrganizationUserService interface {
 	CreateOrganizationUser(ctx context.Context, params CreateOrganizationUserParams) (*models.OrganizationUser, error)
 	GetUsersByOrganizationID(ctx context.Context, params GetUsersByOrganizationIDParams) ([]*models.UserWithRole, error)
-	UpdateUserRole(ctx context.Context, params UpdateUserRoleParams) error
+	GetOrganizationUser(ctx context.Context, params GetOrganizationUserParams) (*models.OrganizationUser, error)
+	UpdateUserRole(ctx context.Context, params UpdateUserRoleParams) (*models.OrganizationUser, error)
 	DeleteUserFromOrganization(ctx context.Context, params DeleteOrganizationUserParams) error
+	BatchOrganizationUsers(ctx context.Context, params BatchOrganizationUsersParams) ([]BatchOperationResult, error)
+	// ImportOrganizationUsers adds every row as a member, or none of them if
+	// any row is rejected, in which case a *ValidationError lists the rows.
+	ImportOrganizationUsers(ctx context.Context, params ImportOrganizationUsersParams) (*ImportReport, error)
+	// ExportOrganizationUsers calls fn for each member without loading the
+	// whole organization into memory.
+	ExportOrganizationUsers(ctx context.Context, params ExportOrganizationUsersParams, fn func(user *models.UserWithRole) error) error
 }
 
 type OrgAccessParams struct {
@@ -77,3 +189,373 @@ type AccessService interface {
 	IsAdmin(ctx context.Context, params OrgAccessParams) error
 	IsMember(ctx context.Context, params OrgAccessParams) error
 }
+
+// IdempotentRequest identifies a request made with an Idempotency-Key header.
+type IdempotentRequest struct {
+	UserID string
+	Key    string
+	Method string
+	Path   string
+}
+
+type BeginIdempotentRequestParams struct {
+	IdempotentRequest
+	RequestHash string
+}
+
+type CompleteIdempotentRequestParams struct {
+	IdempotentRequest
+	StatusCode      int
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+}
+
+type IdempotencyService interface {
+	// BeginRequest claims the key for a new request and returns nil, or
+	// returns the stored outcome of an earlier request that should be replayed.
+	BeginRequest(ctx context.Context, params BeginIdempotentRequestParams) (*models.IdempotencyKey, error)
+	CompleteRequest(ctx context.Context, params CompleteIdempotentRequestParams) error
+	// ReleaseRequest forgets a claimed key so the request can be retried.
+	ReleaseRequest(ctx context.Context, req IdempotentRequest) error
+	// PurgeExpired deletes keys whose replay window has passed and returns
+	// how many were deleted.
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+type CreateWebhookParams struct {
+	OrgID        string
+	ActingUserID string
+	URL          string
+	Events       []models.EventType
+	Description  string
+}
+
+type ListWebhooksParams struct {
+	OrgID        string
+	ActingUserID string
+}
+
+type WebhookParams struct {
+	OrgID        string
+	ActingUserID string
+	WebhookID    string
+}
+
+type RedeliverWebhookParams struct {
+	OrgID        string
+	ActingUserID string
+	WebhookID    string
+	DeliveryID   string
+}
+
+// MaxListedDeliveries bounds the delivery log returned for a webhook.
+const MaxListedDeliveries = 100
+
+type WebhookService interface {
+	// CreateWebhook registers an endpoint. The returned webhook carries the
+	// signing secret, which is not shown again.
+	CreateWebhook(ctx context.Context, params CreateWebhookParams) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context, params ListWebhooksParams) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, params WebhookParams) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, params WebhookParams) error
+	// ListWebhookDeliveries returns the most recent deliveries, newest first.
+	ListWebhookDeliveries(ctx context.Context, params WebhookParams) ([]*models.WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, params RedeliverWebhookParams) (*models.WebhookDelivery, error)
+}
+
+type ListEventsParams struct {
+	OrgID        string
+	ActingUserID string
+	// AfterPosition is the position of the last event the caller has seen.
+	AfterPosition int64
+}
+
+type SubscribeEventsParams struct {
+	OrgID        string
+	ActingUserID string
+}
+
+// MaxListedEvents bounds the events returned by one ListEvents call.
+const MaxListedEvents = 100
+
+type EventService interface {
+	// ListEvents returns up to MaxListedEvents stored events after
+	// params.AfterPosition, in position order, so a subscriber can catch
+	// up. An event listed after position N is never followed by one with
+	// a lower position.
+	ListEvents(ctx context.Context, params ListEventsParams) ([]*models.Event, error)
+	// SubscribeEvents delivers the organization's events as they are
+	// published until the subscription is closed. Live events may arrive
+	// out of position order, so subscribers should treat them as a signal
+	// to list the events after the last position they handled.
+	SubscribeEvents(ctx context.Context, params SubscribeEventsParams) (*EventSubscription, error)
+}
+
+type ListJobsParams struct {
+	ActingUserID string
+	// Status and Kind filter the jobs when set.
+	Status models.JobStatus
+	Kind   string
+}
+
+type JobParams struct {
+	ActingUserID string
+	JobID        string
+}
+
+// MaxListedJobs bounds the jobs returned by ListJobs.
+const MaxListedJobs = 100
+
+// JobService lets server operators inspect the background job queue. Every
+// method returns ErrForbidden for users that are not operators.
+type JobService interface {
+	// ListJobs returns the most recent jobs, newest first.
+	ListJobs(ctx context.Context, params ListJobsParams) ([]*models.Job, error)
+	GetJob(ctx context.Context, params JobParams) (*models.Job, error)
+	// RetryJob queues a dead job again with a fresh set of attempts.
+	RetryJob(ctx context.Context, params JobParams) (*models.Job, error)
+}
+
+type UpdateNotificationPreferencesParams struct {
+	ActingUserID string
+	Locale       string
+	// Channels turns channels on or off; channels left out are unchanged.
+	Channels map[models.NotificationChannel]bool
+}
+
+// NotificationService manages how users want to be notified. Preferences
+// are returned with the locale and every channel filled in.
+type NotificationService interface {
+	GetNotificationPreferences(ctx context.Context, actingUserID string) (*models.NotificationPreferences, error)
+	UpdateNotificationPreferences(ctx context.Context, params UpdateNotificationPreferencesParams) (*models.NotificationPreferences, error)
+	// Unsubscribe turns off the channel named in an unsubscribe token from
+	// a notification. It needs no signed-in user.
+	Unsubscribe(ctx context.Context, token string) error
+}
+
+type UploadAttachmentParams struct {
+	OrgID        string
+	ActingUserID string
+	Filename     string
+	Content      []byte
+}
+
+type AttachmentParams struct {
+	OrgID        string
+	ActingUserID string
+	AttachmentID string
+}
+
+// AttachmentVariant selects the file a download link points to.
+type AttachmentVariant string
+
+const (
+	AttachmentOriginal  AttachmentVariant = "original"
+	AttachmentThumbnail AttachmentVariant = "thumbnail"
+)
+
+// OpenAttachmentParams carries the parts of a signed download link.
+type OpenAttachmentParams struct {
+	AttachmentID string
+	Variant      AttachmentVariant
+	// Expires is the Unix time after which the link is no longer valid.
+	Expires   int64
+	Signature string
+}
+
+// SignedAttachment is an attachment with download links that are valid
+// until ExpiresAt. ThumbnailURL is empty for files without a thumbnail.
+type SignedAttachment struct {
+	*models.Attachment
+	URL          string
+	ThumbnailURL string
+	ExpiresAt    time.Time
+}
+
+// AttachmentContent is an opened attachment file. The caller must close
+// Body.
+type AttachmentContent struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Body        io.ReadCloser
+}
+
+type AttachmentService interface {
+	// UploadAttachment stores a file of one of AttachmentContentTypes,
+	// making a thumbnail for images.
+	UploadAttachment(ctx context.Context, params UploadAttachmentParams) (*SignedAttachment, error)
+	GetAttachment(ctx context.Context, params AttachmentParams) (*SignedAttachment, error)
+	DeleteAttachment(ctx context.Context, params AttachmentParams) error
+	// OpenAttachment serves a signed download link. It needs no signed-in
+	// user; the signature authorizes the request.
+	OpenAttachment(ctx context.Context, params OpenAttachmentParams) (*AttachmentContent, error)
+}
+
+// LocationDetails are the editable fields of a location.
+type LocationDetails struct {
+	Name         string
+	Address      models.Address
+	TimeZone     string
+	OpeningHours []models.OpeningHours
+}
+
+type CreateLocationParams struct {
+	OrgID        string
+	ActingUserID string
+	LocationDetails
+}
+
+type ListLocationsParams struct {
+	OrgID        string
+	ActingUserID string
+}
+
+type LocationParams struct {
+	OrgID        string
+	ActingUserID string
+	LocationID   string
+}
+
+type UpdateLocationParams struct {
+	LocationParams
+	LocationDetails
+	Version int
+}
+
+type DeleteLocationParams struct {
+	LocationParams
+	Version int
+}
+
+// LocationService manages an organization's locations. Members can read
+// them; only admins can change them.
+type LocationService interface {
+	CreateLocation(ctx context.Context, params CreateLocationParams) (*models.Location, error)
+	ListLocations(ctx context.Context, params ListLocationsParams) ([]*models.Location, error)
+	GetLocation(ctx context.Context, params LocationParams) (*models.Location, error)
+	// UpdateLocation replaces every field of a location.
+	UpdateLocation(ctx context.Context, params UpdateLocationParams) (*models.Location, error)
+	DeleteLocation(ctx context.Context, params DeleteLocationParams) error
+}
+
+type PublishTermsParams struct {
+	OrgID        string
+	ActingUserID string
+	Title        string
+	Body         string
+}
+
+type ListTermsParams struct {
+	OrgID        string
+	ActingUserID string
+}
+
+// TermsParams identifies a version of an organization's terms; Version
+// CurrentTermsVersion means the latest one.
+type TermsParams struct {
+	OrgID        string
+	ActingUserID string
+	Version      int
+}
+
+// CurrentTermsVersion selects an organization's latest terms.
+const CurrentTermsVersion = 0
+
+// AcceptTermsParams records the acting user's acceptance. ContentHash is
+// the hash of the text the user was shown, as returned with the terms,
+// and IPAddress is the address the request came from.
+type AcceptTermsParams struct {
+	TermsParams
+	ContentHash string
+	IPAddress   string
+}
+
+// TermsService manages the versioned rental terms of organizations and
+// records which members accepted them. Admins publish new versions;
+// members read them and accept the current one.
+type TermsService interface {
+	PublishTerms(ctx context.Context, params PublishTermsParams) (*models.Terms, error)
+	ListTerms(ctx context.Context, params ListTermsParams) ([]*models.Terms, error)
+	GetTerms(ctx context.Context, params TermsParams) (*models.Terms, error)
+	// AcceptTerms fails with ErrTermsOutdated unless the version is the
+	// current one and ContentHash matches it. Accepting the same version
+	// again returns the original acceptance.
+	AcceptTerms(ctx context.Context, params AcceptTermsParams) (*models.TermsAcceptance, error)
+	// GetTermsAcceptance returns the acting user's acceptance of a version.
+	GetTermsAcceptance(ctx context.Context, params TermsParams) (*models.TermsAcceptance, error)
+}
+
+// CustomerProfile are the contact details customers manage themselves.
+// Address is optional; leave it as the zero value to give none.
+type CustomerProfile struct {
+	Phone   string
+	Address models.Address
+}
+
+type RegisterCustomerParams struct {
+	OrgID        string
+	ActingUserID string
+	CustomerProfile
+}
+
+// OwnCustomerParams identifies the acting user's own customer record.
+type OwnCustomerParams struct {
+	OrgID        string
+	ActingUserID string
+}
+
+type UpdateOwnCustomerParams struct {
+	OwnCustomerParams
+	CustomerProfile
+	Version int
+}
+
+type ListCustomersParams struct {
+	OrgID        string
+	ActingUserID string
+}
+
+type CustomerParams struct {
+	OrgID        string
+	ActingUserID string
+	CustomerID   string
+}
+
+type SetCustomerVerificationParams struct {
+	CustomerParams
+	Status  models.VerificationStatus
+	Version int
+}
+
+// CustomerService manages the external customers of organizations. Any
+// signed-in user can register as a customer and manage their own profile
+// without becoming a member. Members can read customers; only admins can
+// change their verification status.
+type CustomerService interface {
+	RegisterCustomer(ctx context.Context, params RegisterCustomerParams) (*models.Customer, error)
+	GetOwnCustomer(ctx context.Context, params OwnCustomerParams) (*models.Customer, error)
+	// UpdateOwnCustomer replaces the acting user's profile.
+	UpdateOwnCustomer(ctx context.Context, params UpdateOwnCustomerParams) (*models.Customer, error)
+	ListCustomers(ctx context.Context, params ListCustomersParams) ([]*models.Customer, error)
+	GetCustomer(ctx context.Context, params CustomerParams) (*models.Customer, error)
+	SetCustomerVerification(ctx context.Context, params SetCustomerVerificationParams) (*models.Customer, error)
+}
+
+type GetStorefrontParams struct {
+	Slug string
+}
+
+// Storefront is what an organization shows in its public catalog.
+type Storefront struct {
+	Organization *models.Organization
+	Locations    []*models.Location
+}
+
+// PublicCatalogService serves the public catalogs of organizations to
+// anonymous callers.
+type PublicCatalogService interface {
+	// GetStorefront returns ErrCatalogNotFound both for unknown slugs and
+	// for organizations without a public catalog.
+	GetStorefront(ctx context.Context, params GetStorefrontParams) (*Storefront, error)
+}
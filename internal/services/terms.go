@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+const (
+	maxTermsTitleLength = 200
+	maxTermsBodyLength  = 100_000
+)
+
+type termsService struct {
+	repo          repositories.TermsRepository
+	accessService AccessService
+	log           *slog.Logger
+}
+
+// NewTermsService creates a service that manages the rental terms of
+// organizations and their acceptances.
+func NewTermsService(repo repositories.TermsRepository, accessService AccessService, log *slog.Logger) *termsService {
+	return &termsService{
+		repo:          repo,
+		accessService: accessService,
+		log:           log.With(slog.String("component", "terms_service")),
+	}
+}
+
+var _ TermsService = (*termsService)(nil)
+
+func (s *termsService) PublishTerms(ctx context.Context, params PublishTermsParams) (*models.Terms, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsAdmin(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to publish terms, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	title := strings.TrimSpace(params.Title)
+	validationErr := &ValidationError{}
+	if title == "" || len(title) > maxTermsTitleLength {
+		validationErr.Add("title", fmt.Sprintf("must be 1 to %d characters", maxTermsTitleLength))
+	}
+	if strings.TrimSpace(params.Body) == "" || len(params.Body) > maxTermsBodyLength {
+		validationErr.Add("body", fmt.Sprintf("must be 1 to %d characters", maxTermsBodyLength))
+	}
+	if err := validationErr.Err(); err != nil {
+		log.Warn("Invalid input for terms", slog.Any("error", err))
+		return nil, err
+	}
+
+	terms, err := s.repo.Create(ctx, &repositories.CreateTermsParams{
+		OrgID:       params.OrgID,
+		Title:       title,
+		Body:        params.Body,
+		ContentHash: termsContentHash(params.Body),
+		CreatedBy:   params.ActingUserID,
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Warn("Organization not found")
+			return nil, ErrOrganizationNotFound
+		}
+		log.Error("Failed to publish terms", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Terms published successfully", slog.String("terms_id", terms.ID), slog.Int("version", terms.Version))
+
+	return terms, nil
+}
+
+func (s *termsService) ListTerms(ctx context.Context, params ListTermsParams) ([]*models.Terms, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to list terms, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	versions, err := s.repo.ListByOrganizationID(ctx, params.OrgID)
+	if err != nil {
+		log.Error("Failed to list terms", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return versions, nil
+}
+
+func (s *termsService) GetTerms(ctx context.Context, params TermsParams) (*models.Terms, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.Int("version", params.Version))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to get terms, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	return s.getTerms(ctx, log, params)
+}
+
+func (s *termsService) AcceptTerms(ctx context.Context, params AcceptTermsParams) (*models.TermsAcceptance, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.Int("version", params.Version))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to accept terms, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	validationErr := &ValidationError{}
+	if params.ContentHash == "" {
+		validationErr.Add("content_hash", "is required")
+	}
+	if _, err := netip.ParseAddr(params.IPAddress); err != nil {
+		validationErr.Add("ip_address", "must be an IP address")
+	}
+	if err := validationErr.Err(); err != nil {
+		log.Warn("Invalid input for terms acceptance", slog.Any("error", err))
+		return nil, err
+	}
+
+	current, err := s.getTerms(ctx, log, TermsParams{OrgID: params.OrgID, Version: CurrentTermsVersion})
+	if err != nil {
+		return nil, err
+	}
+	if params.Version != CurrentTermsVersion && params.Version != current.Version {
+		log.Warn("Refusing to accept terms that are not current", slog.Int("current_version", current.Version))
+		return nil, ErrTermsOutdated
+	}
+	if !strings.EqualFold(params.ContentHash, current.ContentHash) {
+		log.Warn("Accepted text does not match the current terms", slog.Int("current_version", current.Version))
+		return nil, ErrTermsOutdated
+	}
+
+	acceptance, err := s.repo.CreateAcceptance(ctx, &repositories.CreateTermsAcceptanceParams{
+		TermsID:     current.ID,
+		UserID:      params.ActingUserID,
+		IPAddress:   params.IPAddress,
+		ContentHash: current.ContentHash,
+	})
+	if errors.Is(err, repositories.ErrConflict) {
+		log.Info("Terms were already accepted")
+		acceptance, err = s.repo.GetAcceptance(ctx, current.ID, params.ActingUserID)
+	}
+	if err != nil {
+		log.Error("Failed to record terms acceptance", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Terms accepted successfully", slog.Int("accepted_version", acceptance.Version))
+
+	return acceptance, nil
+}
+
+func (s *termsService) GetTermsAcceptance(ctx context.Context, params TermsParams) (*models.TermsAcceptance, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.Int("version", params.Version))
+
+	if err := s.accessService.IsMember(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to get terms acceptance, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	terms, err := s.getTerms(ctx, log, params)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptance, err := s.repo.GetAcceptance(ctx, terms.ID, params.ActingUserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Info("Terms have not been accepted")
+			return nil, ErrTermsAcceptanceNotFound
+		}
+		log.Error("Failed to retrieve terms acceptance", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return acceptance, nil
+}
+
+// getTerms loads a version of an organization's terms, or the current
+// one for CurrentTermsVersion.
+func (s *termsService) getTerms(ctx context.Context, log *slog.Logger, params TermsParams) (*models.Terms, error) {
+	var terms *models.Terms
+	var err error
+	switch {
+	case params.Version == CurrentTermsVersion:
+		terms, err = s.repo.GetLatest(ctx, params.OrgID)
+	case params.Version > 0:
+		terms, err = s.repo.GetByVersion(ctx, params.OrgID, params.Version)
+	default:
+		return nil, NewValidationError("version", "must be a positive integer or current")
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Warn("Terms not found")
+			return nil, ErrTermsNotFound
+		}
+		log.Error("Failed to retrieve terms", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+	return terms, nil
+}
+
+// termsContentHash returns the hex SHA-256 of the terms as shown to users.
+func termsContentHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
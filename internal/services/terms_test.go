@@ -0,0 +1,183 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTermsRepository keeps the terms of a single organization in memory.
+type fakeTermsRepository struct {
+	versions    []*models.Terms
+	acceptances map[string]*models.TermsAcceptance
+}
+
+func (f *fakeTermsRepository) Create(ctx context.Context, params *repositories.CreateTermsParams) (*models.Terms, error) {
+	terms := &models.Terms{
+		ID: uuid.New().String(), OrgID: params.OrgID, Version: len(f.versions) + 1,
+		Title: params.Title, Body: params.Body, ContentHash: params.ContentHash, CreatedBy: params.CreatedBy,
+	}
+	f.versions = append(f.versions, terms)
+	return terms, nil
+}
+
+func (f *fakeTermsRepository) GetLatest(ctx context.Context, orgID string) (*models.Terms, error) {
+	if len(f.versions) == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	return f.versions[len(f.versions)-1], nil
+}
+
+func (f *fakeTermsRepository) GetByVersion(ctx context.Context, orgID string, version int) (*models.Terms, error) {
+	if version > len(f.versions) {
+		return nil, repositories.ErrNotFound
+	}
+	return f.versions[version-1], nil
+}
+
+func (f *fakeTermsRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Terms, error) {
+	return f.versions, nil
+}
+
+func (f *fakeTermsRepository) CreateAcceptance(ctx context.Context, params *repositories.CreateTermsAcceptanceParams) (*models.TermsAcceptance, error) {
+	key := params.TermsID + "/" + params.UserID
+	if _, ok := f.acceptances[key]; ok {
+		return nil, repositories.ErrConflict
+	}
+	var terms *models.Terms
+	for _, t := range f.versions {
+		if t.ID == params.TermsID {
+			terms = t
+		}
+	}
+	acceptance := &models.TermsAcceptance{
+		ID: uuid.New().String(), TermsID: terms.ID, OrgID: terms.OrgID, Version: terms.Version,
+		UserID: params.UserID, IPAddress: params.IPAddress, ContentHash: params.ContentHash, AcceptedAt: time.Now(),
+	}
+	f.acceptances[key] = acceptance
+	return acceptance, nil
+}
+
+func (f *fakeTermsRepository) GetAcceptance(ctx context.Context, termsID, userID string) (*models.TermsAcceptance, error) {
+	acceptance, ok := f.acceptances[termsID+"/"+userID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return acceptance, nil
+}
+
+func hashOf(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTermsService(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+	memberUserID := uuid.New().String()
+
+	repo := &fakeTermsRepository{acceptances: map[string]*models.TermsAcceptance{}}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != adminUserID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+		IsMemberFunc: func(ctx context.Context, params services.OrgAccessParams) error { return nil },
+	}
+	service := services.NewTermsService(repo, accessService, logger.NewTestLogger(t))
+
+	current := services.TermsParams{OrgID: orgID, ActingUserID: memberUserID, Version: services.CurrentTermsVersion}
+	accept := func(version int, hash string) (*models.TermsAcceptance, error) {
+		return service.AcceptTerms(ctx, services.AcceptTermsParams{
+			TermsParams: services.TermsParams{OrgID: orgID, ActingUserID: memberUserID, Version: version},
+			ContentHash: hash,
+			IPAddress:   "203.0.113.7",
+		})
+	}
+
+	t.Run("no terms yet", func(t *testing.T) {
+		_, err := service.GetTerms(ctx, current)
+		assert.ErrorIs(t, err, services.ErrTermsNotFound)
+	})
+
+	t.Run("only admins publish", func(t *testing.T) {
+		_, err := service.PublishTerms(ctx, services.PublishTermsParams{OrgID: orgID, ActingUserID: memberUserID, Title: "Terms", Body: "v1"})
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+	})
+
+	t.Run("rejects empty terms", func(t *testing.T) {
+		_, err := service.PublishTerms(ctx, services.PublishTermsParams{OrgID: orgID, ActingUserID: adminUserID, Title: " ", Body: "\n"})
+		var validationErr *services.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 2)
+	})
+
+	v1, err := service.PublishTerms(ctx, services.PublishTermsParams{OrgID: orgID, ActingUserID: adminUserID, Title: " Rental terms ", Body: "Return items clean."})
+	require.NoError(t, err)
+	assert.Equal(t, "Rental terms", v1.Title)
+	assert.Equal(t, hashOf("Return items clean."), v1.ContentHash)
+
+	t.Run("accepts the current terms once", func(t *testing.T) {
+		first, err := accept(services.CurrentTermsVersion, v1.ContentHash)
+		require.NoError(t, err)
+		assert.Equal(t, 1, first.Version)
+		assert.Equal(t, "203.0.113.7", first.IPAddress)
+
+		again, err := accept(1, v1.ContentHash)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, again.ID)
+
+		acceptance, err := service.GetTermsAcceptance(ctx, current)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, acceptance.ID)
+	})
+
+	t.Run("rejects text the user was not shown", func(t *testing.T) {
+		_, err := accept(1, hashOf("Something else."))
+		assert.ErrorIs(t, err, services.ErrTermsOutdated)
+	})
+
+	t.Run("rejects an invalid IP address", func(t *testing.T) {
+		_, err := service.AcceptTerms(ctx, services.AcceptTermsParams{TermsParams: current, ContentHash: v1.ContentHash, IPAddress: "unknown"})
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+
+	v2, err := service.PublishTerms(ctx, services.PublishTermsParams{OrgID: orgID, ActingUserID: adminUserID, Title: "Rental terms", Body: "Return items clean and dry."})
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+
+	t.Run("new version needs a new acceptance", func(t *testing.T) {
+		_, err := service.GetTermsAcceptance(ctx, current)
+		assert.ErrorIs(t, err, services.ErrTermsAcceptanceNotFound)
+
+		_, err = accept(1, v1.ContentHash)
+		assert.ErrorIs(t, err, services.ErrTermsOutdated)
+
+		acceptance, err := accept(services.CurrentTermsVersion, v2.ContentHash)
+		require.NoError(t, err)
+		assert.Equal(t, 2, acceptance.Version)
+	})
+
+	t.Run("older versions stay readable", func(t *testing.T) {
+		terms, err := service.GetTerms(ctx, services.TermsParams{OrgID: orgID, ActingUserID: memberUserID, Version: 1})
+		require.NoError(t, err)
+		assert.Equal(t, v1.ID, terms.ID)
+
+		_, err = service.GetTerms(ctx, services.TermsParams{OrgID: orgID, ActingUserID: memberUserID, Version: 3})
+		assert.ErrorIs(t, err, services.ErrTermsNotFound)
+	})
+}
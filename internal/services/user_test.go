@@ -14,6 +14,7 @@ type mockUserRepository struct {
 	createFunc  func(ctx context.Context, params *repositories.CreateUserParams) (*models.User, error)
 	getByIDFunc func(ctx context.Context, id string) (*models.User, error)
 	findOrCreateByGoogleIDFunc func(ctx context.Context, googleID, email string) (*models.User, error)
+	updateNotificationPreferencesFunc func(ctx context.Context, id string, prefs models.NotificationPreferences) (*models.User, error)
 }
 
 func (m *mockUserRepository) Create(ctx context.Context, params *repositories.CreateUserParams) (*models.User, error) {
@@ -28,6 +29,10 @@ func (m *mockUserRepository) FindOrCreateByGoogleID(ctx context.Context, googleI
 	return m.findOrCreateByGoogleIDFunc(ctx, googleID, email)
 }
 
+func (m *mockUserRepository) UpdateNotificationPreferences(ctx context.Context, id string, prefs models.NotificationPreferences) (*models.User, error) {
+	return m.updateNotificationPreferencesFunc(ctx, id, prefs)
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	t.Run("create user successfully", func(t *testing.T) {
 		repo := &mockUserRepository{
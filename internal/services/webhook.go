@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// webhookSecretPrefix makes leaked secrets easy to recognize.
+const webhookSecretPrefix = "whsec_"
+
+const maxWebhookURLLength = 2048
+
+type webhookService struct {
+	repo          repositories.WebhookRepository
+	accessService AccessService
+	targets       webhook.TargetPolicy
+	log           *slog.Logger
+}
+
+// NewWebhookService creates a service that manages webhooks and queues
+// published events for delivery. Webhook URLs must satisfy targets; the
+// dispatcher checks them again when it connects.
+func NewWebhookService(repo repositories.WebhookRepository, accessService AccessService, targets webhook.TargetPolicy, log *slog.Logger) *webhookService {
+	return &webhookService{
+		repo:          repo,
+		accessService: accessService,
+		targets:       targets,
+		log:           log.With(slog.String("component", "webhook_service")),
+	}
+}
+
+var _ WebhookService = (*webhookService)(nil)
+
+func (s *webhookService) CreateWebhook(ctx context.Context, params CreateWebhookParams) (*models.Webhook, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsAdmin(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to create webhook, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	validationErr := &ValidationError{}
+	if u, err := url.Parse(params.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(params.URL) > maxWebhookURLLength {
+		validationErr.Add("url", "must be an absolute http or https URL")
+	} else if err := s.targets.CheckURL(ctx, u); err != nil {
+		log.Warn("Rejected webhook target", slog.String("url", params.URL), slog.Any("error", err))
+		switch {
+		case errors.Is(err, webhook.ErrInsecureURL):
+			validationErr.Add("url", "must use https")
+		case errors.Is(err, webhook.ErrForbiddenAddress):
+			validationErr.Add("url", "must not point at a loopback, private, shared, link-local, unspecified or multicast address")
+		default:
+			validationErr.Add("url", "must have a host that resolves")
+		}
+	}
+	if len(params.Events) == 0 {
+		validationErr.Add("events", "must contain at least one event type")
+	}
+	seen := make(map[models.EventType]bool, len(params.Events))
+	for _, event := range params.Events {
+		if !models.EventTypes[event] {
+			validationErr.Add("events", "contains unknown event type "+string(event))
+		} else if seen[event] {
+			validationErr.Add("events", "contains duplicate event type "+string(event))
+		}
+		seen[event] = true
+	}
+	if err := validationErr.Err(); err != nil {
+		log.Warn("Invalid input for webhook", slog.Any("error", err))
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		log.Error("Failed to generate webhook secret", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	hook, err := s.repo.Create(ctx, &repositories.CreateWebhookParams{
+		OrgID:       params.OrgID,
+		URL:         params.URL,
+		Secret:      secret,
+		Events:      params.Events,
+		Description: params.Description,
+		CreatedBy:   params.ActingUserID,
+	})
+	if err != nil {
+		log.Error("Failed to create webhook", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Webhook created successfully", slog.String("webhook_id", hook.ID))
+
+	return hook, nil
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context, params ListWebhooksParams) ([]*models.Webhook, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID))
+
+	if err := s.accessService.IsAdmin(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Failed to list webhooks, probably due to insufficient permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	webhooks, err := s.repo.ListByOrganizationID(ctx, params.OrgID)
+	if err != nil {
+		log.Error("Failed to list webhooks", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return webhooks, nil
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, params WebhookParams) (*models.Webhook, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("webhook_id", params.WebhookID))
+
+	if err := s.authorizeWebhook(ctx, log, params); err != nil {
+		return nil, err
+	}
+
+	hook, err := s.repo.GetByID(ctx, params.OrgID, params.WebhookID)
+	if err != nil {
+		return nil, mapWebhookError(log, err, "Failed to retrieve webhook")
+	}
+
+	return hook, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, params WebhookParams) error {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("webhook_id", params.WebhookID))
+
+	if err := s.authorizeWebhook(ctx, log, params); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, params.OrgID, params.WebhookID); err != nil {
+		return mapWebhookError(log, err, "Failed to delete webhook")
+	}
+
+	log.Info("Webhook deleted successfully")
+
+	return nil
+}
+
+func (s *webhookService) ListWebhookDeliveries(ctx context.Context, params WebhookParams) ([]*models.WebhookDelivery, error) {
+	log := s.log.With(slog.String("acting_user_id", params.ActingUserID), slog.String("org_id", params.OrgID), slog.String("webhook_id", params.WebhookID))
+
+	if err := s.authorizeWebhook(ctx, log, params); err != nil {
+		return nil, err
+	}
+
+	// Distinguish an unknown webhook from one without deliveries.
+	if _, err := s.repo.GetByID(ctx, params.OrgID, params.WebhookID); err != nil {
+		return nil, mapWebhookError(log, err, "Failed to retrieve webhook")
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, params.OrgID, params.WebhookID, MaxListedDeliveries)
+	if err != nil {
+		log.Error("Failed to list webhook deliveries", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhook queues a delivery again, whatever its status, with a
+// fresh retry budget.
+func (s *webhookService) RedeliverWebhook(ctx context.Context, params RedeliverWebhookParams) (*models.WebhookDelivery, error) {
+	log := s.log.With(
+		slog.String("acting_user_id", params.ActingUserID),
+		slog.String("org_id", params.OrgID),
+		slog.String("webhook_id", params.WebhookID),
+		slog.String("delivery_id", params.DeliveryID),
+	)
+
+	if err := s.authorizeWebhook(ctx, log, WebhookParams{OrgID: params.OrgID, ActingUserID: params.ActingUserID, WebhookID: params.WebhookID}); err != nil {
+		return nil, err
+	}
+	if err := uuid.Validate(params.DeliveryID); err != nil {
+		log.Warn("Invalid delivery ID provided")
+		return nil, NewValidationError("deliveryID", "must be a valid UUID")
+	}
+
+	delivery, err := s.repo.Redeliver(ctx, params.OrgID, params.WebhookID, params.DeliveryID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Warn("Webhook delivery not found")
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		log.Error("Failed to redeliver webhook delivery", slog.Any("error", err))
+		return nil, ErrInternalServer
+	}
+
+	log.Info("Webhook delivery queued for redelivery")
+
+	return delivery, nil
+}
+
+// webhookPayload is the JSON body sent to webhook endpoints.
+type webhookPayload struct {
+	ID             string           `json:"id"`
+	Type           models.EventType `json:"type"`
+	OrganizationID string           `json:"organization_id"`
+	OccurredAt     time.Time        `json:"occurred_at"`
+	Data           any              `json:"data"`
+}
+
+// Publish queues the event for every webhook of its organization that
+// subscribes to it. Delivery happens asynchronously. Publishing an event
+// twice queues it only once per webhook, so Publish can serve as an
+// at-least-once outbox sink.
+func (s *webhookService) Publish(ctx context.Context, event models.Event) error {
+	log := s.log.With(slog.String("event_id", event.ID), slog.String("event_type", string(event.Type)), slog.String("org_id", event.OrgID))
+
+	payload, err := json.Marshal(webhookPayload{
+		ID:             event.ID,
+		Type:           event.Type,
+		OrganizationID: event.OrgID,
+		OccurredAt:     event.OccurredAt.UTC(),
+		Data:           event.Data,
+	})
+	if err != nil {
+		log.Error("Failed to encode webhook payload", slog.Any("error", err))
+		return ErrInternalServer
+	}
+
+	queued, err := s.repo.EnqueueDeliveries(ctx, &repositories.EnqueueDeliveriesParams{
+		OrgID:     event.OrgID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   payload,
+	})
+	if err != nil {
+		log.Error("Failed to queue webhook deliveries", slog.Any("error", err))
+		return ErrInternalServer
+	}
+
+	log.Debug("Webhook deliveries queued", slog.Int("deliveries", queued))
+
+	return nil
+}
+
+func (s *webhookService) authorizeWebhook(ctx context.Context, log *slog.Logger, params WebhookParams) error {
+	if err := s.accessService.IsAdmin(ctx, OrgAccessParams{OrgID: params.OrgID, UserID: params.ActingUserID}); err != nil {
+		log.Warn("Webhook access denied, probably due to insufficient permissions", slog.Any("error", err))
+		return err
+	}
+	if err := uuid.Validate(params.WebhookID); err != nil {
+		log.Warn("Invalid webhook ID provided")
+		return NewValidationError("webhookID", "must be a valid UUID")
+	}
+	return nil
+}
+
+func mapWebhookError(log *slog.Logger, err error, msg string) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		log.Warn("Webhook not found")
+		return ErrWebhookNotFound
+	}
+	log.Error(msg, slog.Any("error", err))
+	return ErrInternalServer
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(b), nil
+}
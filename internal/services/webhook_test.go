@@ -0,0 +1,273 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/services"
+	"github.com/espennoreng/go-http-rental-server/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWebhookRepository struct {
+	CreateFunc            func(ctx context.Context, params *repositories.CreateWebhookParams) (*models.Webhook, error)
+	GetByIDFunc           func(ctx context.Context, orgID, id string) (*models.Webhook, error)
+	EnqueueDeliveriesFunc func(ctx context.Context, params *repositories.EnqueueDeliveriesParams) (int, error)
+	ListDeliveriesFunc    func(ctx context.Context, orgID, webhookID string, limit int) ([]*models.WebhookDelivery, error)
+	RedeliverFunc         func(ctx context.Context, orgID, webhookID, deliveryID string) (*models.WebhookDelivery, error)
+}
+
+func (m *mockWebhookRepository) Create(ctx context.Context, params *repositories.CreateWebhookParams) (*models.Webhook, error) {
+	return m.CreateFunc(ctx, params)
+}
+
+func (m *mockWebhookRepository) GetByID(ctx context.Context, orgID, id string) (*models.Webhook, error) {
+	return m.GetByIDFunc(ctx, orgID, id)
+}
+
+func (m *mockWebhookRepository) ListByOrganizationID(ctx context.Context, orgID string) ([]*models.Webhook, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) Delete(ctx context.Context, orgID, id string) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) EnqueueDeliveries(ctx context.Context, params *repositories.EnqueueDeliveriesParams) (int, error) {
+	return m.EnqueueDeliveriesFunc(ctx, params)
+}
+
+func (m *mockWebhookRepository) ListDeliveries(ctx context.Context, orgID, webhookID string, limit int) ([]*models.WebhookDelivery, error) {
+	return m.ListDeliveriesFunc(ctx, orgID, webhookID, limit)
+}
+
+func (m *mockWebhookRepository) Redeliver(ctx context.Context, orgID, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	return m.RedeliverFunc(ctx, orgID, webhookID, deliveryID)
+}
+
+func (m *mockWebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*repositories.ClaimedDelivery, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) RecordDeliveryAttempt(ctx context.Context, params *repositories.RecordDeliveryAttemptParams) error {
+	return nil
+}
+
+// fakeResolver resolves hosts from a fixed table, so tests need no DNS.
+type fakeResolver map[string][]netip.Addr
+
+func (f fakeResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs, ok := f[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+var testWebhookTargets = webhook.TargetPolicy{
+	Resolver: fakeResolver{
+		"example.com":      {netip.MustParseAddr("93.184.216.34")},
+		"internal.example": {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.5")},
+	},
+}
+
+func TestWebhookService_CreateWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	adminUserID := uuid.New().String()
+
+	repo := &mockWebhookRepository{
+		CreateFunc: func(ctx context.Context, params *repositories.CreateWebhookParams) (*models.Webhook, error) {
+			return &models.Webhook{ID: uuid.New().String(), OrgID: params.OrgID, URL: params.URL, Secret: params.Secret, Events: params.Events}, nil
+		},
+	}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error {
+			if params.UserID != adminUserID {
+				return services.ErrUnauthorized
+			}
+			return nil
+		},
+	}
+	service := services.NewWebhookService(repo, accessService, testWebhookTargets, logger.NewTestLogger(t))
+
+	t.Run("generates a signing secret", func(t *testing.T) {
+		webhook, err := service.CreateWebhook(ctx, services.CreateWebhookParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			URL:          "https://example.com/hooks",
+			Events:       []models.EventType{models.EventMemberAdded},
+		})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(webhook.Secret, "whsec_"))
+		assert.Len(t, webhook.Secret, len("whsec_")+64)
+	})
+
+	t.Run("rejects invalid URL and events", func(t *testing.T) {
+		_, err := service.CreateWebhook(ctx, services.CreateWebhookParams{
+			OrgID:        orgID,
+			ActingUserID: adminUserID,
+			URL:          "ftp://example.com",
+			Events:       []models.EventType{models.EventMemberAdded, models.EventMemberAdded, "booking.exploded"},
+		})
+
+		var validationErr *services.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 3)
+		assert.Equal(t, "url", validationErr.Fields[0].Field)
+	})
+
+	t.Run("rejects internal targets", func(t *testing.T) {
+		for name, url := range map[string]string{
+			"IPv4 loopback":             "https://127.0.0.1/hooks",
+			"IPv6 loopback":             "https://[::1]/hooks",
+			"IPv4-mapped loopback":      "https://[::ffff:127.0.0.1]/hooks",
+			"10/8 private":              "https://10.1.2.3/hooks",
+			"172.16/12 private":         "https://172.16.0.1/hooks",
+			"192.168/16 private":        "https://192.168.1.10/hooks",
+			"IPv6 unique local":         "https://[fd00::1]/hooks",
+			"metadata link-local":       "http://169.254.169.254/latest/meta-data/",
+			"IPv6 link-local":           "https://[fe80::1]/hooks",
+			"IPv4 unspecified":          "https://0.0.0.0/hooks",
+			"IPv6 unspecified":          "https://[::]/hooks",
+			"IPv4 multicast":            "https://224.0.0.1/hooks",
+			"IPv6 multicast":            "https://[ff02::1]/hooks",
+			"host with private address": "https://internal.example/hooks",
+		} {
+			_, err := service.CreateWebhook(ctx, services.CreateWebhookParams{
+				OrgID:        orgID,
+				ActingUserID: adminUserID,
+				URL:          url,
+				Events:       []models.EventType{models.EventMemberAdded},
+			})
+
+			var validationErr *services.ValidationError
+			if assert.ErrorAs(t, err, &validationErr, name) {
+				assert.Equal(t, "url", validationErr.Fields[0].Field, name)
+			}
+		}
+	})
+
+	t.Run("requires https and a resolvable host", func(t *testing.T) {
+		for _, url := range []string{"http://example.com/hooks", "https://unknown.example/hooks"} {
+			_, err := service.CreateWebhook(ctx, services.CreateWebhookParams{
+				OrgID:        orgID,
+				ActingUserID: adminUserID,
+				URL:          url,
+				Events:       []models.EventType{models.EventMemberAdded},
+			})
+
+			var validationErr *services.ValidationError
+			assert.ErrorAs(t, err, &validationErr, url)
+		}
+	})
+
+	t.Run("non-admin is rejected", func(t *testing.T) {
+		_, err := service.CreateWebhook(ctx, services.CreateWebhookParams{
+			OrgID:        orgID,
+			ActingUserID: uuid.New().String(),
+			URL:          "https://example.com/hooks",
+			Events:       []models.EventType{models.EventMemberAdded},
+		})
+
+		assert.ErrorIs(t, err, services.ErrUnauthorized)
+	})
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	var enqueued *repositories.EnqueueDeliveriesParams
+	repo := &mockWebhookRepository{
+		EnqueueDeliveriesFunc: func(ctx context.Context, params *repositories.EnqueueDeliveriesParams) (int, error) {
+			enqueued = params
+			return 1, nil
+		},
+	}
+	service := services.NewWebhookService(repo, &mockAccessService{}, testWebhookTargets, logger.NewTestLogger(t))
+
+	event := models.Event{
+		ID:         uuid.New().String(),
+		Type:       models.EventMemberRoleChanged,
+		OrgID:      uuid.New().String(),
+		OccurredAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Data:       models.MemberEventData{UserID: "u1", Role: models.RoleAdmin, Version: 2},
+	}
+
+	err := service.Publish(context.Background(), event)
+
+	require.NoError(t, err)
+	require.NotNil(t, enqueued)
+	assert.Equal(t, event.ID, enqueued.EventID)
+	assert.Equal(t, event.Type, enqueued.EventType)
+	assert.JSONEq(t, `{
+		"id": "`+event.ID+`",
+		"type": "member.role_changed",
+		"organization_id": "`+event.OrgID+`",
+		"occurred_at": "2025-03-01T12:00:00Z",
+		"data": {"user_id": "u1", "role": "admin", "version": 2}
+	}`, string(enqueued.Payload))
+	assert.True(t, json.Valid(enqueued.Payload))
+}
+
+func TestWebhookService_Deliveries(t *testing.T) {
+	ctx := context.Background()
+
+	orgID := uuid.New().String()
+	webhookID := uuid.New().String()
+
+	repo := &mockWebhookRepository{
+		GetByIDFunc: func(ctx context.Context, orgID, id string) (*models.Webhook, error) {
+			if id != webhookID {
+				return nil, repositories.ErrNotFound
+			}
+			return &models.Webhook{ID: id, OrgID: orgID}, nil
+		},
+		ListDeliveriesFunc: func(ctx context.Context, orgID, webhookID string, limit int) ([]*models.WebhookDelivery, error) {
+			assert.Equal(t, services.MaxListedDeliveries, limit)
+			return []*models.WebhookDelivery{{ID: uuid.New().String(), WebhookID: webhookID}}, nil
+		},
+		RedeliverFunc: func(ctx context.Context, orgID, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+			return nil, repositories.ErrNotFound
+		},
+	}
+	accessService := &mockAccessService{
+		IsAdminFunc: func(ctx context.Context, params services.OrgAccessParams) error { return nil },
+	}
+	service := services.NewWebhookService(repo, accessService, testWebhookTargets, logger.NewTestLogger(t))
+
+	t.Run("lists deliveries", func(t *testing.T) {
+		deliveries, err := service.ListWebhookDeliveries(ctx, services.WebhookParams{OrgID: orgID, WebhookID: webhookID})
+
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+	})
+
+	t.Run("unknown webhook", func(t *testing.T) {
+		_, err := service.ListWebhookDeliveries(ctx, services.WebhookParams{OrgID: orgID, WebhookID: uuid.New().String()})
+
+		assert.ErrorIs(t, err, services.ErrWebhookNotFound)
+	})
+
+	t.Run("redeliver unknown delivery", func(t *testing.T) {
+		_, err := service.RedeliverWebhook(ctx, services.RedeliverWebhookParams{OrgID: orgID, WebhookID: webhookID, DeliveryID: uuid.New().String()})
+
+		assert.ErrorIs(t, err, services.ErrWebhookDeliveryNotFound)
+	})
+
+	t.Run("redeliver with invalid delivery ID", func(t *testing.T) {
+		_, err := service.RedeliverWebhook(ctx, services.RedeliverWebhookParams{OrgID: orgID, WebhookID: webhookID, DeliveryID: "nope"})
+
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+}
@@ -0,0 +1,46 @@
+// Package storage keeps blobs such as uploaded files outside the database.
+// Keys are slash-separated paths chosen by the caller; stores do not
+// interpret them beyond that.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobInfo describes a stored blob.
+type BlobInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// BlobStore stores and retrieves blobs by key.
+type BlobStore interface {
+	// Put stores size bytes from r under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key. The caller must close it. It
+	// returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidateKey rejects keys that could escape a store's namespace, such as
+// absolute paths or ".." segments.
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
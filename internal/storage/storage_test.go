@@ -0,0 +1,142 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlobStore checks the behaviour every BlobStore must have.
+func testBlobStore(t *testing.T, store storage.BlobStore) {
+	ctx := context.Background()
+	key := "org-1/attachments/photo.jpg"
+
+	put := func(body, contentType string) {
+		t.Helper()
+		require.NoError(t, store.Put(ctx, key, strings.NewReader(body), int64(len(body)), contentType))
+	}
+	get := func() (string, *storage.BlobInfo) {
+		t.Helper()
+		r, info, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		defer r.Close()
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(body), info
+	}
+
+	t.Run("stores and reads back a blob", func(t *testing.T) {
+		put("first", "image/jpeg")
+
+		body, info := get()
+		assert.Equal(t, "first", body)
+		assert.Equal(t, &storage.BlobInfo{Size: 5, ContentType: "image/jpeg"}, info)
+	})
+
+	t.Run("replaces existing blobs", func(t *testing.T) {
+		put("second version", "image/png")
+
+		body, info := get()
+		assert.Equal(t, "second version", body)
+		assert.Equal(t, "image/png", info.ContentType)
+	})
+
+	t.Run("deletes blobs", func(t *testing.T) {
+		require.NoError(t, store.Delete(ctx, key))
+
+		_, _, err := store.Get(ctx, key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		assert.NoError(t, store.Delete(ctx, key), "deleting a missing blob is not an error")
+	})
+
+	t.Run("rejects keys outside the store", func(t *testing.T) {
+		for _, key := range []string{"", "/etc/passwd", "a/../../b", "a//b", "a/"} {
+			err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain")
+			assert.Error(t, err, key)
+		}
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+// fakeS3 is a path-style S3 stand-in that keeps objects in memory and
+// requires requests to be signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio/") || !strings.Contains(auth, "/eu-north-1/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "rentals" {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Write(obj.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: map[string]fakeObject{}})
+	t.Cleanup(server.Close)
+
+	store, err := storage.NewS3Store(storage.S3Options{
+		Endpoint:        server.URL,
+		Region:          "eu-north-1",
+		Bucket:          "rentals",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+	})
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+
+	t.Run("reports S3 errors", func(t *testing.T) {
+		denied, err := storage.NewS3Store(storage.S3Options{Endpoint: server.URL, Region: "eu-north-1", Bucket: "rentals", AccessKeyID: "other"})
+		require.NoError(t, err)
+
+		err = denied.Put(context.Background(), "a", strings.NewReader("x"), 1, "text/plain")
+		assert.ErrorContains(t, err, "AccessDenied")
+	})
+}
@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/backoff"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+)
+
+const userAgent = "go-http-rental-server-webhooks/1.0"
+
+// maxErrorBodyBytes bounds how much of a failed response is kept in the
+// delivery log.
+const maxErrorBodyBytes = 512
+
+// Options tunes the dispatcher. Zero values are replaced by the defaults
+// from DefaultOptions.
+type Options struct {
+	// PollInterval is how often the queue is checked for due deliveries.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of deliveries claimed per poll.
+	BatchSize int
+	// Timeout bounds a single HTTP attempt.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts before a delivery is marked failed.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failed attempt. It doubles
+	// with every further attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Targets limits where deliveries may be sent. The zero value allows
+	// only https URLs on public addresses.
+	Targets TargetPolicy
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.PollInterval <= 0 {
+		o.PollInterval = d.PollInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = d.BatchSize
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = d.Timeout
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = d.MaxAttempts
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = d.BaseBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = d.MaxBackoff
+	}
+	return o
+}
+
+// Dispatcher sends queued deliveries to their webhooks and records the
+// outcome. Several dispatchers may share a queue.
+type Dispatcher struct {
+	repo   repositories.WebhookRepository
+	client *http.Client
+	opts   Options
+	log    *slog.Logger
+	now    func() time.Time
+}
+
+// NewDispatcher creates a dispatcher. Redirects are not followed, so a
+// webhook must point at its final URL. Every connection is checked
+// against opts.Targets after DNS resolution, and no proxy is used, so the
+// check applies to the address actually dialed.
+func NewDispatcher(repo repositories.WebhookRepository, opts Options, log *slog.Logger) *Dispatcher {
+	opts = opts.withDefaults()
+	dialer := &net.Dialer{
+		Timeout:   opts.Timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   opts.Targets.dialControl,
+	}
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout: opts.Timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		log:  log.With(slog.String("component", "webhook_dispatcher")),
+		now:  time.Now,
+	}
+}
+
+// Run dispatches due deliveries every poll interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.log.Info("Webhook dispatcher started", slog.Duration("poll_interval", d.opts.PollInterval))
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches come back so a backlog drains
+		// without waiting for the next tick.
+		for {
+			n, err := d.DispatchDue(ctx)
+			if err != nil || n < d.opts.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.log.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims one batch of due deliveries, attempts each and returns
+// how many were attempted.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// The lease must outlast every attempt in the batch, or another
+	// dispatcher could claim a delivery that is still being sent.
+	lease := time.Duration(d.opts.BatchSize)*d.opts.Timeout + time.Minute
+
+	claimed, err := d.repo.ClaimDueDeliveries(ctx, d.opts.BatchSize, lease)
+	if err != nil {
+		d.log.Error("Failed to claim webhook deliveries", slog.Any("error", err))
+		return 0, err
+	}
+
+	for _, c := range claimed {
+		d.deliver(ctx, c)
+	}
+
+	return len(claimed), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, c *repositories.ClaimedDelivery) {
+	delivery := c.Delivery
+	log := d.log.With(
+		slog.String("delivery_id", delivery.ID),
+		slog.String("webhook_id", delivery.WebhookID),
+		slog.String("event_type", string(delivery.EventType)),
+		slog.Int("attempt", delivery.Attempts+1),
+	)
+
+	statusCode, sendErr := d.send(ctx, c)
+
+	params := &repositories.RecordDeliveryAttemptParams{
+		ID:         delivery.ID,
+		Succeeded:  sendErr == nil,
+		StatusCode: statusCode,
+	}
+	if sendErr != nil {
+		params.Error = sendErr.Error()
+		if attempt := delivery.Attempts + 1; attempt < d.opts.MaxAttempts {
+			next := d.now().Add(backoff.Exponential(attempt, d.opts.BaseBackoff, d.opts.MaxBackoff))
+			params.NextAttemptAt = &next
+			log.Warn("Webhook delivery failed, will retry", slog.Any("error", sendErr), slog.Time("next_attempt_at", next))
+		} else {
+			log.Warn("Webhook delivery failed permanently", slog.Any("error", sendErr))
+		}
+	} else {
+		log.Info("Webhook delivered", slog.Int("status_code", statusCode))
+	}
+
+	// Record the outcome even if ctx was cancelled during the attempt.
+	if err := d.repo.RecordDeliveryAttempt(context.WithoutCancel(ctx), params); err != nil {
+		log.Error("Failed to record webhook delivery attempt", slog.Any("error", err))
+	}
+}
+
+// send posts the payload and returns the response status, or zero if no
+// response was received. Only 2xx responses count as delivered.
+func (d *Dispatcher) send(ctx context.Context, c *repositories.ClaimedDelivery) (int, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.opts.Targets.checkScheme(u); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(c.Delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, string(c.Delivery.EventType))
+	req.Header.Set(HeaderDelivery, c.Delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(c.Secret, d.now(), c.Delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(body) > 0 {
+			return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+		}
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
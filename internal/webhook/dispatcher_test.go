@@ -0,0 +1,205 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/logger"
+	"github.com/espennoreng/go-http-rental-server/internal/models"
+	"github.com/espennoreng/go-http-rental-server/internal/repositories"
+	"github.com/espennoreng/go-http-rental-server/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue is an in-memory delivery queue. Embedding the interface keeps
+// the fake to the methods the dispatcher uses.
+type fakeQueue struct {
+	repositories.WebhookRepository
+
+	mu       sync.Mutex
+	pending  []*repositories.ClaimedDelivery
+	recorded []*repositories.RecordDeliveryAttemptParams
+}
+
+func (q *fakeQueue) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*repositories.ClaimedDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limit > len(q.pending) {
+		limit = len(q.pending)
+	}
+	claimed := q.pending[:limit]
+	q.pending = q.pending[limit:]
+	return claimed, nil
+}
+
+func (q *fakeQueue) RecordDeliveryAttempt(ctx context.Context, params *repositories.RecordDeliveryAttemptParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.recorded = append(q.recorded, params)
+	return nil
+}
+
+// loopbackTargets lets the dispatcher reach httptest servers, which listen
+// on plain http on loopback addresses.
+var loopbackTargets = webhook.TargetPolicy{
+	AllowHTTP: true,
+	Allow:     []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")},
+}
+
+func newClaimedDelivery(url string, attempts int) *repositories.ClaimedDelivery {
+	return &repositories.ClaimedDelivery{
+		Delivery: &models.WebhookDelivery{
+			ID:        "delivery-1",
+			WebhookID: "webhook-1",
+			EventType: models.EventMemberAdded,
+			Payload:   []byte(`{"type":"member.added"}`),
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func TestDispatcher_DeliversSignedRequest(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(receiver.URL, 0)}}
+	dispatcher := webhook.NewDispatcher(queue, webhook.Options{Targets: loopbackTargets}, logger.NewTestLogger(t))
+
+	n, err := dispatcher.DispatchDue(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	req := <-requests
+	assert.Equal(t, "member.added", req.header.Get(webhook.HeaderEvent))
+	assert.Equal(t, "delivery-1", req.header.Get(webhook.HeaderDelivery))
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.NoError(t, webhook.Verify("whsec_test", req.header.Get(webhook.HeaderSignature), req.body, time.Now(), time.Minute))
+
+	require.Len(t, queue.recorded, 1)
+	assert.True(t, queue.recorded[0].Succeeded)
+	assert.Equal(t, http.StatusNoContent, queue.recorded[0].StatusCode)
+}
+
+func TestDispatcher_SchedulesRetryOnFailure(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	opts := webhook.Options{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: time.Hour, Targets: loopbackTargets}
+
+	t.Run("retries with backoff", func(t *testing.T) {
+		queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(receiver.URL, 1)}}
+		dispatcher := webhook.NewDispatcher(queue, opts, logger.NewTestLogger(t))
+
+		before := time.Now()
+		_, err := dispatcher.DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, queue.recorded, 1)
+		recorded := queue.recorded[0]
+		assert.False(t, recorded.Succeeded)
+		assert.Equal(t, http.StatusServiceUnavailable, recorded.StatusCode)
+		assert.Contains(t, recorded.Error, "try later")
+		require.NotNil(t, recorded.NextAttemptAt)
+		assert.WithinDuration(t, before.Add(2*time.Minute), *recorded.NextAttemptAt, 5*time.Second)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(receiver.URL, 2)}}
+		dispatcher := webhook.NewDispatcher(queue, opts, logger.NewTestLogger(t))
+
+		_, err := dispatcher.DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, queue.recorded, 1)
+		assert.False(t, queue.recorded[0].Succeeded)
+		assert.Nil(t, queue.recorded[0].NextAttemptAt)
+	})
+
+	t.Run("unreachable endpoint", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(closed.URL, 0)}}
+		dispatcher := webhook.NewDispatcher(queue, opts, logger.NewTestLogger(t))
+
+		_, err := dispatcher.DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, queue.recorded, 1)
+		assert.Zero(t, queue.recorded[0].StatusCode)
+		assert.NotEmpty(t, queue.recorded[0].Error)
+		assert.NotNil(t, queue.recorded[0].NextAttemptAt)
+	})
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect should not be followed")
+	}))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(receiver.URL, 0)}}
+	dispatcher := webhook.NewDispatcher(queue, webhook.Options{Targets: loopbackTargets}, logger.NewTestLogger(t))
+
+	_, err := dispatcher.DispatchDue(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, queue.recorded, 1)
+	assert.False(t, queue.recorded[0].Succeeded)
+	assert.Equal(t, http.StatusTemporaryRedirect, queue.recorded[0].StatusCode)
+}
+
+func TestDispatcher_RefusesInternalTargets(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("internal address should not be reached")
+	}))
+	defer receiver.Close()
+
+	t.Run("checks the dialed address", func(t *testing.T) {
+		// http is allowed here, so only the connect-time check stands in
+		// the way, as when DNS changes after registration.
+		queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(receiver.URL, 0)}}
+		dispatcher := webhook.NewDispatcher(queue, webhook.Options{Targets: webhook.TargetPolicy{AllowHTTP: true}}, logger.NewTestLogger(t))
+
+		_, err := dispatcher.DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, queue.recorded, 1)
+		assert.False(t, queue.recorded[0].Succeeded)
+		assert.Zero(t, queue.recorded[0].StatusCode)
+		assert.Contains(t, queue.recorded[0].Error, webhook.ErrForbiddenAddress.Error())
+	})
+
+	t.Run("requires https by default", func(t *testing.T) {
+		queue := &fakeQueue{pending: []*repositories.ClaimedDelivery{newClaimedDelivery(receiver.URL, 0)}}
+		dispatcher := webhook.NewDispatcher(queue, webhook.Options{}, logger.NewTestLogger(t))
+
+		_, err := dispatcher.DispatchDue(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, queue.recorded, 1)
+		assert.Equal(t, webhook.ErrInsecureURL.Error(), queue.recorded[0].Error)
+	})
+}
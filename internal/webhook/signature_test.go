@@ -0,0 +1,43 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/espennoreng/go-http-rental-server/internal/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"member.added"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := webhook.Sign("secret", signedAt, body)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", "secret", header, body, signedAt.Add(time.Minute), nil},
+		{"wrong secret", "other", header, body, signedAt, webhook.ErrInvalidSignature},
+		{"tampered body", "secret", header, []byte(`{"type":"member.removed"}`), signedAt, webhook.ErrInvalidSignature},
+		{"too old", "secret", header, body, signedAt.Add(10 * time.Minute), webhook.ErrSignatureExpired},
+		{"malformed", "secret", "v1=abc", body, signedAt, webhook.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.header, tt.body, tt.now, 5*time.Minute)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+var (
+	ErrInsecureURL = errors.New("webhook URL must use https")
+	// ErrForbiddenAddress keeps webhooks from reaching the server's own
+	// network, such as cloud metadata endpoints or internal services.
+	ErrForbiddenAddress = errors.New("webhook URL must not resolve to a loopback, private, shared, link-local, unspecified or multicast address")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598. Cloud
+// providers use it for internal networks, but IsPrivate does not cover it.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Resolver looks up the addresses of a host. *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// TargetPolicy decides which URLs webhooks may be sent to. The zero value
+// allows only https URLs on public addresses.
+type TargetPolicy struct {
+	// AllowHTTP permits plain http URLs, for development.
+	AllowHTTP bool
+	// Allow lists networks that are trusted even though they are not
+	// public, such as a receiver on the server's own internal network.
+	Allow []netip.Prefix
+	// Resolver defaults to net.DefaultResolver.
+	Resolver Resolver
+}
+
+// CheckURL returns ErrInsecureURL or ErrForbiddenAddress if webhooks may
+// not be sent to u, or the lookup error if its host does not resolve.
+// Every address the host resolves to must be allowed, since the one
+// dialed is not known in advance.
+func (p TargetPolicy) CheckURL(ctx context.Context, u *url.URL) error {
+	if err := p.checkScheme(u); err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(addr)
+	}
+
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := p.CheckAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckAddr returns ErrForbiddenAddress unless addr is public or in one
+// of the allowed networks.
+func (p TargetPolicy) CheckAddr(addr netip.Addr) error {
+	// IPv4-mapped IPv6 addresses such as ::ffff:127.0.0.1 reach the IPv4
+	// address, so they are judged as one.
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range p.Allow {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) ||
+		addr.IsUnspecified() || addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func (p TargetPolicy) checkScheme(u *url.URL) error {
+	if u.Scheme == "https" || (p.AllowHTTP && u.Scheme == "http") {
+		return nil
+	}
+	return ErrInsecureURL
+}
+
+// dialControl checks the address a connection is about to be made to. It
+// runs after DNS resolution, so a host that resolved to a public address
+// at registration cannot be pointed at an internal one later.
+func (p TargetPolicy) dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	return p.CheckAddr(addrPort.Addr())
+}
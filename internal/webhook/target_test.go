@@ -0,0 +1,51 @@
+package webhook_test
+
+import (
+	"context"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/espennoreng/go-http-rental-server/internal/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetPolicy_CheckAddr(t *testing.T) {
+	var policy webhook.TargetPolicy
+
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "::ffff:127.0.0.1", // loopback
+		"10.0.0.1", "172.31.255.255", "192.168.0.1", "fc00::1", // private
+		"100.64.0.1", "100.127.255.255", // shared address space
+		"169.254.169.254", "fe80::1%eth0", // link-local
+		"0.0.0.0", "::", // unspecified
+		"224.0.0.1", "239.255.255.250", "ff02::1", // multicast
+	} {
+		assert.ErrorIs(t, policy.CheckAddr(netip.MustParseAddr(addr)), webhook.ErrForbiddenAddress, addr)
+	}
+
+	for _, addr := range []string{"93.184.216.34", "100.128.0.1", "2606:2800:220:1:248:1893:25c8:1946"} {
+		assert.NoError(t, policy.CheckAddr(netip.MustParseAddr(addr)), addr)
+	}
+
+	t.Run("allowed networks", func(t *testing.T) {
+		policy := webhook.TargetPolicy{Allow: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}}
+
+		assert.NoError(t, policy.CheckAddr(netip.MustParseAddr("10.20.1.2")))
+		assert.ErrorIs(t, policy.CheckAddr(netip.MustParseAddr("10.21.1.2")), webhook.ErrForbiddenAddress)
+	})
+}
+
+func TestTargetPolicy_CheckURL(t *testing.T) {
+	check := func(policy webhook.TargetPolicy, raw string) error {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return policy.CheckURL(context.Background(), u)
+	}
+
+	assert.ErrorIs(t, check(webhook.TargetPolicy{}, "http://93.184.216.34/hooks"), webhook.ErrInsecureURL)
+	assert.NoError(t, check(webhook.TargetPolicy{AllowHTTP: true}, "http://93.184.216.34/hooks"))
+	assert.ErrorIs(t, check(webhook.TargetPolicy{}, "https://[::ffff:a9fe:a9fe]/latest/meta-data/"), webhook.ErrForbiddenAddress)
+}
@@ -0,0 +1,5 @@
+ALTER TABLE organization_users
+DROP COLUMN version;
+
+ALTER TABLE organizations
+DROP COLUMN version;
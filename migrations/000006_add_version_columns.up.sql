@@ -0,0 +1,5 @@
+ALTER TABLE organizations
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE organization_users
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
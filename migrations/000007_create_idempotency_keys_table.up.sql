@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id UUID NOT NULL,
+	idempotency_key TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	status_code INTEGER,
+	response_headers JSONB,
+	response_body BYTEA,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMPTZ NOT NULL,
+
+	PRIMARY KEY (user_id, idempotency_key, method, path),
+
+	FOREIGN KEY (user_id)
+		REFERENCES users(id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	organization_id UUID NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT[] NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_by UUID NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	FOREIGN KEY (organization_id)
+		REFERENCES organizations(id)
+		ON DELETE CASCADE,
+	FOREIGN KEY (created_by)
+		REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_organization_id ON webhooks (organization_id);
+
+-- webhook_deliveries is both the delivery log and the durable queue the
+-- dispatcher claims pending rows from.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	webhook_id UUID NOT NULL,
+	event_id UUID NOT NULL,
+	event_type TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_attempt_at TIMESTAMPTZ,
+	last_status_code INTEGER,
+	last_error TEXT,
+	delivered_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	UNIQUE (webhook_id, event_id),
+
+	FOREIGN KEY (webhook_id)
+		REFERENCES webhooks(id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at DESC);
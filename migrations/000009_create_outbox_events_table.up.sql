@@ -0,0 +1,18 @@
+-- outbox_events is written in the same transaction as the change it
+-- describes and drained by the outbox dispatcher. seq orders events and
+-- survives after publication so consumers can resume from a position.
+CREATE TABLE IF NOT EXISTS outbox_events (
+	seq BIGSERIAL PRIMARY KEY,
+	id UUID NOT NULL UNIQUE,
+	organization_id UUID NOT NULL,
+	event_type TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	occurred_at TIMESTAMPTZ NOT NULL,
+	published_at TIMESTAMPTZ,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events (next_attempt_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_organization_id ON outbox_events (organization_id, seq);
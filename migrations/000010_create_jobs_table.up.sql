@@ -0,0 +1,25 @@
+-- jobs is the background job queue. Workers claim due pending jobs with
+-- FOR UPDATE SKIP LOCKED; a running job whose lease has expired belongs to a
+-- worker that died and is claimed again. Jobs that exhaust their attempts
+-- stay in the dead state until an operator retries them.
+CREATE TABLE IF NOT EXISTS jobs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	kind TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'succeeded', 'dead')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL CHECK (max_attempts > 0),
+	run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMPTZ,
+	last_error TEXT,
+	-- unique_key deduplicates jobs enqueued by several replicas, such as
+	-- one firing of a cron schedule.
+	unique_key TEXT UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_leased ON jobs (locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs (status, created_at DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD COLUMN notification_preferences JSONB NOT NULL DEFAULT '{}'::jsonb;
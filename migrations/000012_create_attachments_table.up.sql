@@ -0,0 +1,21 @@
+-- attachments holds the metadata of files kept in the blob store. The
+-- blobs themselves are addressed by storage_key and thumbnail_key.
+CREATE TABLE IF NOT EXISTS attachments (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	organization_id UUID NOT NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL CHECK (size >= 0),
+	storage_key TEXT NOT NULL UNIQUE,
+	thumbnail_key TEXT,
+	created_by UUID NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	FOREIGN KEY (organization_id)
+		REFERENCES organizations(id)
+		ON DELETE CASCADE,
+	FOREIGN KEY (created_by)
+		REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_organization_id ON attachments (organization_id, created_at DESC);
@@ -0,0 +1,23 @@
+-- locations are the depots of an organization. Opening hours are stored
+-- as a JSON array of {day, opens, closes} periods in the location's time
+-- zone.
+CREATE TABLE IF NOT EXISTS locations (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	organization_id UUID NOT NULL,
+	name TEXT NOT NULL,
+	street TEXT NOT NULL,
+	postal_code TEXT NOT NULL DEFAULT '',
+	city TEXT NOT NULL,
+	country TEXT NOT NULL,
+	time_zone TEXT NOT NULL,
+	opening_hours JSONB NOT NULL DEFAULT '[]',
+	version INTEGER NOT NULL DEFAULT 1,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	UNIQUE (organization_id, name),
+
+	FOREIGN KEY (organization_id)
+		REFERENCES organizations(id)
+		ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- currency is the ISO 4217 code an organization prices and invoices in.
+ALTER TABLE organizations
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'NOK';
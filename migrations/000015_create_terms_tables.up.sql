@@ -0,0 +1,40 @@
+-- terms are the published versions of an organization's rental terms.
+-- Versions are never edited; publishing new terms adds the next version.
+CREATE TABLE IF NOT EXISTS terms (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	organization_id UUID NOT NULL,
+	version INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	body TEXT NOT NULL,
+	-- content_hash is the hex SHA-256 of body.
+	content_hash TEXT NOT NULL,
+	created_by UUID NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	UNIQUE (organization_id, version),
+
+	FOREIGN KEY (organization_id)
+		REFERENCES organizations(id)
+		ON DELETE CASCADE,
+	FOREIGN KEY (created_by)
+		REFERENCES users(id)
+);
+
+-- terms_acceptances record who accepted which version, from where, and
+-- the hash of the text they were shown. They are kept as evidence, so
+-- neither the terms nor the user can be deleted while one exists.
+CREATE TABLE IF NOT EXISTS terms_acceptances (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	terms_id UUID NOT NULL,
+	user_id UUID NOT NULL,
+	ip_address TEXT NOT NULL,
+	content_hash TEXT NOT NULL,
+	accepted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	UNIQUE (terms_id, user_id),
+
+	FOREIGN KEY (terms_id)
+		REFERENCES terms(id),
+	FOREIGN KEY (user_id)
+		REFERENCES users(id)
+);
@@ -0,0 +1,27 @@
+-- customers are external renters of an organization. Unlike members in
+-- organization_users, they have no access to the organization's internals.
+-- The address columns are empty when no address was given.
+CREATE TABLE IF NOT EXISTS customers (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	organization_id UUID NOT NULL,
+	user_id UUID NOT NULL,
+	phone TEXT NOT NULL DEFAULT '',
+	street TEXT NOT NULL DEFAULT '',
+	postal_code TEXT NOT NULL DEFAULT '',
+	city TEXT NOT NULL DEFAULT '',
+	country TEXT NOT NULL DEFAULT '',
+	verification_status TEXT NOT NULL DEFAULT 'unverified'
+		CHECK (verification_status IN ('unverified', 'pending', 'verified', 'rejected')),
+	version INTEGER NOT NULL DEFAULT 1,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	UNIQUE (organization_id, user_id),
+
+	FOREIGN KEY (organization_id)
+		REFERENCES organizations(id)
+		ON DELETE CASCADE,
+	FOREIGN KEY (user_id)
+		REFERENCES users(id)
+		ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE organizations
+DROP COLUMN public_catalog,
+DROP COLUMN slug;
@@ -0,0 +1,5 @@
+-- slug names an organization in public URLs. It is optional, since only
+-- organizations that publish a public catalog need one.
+ALTER TABLE organizations
+ADD COLUMN slug TEXT UNIQUE CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$'),
+ADD COLUMN public_catalog BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_outbox_events_organization_position;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_organization_id ON outbox_events (organization_id, seq);
+
+ALTER TABLE outbox_events DROP COLUMN position;
+DROP SEQUENCE IF EXISTS outbox_events_position_seq;
@@ -0,0 +1,15 @@
+-- position orders events by when they became visible, which seq cannot:
+-- seq is taken at INSERT, so a transaction holding a lower seq can commit
+-- after one holding a higher seq. Dispatchers assign positions while
+-- holding an advisory lock, so positions increase in commit order and a
+-- reader that has seen position N will never be shown a lower one later.
+CREATE SEQUENCE IF NOT EXISTS outbox_events_position_seq;
+
+ALTER TABLE outbox_events ADD COLUMN position BIGINT UNIQUE;
+
+-- Events stored so far are committed, so seq is a safe position for them.
+UPDATE outbox_events SET position = seq;
+SELECT setval('outbox_events_position_seq', COALESCE(MAX(position), 0) + 1, false) FROM outbox_events;
+
+DROP INDEX IF EXISTS idx_outbox_events_organization_id;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_organization_position ON outbox_events (organization_id, position) WHERE position IS NOT NULL;
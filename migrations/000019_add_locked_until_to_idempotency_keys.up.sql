@@ -0,0 +1,4 @@
+-- locked_until bounds how long a request may hold its key without storing
+-- a response. A retry after that takes the key over, so a request that
+-- crashed or timed out does not block its key until expires_at.
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMPTZ NOT NULL DEFAULT NOW();